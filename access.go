@@ -16,6 +16,20 @@
 
 package owid
 
+// Scope of an operation that requires an access key.
+type Scope string
+
+// Scopes for each of the operations that require access control.
+const (
+	ScopeRegister Scope = "register" // Register a new creator
+	ScopeSign     Scope = "sign"     // Sign an OWID with a creator's key
+	ScopeAdmin    Scope = "admin"    // Change the status of a creator
+)
+
+// accessKeyHeader is the HTTP header that carries the access key for end
+// points that don't accept it as a parameter.
+const accessKeyHeader = "X-OWID-Access-Key"
+
 // Access interface for validating entitlement to access the network.
 type Access interface {
 
@@ -24,3 +38,15 @@ type Access interface {
 	// provide the reason.
 	GetAllowed(accessKey string) (bool, error)
 }
+
+// AccessScoped interface for validating entitlement to perform a specific
+// operation. If the Access implementation used with Services also implements
+// AccessScoped then GetAllowedScope is used in preference to GetAllowed.
+type AccessScoped interface {
+	Access
+
+	// GetAllowedScope returns true if the accessKey is allowed to perform
+	// operations with the scope, otherwise false. If false is returned then
+	// the error will provide the reason.
+	GetAllowedScope(accessKey string, scope Scope) (bool, error)
+}
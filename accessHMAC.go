@@ -0,0 +1,108 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessHMAC is an implementation of AccessScoped where the access key is a
+// token containing the scope and expiry time signed with a shared secret. This
+// avoids the need to distribute and store a list of keys for each scope.
+type AccessHMAC struct {
+	secret []byte // The shared secret used to sign tokens
+	clock  Clock  // Clock used to check expiry, or nil for the system clock
+}
+
+// NewAccessHMAC creates a new instance of the AccessHMAC structure using the
+// shared secret provided.
+func NewAccessHMAC(secret []byte) *AccessHMAC {
+	var a AccessHMAC
+	a.secret = secret
+	return &a
+}
+
+// SetClock sets the clock used to check whether tokens have expired. Nil uses
+// the system clock.
+func (a *AccessHMAC) SetClock(c Clock) { a.clock = c }
+
+// NewToken returns a new access token for the scope which expires at the time
+// provided.
+func (a *AccessHMAC) NewToken(scope Scope, expires time.Time) string {
+	p := fmt.Sprintf("%s.%d", scope, expires.Unix())
+	return p + "." + base64.RawURLEncoding.EncodeToString(a.mac(p))
+}
+
+// GetAllowed returns true if the token is signed with the shared secret and
+// has not expired, irrespective of the scope.
+func (a *AccessHMAC) GetAllowed(accessKey string) (bool, error) {
+	_, err := a.parse(accessKey)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetAllowedScope returns true if the token is signed with the shared secret,
+// has not expired and was issued for the scope.
+func (a *AccessHMAC) GetAllowedScope(
+	accessKey string,
+	scope Scope) (bool, error) {
+	s, err := a.parse(accessKey)
+	if err != nil {
+		return false, err
+	}
+	if s != scope {
+		return false, fmt.Errorf("token scope '%s' not '%s'", s, scope)
+	}
+	return true, nil
+}
+
+// parse validates the token returning the scope it was issued for.
+func (a *AccessHMAC) parse(token string) (Scope, error) {
+	p := strings.Split(token, ".")
+	if len(p) != 3 {
+		return "", fmt.Errorf("token invalid")
+	}
+	m, err := base64.RawURLEncoding.DecodeString(p[2])
+	if err != nil {
+		return "", err
+	}
+	if hmac.Equal(m, a.mac(p[0]+"."+p[1])) == false {
+		return "", fmt.Errorf("token signature invalid")
+	}
+	e, err := strconv.ParseInt(p[1], 10, 64)
+	if err != nil {
+		return "", err
+	}
+	if clockNow(a.clock).Unix() > e {
+		return "", fmt.Errorf("token expired")
+	}
+	return Scope(p[0]), nil
+}
+
+func (a *AccessHMAC) mac(p string) []byte {
+	h := hmac.New(sha256.New, a.secret)
+	h.Write([]byte(p))
+	return h.Sum(nil)
+}
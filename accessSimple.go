@@ -19,7 +19,8 @@ package owid
 // AccessSimple is a implementation of swift.Access for testing where a list
 // of keys returns true, and all others return false.
 type AccessSimple struct {
-	validKeys map[string]bool // A list of the keys that are valid.
+	validKeys  map[string]bool           // A list of the keys that are valid.
+	scopedKeys map[Scope]map[string]bool // Keys that are valid for a scope.
 }
 
 // NewAccessSimple creates a new instance of the AccessSimple structure
@@ -31,12 +32,40 @@ func NewAccessSimple(validKeys []string) *AccessSimple {
 		m[k] = true
 	}
 	a.validKeys = m
+	a.scopedKeys = make(map[Scope]map[string]bool)
 
 	return &a
 }
 
+// NewAccessSimpleScoped creates a new instance of the AccessSimple structure
+// where each scope has a distinct list of valid keys. Scopes without keys are
+// refused, and no key is valid for GetAllowed.
+func NewAccessSimpleScoped(scopedKeys map[Scope][]string) *AccessSimple {
+	a := NewAccessSimple(nil)
+	for s, ks := range scopedKeys {
+		m := make(map[string]bool)
+		for _, k := range ks {
+			m[k] = true
+		}
+		a.scopedKeys[s] = m
+	}
+	return a
+}
+
 // GetAllowed validates access key can access swift handlers
 func (a *AccessSimple) GetAllowed(accessKey string) (bool, error) {
-	return a.validKeys[accessKey], nil
+	return containsConstantTime(a.validKeys, accessKey), nil
 
 }
+
+// GetAllowedScope validates the access key can perform operations with the
+// scope. If scoped keys have been provided then only the keys for the scope
+// are valid. Otherwise the keys valid for GetAllowed are used.
+func (a *AccessSimple) GetAllowedScope(
+	accessKey string,
+	scope Scope) (bool, error) {
+	if len(a.scopedKeys) > 0 {
+		return containsConstantTime(a.scopedKeys[scope], accessKey), nil
+	}
+	return a.GetAllowed(accessKey)
+}
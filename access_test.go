@@ -0,0 +1,147 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestAccessSimpleScoped(t *testing.T) {
+	a := NewAccessSimpleScoped(map[Scope][]string{
+		ScopeRegister: {"register"},
+		ScopeSign:     {"sign"}})
+	testAccessScope(t, a, "register", ScopeRegister, true)
+	testAccessScope(t, a, "register", ScopeSign, false)
+	testAccessScope(t, a, "sign", ScopeSign, true)
+	testAccessScope(t, a, "sign", ScopeRegister, false)
+	testAccessScope(t, a, "other", ScopeSign, false)
+	testAccessScope(t, a, "sign", ScopeAdmin, false)
+	testAccessScope(t, a, "register", ScopeAdmin, false)
+	v, _ := a.GetAllowed("sign")
+	if v {
+		t.Error("scoped key should not be valid without a scope")
+	}
+}
+
+func TestAccessSimpleUnscoped(t *testing.T) {
+	a := NewAccessSimple([]string{"key1"})
+	testAccessScope(t, a, "key1", ScopeRegister, true)
+	testAccessScope(t, a, "key1", ScopeSign, true)
+	testAccessScope(t, a, "key2", ScopeSign, false)
+}
+
+func TestAccessHMAC(t *testing.T) {
+	a := NewAccessHMAC([]byte("secret"))
+	k := a.NewToken(ScopeSign, time.Now().Add(time.Minute))
+	testAccessScope(t, a, k, ScopeSign, true)
+	testAccessScope(t, a, k, ScopeRegister, false)
+	testAccessScope(t, a, k[:len(k)-1], ScopeSign, false)
+	e := a.NewToken(ScopeSign, time.Now().Add(-time.Minute))
+	testAccessScope(t, a, e, ScopeSign, false)
+	o := NewAccessHMAC([]byte("other"))
+	testAccessScope(t, o, k, ScopeSign, false)
+}
+
+// TestRegisterHandlerAccessDenied checks that the register handler refuses
+// requests without a valid access key for the register scope.
+func TestRegisterHandlerAccessDenied(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.access = NewAccessSimpleScoped(map[Scope][]string{
+		ScopeRegister: {"key2"},
+		ScopeSign:     {"key1"}})
+	req, err := http.NewRequest("GET", "/owid/register?accesskey=key1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = registerDomain
+	rr := httptest.NewRecorder()
+	HandlerRegister(s).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNetworkAuthenticationRequired {
+		t.Errorf("expected status '%d', found '%d'",
+			http.StatusNetworkAuthenticationRequired,
+			rr.Code)
+	}
+	c, err := s.store.GetCreator(registerDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != nil {
+		t.Error("creator should not have been registered")
+	}
+}
+
+func testAccessScope(
+	t *testing.T,
+	a AccessScoped,
+	k string,
+	s Scope,
+	expected bool) {
+	v, _ := a.GetAllowedScope(k, s)
+	if v != expected {
+		t.Errorf("key '%s' scope '%s' expected '%t'", k, s, expected)
+	}
+}
+
+// TestRegisterHandlerInvalidDomain checks that hosts that can't be registered
+// are refused with a bad request.
+func TestRegisterHandlerInvalidDomain(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", "/owid/register?accesskey=key1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "localhost:8080"
+	rr := httptest.NewRecorder()
+	HandlerRegister(s).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status '%d', found '%d'",
+			http.StatusBadRequest,
+			rr.Code)
+	}
+}
+
+// TestStatusHandlerSignKeyDenied checks that a key only valid for signing
+// can't use the admin end points.
+func TestStatusHandlerSignKeyDenied(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.access = NewAccessSimpleScoped(map[Scope][]string{
+		ScopeSign: {"key1"}})
+	rr := post(
+		t,
+		HandlerCreatorStatus(s),
+		testDomain,
+		"/owid/api/v1/status",
+		url.Values{"status": {string(CreatorSuspended)}})
+	if rr.Code != http.StatusNetworkAuthenticationRequired {
+		t.Errorf("expected status '%d', found '%d'",
+			http.StatusNetworkAuthenticationRequired,
+			rr.Code)
+	}
+}
@@ -0,0 +1,142 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Prefixes of the ads.cert DNS records. The delivery policy record names the
+// ads.cert identity domain and the key record on that domain holds the call
+// sign public keys.
+const (
+	adsCertDeliveryPrefix = "_delivery._adscert."
+	adsCertKeyPrefix      = "_adscert."
+)
+
+// Versions in the v= field of the ads.cert records.
+const (
+	adsCertDeliveryVersion = "adpf"
+	adsCertKeyVersion      = "adcrtd"
+)
+
+// AdsCertRecord contains the ads.cert call sign keys published for a domain.
+type AdsCertRecord struct {
+	Domain         string   `json:"domain"`         // Domain the records were looked up for
+	IdentityDomain string   `json:"identityDomain"` // Domain publishing the keys
+	Algorithm      string   `json:"algorithm"`      // Key algorithm, for example x25519
+	Keys           []string `json:"keys"`           // Base 64 public keys
+}
+
+// LookupAdsCert returns the ads.cert keys for the domain. The delivery policy
+// record names the identity domain holding the keys. If there is no policy
+// record the domain itself is used. Any port is ignored.
+func LookupAdsCert(ctx context.Context, domain string) (*AdsCertRecord, error) {
+	h := hostDomain(domain)
+	r := AdsCertRecord{Domain: domain, IdentityDomain: h}
+	rs, err := lookupTXT(ctx, adsCertDeliveryPrefix+h)
+	if err == nil {
+		for _, t := range rs {
+			f := parseAdsCertRecord(t)
+			if f["v"] == adsCertDeliveryVersion && f["a"] != "" {
+				r.IdentityDomain = strings.Split(f["a"], ",")[0]
+				break
+			}
+		}
+	}
+	rs, err = lookupTXT(ctx, adsCertKeyPrefix+r.IdentityDomain)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"ads.cert keys for '%s' not found: %s",
+			r.IdentityDomain,
+			err.Error())
+	}
+	for _, t := range rs {
+		f := parseAdsCertRecord(t)
+		if f["v"] == adsCertKeyVersion && f["p"] != "" {
+			r.Algorithm = f["k"]
+			r.Keys = append(r.Keys, f["p"])
+		}
+	}
+	if len(r.Keys) == 0 {
+		return nil, fmt.Errorf(
+			"ads.cert keys for '%s' not found",
+			r.IdentityDomain)
+	}
+	return &r, nil
+}
+
+// parseAdsCertRecord returns the key value pairs separated by spaces in the
+// TXT record.
+func parseAdsCertRecord(t string) map[string]string {
+	f := make(map[string]string)
+	for _, p := range strings.Fields(t) {
+		k, v, ok := strings.Cut(p, "=")
+		if ok {
+			f[k] = v
+		}
+	}
+	return f
+}
+
+// TrustReport combines the outcome of verifying an OWID with the ads.cert
+// records for its domain. Buyers that require both mechanisms should only
+// trust the OWID if Trusted is true.
+type TrustReport struct {
+	Domain              string         `json:"domain"`              // Domain of the OWID
+	OWID                *VerifyReport  `json:"owid"`                // Outcome of verifying the OWID
+	OWIDError           string         `json:"owidError"`           // Error verifying the OWID, or empty
+	AdsCert             *AdsCertRecord `json:"adsCert"`             // ads.cert keys, or nil if not found
+	AdsCertError        string         `json:"adsCertError"`        // Error finding the keys, or empty
+	OrganizationMatched bool           `json:"organizationMatched"` // True if both are for the same organization
+	Trusted             bool           `json:"trusted"`             // True if the OWID is valid and both agree
+}
+
+// TrustReport verifies the OWID and cross-checks its domain against the
+// ads.cert records. The organizations match if the OWID's domain, the domain
+// in the creator's public information and the ads.cert identity domain have
+// the same registrable domain. Errors from either mechanism are recorded in
+// the report.
+func (v *Verifier) TrustReport(
+	ctx context.Context,
+	o *OWID,
+	others ...*OWID) *TrustReport {
+	t := TrustReport{Domain: o.Domain}
+	var err error
+	t.OWID, err = v.VerifyWithReportContext(ctx, o, others...)
+	if err != nil {
+		t.OWIDError = err.Error()
+	}
+	t.AdsCert, err = LookupAdsCert(ctx, o.Domain)
+	if err != nil {
+		t.AdsCertError = err.Error()
+	}
+	if t.AdsCert != nil {
+		d := registrableDomain(hostDomain(o.Domain))
+		t.OrganizationMatched =
+			registrableDomain(t.AdsCert.IdentityDomain) == d &&
+				(t.OWID.CreatorDomain == "" ||
+					registrableDomain(hostDomain(t.OWID.CreatorDomain)) == d)
+	}
+	t.Trusted = t.OWIDError == "" &&
+		t.OWID.Valid &&
+		t.AdsCert != nil &&
+		t.OrganizationMatched
+	return &t
+}
@@ -0,0 +1,82 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestTrustReport checks an OWID is only trusted when the ads.cert keys are
+// published by the same organization.
+func TestTrustReport(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := map[string][]string{
+		"_adscert." + u.Hostname(): {
+			"v=adcrtd k=x25519 h=sha256 p=AAAA",
+			"unrelated"},
+		"_adscert.other.com": {"v=adcrtd k=x25519 h=sha256 p=BBBB"}}
+	l := lookupTXT
+	lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if v, ok := r[name]; ok {
+			return v, nil
+		}
+		return nil, errors.New("not found")
+	}
+	defer func() { lookupTXT = l }()
+	v := NewVerifier(u.Scheme, nil)
+	p := v.TrustReport(context.Background(), o)
+	if p.Trusted == false ||
+		len(p.AdsCert.Keys) != 1 ||
+		p.AdsCert.Algorithm != "x25519" {
+		t.Fatalf("expected trusted report '%v' '%s'", p.AdsCert, p.AdsCertError)
+	}
+	r["_delivery._adscert."+u.Hostname()] = []string{"v=adpf a=other.com"}
+	p = v.TrustReport(context.Background(), o)
+	if p.Trusted || p.OrganizationMatched || p.OWID.Valid == false {
+		t.Fatal("different organization should not be trusted")
+	}
+	delete(r, "_adscert.other.com")
+	p = v.TrustReport(context.Background(), o)
+	if p.Trusted || p.AdsCert != nil || p.AdsCertError == "" {
+		t.Fatal("missing ads.cert keys should not be trusted")
+	}
+}
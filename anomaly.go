@@ -0,0 +1,247 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// VerifyHook is called by a Verifier with the outcome of every verification so
+// that consumers can record metrics or detect anomalies. Age is the complete
+// minutes since the OWID was created and is negative for future dated OWIDs.
+// Err is the error returned by the verifier, or nil. Implementations must be
+// safe to call from multiple goroutines and should return quickly.
+type VerifyHook interface {
+	Verified(domain string, age int, valid bool, err error)
+}
+
+// VerifyHookFunc adapts a function to the VerifyHook interface.
+type VerifyHookFunc func(domain string, age int, valid bool, err error)
+
+// Verified calls the function.
+func (f VerifyHookFunc) Verified(domain string, age int, valid bool, err error) {
+	f(domain, age, valid, err)
+}
+
+// AnomalyKind is the type of anomaly found by the AnomalyDetector.
+type AnomalyKind string
+
+// Kinds of anomaly.
+const (
+	AnomalyFailureSpike  AnomalyKind = "failure-spike"  // Failure rate for a domain crossed the threshold
+	AnomalyImprobableAge AnomalyKind = "improbable-age" // OWID is future dated or older than the maximum age
+)
+
+// Anomaly describes something unusual seen by the AnomalyDetector.
+type Anomaly struct {
+	Domain string      // Domain of the OWIDs
+	Kind   AnomalyKind // Type of anomaly
+	Detail string      // Human readable explanation
+	Time   time.Time   // When the anomaly was detected
+}
+
+// AnomalyOptions control when the AnomalyDetector reports anomalies.
+type AnomalyOptions struct {
+	Window      time.Duration // Period failure rates are measured over
+	FailureRate float64       // Fraction of failures in the window that is a spike
+	MinSamples  int           // Verifications in the window before a spike is reported
+	MaxAge      time.Duration // Age above which an OWID is improbable, or zero for no limit
+}
+
+// Defaults used for options that are not set.
+const (
+	defaultAnomalyWindow      = 5 * time.Minute
+	defaultAnomalyFailureRate = 0.5
+	defaultAnomalyMinSamples  = 20
+)
+
+// Upper bounds in minutes of the age buckets, and the names of the buckets
+// including the last which holds all older OWIDs.
+var (
+	anomalyAgeBounds = []int{1, 60, 1440, 10080}
+	anomalyAgeNames  = []string{"1m", "1h", "1d", "7d", "older"}
+)
+
+// Name of the age bucket for future dated OWIDs.
+const anomalyAgeFuture = "future"
+
+// AnomalyMetrics describes the verifications seen for each domain.
+type AnomalyMetrics struct {
+	Domains map[string]AnomalyDomainMetrics // Metrics keyed on domain
+}
+
+// AnomalyDomainMetrics are the totals for a single domain.
+type AnomalyDomainMetrics struct {
+	Verified uint64            // Total verifications
+	Failed   uint64            // Verifications that were invalid or errored
+	Ages     map[string]uint64 // Verifications by age bucket
+	Spiking  bool              // True if the failure rate is above the threshold
+}
+
+// anomalyDomain is the rolling window and totals for a single domain.
+type anomalyDomain struct {
+	times    []time.Time // Times of verifications in the window
+	failures []bool      // True for the verifications in times that failed
+	metrics  AnomalyDomainMetrics
+}
+
+// AnomalyDetector is a VerifyHook that keeps a rolling window of outcomes for
+// each domain. It reports a spike when the failure rate for a domain crosses
+// the threshold, and again only after the rate has fallen back below it. OWIDs
+// with improbable ages are reported individually.
+type AnomalyDetector struct {
+	mutex     sync.Mutex
+	options   AnomalyOptions
+	onAnomaly func(*Anomaly) // Called for every anomaly
+	clock     Clock          // Clock used for the window, or nil for the system clock
+	domains   map[string]*anomalyDomain
+}
+
+// NewAnomalyDetector creates a detector with the options that calls the
+// function for every anomaly. Options that are zero use defaults.
+func NewAnomalyDetector(
+	o AnomalyOptions,
+	onAnomaly func(*Anomaly)) *AnomalyDetector {
+	if o.Window <= 0 {
+		o.Window = defaultAnomalyWindow
+	}
+	if o.FailureRate <= 0 {
+		o.FailureRate = defaultAnomalyFailureRate
+	}
+	if o.MinSamples <= 0 {
+		o.MinSamples = defaultAnomalyMinSamples
+	}
+	return &AnomalyDetector{
+		options:   o,
+		onAnomaly: onAnomaly,
+		domains:   make(map[string]*anomalyDomain)}
+}
+
+// SetClock sets the clock used for the rolling window. Nil uses the system
+// clock.
+func (a *AnomalyDetector) SetClock(c Clock) { a.clock = c }
+
+// Verified records the outcome and reports any anomalies.
+func (a *AnomalyDetector) Verified(domain string, age int, valid bool, err error) {
+	n := clockNow(a.clock).UTC()
+	f := valid == false || err != nil
+	var as []*Anomaly
+	if age < 0 ||
+		(a.options.MaxAge > 0 && age > int(a.options.MaxAge.Minutes())) {
+		as = append(as, &Anomaly{
+			Domain: domain,
+			Kind:   AnomalyImprobableAge,
+			Detail: fmt.Sprintf("OWID age '%d' minutes", age),
+			Time:   n})
+	}
+	a.mutex.Lock()
+	d := a.domains[domain]
+	if d == nil {
+		d = &anomalyDomain{
+			metrics: AnomalyDomainMetrics{Ages: make(map[string]uint64)}}
+		a.domains[domain] = d
+	}
+	d.add(n, f, a.options.Window)
+	d.metrics.Ages[anomalyAgeBucket(age)]++
+	r := d.failureRate()
+	if r >= a.options.FailureRate && len(d.times) >= a.options.MinSamples {
+		if d.metrics.Spiking == false {
+			d.metrics.Spiking = true
+			as = append(as, &Anomaly{
+				Domain: domain,
+				Kind:   AnomalyFailureSpike,
+				Detail: fmt.Sprintf(
+					"'%d' of '%d' verifications failed in '%s'",
+					int(r*float64(len(d.times))+0.5),
+					len(d.times),
+					a.options.Window),
+				Time: n})
+		}
+	} else if r < a.options.FailureRate {
+		d.metrics.Spiking = false
+	}
+	a.mutex.Unlock()
+	if a.onAnomaly != nil {
+		for _, i := range as {
+			a.onAnomaly(i)
+		}
+	}
+}
+
+// Metrics returns a copy of the totals for each domain.
+func (a *AnomalyDetector) Metrics() AnomalyMetrics {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	m := AnomalyMetrics{
+		Domains: make(map[string]AnomalyDomainMetrics, len(a.domains))}
+	for k, d := range a.domains {
+		c := d.metrics
+		c.Ages = make(map[string]uint64, len(d.metrics.Ages))
+		for b, v := range d.metrics.Ages {
+			c.Ages[b] = v
+		}
+		m.Domains[k] = c
+	}
+	return m
+}
+
+// add records the outcome at the time and drops outcomes older than the
+// window.
+func (d *anomalyDomain) add(t time.Time, failed bool, window time.Duration) {
+	d.metrics.Verified++
+	if failed {
+		d.metrics.Failed++
+	}
+	d.times = append(d.times, t)
+	d.failures = append(d.failures, failed)
+	c := t.Add(-window)
+	i := 0
+	for i < len(d.times) && d.times[i].Before(c) {
+		i++
+	}
+	d.times = d.times[i:]
+	d.failures = d.failures[i:]
+}
+
+// failureRate returns the fraction of the outcomes in the window that failed.
+func (d *anomalyDomain) failureRate() float64 {
+	if len(d.failures) == 0 {
+		return 0
+	}
+	f := 0
+	for _, v := range d.failures {
+		if v {
+			f++
+		}
+	}
+	return float64(f) / float64(len(d.failures))
+}
+
+// anomalyAgeBucket returns the name of the bucket for the age in minutes.
+func anomalyAgeBucket(age int) string {
+	if age < 0 {
+		return anomalyAgeFuture
+	}
+	for i, b := range anomalyAgeBounds {
+		if age < b {
+			return anomalyAgeNames[i]
+		}
+	}
+	return anomalyAgeNames[len(anomalyAgeNames)-1]
+}
@@ -0,0 +1,108 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestAnomalyDetectorSpike checks a spike is reported once when the failure
+// rate crosses the threshold and again after it has recovered.
+func TestAnomalyDetectorSpike(t *testing.T) {
+	var as []*Anomaly
+	a := NewAnomalyDetector(
+		AnomalyOptions{Window: time.Minute, FailureRate: 0.5, MinSamples: 4},
+		func(i *Anomaly) { as = append(as, i) })
+	k := NewManualClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	a.SetClock(k)
+	for i := 0; i < 4; i++ {
+		a.Verified(testDomain, 0, true, nil)
+	}
+	for i := 0; i < 6; i++ {
+		a.Verified(testDomain, 0, false, nil)
+	}
+	if len(as) != 1 || as[0].Kind != AnomalyFailureSpike {
+		t.Fatalf("expected one spike but got '%d' anomalies", len(as))
+	}
+	if a.Metrics().Domains[testDomain].Spiking == false {
+		t.Fatal("domain should be spiking")
+	}
+	k.Advance(2 * time.Minute)
+	a.Verified(testDomain, 0, true, nil)
+	if a.Metrics().Domains[testDomain].Spiking {
+		t.Fatal("failures outside the window should be forgotten")
+	}
+	for i := 0; i < 4; i++ {
+		a.Verified(testDomain, 0, false, errors.New("failed"))
+	}
+	if len(as) != 2 {
+		t.Fatalf("expected a second spike but got '%d' anomalies", len(as))
+	}
+	m := a.Metrics().Domains[testDomain]
+	if m.Verified != 15 || m.Failed != 10 || m.Ages["1m"] != 15 {
+		t.Fatalf("unexpected metrics '%v'", m)
+	}
+}
+
+// TestAnomalyDetectorAge checks future dated and old OWIDs are reported and
+// counted in the right buckets.
+func TestAnomalyDetectorAge(t *testing.T) {
+	var as []*Anomaly
+	a := NewAnomalyDetector(
+		AnomalyOptions{MaxAge: 24 * time.Hour},
+		func(i *Anomaly) { as = append(as, i) })
+	a.Verified(testDomain, -10, true, nil)
+	a.Verified(testDomain, 90, true, nil)
+	a.Verified(testDomain, 2000, true, nil)
+	if len(as) != 2 ||
+		as[0].Kind != AnomalyImprobableAge ||
+		as[1].Kind != AnomalyImprobableAge {
+		t.Fatalf("expected two improbable ages but got '%d' anomalies", len(as))
+	}
+	m := a.Metrics().Domains[testDomain]
+	if m.Ages[anomalyAgeFuture] != 1 || m.Ages["1d"] != 1 || m.Ages["7d"] != 1 {
+		t.Fatalf("unexpected age buckets '%v'", m.Ages)
+	}
+}
+
+// TestVerifierHook checks the hook is called with the outcome of a refused
+// OWID.
+func TestVerifierHook(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Version = 9
+	var d string
+	var e error
+	v := NewVerifier("http", nil)
+	v.SetVerifyHook(VerifyHookFunc(
+		func(domain string, age int, valid bool, err error) {
+			d = domain
+			e = err
+		}))
+	v.Verify(o)
+	if d != testDomain || e == nil {
+		t.Fatal("hook not called with the outcome")
+	}
+}
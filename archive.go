@@ -0,0 +1,158 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// ArchivedKey is a public key that a creator no longer uses together with the
+// period that OWIDs were signed with it.
+type ArchivedKey struct {
+	Domain        string    `json:"domain"`        // Domain of the creator
+	PublicKeySPKI string    `json:"publicKeySPKI"` // The public key in SPKI form
+	Created       time.Time `json:"created"`       // When the key was created
+	Retired       time.Time `json:"retired"`       // When the key stopped being used
+}
+
+// covers returns true if an OWID dated t could have been signed with the key.
+func (k *ArchivedKey) covers(t time.Time) bool {
+	// OWID dates are truncated to the minute so allow for the key being
+	// created part way through the minute.
+	return t.Before(k.Created.Truncate(time.Minute)) == false &&
+		t.After(k.Retired) == false
+}
+
+// KeyArchive is implemented by stores of retired public keys used to verify
+// OWIDs signed before a creator's current key was created, for example in
+// audit and dispute workflows.
+type KeyArchive interface {
+
+	// ArchiveKey adds the key to the archive.
+	ArchiveKey(k *ArchivedKey) error
+
+	// GetArchivedKeys returns all the archived keys for the domain.
+	GetArchivedKeys(domain string) ([]*ArchivedKey, error)
+}
+
+// ArchiveCreatorKey records the creator's public key in the archive as retired
+// at the time provided. Call before a creator's keys are replaced or removed.
+func ArchiveCreatorKey(a KeyArchive, c *Creator, retired time.Time) error {
+	k, err := c.SubjectPublicKeyInfo()
+	if err != nil {
+		return err
+	}
+	return a.ArchiveKey(&ArchivedKey{
+		Domain:        c.domain,
+		PublicKeySPKI: k,
+		Created:       c.created,
+		Retired:       retired.UTC()})
+}
+
+// verifyArchivedKeys tries the archived keys for the OWID's domain that were in
+// use when the OWID was created returning the key that verified the OWID, or
+// an empty string if none did.
+func verifyArchivedKeys(
+	a KeyArchive,
+	o *OWID,
+	others []*OWID) (string, error) {
+	ks, err := a.GetArchivedKeys(o.Domain)
+	if err != nil {
+		return "", err
+	}
+	for _, k := range ks {
+		if k.covers(o.Date) == false {
+			continue
+		}
+		v, err := o.VerifyWithPublicKey(k.PublicKeySPKI, others...)
+		if err != nil {
+			return "", err
+		}
+		if v {
+			return k.PublicKeySPKI, nil
+		}
+	}
+	return "", nil
+}
+
+// KeyArchiveFile keeps archived keys in a file as JSON lines. Keys are only
+// ever appended.
+type KeyArchiveFile struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewKeyArchiveFile creates an archive that uses the file at the path,
+// creating the file when the first key is archived.
+func NewKeyArchiveFile(path string) *KeyArchiveFile {
+	return &KeyArchiveFile{path: path}
+}
+
+// ArchiveKey appends the key to the file as a single line of JSON.
+func (a *KeyArchiveFile) ArchiveKey(k *ArchivedKey) error {
+	if k.Domain == "" || k.PublicKeySPKI == "" {
+		return errors.New("archived key requires domain and public key")
+	}
+	j, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(j, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// GetArchivedKeys reads the file returning the keys for the domain.
+func (a *KeyArchiveFile) GetArchivedKeys(domain string) ([]*ArchivedKey, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	f, err := os.Open(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ks []*ArchivedKey
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var k ArchivedKey
+		err = json.Unmarshal(s.Bytes(), &k)
+		if err != nil {
+			return nil, err
+		}
+		if sameDomain(k.Domain, domain) {
+			ks = append(ks, &k)
+		}
+	}
+	return ks, s.Err()
+}
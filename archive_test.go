@@ -0,0 +1,71 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestKeyArchive replaces a creator's keys and checks that OWIDs signed with
+// the retired key only verify once the key has been archived.
+func TestKeyArchive(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewKeyArchiveFile(filepath.Join(t.TempDir(), "archive.jsonl"))
+	err = ArchiveCreatorKey(a, c, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(n)
+	v, err := s.Verify(testDomain, o)
+	if err != nil || v {
+		t.Fatalf("OWID verified without archive '%v'", err)
+	}
+	s.SetKeyArchive(a)
+	v, err = s.Verify(testDomain, o)
+	if err != nil || v == false {
+		t.Fatalf("OWID not verified with archive '%v'", err)
+	}
+	ks, err := a.GetArchivedKeys(testDomain)
+	if err != nil || len(ks) != 1 {
+		t.Fatalf("expected one archived key, found '%d'", len(ks))
+	}
+	o.Date = ks[0].Retired.Add(time.Hour)
+	if ks[0].covers(o.Date) {
+		t.Fatal("key should not cover dates after it was retired")
+	}
+	o.Date = ks[0].Created.Add(-time.Hour)
+	if ks[0].covers(o.Date) {
+		t.Fatal("key should not cover dates before it was created")
+	}
+}
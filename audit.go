@@ -0,0 +1,155 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditOperation is the lifecycle operation recorded in the audit log.
+type AuditOperation string
+
+// Operations recorded in the audit log.
+const (
+	AuditRegister AuditOperation = "register" // New creator and keys registered
+	AuditSuspend  AuditOperation = "suspend"  // Creator suspended from signing
+	AuditResume   AuditOperation = "resume"   // Suspended creator made active
+	AuditActivate AuditOperation = "activate" // Pending creator met the domain challenge
+	AuditVersion  AuditOperation = "version"  // Preferred OWID version changed
+
+	// AuditQuarantine is only sent to webhooks when a creator is excluded
+	// from a store because its record or keys could not be loaded.
+	AuditQuarantine AuditOperation = "quarantine"
+)
+
+// AuditEvent records a single change to a creator. The private key is never
+// included.
+type AuditEvent struct {
+	Operation AuditOperation `json:"operation"` // The operation performed
+	Domain    string         `json:"domain"`    // Domain of the creator
+	Actor     string         `json:"actor"`     // Identifier of the access key used
+	Timestamp time.Time      `json:"timestamp"` // When the operation completed
+	Before    *PublicCreator `json:"before"`    // State before, or nil if none
+	After     *PublicCreator `json:"after"`     // State after, or nil if none
+}
+
+// AuditSink is implemented by destinations for audit events. Implementations
+// must only ever append events.
+type AuditSink interface {
+
+	// Write appends the event to the audit log.
+	Write(e *AuditEvent) error
+}
+
+// AuditFile appends audit events to a file as JSON lines.
+type AuditFile struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewAuditFile creates a sink that appends events to the file at the path,
+// creating the file if it does not exist.
+func NewAuditFile(path string) *AuditFile {
+	return &AuditFile{path: path}
+}
+
+// Write appends the event to the file as a single line of JSON.
+func (a *AuditFile) Write(e *AuditEvent) error {
+	j, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(j, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AuditWebhook posts audit events as JSON to a URL.
+type AuditWebhook struct {
+	url string
+}
+
+// NewAuditWebhook creates a sink that posts each event to the URL.
+func NewAuditWebhook(url string) *AuditWebhook {
+	return &AuditWebhook{url: url}
+}
+
+// Write posts the event to the URL. An error is returned if the response is
+// not a success status code.
+func (a *AuditWebhook) Write(e *AuditEvent) error {
+	j, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	r, err := client.Post(a.url, "application/json", bytes.NewReader(j))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return fmt.Errorf(
+			"audit webhook '%s' return code '%d'",
+			a.url,
+			r.StatusCode)
+	}
+	return nil
+}
+
+// auditActor returns an identifier for the access key that can be recorded
+// without revealing the key.
+func auditActor(accessKey string) string {
+	if accessKey == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(accessKey))
+	return hex.EncodeToString(h[:8])
+}
+
+// auditCreator returns the public state of the creator for the audit log, or
+// nil if there is no creator.
+func auditCreator(c *Creator) *PublicCreator {
+	if c == nil {
+		return nil
+	}
+	k, _ := c.SubjectPublicKeyInfo()
+	return &PublicCreator{
+		Domain:        c.domain,
+		Name:          c.name,
+		PublicKeySPKI: k,
+		ContractURL:   c.contractURL,
+		Created:       c.created,
+		Email:         c.contact.Email,
+		DpoURL:        c.contact.DpoURL,
+		Jurisdiction:  c.contact.Jurisdiction,
+		Status:        string(c.status)}
+}
@@ -0,0 +1,92 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAuditFile registers a creator and checks the event appended to the file
+// contains the public state and not the access key.
+func TestAuditFile(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "audit.log")
+	s.SetAuditSink(NewAuditFile(p))
+	d := Register{
+		Services:    s,
+		Domain:      "audit." + testDomain,
+		Name:        testOrgName,
+		ContractURL: registerContractURL,
+		AccessKey:   "key"}
+	err = storeCreator(s, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(l) != 1 {
+		t.Fatalf("expected 1 event, found '%d'", len(l))
+	}
+	if strings.Contains(l[0], "PRIVATE") || strings.Contains(l[0], `"key"`) {
+		t.Fatal("audit event contains secrets")
+	}
+	var e AuditEvent
+	err = json.Unmarshal([]byte(l[0]), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Operation != AuditRegister || e.Domain != d.Domain {
+		t.Fatalf("unexpected event '%s' for '%s'", e.Operation, e.Domain)
+	}
+	if e.Before != nil || e.After == nil || e.After.Name != testOrgName {
+		t.Fatal("unexpected before or after state")
+	}
+	if e.Actor != auditActor("key") {
+		t.Fatalf("actor '%s' not expected", e.Actor)
+	}
+}
+
+// TestAuditWebhook checks that events are posted to the webhook.
+func TestAuditWebhook(t *testing.T) {
+	var e AuditEvent
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&e)
+		}))
+	defer h.Close()
+	err := NewAuditWebhook(h.URL).Write(&AuditEvent{
+		Operation: AuditRegister,
+		Domain:    testDomain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Domain != testDomain {
+		t.Fatalf("domain '%s' not received", e.Domain)
+	}
+}
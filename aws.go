@@ -1,3 +1,5 @@
+//go:build !js
+
 /* ****************************************************************************
  * Copyright 2020 51 Degrees Mobile Experts Limited (51degrees.com)
  *
@@ -17,55 +19,229 @@
 package owid
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
-	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 // Connect to AWS DynamoDB. Concrete implementation of store.go
 
+// The longest time to wait for a new table to become active.
+const awsTableWait = 2 * time.Minute
+
 // AWS is a implementation of owid.Store for Amazon's Dynamo DB storage.
 type AWS struct {
-	timestamp time.Time          // The last time the maps were refreshed
-	svc       *dynamodb.DynamoDB // Reference to the creators table
+	svc     *dynamodb.Client // Client for the creators table
+	table   string           // Name of the creators table
+	options AWSOptions       // Options used when creating the table
 	common
 }
 
+// AWSOptions control the connection to DynamoDB and the table used by the AWS
+// store. Empty values use the defaults from the environment and shared
+// configuration files.
+type AWSOptions struct {
+	Endpoint            string // URL of the endpoint, for example DynamoDB Local
+	Region              string // Region of the table
+	AccessKeyID         string // Access key ID, or empty for the default credentials
+	SecretAccessKey     string // Secret access key used with AccessKeyID
+	TablePrefix         string // Prefix added to the table name, for example the environment
+	SkipCreateTable     bool   // True if the table must already exist
+	PointInTimeRecovery bool   // True to enable point in time recovery on the table
+	TimeToLiveAttribute string // Attribute holding the Unix time items expire, or empty for no TTL
+}
+
 // Item is the dynamodb table item representation of a Creator
 type Item struct {
-	Owidcreator string
-	Domain      string
-	PrivateKey  string
-	PublicKey   string
-	Name        string
-	ContractURL string
+	Owidcreator  string
+	Domain       string
+	PrivateKey   string
+	PublicKey    string
+	Name         string
+	ContractURL  string
+	Created      time.Time
+	Email        string
+	DpoURL       string
+	Jurisdiction string
+	Status       string
+	Version      int       // Preferred OWID version, or zero for the default
+	Modified     time.Time // Time the public information last changed, or zero
+}
+
+// contact returns the contact details from the item.
+func (i *Item) contact() Contact {
+	return Contact{
+		Email:        i.Email,
+		DpoURL:       i.DpoURL,
+		Jurisdiction: i.Jurisdiction}
+}
+
+// attributes returns the item as DynamoDB attributes. The created time is
+// stored as an RFC 3339 string as it was by earlier versions.
+func (i *Item) attributes() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		creatorsTablePartitionKeyName: awsString(i.Owidcreator),
+		creatorsTableDomainAttribute:  awsString(i.Domain),
+		"PrivateKey":                  awsString(i.PrivateKey),
+		"PublicKey":                   awsString(i.PublicKey),
+		"Name":                        awsString(i.Name),
+		"ContractURL":                 awsString(i.ContractURL),
+		"Created":                     awsString(i.Created.Format(time.RFC3339Nano)),
+		"Email":                       awsString(i.Email),
+		"DpoURL":                      awsString(i.DpoURL),
+		"Jurisdiction":                awsString(i.Jurisdiction),
+		"Status":                      awsString(i.Status),
+		"PreferredVersion":            awsString(strconv.Itoa(i.Version)),
+		"Modified":                    awsString(i.Modified.Format(time.RFC3339Nano))}
+}
+
+// newItem returns the item from the DynamoDB attributes. Attributes missing
+// from items written by earlier versions are left empty.
+func newItem(m map[string]types.AttributeValue) (*Item, error) {
+	var i Item
+	var err error
+	i.Owidcreator = awsValue(m, creatorsTablePartitionKeyName)
+	i.Domain = awsValue(m, creatorsTableDomainAttribute)
+	if i.Domain == "" {
+		return nil, errors.New("item has no domain")
+	}
+	i.PrivateKey = awsValue(m, "PrivateKey")
+	i.PublicKey = awsValue(m, "PublicKey")
+	i.Name = awsValue(m, "Name")
+	i.ContractURL = awsValue(m, "ContractURL")
+	if c := awsValue(m, "Created"); c != "" {
+		i.Created, err = time.Parse(time.RFC3339Nano, c)
+		if err != nil {
+			return nil, fmt.Errorf("item '%s' created %s", i.Domain, err.Error())
+		}
+	}
+	i.Email = awsValue(m, "Email")
+	i.DpoURL = awsValue(m, "DpoURL")
+	i.Jurisdiction = awsValue(m, "Jurisdiction")
+	i.Status = awsValue(m, "Status")
+	if c := awsValue(m, "Modified"); c != "" {
+		i.Modified, err = time.Parse(time.RFC3339Nano, c)
+		if err != nil {
+			return nil, fmt.Errorf("item '%s' modified %s", i.Domain, err.Error())
+		}
+	}
+	if v := awsValue(m, "PreferredVersion"); v != "" {
+		i.Version, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"item '%s' preferred version %s",
+				i.Domain,
+				err.Error())
+		}
+	}
+	return &i, nil
+}
+
+// creator returns the creator the item represents.
+func (i *Item) creator() *Creator {
+	c := newCreator(
+		i.Domain,
+		i.PrivateKey,
+		i.PublicKey,
+		i.Name,
+		i.ContractURL,
+		i.Created,
+		i.contact(),
+		CreatorStatus(i.Status))
+	c.version = byte(i.Version)
+	c.modified = i.Modified
+	return c
+}
+
+// awsString returns a DynamoDB string attribute.
+func awsString(s string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: s}
+}
+
+// awsValue returns the string or number attribute as a string, or an empty
+// string if the attribute is missing.
+func awsValue(m map[string]types.AttributeValue, name string) string {
+	switch v := m[name].(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	}
+	return ""
+}
+
+// newAWSConfig returns the AWS configuration for the options. The region and
+// credentials default to those from the environment and shared files.
+func newAWSConfig(o AWSOptions) (aws.Config, error) {
+	var f []func(*config.LoadOptions) error
+	if o.Region != "" {
+		f = append(f, config.WithRegion(o.Region))
+	}
+	if o.AccessKeyID != "" {
+		f = append(f, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(
+				o.AccessKeyID,
+				o.SecretAccessKey,
+				"")))
+	}
+	return config.LoadDefaultConfig(context.Background(), f...)
 }
 
 // NewAWS creates a new instance of the AWS structure
 func NewAWS() (*AWS, error) {
-	var a AWS
-	var sess *session.Session
+	return NewAWSWithOptions(AWSOptions{})
+}
 
-	// Configure session with credentials from .aws/credentials or env and
-	// region from .aws/config or env
-	sess = session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-	}))
+// newAWS returns the AWS store for the configuration.
+func (c *Configuration) newAWS() (*AWS, error) {
+	return NewAWSWithOptions(AWSOptions{
+		Endpoint:            c.AwsEndpoint,
+		Region:              c.AwsRegion,
+		AccessKeyID:         c.AwsAccessKeyID,
+		SecretAccessKey:     c.AwsSecretAccessKey,
+		SkipCreateTable:     c.AwsSkipCreateTable,
+		TablePrefix:         c.AwsTablePrefix,
+		PointInTimeRecovery: c.AwsPointInTimeRecovery,
+		TimeToLiveAttribute: c.AwsTTLAttribute})
+}
+
+// NewAWSWithOptions creates a new instance of the AWS structure using the
+// endpoint, credentials, table name prefix and table settings provided. The
+// table settings are applied each time the store is created so they can be
+// changed for existing tables.
+func NewAWSWithOptions(o AWSOptions) (*AWS, error) {
+	var a AWS
+	a.options = o
+	a.table = o.TablePrefix + creatorsTableName
 
-	if sess == nil {
-		return nil, errors.New("AWS session is nil")
+	cfg, err := newAWSConfig(o)
+	if err != nil {
+		return nil, err
 	}
-	a.svc = dynamodb.New(sess)
+	a.svc = dynamodb.NewFromConfig(cfg, func(d *dynamodb.Options) {
+		if o.Endpoint != "" {
+			d.BaseEndpoint = aws.String(o.Endpoint)
+		}
+	})
 
-	_, err := a.awsCreateCreatorsTable()
+	if o.SkipCreateTable == false {
+		err = a.awsCreateCreatorsTable()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = a.awsConfigureTable()
 	if err != nil {
 		return nil, err
 	}
@@ -85,205 +261,248 @@ func (a *AWS) setCreator(c *Creator) error {
 		c.privateKey,
 		c.publicKey,
 		c.name,
-		c.contractURL}
-
-	av, err := dynamodbattribute.MarshalMap(item)
+		c.contractURL,
+		c.created,
+		c.contact.Email,
+		c.contact.DpoURL,
+		c.contact.Jurisdiction,
+		string(c.status),
+		int(c.version),
+		c.modified}
+
+	_, err := a.svc.PutItem(context.Background(), &dynamodb.PutItemInput{
+		Item:      item.attributes(),
+		TableName: aws.String(a.table),
+	})
 	if err != nil {
-		fmt.Println("Got error marshalling new creator item:")
-		return err
-	}
-
-	input := &dynamodb.PutItemInput{
-		Item:      av,
-		TableName: aws.String(creatorsTableName),
-	}
-
-	_, err = a.svc.PutItem(input)
-	if err != nil {
-		fmt.Println("Got error calling PutItem:")
-		return err
+		return fmt.Errorf("AWS PutItem for '%s' %s", c.domain, err.Error())
 	}
+	a.putCreator(c)
 
 	return nil
 }
 
 // GetCreator gets creator for domain from internal map, updating the internal
-// map if the creator is not in the map.
+// map if the creator is not in the map. Domains that are not found are
+// remembered for a short time so that they don't cause a refresh per request.
+// If the update fails the creators already held are used until they are
+// older than the maximum staleness.
 func (a *AWS) GetCreator(domain string) (*Creator, error) {
 	c, err := a.common.getCreator(domain)
 	if err != nil {
 		return nil, err
 	}
-	if c == nil {
-		err = a.refresh()
+	if c == nil && a.shouldRefresh(domain) {
+		err = a.refreshError(a.refresh())
 		if err != nil {
 			return nil, err
 		}
 		c, err = a.common.getCreator(domain)
+		if c == nil {
+			a.addMiss(domain)
+		}
 	}
 	return c, err
 }
 
-func (a *AWS) getCreatorDirect(domain string) (*Creator, error) {
-	result, err := a.svc.GetItem(&dynamodb.GetItemInput{
-		TableName: aws.String(creatorsTableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			creatorsTablePartitionKeyName: {
-				S: aws.String(creatorsTablePartitionKey),
-			},
-			creatorsTableDomainAttribute: {
-				S: aws.String(domain),
-			},
-		},
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	if result.Item == nil {
-		msg := "Could not find '" + domain + "'"
-		return nil, errors.New(msg)
-	}
-
-	item := Item{}
-
-	err = dynamodbattribute.UnmarshalMap(result.Item, &item)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to unmarshal Record, %v", err))
-	}
-
-	c := newCreator(
-		item.Domain,
-		item.PrivateKey,
-		item.PublicKey,
-		item.Name,
-		item.ContractURL)
-	return c, nil
-}
-
-func (a *AWS) awsCreateCreatorsTable() (*dynamodb.CreateTableOutput, error) {
+// awsCreateCreatorsTable creates the creators table if it does not exist and
+// waits for it to become active.
+func (a *AWS) awsCreateCreatorsTable() error {
 	input := &dynamodb.CreateTableInput{
-		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+		AttributeDefinitions: []types.AttributeDefinition{
 			{
 				AttributeName: aws.String(creatorsTablePartitionKeyName),
-				AttributeType: aws.String("S"),
+				AttributeType: types.ScalarAttributeTypeS,
 			},
 			{
 				AttributeName: aws.String(creatorsTableDomainAttribute),
-				AttributeType: aws.String("S"),
+				AttributeType: types.ScalarAttributeTypeS,
 			},
 		},
-		KeySchema: []*dynamodb.KeySchemaElement{
+		KeySchema: []types.KeySchemaElement{
 			{
 				AttributeName: aws.String(creatorsTablePartitionKeyName),
-				KeyType:       aws.String("HASH"),
+				KeyType:       types.KeyTypeHash,
 			},
 			{
 				AttributeName: aws.String(creatorsTableDomainAttribute),
-				KeyType:       aws.String("RANGE"),
+				KeyType:       types.KeyTypeRange,
 			},
 		},
-		BillingMode: aws.String("PAY_PER_REQUEST"),
-		TableName:   aws.String(creatorsTableName),
+		BillingMode: types.BillingModePayPerRequest,
+		TableName:   aws.String(a.table),
 	}
 
-	o, err := a.svc.CreateTable(input)
+	ctx := context.Background()
+	_, err := a.svc.CreateTable(ctx, input)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case dynamodb.ErrCodeTableAlreadyExistsException:
-				break
-			case dynamodb.ErrCodeResourceInUseException:
-				break
-			default:
-				return o, err
-			}
-		} else {
-			return o, err
+		var inUse *types.ResourceInUseException
+		var exists *types.TableAlreadyExistsException
+		if errors.As(err, &inUse) == false && errors.As(err, &exists) == false {
+			return err
 		}
 	}
 
-	for {
-		input := &dynamodb.DescribeTableInput{
-			TableName: aws.String(creatorsTableName),
+	return dynamodb.NewTableExistsWaiter(a.svc).Wait(
+		ctx,
+		&dynamodb.DescribeTableInput{TableName: aws.String(a.table)},
+		awsTableWait)
+}
+
+// awsConfigureTable applies the point in time recovery and time to live
+// options to the creators table.
+func (a *AWS) awsConfigureTable() error {
+	ctx := context.Background()
+	if a.options.PointInTimeRecovery {
+		_, err := a.svc.UpdateContinuousBackups(
+			ctx,
+			&dynamodb.UpdateContinuousBackupsInput{
+				TableName: aws.String(a.table),
+				PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+					PointInTimeRecoveryEnabled: aws.Bool(true)}})
+		if err != nil {
+			return fmt.Errorf("point in time recovery %s", err.Error())
 		}
-		result, err := a.svc.DescribeTable(input)
+	}
+	if a.options.TimeToLiveAttribute != "" {
+		d, err := a.svc.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{
+			TableName: aws.String(a.table)})
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("time to live %s", err.Error())
+		}
+		t := d.TimeToLiveDescription
+		if t != nil &&
+			t.TimeToLiveStatus == types.TimeToLiveStatusEnabled &&
+			aws.ToString(t.AttributeName) == a.options.TimeToLiveAttribute {
+			return nil
 		}
-		if *result.Table.TableStatus == "ACTIVE" {
-			break
+		_, err = a.svc.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String(a.table),
+			TimeToLiveSpecification: &types.TimeToLiveSpecification{
+				AttributeName: aws.String(a.options.TimeToLiveAttribute),
+				Enabled:       aws.Bool(true)}})
+		if err != nil {
+			return fmt.Errorf("time to live %s", err.Error())
 		}
 	}
-
-	return o, nil
+	return nil
 }
 
 func (a *AWS) refresh() error {
 	// Fetch the creators
-	cs, err := a.fetchCreators()
+	cs, f, err := a.fetchCreators()
 	if err != nil {
 		return err
 	}
 	// In a single atomic operation update the reference to the creators.
-	a.mutex.Lock()
-	a.creators = cs
-	a.mutex.Unlock()
+	a.setCreators(cs, f)
 
 	return nil
 }
 
-func (a *AWS) fetchCreators() (map[string]*Creator, error) {
+// fetchCreators scans the creators partition of the table reading every page.
+// Items that can't be read are returned with their errors keyed on domain.
+func (a *AWS) fetchCreators() (
+	map[string]*Creator,
+	map[string]error,
+	error) {
 
 	cs := make(map[string]*Creator)
-
-	filt := expression.Name(creatorsTablePartitionKeyName).Equal(expression.Value(creatorsTablePartitionKey))
-
-	proj := expression.NamesList(expression.Name(creatorsTableDomainAttribute),
-		expression.Name("PrivateKey"),
-		expression.Name("PublicKey"),
-		expression.Name("Name"))
-
-	expr, err := expression.NewBuilder().WithFilter(filt).WithProjection(proj).Build()
-	if err != nil {
-		fmt.Println("Got error building expression:")
-		fmt.Println(err.Error())
-		return nil, err
+	f := make(map[string]error)
+
+	p := dynamodb.NewScanPaginator(a.svc, &dynamodb.ScanInput{
+		TableName:        aws.String(a.table),
+		FilterExpression: aws.String("#p = :p"),
+		ExpressionAttributeNames: map[string]string{
+			"#p": creatorsTablePartitionKeyName},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":p": awsString(creatorsTablePartitionKey)},
+	})
+	for p.HasMorePages() {
+		result, err := p.NextPage(context.Background())
+		if err != nil {
+			return nil, nil, fmt.Errorf("AWS Scan %s", err.Error())
+		}
+		for _, i := range result.Items {
+			item, err := newItem(i)
+			if err != nil {
+				d := awsValue(i, creatorsTableDomainAttribute)
+				if d == "" {
+					d = fmt.Sprintf("item %d", len(f))
+				}
+				f[d] = err
+				continue
+			}
+			cs[item.Domain] = item.creator()
+		}
 	}
 
-	params := &dynamodb.ScanInput{
-		ExpressionAttributeNames:  expr.Names(),
-		ExpressionAttributeValues: expr.Values(),
-		FilterExpression:          expr.Filter(),
-		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String(creatorsTableName),
-	}
+	return cs, f, nil
+}
 
-	// Make the DynamoDB Query API call
-	result, err := a.svc.Scan(params)
+func (a *AWS) schemaBackend() string { return schemaBackendAWS }
+
+// getSchemaVersion reads the schema version item, or zero if there is none. The
+// item uses a different partition key to the creators so scans for creators
+// exclude it.
+func (a *AWS) getSchemaVersion() (int, error) {
+	r, err := a.svc.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String(a.table),
+		Key: map[string]types.AttributeValue{
+			creatorsTablePartitionKeyName: awsString(schemaPartitionKey),
+			creatorsTableDomainAttribute:  awsString(schemaRowKey)}})
 	if err != nil {
-		fmt.Println("Query API call failed:")
-		fmt.Println((err.Error()))
-		return nil, err
+		return 0, err
+	}
+	if r.Item == nil {
+		return 0, nil
 	}
+	return strconv.Atoi(awsValue(r.Item, "Version"))
+}
 
-	for _, i := range result.Items {
-		item := Item{}
+// setSchemaVersion writes the schema version item.
+func (a *AWS) setSchemaVersion(v int) error {
+	_, err := a.svc.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String(a.table),
+		Item: map[string]types.AttributeValue{
+			creatorsTablePartitionKeyName: awsString(schemaPartitionKey),
+			creatorsTableDomainAttribute:  awsString(schemaRowKey),
+			"Version": &types.AttributeValueMemberN{
+				Value: strconv.Itoa(v)}}})
+	return err
+}
 
-		err = dynamodbattribute.UnmarshalMap(i, &item)
-		if err != nil {
-			fmt.Println("Got error un-marshalling:")
-			fmt.Println(err.Error())
-			return nil, err
-		}
+// PublisherS3 publishes public information to an AWS S3 bucket.
+type PublisherS3 struct {
+	svc    *s3.Client
+	bucket string // Name of the bucket
+	prefix string // Prefix added to every object key
+}
 
-		cs[item.Domain] = newCreator(
-			item.Domain,
-			item.PrivateKey,
-			item.PublicKey,
-			item.Name,
-			item.ContractURL)
+// NewPublisherS3 creates a publisher for the bucket using the default
+// credentials and region. The prefix is added to every object key.
+func NewPublisherS3(bucket string, prefix string) (*PublisherS3, error) {
+	cfg, err := newAWSConfig(AWSOptions{})
+	if err != nil {
+		return nil, err
 	}
+	return &PublisherS3{
+		svc:    s3.NewFromConfig(cfg),
+		bucket: bucket,
+		prefix: prefix}, nil
+}
 
-	return cs, nil
+// Put writes the data to the object with the name.
+func (p *PublisherS3) Put(
+	name string,
+	contentType string,
+	cacheControl string,
+	data []byte) error {
+	_, err := p.svc.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:       aws.String(p.bucket),
+		Key:          aws.String(p.prefix + name),
+		ContentType:  aws.String(contentType),
+		CacheControl: aws.String(cacheControl),
+		Body:         bytes.NewReader(data)})
+	return err
 }
@@ -0,0 +1,116 @@
+//go:build !js
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TestAWSItem checks creators survive conversion to and from DynamoDB
+// attributes, and that items written with empty attributes as nulls by the
+// earlier SDK can be read.
+func TestAWSItem(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := Item{
+		creatorsTablePartitionKey,
+		c.domain,
+		c.privateKey,
+		c.publicKey,
+		c.name,
+		c.contractURL,
+		c.created,
+		"",
+		"",
+		"GB",
+		string(c.status),
+		int(owidVersion5),
+		c.created.Add(time.Hour)}
+	a := i.attributes()
+	a["Email"] = &types.AttributeValueMemberNULL{Value: true}
+	n, err := newItem(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := n.creator()
+	if r.domain != c.domain ||
+		r.privateKey != c.privateKey ||
+		r.publicKey != c.publicKey ||
+		r.created.Equal(c.created) == false ||
+		r.contact.Jurisdiction != "GB" ||
+		r.contact.Email != "" ||
+		r.status != c.status ||
+		r.version != owidVersion5 ||
+		r.modified.Equal(c.created.Add(time.Hour)) == false {
+		t.Fatal("creator fields changed after attribute round trip")
+	}
+	delete(a, creatorsTableDomainAttribute)
+	_, err = newItem(a)
+	if err == nil {
+		t.Fatal("item without domain should be an error")
+	}
+}
+
+// TestAWSDynamoDBLocal stores and reads a creator using DynamoDB Local. Set
+// OWID_DYNAMODB_ENDPOINT to the endpoint, for example http://localhost:8000,
+// to run.
+func TestAWSDynamoDBLocal(t *testing.T) {
+	e := os.Getenv("OWID_DYNAMODB_ENDPOINT")
+	if e == "" {
+		t.Skip("OWID_DYNAMODB_ENDPOINT not set")
+	}
+	a, err := NewAWSWithOptions(AWSOptions{
+		Endpoint:        e,
+		Region:          "us-east-1",
+		AccessKeyID:     "local",
+		SecretAccessKey: "local",
+		TablePrefix:     "test" + time.Now().Format("20060102150405") + "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = MigrateSchema(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, f, err := a.fetchCreators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f) != 0 || cs[testDomain] == nil {
+		t.Fatal("creator not read back from DynamoDB Local")
+	}
+	err = cs[testDomain].SelfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !js
+
 /* ****************************************************************************
  * Copyright 2020 51 Degrees Mobile Experts Limited (51degrees.com)
  *
@@ -17,35 +19,85 @@
 package owid
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 )
 
-const (
-	azureTimeout = 2
-)
+// The longest time an operation, including retries and paging, can take.
+const azureTimeout = 10 * time.Second
 
 // Azure is a concrete implementation of store.go, connecting to Azure table
 // storage
 type Azure struct {
-	timestamp     time.Time      // The last time the maps were refreshed
-	creatorsTable *storage.Table // Reference to the creator table
+	tables *azureTables // Client for the Table service
 	common
 }
 
-// NewAzure creates a new instance of the Azure structure.
+// NewAzure creates a new instance of the Azure structure using the storage
+// account key.
 func NewAzure(account string, accessKey string) (*Azure, error) {
-	var a Azure
-	c, err := storage.NewBasicClient(account, accessKey)
+	t, err := newAzureTablesSharedKey(
+		azureTableEndpoint(account),
+		account,
+		accessKey)
+	if err != nil {
+		return nil, err
+	}
+	return newAzure(t)
+}
+
+// NewAzureWithCredential creates a new instance of the Azure structure that
+// authenticates with the credential, for example a managed identity from
+// azidentity.NewDefaultAzureCredential, instead of an account key.
+func NewAzureWithCredential(
+	account string,
+	cred azcore.TokenCredential) (*Azure, error) {
+	return newAzure(newAzureTablesCredential(azureTableEndpoint(account), cred))
+}
+
+// NewAzureFromConnectionString creates a new instance of the Azure structure
+// for the storage account connection string, including the
+// UseDevelopmentStorage=true form used with the Azurite emulator.
+func NewAzureFromConnectionString(cs string) (*Azure, error) {
+	t, err := newAzureTablesConnectionString(cs)
+	if err != nil {
+		return nil, err
+	}
+	return newAzure(t)
+}
+
+// newAzure returns the Azure store for the configuration. A connection string
+// is used if present, then the account key. If only the account is present
+// the default Azure credential is used, for example a managed identity.
+func (c *Configuration) newAzure() (*Azure, error) {
+	if c.AzureConnectionString != "" {
+		return NewAzureFromConnectionString(c.AzureConnectionString)
+	}
+	if c.AzureStorageAccessKey != "" {
+		return NewAzure(c.AzureStorageAccount, c.AzureStorageAccessKey)
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
 	if err != nil {
 		return nil, err
 	}
-	ts := c.GetTableService()
+	return NewAzureWithCredential(c.AzureStorageAccount, cred)
+}
+
+func newAzure(t *azureTables) (*Azure, error) {
+	var a Azure
 	a.mutex = &sync.Mutex{}
-	a.creatorsTable = ts.GetTableReference(creatorsTableName)
-	err = azureCreateTable(a.creatorsTable)
+	a.tables = t
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+	err := t.createTable(ctx, creatorsTableName)
 	if err != nil {
 		return nil, err
 	}
@@ -57,43 +109,55 @@ func NewAzure(account string, accessKey string) (*Azure, error) {
 }
 
 // GetCreator gets creator for domain from internal map, updating the internal
-// map if the creator is not in the map.
+// map if the creator is not in the map. Domains that are not found are
+// remembered for a short time so that they don't cause a refresh per request.
+// If the update fails the creators already held are used until they are
+// older than the maximum staleness.
 func (a *Azure) GetCreator(domain string) (*Creator, error) {
 	c, err := a.common.getCreator(domain)
 	if err != nil {
 		return nil, err
 	}
-	if c == nil {
-		err = a.refresh()
+	if c == nil && a.shouldRefresh(domain) {
+		err = a.refreshError(a.refresh())
 		if err != nil {
 			return nil, err
 		}
 		c, err = a.common.getCreator(domain)
+		if c == nil {
+			a.addMiss(domain)
+		}
 	}
 	return c, err
 }
 
 func (a *Azure) setCreator(creator *Creator) error {
-	e := a.creatorsTable.GetEntityReference(creatorsTablePartitionKey, creator.domain)
-	e.Properties = make(map[string]interface{})
-	e.Properties[privateKeyFieldName] = creator.privateKey
-	e.Properties[publicKeyFieldName] = creator.publicKey
-	e.Properties[nameFieldName] = creator.name
-	return e.Insert(storage.FullMetadata, nil)
-}
-
-func azureCreateTable(t *storage.Table) error {
-	err := t.Create(azureTimeout, storage.FullMetadata, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+	e := azureEntity{
+		privateKeyFieldName:       creator.privateKey,
+		publicKeyFieldName:        creator.publicKey,
+		nameFieldName:             creator.name,
+		contractURLFieldName:      creator.contractURL,
+		createdFieldName:          creator.created,
+		emailFieldName:            creator.contact.Email,
+		dpoURLFieldName:           creator.contact.DpoURL,
+		jurisdictionFieldName:     creator.contact.Jurisdiction,
+		statusFieldName:           string(creator.status),
+		preferredVersionFieldName: int(creator.version)}
+	if creator.modified.IsZero() == false {
+		e[modifiedFieldName] = creator.modified
+	}
+	err := a.tables.upsertEntity(
+		ctx,
+		creatorsTableName,
+		creatorsTablePartitionKey,
+		creator.domain,
+		e)
 	if err != nil {
-		switch e := err.(type) {
-		case storage.AzureStorageServiceError:
-			if e.Code != "TableAlreadyExists" {
-				return err
-			}
-		default:
-			return err
-		}
+		return err
 	}
+	a.putCreator(creator)
 	return nil
 }
 
@@ -104,36 +168,189 @@ func (a *Azure) refresh() error {
 		return err
 	}
 	// In a single atomic operation update the reference to the creators.
-	a.mutex.Lock()
-	a.creators = cs
-	a.mutex.Unlock()
+	a.setCreators(cs, nil)
 
 	return nil
 }
 
 func (a *Azure) fetchCreators() (map[string]*Creator, error) {
-	var err error
 	cs := make(map[string]*Creator)
 
-	// Fetch all the records from the nodes table in Azure.
-	e, err := a.creatorsTable.QueryEntities(
-		azureTimeout,
-		storage.FullMetadata,
+	// Fetch all the records from the creators table in Azure.
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+	es, err := a.tables.queryEntities(ctx, creatorsTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Iterate over the records creating creators and adding them to the
+	// creators map.
+	for _, i := range es {
+		if azureString(i["PartitionKey"]) != creatorsTablePartitionKey {
+			continue
+		}
+		d := azureString(i["RowKey"])
+		c := newCreator(
+			d,
+			azureString(i[privateKeyFieldName]),
+			azureString(i[publicKeyFieldName]),
+			azureString(i[nameFieldName]),
+			azureString(i[contractURLFieldName]),
+			azureTime(i[createdFieldName]),
+			Contact{
+				Email:        azureString(i[emailFieldName]),
+				DpoURL:       azureString(i[dpoURLFieldName]),
+				Jurisdiction: azureString(i[jurisdictionFieldName])},
+			CreatorStatus(azureString(i[statusFieldName])))
+		c.version = byte(azureInt(i[preferredVersionFieldName]))
+		c.modified = azureTime(i[modifiedFieldName])
+		cs[d] = c
+	}
+
+	return cs, nil
+}
+
+// Property of the schema entity that holds the version.
+const azureSchemaVersionProperty = "version"
+
+func (a *Azure) schemaBackend() string { return schemaBackendAzure }
+
+// getSchemaVersion reads the schema entity, or zero if there is none. The
+// entity uses a different partition key to the creators.
+func (a *Azure) getSchemaVersion() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+	e, err := a.tables.getEntity(
+		ctx,
+		creatorsTableName,
+		schemaPartitionKey,
+		schemaRowKey)
+	if err != nil || e == nil {
+		return 0, err
+	}
+	switch v := e[azureSchemaVersionProperty].(type) {
+	case float64:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("Azure schema version '%v' invalid",
+		e[azureSchemaVersionProperty])
+}
+
+// setSchemaVersion writes the schema entity.
+func (a *Azure) setSchemaVersion(v int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), azureTimeout)
+	defer cancel()
+	return a.tables.upsertEntity(
+		ctx,
+		creatorsTableName,
+		schemaPartitionKey,
+		schemaRowKey,
+		azureEntity{azureSchemaVersionProperty: v})
+}
+
+// azureString returns the property as a string, or an empty string if the
+// property is missing. Records created by earlier versions might not contain
+// all the properties.
+func azureString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// azureInt returns the property as an integer, or zero if the property is
+// missing. Numbers are decoded from the JSON response as float64.
+func azureInt(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	}
+	return 0
+}
+
+// azureTime returns the property as a time, or the zero time if the property
+// is missing or can't be parsed.
+func azureTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case string:
+		p, _ := time.Parse(time.RFC3339Nano, t)
+		return p
+	}
+	return time.Time{}
+}
+
+// PublisherAzure publishes public information to an Azure blob container.
+type PublisherAzure struct {
+	client    *azblob.Client
+	container string
+}
+
+// NewPublisherAzure creates a publisher for the container in the storage
+// account.
+func NewPublisherAzure(
+	account string,
+	accessKey string,
+	container string) (*PublisherAzure, error) {
+	k, err := azblob.NewSharedKeyCredential(account, accessKey)
+	if err != nil {
+		return nil, err
+	}
+	c, err := azblob.NewClientWithSharedKeyCredential(
+		azureBlobEndpoint(account),
+		k,
 		nil)
 	if err != nil {
 		return nil, err
 	}
+	return &PublisherAzure{client: c, container: container}, nil
+}
 
-	// Iterate over the records creating nodes and adding them to the creators
-	// map.
-	for _, i := range e.Entities {
-		cs[i.RowKey] = newCreator(
-			i.RowKey,
-			i.Properties[privateKeyFieldName].(string),
-			i.Properties[publicKeyFieldName].(string),
-			i.Properties[nameFieldName].(string),
-			i.Properties[contractURLFieldName].(string))
+// NewPublisherAzureWithCredential creates a publisher for the container in
+// the storage account that authenticates with the credential, for example a
+// managed identity.
+func NewPublisherAzureWithCredential(
+	account string,
+	cred azcore.TokenCredential,
+	container string) (*PublisherAzure, error) {
+	c, err := azblob.NewClient(azureBlobEndpoint(account), cred, nil)
+	if err != nil {
+		return nil, err
 	}
+	return &PublisherAzure{client: c, container: container}, nil
+}
+
+// azureBlobEndpoint returns the Blob service endpoint for the account.
+func azureBlobEndpoint(account string) string {
+	return fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+}
 
-	return cs, err
+// Put writes the data to the block blob with the name.
+func (p *PublisherAzure) Put(
+	name string,
+	contentType string,
+	cacheControl string,
+	data []byte) error {
+	_, err := p.client.UploadBuffer(
+		context.Background(),
+		p.container,
+		name,
+		data,
+		&azblob.UploadBufferOptions{
+			HTTPHeaders: &blob.HTTPHeaders{
+				BlobContentType:  to.Ptr(contentType),
+				BlobCacheControl: to.Ptr(cacheControl)}})
+	return err
 }
@@ -0,0 +1,328 @@
+//go:build !js
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// Version of the Table service REST API. 2019-02-02 or later is needed for
+// Azure AD credentials.
+const azureTableVersion = "2019-02-02"
+
+// Scope requested for tokens used with the Table service.
+const azureTableScope = "https://storage.azure.com/.default"
+
+// Account name, key and endpoint of the Azurite storage emulator used when a
+// connection string sets UseDevelopmentStorage.
+const (
+	azuriteAccount  = "devstoreaccount1"
+	azuriteKey      = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	azuriteEndpoint = "http://127.0.0.1:10002/" + azuriteAccount
+)
+
+// azureEntity is a table entity as returned without OData metadata.
+type azureEntity map[string]interface{}
+
+// azureTables is a client for the parts of the Azure Table service REST API
+// the store uses. It is built on the azcore pipeline so it shares retries,
+// logging and credentials with the other Azure SDK clients. Changes must pass
+// TestAzureAzurite, which only runs with the integration build tag, as the
+// other tests use a fake service.
+type azureTables struct {
+	endpoint string           // Table service endpoint without a trailing slash
+	pipeline runtime.Pipeline // Pipeline including the authentication policy
+}
+
+// newAzureTables returns a client for the endpoint that authenticates
+// requests with the policy provided.
+func newAzureTables(
+	endpoint string,
+	auth policy.Policy,
+	o *policy.ClientOptions) *azureTables {
+	return &azureTables{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		pipeline: runtime.NewPipeline(
+			"owid",
+			"",
+			runtime.PipelineOptions{
+				PerCall:  []policy.Policy{azureTableHeaders{}},
+				PerRetry: []policy.Policy{auth}},
+			o)}
+}
+
+// newAzureTablesSharedKey returns a client that signs requests with the
+// account key.
+func newAzureTablesSharedKey(
+	endpoint string,
+	account string,
+	key string) (*azureTables, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("Azure account key invalid: %s", err.Error())
+	}
+	return newAzureTables(
+		endpoint,
+		&azureSharedKey{account: account, key: k},
+		nil), nil
+}
+
+// newAzureTablesCredential returns a client that authenticates with tokens
+// from the credential, for example a managed identity.
+func newAzureTablesCredential(
+	endpoint string,
+	cred azcore.TokenCredential) *azureTables {
+	return newAzureTables(
+		endpoint,
+		runtime.NewBearerTokenPolicy(cred, []string{azureTableScope}, nil),
+		nil)
+}
+
+// newAzureTablesConnectionString returns a client for the account in the
+// connection string. Only account key connection strings are supported.
+func newAzureTablesConnectionString(cs string) (*azureTables, error) {
+	v := make(map[string]string)
+	for _, p := range strings.Split(cs, ";") {
+		i := strings.Index(p, "=")
+		if i > 0 {
+			v[strings.ToLower(strings.TrimSpace(p[:i]))] = strings.TrimSpace(p[i+1:])
+		}
+	}
+	if strings.EqualFold(v["usedevelopmentstorage"], "true") {
+		return newAzureTablesSharedKey(azuriteEndpoint, azuriteAccount, azuriteKey)
+	}
+	a, k := v["accountname"], v["accountkey"]
+	if a == "" || k == "" {
+		return nil, fmt.Errorf(
+			"Azure connection string requires AccountName and AccountKey")
+	}
+	e := v["tableendpoint"]
+	if e == "" {
+		p := v["defaultendpointsprotocol"]
+		if p == "" {
+			p = "https"
+		}
+		s := v["endpointsuffix"]
+		if s == "" {
+			s = "core.windows.net"
+		}
+		e = fmt.Sprintf("%s://%s.table.%s", p, a, s)
+	}
+	return newAzureTablesSharedKey(e, a, k)
+}
+
+// azureTableEndpoint returns the Table service endpoint for the account.
+func azureTableEndpoint(account string) string {
+	return fmt.Sprintf("https://%s.table.core.windows.net", account)
+}
+
+// createTable creates the table if it does not already exist.
+func (t *azureTables) createTable(ctx context.Context, table string) error {
+	r, err := t.newRequest(ctx, http.MethodPost, "/Tables")
+	if err != nil {
+		return err
+	}
+	r.Raw().Header.Set("Prefer", "return-no-content")
+	err = runtime.MarshalAsJSON(r, map[string]string{"TableName": table})
+	if err != nil {
+		return err
+	}
+	_, err = t.do(r, http.StatusCreated, http.StatusNoContent, http.StatusConflict)
+	return err
+}
+
+// queryEntities returns all the entities in the table following continuation
+// tokens until the last page.
+func (t *azureTables) queryEntities(
+	ctx context.Context,
+	table string) ([]azureEntity, error) {
+	var es []azureEntity
+	var np, nr string
+	for {
+		r, err := t.newRequest(ctx, http.MethodGet, "/"+url.PathEscape(table)+"()")
+		if err != nil {
+			return nil, err
+		}
+		if np != "" || nr != "" {
+			q := r.Raw().URL.Query()
+			q.Set("NextPartitionKey", np)
+			q.Set("NextRowKey", nr)
+			r.Raw().URL.RawQuery = q.Encode()
+		}
+		p, err := t.do(r, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+		var v struct {
+			Value []azureEntity `json:"value"`
+		}
+		err = runtime.UnmarshalAsJSON(p, &v)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, v.Value...)
+		np = p.Header.Get("x-ms-continuation-NextPartitionKey")
+		nr = p.Header.Get("x-ms-continuation-NextRowKey")
+		if np == "" && nr == "" {
+			return es, nil
+		}
+	}
+}
+
+// getEntity returns the entity with the keys, or nil if there is none.
+func (t *azureTables) getEntity(
+	ctx context.Context,
+	table string,
+	partitionKey string,
+	rowKey string) (azureEntity, error) {
+	r, err := t.newRequest(
+		ctx,
+		http.MethodGet,
+		azureEntityPath(table, partitionKey, rowKey))
+	if err != nil {
+		return nil, err
+	}
+	p, err := t.do(r, http.StatusOK, http.StatusNotFound)
+	if err != nil || p.StatusCode == http.StatusNotFound {
+		return nil, err
+	}
+	var e azureEntity
+	err = runtime.UnmarshalAsJSON(p, &e)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// upsertEntity inserts the entity or replaces an existing one with the same
+// keys. Time values are annotated so they are stored as Edm.DateTime.
+func (t *azureTables) upsertEntity(
+	ctx context.Context,
+	table string,
+	partitionKey string,
+	rowKey string,
+	properties azureEntity) error {
+	r, err := t.newRequest(
+		ctx,
+		http.MethodPut,
+		azureEntityPath(table, partitionKey, rowKey))
+	if err != nil {
+		return err
+	}
+	e := azureEntity{"PartitionKey": partitionKey, "RowKey": rowKey}
+	for k, v := range properties {
+		if d, ok := v.(time.Time); ok {
+			e[k] = d.UTC().Format(time.RFC3339Nano)
+			e[k+"@odata.type"] = "Edm.DateTime"
+		} else {
+			e[k] = v
+		}
+	}
+	err = runtime.MarshalAsJSON(r, e)
+	if err != nil {
+		return err
+	}
+	_, err = t.do(r, http.StatusNoContent)
+	return err
+}
+
+// newRequest returns a request for the path relative to the endpoint.
+func (t *azureTables) newRequest(
+	ctx context.Context,
+	method string,
+	path string) (*policy.Request, error) {
+	return runtime.NewRequest(ctx, method, t.endpoint+path)
+}
+
+// do sends the request returning an error if the status code is not one of
+// those expected.
+func (t *azureTables) do(
+	r *policy.Request,
+	expected ...int) (*http.Response, error) {
+	p, err := t.pipeline.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	if runtime.HasStatusCode(p, expected...) == false {
+		return nil, runtime.NewResponseError(p)
+	}
+	return p, nil
+}
+
+// azureEntityPath returns the path of the entity with the keys. Single quotes
+// in the keys are doubled as required by OData.
+func azureEntityPath(table string, partitionKey string, rowKey string) string {
+	q := func(s string) string {
+		return url.PathEscape(strings.ReplaceAll(s, "'", "''"))
+	}
+	return fmt.Sprintf(
+		"/%s(PartitionKey='%s',RowKey='%s')",
+		url.PathEscape(table),
+		q(partitionKey),
+		q(rowKey))
+}
+
+// azureTableHeaders adds the headers every Table service request needs.
+type azureTableHeaders struct{}
+
+func (azureTableHeaders) Do(r *policy.Request) (*http.Response, error) {
+	h := r.Raw().Header
+	h.Set("x-ms-version", azureTableVersion)
+	h.Set("DataServiceVersion", "3.0")
+	h.Set("Accept", "application/json;odata=nometadata")
+	return r.Next()
+}
+
+// azureSharedKey signs requests with the account key using the Shared Key
+// Lite scheme for the Table service.
+type azureSharedKey struct {
+	account string
+	key     []byte
+}
+
+func (k *azureSharedKey) Do(r *policy.Request) (*http.Response, error) {
+	q := r.Raw()
+	d := time.Now().UTC().Format(http.TimeFormat)
+	q.Header.Set("x-ms-date", d)
+	q.Header.Set("Authorization", "SharedKeyLite "+k.account+":"+k.sign(d, q.URL))
+	return r.Next()
+}
+
+// sign returns the Shared Key Lite signature for the date and URL.
+func (k *azureSharedKey) sign(date string, u *url.URL) string {
+	s := "/" + k.account + u.EscapedPath()
+	if c := u.Query().Get("comp"); c != "" {
+		s += "?comp=" + c
+	}
+	h := hmac.New(sha256.New, k.key)
+	h.Write([]byte(date + "\n" + s))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
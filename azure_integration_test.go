@@ -0,0 +1,106 @@
+//go:build integration && !js
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestAzureAzurite runs the Azure store against the Azurite emulator so that
+// the Table service client, including Shared Key Lite signing, is checked
+// against a real implementation of the service. It only builds with the
+// integration tag and fails if the emulator can't be reached. Start the
+// emulator with azurite-table and run:
+//
+//	go test -tags integration -run TestAzureAzurite .
+//
+// OWID_AZURITE_CONNECTION_STRING overrides the default development storage
+// connection string.
+func TestAzureAzurite(t *testing.T) {
+	cs := os.Getenv("OWID_AZURITE_CONNECTION_STRING")
+	if cs == "" {
+		cs = "UseDevelopmentStorage=true"
+	}
+	a, err := NewAzureFromConnectionString(cs)
+	if err != nil {
+		t.Fatalf("Azurite not available: %s", err.Error())
+	}
+
+	// Domains are unique to the run as the emulator may hold creators from
+	// earlier runs. More than one page of entities is not needed to follow
+	// continuation tokens as TestAzureStore covers them.
+	s := time.Now().UnixNano()
+	ds := []string{
+		fmt.Sprintf("a%d.com", s),
+		fmt.Sprintf("o'brien%d.com", s),
+		fmt.Sprintf("b%d.com", s)}
+	for _, d := range ds {
+		c, err := newTestCreator(d, testOrgName, registerContractURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.modified = time.Now().UTC()
+		err = a.setCreator(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = MigrateSchema(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := a.getSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != latestSchemaVersion(schemaBackendAzure) {
+		t.Fatalf("expected latest schema version but got '%d'", v)
+	}
+
+	// Read the creators back with a new store so that they come from the
+	// emulator and not the store's map.
+	n, err := NewAzureFromConnectionString(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range ds {
+		c, err := n.GetCreator(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c == nil || c.SelfTest() != nil {
+			t.Fatalf("creator '%s' not read back from Azurite", d)
+		}
+		// Times are compared to the millisecond as the service might not
+		// store more precision.
+		e := a.GetCreators()[d]
+		if c.privateKey != e.privateKey ||
+			c.created.Sub(e.created).Abs() >= time.Millisecond ||
+			c.modified.Sub(e.modified).Abs() >= time.Millisecond {
+			t.Fatalf("creator '%s' differs when read back", d)
+		}
+	}
+	c, err := n.GetCreator(fmt.Sprintf("missing%d.com", s))
+	if err != nil || c != nil {
+		t.Fatal("missing creator should not be found")
+	}
+}
@@ -0,0 +1,197 @@
+//go:build !js
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestAzureServer returns a server that implements enough of the Table
+// service for the Azure store. Queries are returned one entity per page to
+// check continuation tokens are followed.
+func newTestAzureServer(t *testing.T) *httptest.Server {
+	var m sync.Mutex
+	es := make(map[string]azureEntity)
+	var keys []string
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(
+				r.Header.Get("Authorization"),
+				"SharedKeyLite "+azuriteAccount+":") == false ||
+				r.Header.Get("x-ms-version") != azureTableVersion {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			m.Lock()
+			defer m.Unlock()
+			p := strings.TrimPrefix(r.URL.Path, "/"+azuriteAccount)
+			switch {
+			case r.Method == http.MethodPost && p == "/Tables":
+				w.WriteHeader(http.StatusNoContent)
+			case r.Method == http.MethodPut:
+				var e azureEntity
+				json.NewDecoder(r.Body).Decode(&e)
+				if _, ok := es[p]; ok == false {
+					keys = append(keys, p)
+				}
+				es[p] = e
+				w.WriteHeader(http.StatusNoContent)
+			case r.Method == http.MethodGet && strings.HasSuffix(p, "()"):
+				i := 0
+				if n := r.URL.Query().Get("NextRowKey"); n != "" {
+					for i < len(keys) && keys[i] != n {
+						i++
+					}
+				}
+				var v []azureEntity
+				if i < len(keys) {
+					v = append(v, es[keys[i]])
+					if i+1 < len(keys) {
+						w.Header().Set("x-ms-continuation-NextPartitionKey", "p")
+						w.Header().Set("x-ms-continuation-NextRowKey", keys[i+1])
+					}
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"value": v})
+			case r.Method == http.MethodGet:
+				e, ok := es[p]
+				if ok == false {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				json.NewEncoder(w).Encode(e)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}))
+}
+
+// TestAzureStore stores creators with the Table service client and reads them
+// back across several pages, along with the schema version.
+func TestAzureStore(t *testing.T) {
+	h := newTestAzureServer(t)
+	defer h.Close()
+	a, err := NewAzureFromConnectionString(
+		"DefaultEndpointsProtocol=http;AccountName=" + azuriteAccount +
+			";AccountKey=" + azuriteKey +
+			";TableEndpoint=" + h.URL + "/" + azuriteAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{testDomain, "o'brien.com", "other.com"} {
+		c, err := newTestCreator(d, testOrgName, registerContractURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = a.setCreator(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = MigrateSchema(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := a.fetchCreators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 3 || cs["o'brien.com"] == nil {
+		t.Fatalf("expected three creators but got '%d'", len(cs))
+	}
+	c := cs[testDomain]
+	if c.created.IsZero() || c.SelfTest() != nil {
+		t.Fatal("creator not read back correctly")
+	}
+	v, err := a.getSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != latestSchemaVersion(schemaBackendAzure) {
+		t.Fatalf("expected latest schema version but got '%d'", v)
+	}
+}
+
+// TestAzureConnectionString checks the endpoint is formed from the parts of
+// a connection string and that incomplete strings are refused.
+func TestAzureConnectionString(t *testing.T) {
+	k, err := newAzureTablesConnectionString(
+		"AccountName=a;AccountKey=" + azuriteKey + ";EndpointSuffix=core.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.endpoint != "https://a.table.core.test" {
+		t.Fatalf("unexpected endpoint '%s'", k.endpoint)
+	}
+	k, err = newAzureTablesConnectionString("UseDevelopmentStorage=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.endpoint != azuriteEndpoint {
+		t.Fatalf("unexpected endpoint '%s'", k.endpoint)
+	}
+	_, err = newAzureTablesConnectionString("AccountName=a")
+	if err == nil {
+		t.Fatal("connection string without key should be refused")
+	}
+}
+
+// TestAzureSharedKey checks the Shared Key Lite string to sign is the date and
+// the canonical resource, that the account is repeated for the path style
+// URLs of the emulator, that the path is used as escaped in the URL and that
+// only the comp parameter is included.
+func TestAzureSharedKey(t *testing.T) {
+	k, err := newAzureTablesConnectionString("UseDevelopmentStorage=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &azureSharedKey{account: azuriteAccount}
+	s.key, err = base64.StdEncoding.DecodeString(azuriteKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := "Sun, 11 Oct 2009 19:52:39 GMT"
+	for u, r := range map[string]string{
+		k.endpoint + "/Tables": "/" + azuriteAccount + "/" + azuriteAccount +
+			"/Tables",
+		k.endpoint + "/owidcreators()?NextRowKey=a&comp=x": "/" +
+			azuriteAccount + "/" + azuriteAccount + "/owidcreators()?comp=x",
+		"https://a.table.core.windows.net" +
+			azureEntityPath("t", "p", "o'brien.com"): "/" + azuriteAccount +
+			"/t(PartitionKey='p',RowKey='o%27%27brien.com')"} {
+		p, err := url.Parse(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := hmac.New(sha256.New, s.key)
+		h.Write([]byte(d + "\n" + r))
+		e := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		if a := s.sign(d, p); a != e {
+			t.Errorf("'%s' signed '%s' expected '%s'", u, a, e)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// The key derivation function used for encrypted backups.
+const backupKDF = "argon2id"
+
+// Default Argon2id parameters for encrypted backups.
+const (
+	backupTime    = 1
+	backupMemory  = 64 * 1024
+	backupThreads = 4
+	backupKeyLen  = 32
+	backupSaltLen = 16
+)
+
+// Maximum Argon2id parameters accepted when importing a backup so that a
+// crafted file can't force excessive memory or CPU use before the data is
+// authenticated.
+const (
+	backupMaxTime    = 10
+	backupMaxMemory  = 256 * 1024
+	backupMaxThreads = 16
+	backupMaxSaltLen = 64
+)
+
+// The version of the backup format that includes the key derivation
+// parameters in the additional authenticated data.
+const backupVersion2 = 2
+
+// backup is the JSON representation of an encrypted creator. The key
+// derivation parameters are stored so that they can change in the future.
+type backup struct {
+	Version int    `json:"version,omitempty"`
+	KDF     string `json:"kdf"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// ExportEncrypted returns the creator including the private key encrypted with
+// AES-GCM using a key derived from the passphrase with Argon2id. Used to back
+// up key material outside the store.
+func (c *Creator) ExportEncrypted(passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	p, err := c.MarshalStorageJSON()
+	if err != nil {
+		return nil, err
+	}
+	b := backup{
+		Version: backupVersion2,
+		KDF:     backupKDF,
+		Time:    backupTime,
+		Memory:  backupMemory,
+		Threads: backupThreads,
+		Salt:    make([]byte, backupSaltLen)}
+	_, err = rand.Read(b.Salt)
+	if err != nil {
+		return nil, err
+	}
+	g, err := b.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	b.Nonce = make([]byte, g.NonceSize())
+	_, err = rand.Read(b.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	b.Data = g.Seal(nil, b.Nonce, p, b.additionalData())
+	return json.Marshal(&b)
+}
+
+// ImportEncrypted returns the creator from data returned by ExportEncrypted.
+// An error is returned if the passphrase is wrong or the data altered.
+func ImportEncrypted(data []byte, passphrase string) (*Creator, error) {
+	var b backup
+	err := json.Unmarshal(data, &b)
+	if err != nil {
+		return nil, err
+	}
+	if b.KDF != backupKDF {
+		return nil, fmt.Errorf("key derivation function '%s' not supported", b.KDF)
+	}
+	g, err := b.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.Nonce) != g.NonceSize() {
+		return nil, fmt.Errorf("nonce length '%d' invalid", len(b.Nonce))
+	}
+	p, err := g.Open(nil, b.Nonce, b.Data, b.additionalData())
+	if err != nil {
+		return nil, errors.New("passphrase incorrect or data altered")
+	}
+	var c Creator
+	err = c.unmarshalJSON(p, true)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// RestoreCreator adds the creator from data returned by ExportEncrypted to the
+// store. An error is returned if the store already contains the domain.
+func RestoreCreator(s Store, data []byte, passphrase string) error {
+	c, err := ImportEncrypted(data, passphrase)
+	if err != nil {
+		return err
+	}
+	e, err := s.GetCreator(c.domain)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		return fmt.Errorf("domain '%s' already exists", c.domain)
+	}
+	return s.setCreator(c)
+}
+
+// additionalData returns the data authenticated along with the encrypted
+// creator. Backups before version 2 only authenticate the key derivation
+// function.
+func (b *backup) additionalData() []byte {
+	if b.Version < backupVersion2 {
+		return []byte(backupKDF)
+	}
+	return []byte(fmt.Sprintf(
+		"%s:%d:%d:%d:%d:%x",
+		b.KDF,
+		b.Version,
+		b.Time,
+		b.Memory,
+		b.Threads,
+		b.Salt))
+}
+
+// cipher returns the AES-GCM cipher for the passphrase and parameters. An
+// error is returned if the parameters are zero or above the maximums.
+func (b *backup) cipher(passphrase string) (cipher.AEAD, error) {
+	if b.Time == 0 || b.Memory == 0 || b.Threads == 0 {
+		return nil, errors.New("key derivation parameters invalid")
+	}
+	if b.Time > backupMaxTime ||
+		b.Memory > backupMaxMemory ||
+		b.Threads > backupMaxThreads ||
+		len(b.Salt) > backupMaxSaltLen {
+		return nil, fmt.Errorf(
+			"key derivation parameters time '%d' memory '%d' threads '%d' "+
+				"salt length '%d' exceed the maximums",
+			b.Time,
+			b.Memory,
+			b.Threads,
+			len(b.Salt))
+	}
+	k := argon2.IDKey(
+		[]byte(passphrase),
+		b.Salt,
+		b.Time,
+		b.Memory,
+		b.Threads,
+		backupKeyLen)
+	a, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(a)
+}
@@ -0,0 +1,112 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBackupRoundTrip(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.ExportEncrypted("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ImportEncrypted(b, "wrong")
+	if err == nil {
+		t.Fatal("wrong passphrase should error")
+	}
+	s := newTestStore()
+	err = RestoreCreator(s, b, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := s.GetCreators()[testDomain]
+	if r == nil ||
+		r.privateKey != c.privateKey ||
+		r.publicKey != c.publicKey ||
+		r.name != c.name ||
+		r.created.Equal(c.created) == false {
+		t.Fatal("restored creator does not match")
+	}
+	err = RestoreCreator(s, b, "passphrase")
+	if err == nil {
+		t.Fatal("restoring an existing domain should error")
+	}
+}
+
+// TestBackupParameters checks key derivation parameters above the maximums
+// are refused, that changing the version is detected and that backups from
+// before version 2 can still be imported.
+func TestBackupParameters(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := c.ExportEncrypted("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []func(b *backup){
+		func(b *backup) { b.Time = backupMaxTime + 1 },
+		func(b *backup) { b.Memory = 1 << 31 },
+		func(b *backup) { b.Threads = 255 },
+		func(b *backup) { b.Salt = make([]byte, backupMaxSaltLen+1) },
+		func(b *backup) { b.Version = 0 }} {
+		var b backup
+		err = json.Unmarshal(j, &b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f(&b)
+		d, err := json.Marshal(&b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ImportEncrypted(d, "passphrase")
+		if err == nil {
+			t.Fatal("altered parameters should error")
+		}
+	}
+	var b backup
+	err = json.Unmarshal(j, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Version = 0
+	g, err := b.cipher("passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.MarshalStorageJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Data = g.Seal(nil, b.Nonce, p, []byte(backupKDF))
+	d, err := json.Marshal(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ImportEncrypted(d, "passphrase")
+	if err != nil || r.privateKey != c.privateKey {
+		t.Fatalf("backup before version 2 not imported '%v'", err)
+	}
+}
@@ -0,0 +1,298 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Prefixes that separate the hashes of leaves and interior nodes so that a
+// leaf can't be presented as an interior node.
+const (
+	batchLeafPrefix byte = 0
+	batchNodePrefix byte = 1
+)
+
+// BatchOWID is an OWID signed as part of a batch. The OWID's signature is the
+// signature of the root OWID, whose payload is the Merkle root of the batch,
+// and the proof is the path from the OWID's payload to the root.
+type BatchOWID struct {
+	OWID  *OWID    `json:"owid"`  // OWID with the signature of the root
+	Index int      `json:"index"` // Position of the OWID in the batch
+	Count int      `json:"count"` // Number of OWIDs in the batch
+	Proof [][]byte `json:"proof"` // Sibling hashes from the leaf to the root
+}
+
+// Root returns the root OWID for the batch calculated from the payload and the
+// proof. The root OWID verifies with the creator's public key in the same way
+// as any other OWID.
+func (b *BatchOWID) Root() (*OWID, error) {
+	r, err := batchRootFromProof(
+		batchLeaf(b.OWID.Payload),
+		b.Index,
+		b.Count,
+		b.Proof)
+	if err != nil {
+		return nil, err
+	}
+	o, err := NewOwid(b.OWID.Domain, b.OWID.Date, r)
+	if err != nil {
+		return nil, err
+	}
+	o.Signature = b.OWID.Signature
+	return o, nil
+}
+
+// Verify returns true if the proof is valid and the root OWID was signed by
+// the creator for the OWID's domain.
+func (b *BatchOWID) Verify(v *Verifier) (bool, error) {
+	r, err := b.Root()
+	if err != nil {
+		return false, err
+	}
+	return v.Verify(r)
+}
+
+// VerifyWithCreator returns true if the proof is valid and the root OWID was
+// signed by the creator.
+func (b *BatchOWID) VerifyWithCreator(c *Creator) (bool, error) {
+	r, err := b.Root()
+	if err != nil {
+		return false, err
+	}
+	return c.Verify(r)
+}
+
+// BatchSigner accumulates payloads and signs the Merkle root of the batch once
+// per interval so that a single signature covers many OWIDs.
+type BatchSigner struct {
+	creator *Creator
+	mutex   sync.Mutex
+	pending []*batchRequest
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// batchRequest is a payload waiting for the next batch to be signed.
+type batchRequest struct {
+	payload []byte
+	result  chan batchResult
+}
+
+// batchResult is the outcome of signing a batch for a single payload.
+type batchResult struct {
+	owid *BatchOWID
+	err  error
+}
+
+// NewBatchSigner creates a signer that signs the pending payloads with the
+// creator every interval. Close must be called to stop the signer.
+func NewBatchSigner(c *Creator, interval time.Duration) *BatchSigner {
+	b := &BatchSigner{
+		creator: c,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{})}
+	go b.run(interval)
+	return b
+}
+
+// Sign adds the payload to the next batch and waits for the batch to be
+// signed, or the context to be done.
+func (b *BatchSigner) Sign(
+	ctx context.Context,
+	payload []byte) (*BatchOWID, error) {
+	err := checkPayload(payload, 0)
+	if err != nil {
+		return nil, err
+	}
+	r := &batchRequest{payload: payload, result: make(chan batchResult, 1)}
+	b.mutex.Lock()
+	if b.isClosed() {
+		b.mutex.Unlock()
+		return nil, errors.New("batch signer closed")
+	}
+	b.pending = append(b.pending, r)
+	b.mutex.Unlock()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case v := <-r.result:
+		return v.owid, v.err
+	}
+}
+
+// Flush signs the pending payloads immediately.
+func (b *BatchSigner) Flush() {
+	b.mutex.Lock()
+	p := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+	if len(p) > 0 {
+		b.sign(p)
+	}
+}
+
+// Close signs any pending payloads and stops the signer.
+func (b *BatchSigner) Close() {
+	close(b.stop)
+	<-b.done
+}
+
+func (b *BatchSigner) isClosed() bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *BatchSigner) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	defer close(b.done)
+	for {
+		select {
+		case <-b.stop:
+			b.Flush()
+			return
+		case <-t.C:
+			b.Flush()
+		}
+	}
+}
+
+// sign creates the Merkle tree for the requests, signs the root and sends each
+// request its OWID and proof.
+func (b *BatchSigner) sign(p []*batchRequest) {
+	l := make([][]byte, len(p))
+	for i, r := range p {
+		l[i] = batchLeaf(r.payload)
+	}
+	t := batchTree(l)
+	o, err := b.creator.CreateOWIDandSign(t[len(t)-1][0])
+	for i, r := range p {
+		if err != nil {
+			r.result <- batchResult{err: err}
+			continue
+		}
+		r.result <- batchResult{owid: &BatchOWID{
+			OWID: &OWID{
+				Version:   o.Version,
+				Domain:    o.Domain,
+				Date:      o.Date,
+				Payload:   r.payload,
+				Signature: o.Signature},
+			Index: i,
+			Count: len(p),
+			Proof: batchProof(t, i)}}
+	}
+}
+
+// batchLeaf returns the leaf hash for the payload.
+func batchLeaf(payload []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{batchLeafPrefix})
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// batchNode returns the hash of the interior node with the children.
+func batchNode(left []byte, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{batchNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// batchTree returns the levels of the Merkle tree from the leaves to the root.
+// A node without a sibling is promoted to the next level unchanged.
+func batchTree(leaves [][]byte) [][][]byte {
+	t := [][][]byte{leaves}
+	for l := leaves; len(l) > 1; {
+		var n [][]byte
+		for i := 0; i < len(l); i += 2 {
+			if i+1 < len(l) {
+				n = append(n, batchNode(l[i], l[i+1]))
+			} else {
+				n = append(n, l[i])
+			}
+		}
+		t = append(t, n)
+		l = n
+	}
+	return t
+}
+
+// batchProof returns the sibling hashes from the leaf at the index to the
+// root. Levels where the node has no sibling are skipped.
+func batchProof(t [][][]byte, index int) [][]byte {
+	var p [][]byte
+	for _, l := range t[:len(t)-1] {
+		s := index ^ 1
+		if s < len(l) {
+			p = append(p, l[s])
+		}
+		index /= 2
+	}
+	return p
+}
+
+// batchRootFromProof returns the root calculated from the leaf, its position
+// in a batch of count leaves and the sibling hashes.
+func batchRootFromProof(
+	leaf []byte,
+	index int,
+	count int,
+	proof [][]byte) ([]byte, error) {
+	if count < 1 || index < 0 || index >= count {
+		return nil, fmt.Errorf(
+			"batch index '%d' invalid for count '%d'",
+			index,
+			count)
+	}
+	h := leaf
+	for count > 1 {
+		s := index ^ 1
+		if s < count {
+			if len(proof) == 0 {
+				return nil, errors.New("batch proof too short")
+			}
+			if len(proof[0]) != sha256.Size {
+				return nil, errors.New("batch proof hash length invalid")
+			}
+			if index%2 == 0 {
+				h = batchNode(h, proof[0])
+			} else {
+				h = batchNode(proof[0], h)
+			}
+			proof = proof[1:]
+		}
+		index /= 2
+		count = (count + 1) / 2
+	}
+	if len(proof) != 0 {
+		return nil, errors.New("batch proof too long")
+	}
+	return h, nil
+}
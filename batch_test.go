@@ -0,0 +1,102 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestBatchProof checks the root calculated from every proof matches the root
+// of the tree for a range of batch sizes.
+func TestBatchProof(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		var l [][]byte
+		for i := 0; i < n; i++ {
+			l = append(l, batchLeaf([]byte(fmt.Sprintf("payload %d", i))))
+		}
+		tr := batchTree(l)
+		r := tr[len(tr)-1][0]
+		for i := range l {
+			v, err := batchRootFromProof(l[i], i, n, batchProof(tr, i))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(v, r) == false {
+				t.Fatalf("root differs for leaf '%d' of '%d'", i, n)
+			}
+		}
+	}
+}
+
+// TestBatchSigner signs payloads concurrently in a single batch and checks
+// each OWID verifies and that altering a payload is detected.
+func TestBatchSigner(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBatchSigner(c, time.Hour)
+	var wg sync.WaitGroup
+	bs := make([]*BatchOWID, 5)
+	errs := make([]error, len(bs))
+	for i := range bs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bs[i], errs[i] = b.Sign(
+				context.Background(),
+				[]byte(fmt.Sprintf("payload %d", i)))
+		}(i)
+	}
+	for {
+		b.mutex.Lock()
+		n := len(b.pending)
+		b.mutex.Unlock()
+		if n == len(bs) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	b.Close()
+	wg.Wait()
+	for i, o := range bs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if o.Count != len(bs) {
+			t.Fatalf("expected count '%d', found '%d'", len(bs), o.Count)
+		}
+		v, err := o.VerifyWithCreator(c)
+		if err != nil || v == false {
+			t.Fatalf("batch OWID '%d' did not verify", i)
+		}
+	}
+	bs[0].OWID.Payload = []byte("altered")
+	v, err := bs[0].VerifyWithCreator(c)
+	if err != nil || v {
+		t.Fatal("altered batch OWID verified")
+	}
+	_, err = b.Sign(context.Background(), []byte(testPayload))
+	if err == nil {
+		t.Fatal("closed batch signer should refuse payloads")
+	}
+}
@@ -0,0 +1,165 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of the circuit breaker for a domain.
+type BreakerState string
+
+// States of a circuit breaker.
+const (
+	BreakerClosed   BreakerState = "closed"    // Requests are sent to the domain
+	BreakerOpen     BreakerState = "open"      // Requests are refused without being sent
+	BreakerHalfOpen BreakerState = "half-open" // A single request is probing the domain
+)
+
+// CircuitOpenError is returned when the public key for a domain is not
+// fetched because recent requests to the domain have failed.
+type CircuitOpenError struct {
+	Domain string    // Domain with the open circuit
+	Until  time.Time // Time the next request will be allowed
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf(
+		"domain '%s' unavailable until '%s'",
+		e.Domain,
+		e.Until.Format(time.RFC3339))
+}
+
+// BreakerMetrics describes the circuit breakers of a verifier.
+type BreakerMetrics struct {
+	States   map[string]BreakerState // State of each domain that has failed
+	Trips    uint64                  // Times a circuit has opened
+	Rejected uint64                  // Requests refused by an open circuit
+}
+
+// breakerDomain is the circuit breaker state for a single domain.
+type breakerDomain struct {
+	failures int       // Consecutive temporary failures
+	opened   time.Time // When the circuit last opened
+	state    BreakerState
+}
+
+// circuitBreaker tracks consecutive failures for each domain and refuses
+// requests to domains that have failed threshold times until the cooldown has
+// passed. A single request is then allowed to probe the domain.
+type circuitBreaker struct {
+	mutex     sync.Mutex
+	threshold int                       // Failures before the circuit opens
+	cooldown  time.Duration             // Time the circuit stays open
+	domains   map[string]*breakerDomain // State for domains that have failed
+	trips     uint64
+	rejected  uint64
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		domains:   make(map[string]*breakerDomain)}
+}
+
+// allow returns a CircuitOpenError if a request to the domain should not be
+// sent.
+func (b *circuitBreaker) allow(domain string, now time.Time) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	d := b.domains[domain]
+	if d == nil || d.state == BreakerClosed {
+		return nil
+	}
+	u := d.opened.Add(b.cooldown)
+	if d.state == BreakerOpen && now.Before(u) == false {
+		d.state = BreakerHalfOpen
+		return nil
+	}
+	b.rejected++
+	return &CircuitOpenError{Domain: domain, Until: u}
+}
+
+// success closes the circuit for the domain.
+func (b *circuitBreaker) success(domain string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.domains, domain)
+}
+
+// failure records a temporary failure for the domain opening the circuit if
+// the threshold is reached or the probe failed.
+func (b *circuitBreaker) failure(domain string, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	d := b.domains[domain]
+	if d == nil {
+		d = &breakerDomain{state: BreakerClosed}
+		b.domains[domain] = d
+	}
+	d.failures++
+	if d.state == BreakerHalfOpen ||
+		(d.state == BreakerClosed && d.failures >= b.threshold) {
+		d.state = BreakerOpen
+		d.opened = now
+		b.trips++
+	}
+}
+
+// abandon returns a half open circuit for the domain to open without
+// recording a failure when the probe stopped before it had an outcome, for
+// example because the caller cancelled it. Another probe is then allowed.
+func (b *circuitBreaker) abandon(domain string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	d := b.domains[domain]
+	if d != nil && d.state == BreakerHalfOpen {
+		d.state = BreakerOpen
+	}
+}
+
+// metrics returns a copy of the current state.
+func (b *circuitBreaker) metrics() BreakerMetrics {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	m := BreakerMetrics{
+		States:   make(map[string]BreakerState, len(b.domains)),
+		Trips:    b.trips,
+		Rejected: b.rejected}
+	for k, d := range b.domains {
+		m.States[k] = d.state
+	}
+	return m
+}
+
+// temporaryError returns true if a request that failed with the error might
+// succeed if retried.
+func temporaryError(err error) bool {
+	var s *statusError
+	if errors.As(err, &s) {
+		return s.code == http.StatusTooManyRequests ||
+			s.code >= http.StatusInternalServerError
+	}
+	var n net.Error
+	return errors.As(err, &n)
+}
@@ -0,0 +1,248 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestVerifierRetryAndBreaker checks failed fetches are retried and that the
+// circuit opens once the threshold is reached so no further requests are
+// sent.
+func TestVerifierRetryAndBreaker(t *testing.T) {
+	var n atomic.Int32
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(u.Scheme, nil)
+	v.SetRetry(2, time.Millisecond)
+	v.SetCircuitBreaker(2, time.Hour)
+	_, err = v.Verify(o)
+	if err == nil {
+		t.Fatal("verify should fail")
+	}
+
+	// Each attempt requests the well known URI and the public key end point.
+	if n.Load() != 6 {
+		t.Fatalf("expected '6' requests, found '%d'", n.Load())
+	}
+	if v.BreakerMetrics().States[u.Host] != BreakerClosed {
+		t.Fatal("circuit should be closed after one failure")
+	}
+	v.Verify(o)
+	m := v.BreakerMetrics()
+	if m.States[u.Host] != BreakerOpen || m.Trips != 1 {
+		t.Fatalf("circuit should be open, found '%v'", m)
+	}
+	n.Store(0)
+	_, err = v.Verify(o)
+	var e *CircuitOpenError
+	if errors.As(err, &e) == false {
+		t.Fatalf("expected circuit open, found '%v'", err)
+	}
+	if n.Load() != 0 {
+		t.Fatal("request sent with the circuit open")
+	}
+	if v.BreakerMetrics().Rejected != 1 {
+		t.Fatal("rejected request not counted")
+	}
+}
+
+// newUnavailableOWID returns an OWID for the domain of a server that always
+// responds service unavailable, the server, and the count of requests sent.
+func newUnavailableOWID(t *testing.T) (*OWID, *httptest.Server, *atomic.Int32) {
+	n := &atomic.Int32{}
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, h, n
+}
+
+// TestVerifierRetryClock checks the wait between retries follows the
+// verifier's clock rather than the system clock.
+func TestVerifierRetryClock(t *testing.T) {
+	o, h, n := newUnavailableOWID(t)
+	defer h.Close()
+	m := NewManualClock(time.Now())
+	v := NewVerifier("http", nil)
+	v.SetClock(m)
+	v.SetRetry(1, time.Hour)
+	d := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(o)
+		d <- err
+	}()
+	select {
+	case <-d:
+		t.Fatal("verify completed without the clock advancing")
+	case <-time.After(100 * time.Millisecond):
+	}
+	for {
+		select {
+		case err := <-d:
+			if err == nil {
+				t.Fatal("verify should fail")
+			}
+			if n.Load() != 4 {
+				t.Fatalf("expected '4' requests, found '%d'", n.Load())
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			m.Advance(time.Hour)
+		}
+	}
+}
+
+// TestVerifierRetryCancel checks the wait between retries stops when the
+// context is cancelled.
+func TestVerifierRetryCancel(t *testing.T) {
+	o, h, n := newUnavailableOWID(t)
+	defer h.Close()
+	v := NewVerifier("http", nil)
+	v.SetRetry(1, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	d := make(chan error, 1)
+	go func() {
+		_, err := v.VerifyWithReportContext(ctx, o)
+		d <- err
+	}()
+	for n.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-d:
+		if errors.Is(err, context.Canceled) == false {
+			t.Fatalf("expected cancelled, found '%v'", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("verify did not stop when cancelled")
+	}
+}
+
+// TestVerifierFetchCancel checks fetching public information from a slow
+// domain stops when the context is done, and that an abandoned probe of a half
+// open circuit allows another probe.
+func TestVerifierFetchCancel(t *testing.T) {
+	d := make(chan struct{})
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-d:
+			}
+		}))
+	defer h.Close()
+	defer close(d)
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(u.Scheme, nil)
+	v.SetCircuitBreaker(1, 0)
+	v.breaker.failure(u.Host, v.now())
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		100*time.Millisecond)
+	defer cancel()
+	s := time.Now()
+	_, err = v.VerifyWithReportContext(ctx, o)
+	if errors.Is(err, context.DeadlineExceeded) == false {
+		t.Fatalf("expected deadline exceeded, found '%v'", err)
+	}
+	if time.Since(s) > 5*time.Second {
+		t.Fatal("verify did not stop when the context was done")
+	}
+	if v.breaker.allow(u.Host, v.now()) != nil {
+		t.Fatal("probe refused after the previous probe was abandoned")
+	}
+}
+
+// TestCircuitBreakerHalfOpen checks a single probe is allowed after the
+// cooldown and that its outcome closes or reopens the circuit.
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b := newCircuitBreaker(1, time.Minute)
+	s := time.Now()
+	b.failure(testDomain, s)
+	if b.allow(testDomain, s.Add(time.Second)) == nil {
+		t.Fatal("open circuit allowed request")
+	}
+	if b.allow(testDomain, s.Add(time.Minute)) != nil {
+		t.Fatal("probe refused after cooldown")
+	}
+	if b.allow(testDomain, s.Add(time.Minute)) == nil {
+		t.Fatal("second probe allowed")
+	}
+	b.failure(testDomain, s.Add(time.Minute))
+	if b.metrics().States[testDomain] != BreakerOpen {
+		t.Fatal("failed probe should reopen the circuit")
+	}
+	if b.allow(testDomain, s.Add(2*time.Minute)) != nil {
+		t.Fatal("probe refused after second cooldown")
+	}
+	b.success(testDomain)
+	if b.allow(testDomain, s.Add(2*time.Minute)) != nil {
+		t.Fatal("closed circuit refused request")
+	}
+	if len(b.metrics().States) != 0 {
+		t.Fatal("closed domain still tracked")
+	}
+}
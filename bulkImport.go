@@ -0,0 +1,264 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Outcomes of importing a creator in a BulkResult.
+const (
+	BulkCreated = "created" // The creator was registered
+	BulkExists  = "exists"  // A creator was already registered for the domain
+	BulkInvalid = "invalid" // The creator failed validation
+	BulkFailed  = "failed"  // The creator could not be stored
+)
+
+// ParseBulkCSV returns the creators in the CSV. The first row is a header
+// naming the BulkCreator fields as they appear in JSON, for example domain,
+// name and contractURL. Columns can be in any order and optional ones left
+// out.
+func ParseBulkCSV(r io.Reader) ([]*BulkCreator, error) {
+	c := csv.NewReader(r)
+	c.TrimLeadingSpace = true
+	h, err := c.Read()
+	if err != nil {
+		return nil, fmt.Errorf("CSV header missing: %s", err.Error())
+	}
+	for _, n := range h {
+		if bulkField(&BulkCreator{}, n) == nil {
+			return nil, fmt.Errorf("CSV column '%s' not supported", n)
+		}
+	}
+	var cs []*BulkCreator
+	for {
+		v, err := c.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b := &BulkCreator{}
+		for i, n := range h {
+			*bulkField(b, n) = strings.TrimSpace(v[i])
+		}
+		cs = append(cs, b)
+	}
+	return cs, nil
+}
+
+// ParseBulkJSON returns the creators in the JSON array.
+func ParseBulkJSON(r io.Reader) ([]*BulkCreator, error) {
+	var cs []*BulkCreator
+	err := json.NewDecoder(r).Decode(&cs)
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// bulkField returns the field of the creator for the CSV column, or nil if
+// the column is not a field.
+func bulkField(b *BulkCreator, column string) *string {
+	switch strings.ToLower(strings.TrimSpace(column)) {
+	case "domain":
+		return &b.Domain
+	case "name":
+		return &b.Name
+	case "contracturl":
+		return &b.ContractURL
+	case "email":
+		return &b.Email
+	case "dpourl":
+		return &b.DpoURL
+	case "jurisdiction":
+		return &b.Jurisdiction
+	case "privatekey":
+		return &b.PrivateKey
+	case "publickey":
+		return &b.PublicKey
+	}
+	return nil
+}
+
+// ImportCreators validates and adds the creators to the store using the
+// registration settings in the configuration, returning the outcome for each
+// in the order provided. Rows that fail do not stop the others.
+func ImportCreators(
+	s Store,
+	c *Configuration,
+	cs []*BulkCreator) []*BulkResult {
+	return importCreators(c, cs, time.Now().UTC(), func(n *Creator) error {
+		return addCreator(s, n)
+	})
+}
+
+// ImportCreators validates and registers the creators, returning the outcome
+// for each in the order provided. Each new creator is audited and notified as
+// if registered with the form.
+func (s *Services) ImportCreators(
+	cs []*BulkCreator,
+	accessKey string) []*BulkResult {
+	return importCreators(s.Config(), cs, s.now().UTC(), func(n *Creator) error {
+		defer s.locks.lock(n.domain)()
+		err := addCreator(s.store, n)
+		if err == nil {
+			s.audit(AuditRegister, n.domain, accessKey, nil, n)
+			s.notify(AuditRegister, n)
+		}
+		return err
+	})
+}
+
+func importCreators(
+	c *Configuration,
+	cs []*BulkCreator,
+	now time.Time,
+	add func(n *Creator) error) []*BulkResult {
+	var sellers *SellersChecker
+	if len(c.SellersSources) > 0 {
+		sellers = NewSellersChecker(c.SellersSources, 0)
+	}
+	rs := make([]*BulkResult, 0, len(cs))
+	for i, b := range cs {
+		r := &BulkResult{Row: i + 1, Domain: b.Domain}
+		rs = append(rs, r)
+		n, err := newBulkCreator(c, b, now)
+		if err == nil && sellers != nil {
+			err = checkSellersListed(c, sellers, n.domain)
+		}
+		if err != nil {
+			r.Status = BulkInvalid
+			r.Error = err.Error()
+			continue
+		}
+		r.Domain = n.domain
+		err = add(n)
+		var e *AlreadyRegisteredError
+		if errors.As(err, &e) {
+			r.Status = BulkExists
+			r.Error = err.Error()
+		} else if err != nil {
+			r.Status = BulkFailed
+			r.Error = err.Error()
+		} else {
+			r.Status = BulkCreated
+		}
+	}
+	return rs
+}
+
+// addCreator adds the creator to the store returning AlreadyRegisteredError
+// if the domain already has a creator.
+func addCreator(s Store, n *Creator) error {
+	e, err := s.GetCreator(n.domain)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		return &AlreadyRegisteredError{Domain: n.domain}
+	}
+	return s.setCreator(n)
+}
+
+// newBulkCreator returns the creator for the import applying the same rules
+// as the register form. Keys are generated unless both are provided.
+func newBulkCreator(
+	c *Configuration,
+	b *BulkCreator,
+	now time.Time) (*Creator, error) {
+	d, err := registrationDomain(b.Domain, c.AllowedRegisterHosts)
+	if err != nil {
+		return nil, err
+	}
+	if e := creatorNameError(b.Name); e != "" {
+		return nil, errors.New(e)
+	}
+	u, err := url.Parse(b.ContractURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("contract URL '%s' invalid", b.ContractURL)
+	}
+	t := Contact{
+		Email:        b.Email,
+		DpoURL:       b.DpoURL,
+		Jurisdiction: b.Jurisdiction}
+	err = t.validate()
+	if err != nil {
+		return nil, err
+	}
+	private, public, err := bulkKeys(c, b)
+	if err != nil {
+		return nil, err
+	}
+	s := CreatorActive
+	if c.DomainChallenge != "" {
+		s = CreatorPending
+	}
+	return newCreator(d, private, public, b.Name, b.ContractURL, now, t, s), nil
+}
+
+// bulkKeys returns the PEM keys provided after checking they are a pair on a
+// supported curve, or new keys if neither is provided.
+func bulkKeys(c *Configuration, b *BulkCreator) (string, string, error) {
+	if b.PrivateKey == "" && b.PublicKey == "" {
+		k, err := CurveByName(c.Curve)
+		if err != nil {
+			return "", "", err
+		}
+		x, err := NewCryptoWithCurve(k)
+		if err != nil {
+			return "", "", err
+		}
+		private, err := x.privateKeyToPemString()
+		if err != nil {
+			return "", "", err
+		}
+		public, err := x.publicKeyToPemString()
+		if err != nil {
+			return "", "", err
+		}
+		return private, public, nil
+	}
+	if b.PrivateKey == "" || b.PublicKey == "" {
+		return "", "", errors.New(
+			"private and public keys must be provided together")
+	}
+	s, err := NewCryptoSignOnly(b.PrivateKey)
+	if err != nil {
+		return "", "", fmt.Errorf("private key invalid: %s", err.Error())
+	}
+	v, err := NewCryptoVerifyOnly(b.PublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("public key invalid: %s", err.Error())
+	}
+	if s.privateKey.PublicKey.Equal(v.publicKey) == false {
+		return "", "", errors.New("public key does not match private key")
+	}
+	_, err = CurveByName(v.publicKey.Curve.Params().Name)
+	if err != nil {
+		return "", "", err
+	}
+	return b.PrivateKey, b.PublicKey, nil
+}
@@ -0,0 +1,137 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseBulkCSV checks columns are matched by name in any order and that
+// unknown columns are refused.
+func TestParseBulkCSV(t *testing.T) {
+	cs, err := ParseBulkCSV(strings.NewReader(
+		"name,Domain,contractURL\nExample Org,example.com,https://example.com/terms\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 1 ||
+		cs[0].Domain != "example.com" ||
+		cs[0].Name != "Example Org" {
+		t.Fatalf("unexpected creators '%v'", cs)
+	}
+	_, err = ParseBulkCSV(strings.NewReader("domain,colour\nexample.com,red\n"))
+	if err == nil {
+		t.Error("unknown column should error")
+	}
+}
+
+// TestImportCreators imports valid, invalid and existing creators checking
+// the outcome of each row.
+func TestImportCreators(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := NewCrypto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := x.privateKeyToPemString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x.publicKeyToPemString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewCrypto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := o.publicKeyToPemString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := registerContractURL
+	rs := ImportCreators(s.store, s.Config(), []*BulkCreator{
+		{Domain: "example.com", Name: testOrgName, ContractURL: u},
+		{Domain: "example.org", Name: testOrgName, ContractURL: u,
+			PrivateKey: private, PublicKey: public},
+		{Domain: "example.net", Name: testOrgName, ContractURL: u,
+			PrivateKey: private, PublicKey: other},
+		{Domain: "example.edu", Name: "Short", ContractURL: u},
+		{Domain: testDomain, Name: testOrgName, ContractURL: u},
+		{Domain: "example.com", Name: testOrgName, ContractURL: u}})
+	for i, e := range []string{
+		BulkCreated,
+		BulkCreated,
+		BulkInvalid,
+		BulkInvalid,
+		BulkExists,
+		BulkExists} {
+		if rs[i].Row != i+1 || rs[i].Status != e {
+			t.Errorf("row '%d' status '%s' not '%s' '%s'",
+				i+1, rs[i].Status, e, rs[i].Error)
+		}
+	}
+	c, err := s.store.GetCreator("example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil || c.publicKey != public {
+		t.Fatal("pre-generated keys not used")
+	}
+}
+
+// TestHandlerImport posts CSV to the handler and checks access is required.
+func TestHandlerImport(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := "domain,name,contractURL\nexample.com," + testOrgName + "," +
+		registerContractURL + "\n"
+	r := httptest.NewRequest(
+		"POST",
+		"/owid/api/v3/import?accesskey=key1",
+		strings.NewReader(b))
+	r.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	HandlerImport(s)(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 but got '%d'", w.Code)
+	}
+	var rs []*BulkResult
+	err = json.Unmarshal(w.Body.Bytes(), &rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Status != BulkCreated {
+		t.Fatalf("unexpected results '%v'", rs)
+	}
+	r = httptest.NewRequest("POST", "/owid/api/v3/import", strings.NewReader(b))
+	r.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	HandlerImport(s)(w, r)
+	if w.Code != http.StatusNetworkAuthenticationRequired {
+		t.Fatalf("expected 511 but got '%d'", w.Code)
+	}
+}
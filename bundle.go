@@ -0,0 +1,247 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BundleVerifier verifies OWIDs using the public keys contained in a bundle
+// without any network access. Used for batch or ETL jobs.
+type BundleVerifier struct {
+	bundle   *Bundle
+	cryptos  map[string]*Crypto       // Crypto instances keyed on domain
+	statuses map[string]CreatorStatus // Status of each creator keyed on domain
+}
+
+// The default maximum age of a bundle used with NewBundleVerifier.
+const defaultBundleMaxAge = 24 * time.Hour
+
+// The time a signed bundle is reused by HandlerBundle before it is recreated.
+// Matches the max-age of the response.
+const bundleCacheDuration = time.Minute
+
+// BundleFromJSON creates a bundle from the JSON provided.
+func BundleFromJSON(j []byte) (*Bundle, error) {
+	var b Bundle
+	err := json.Unmarshal(j, &b)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// NewBundleVerifier creates a verifier for the creators in the bundle. The
+// bundle must be signed with the private key associated with the trusted
+// public key in PEM format provided, and be created no more than the maximum
+// age ago. Zero uses a maximum age of 24 hours. Each creator's public
+// information must carry a valid self signature.
+func NewBundleVerifier(
+	b *Bundle,
+	public string,
+	maxAge time.Duration) (*BundleVerifier, error) {
+	ok, err := b.VerifySignature(public)
+	if err != nil {
+		return nil, fmt.Errorf("bundle from '%s' %s", b.Domain, err.Error())
+	}
+	if ok == false {
+		return nil, fmt.Errorf(
+			"bundle from '%s' signature is not valid",
+			b.Domain)
+	}
+	if maxAge == 0 {
+		maxAge = defaultBundleMaxAge
+	}
+	n := time.Now().UTC()
+	if b.Created.After(n.Add(defaultFutureTolerance)) ||
+		n.Sub(b.Created) > maxAge {
+		return nil, fmt.Errorf(
+			"bundle from '%s' created '%s' has expired",
+			b.Domain,
+			b.Created.Format(time.RFC3339))
+	}
+	v := BundleVerifier{
+		bundle:   b,
+		cryptos:  make(map[string]*Crypto),
+		statuses: make(map[string]CreatorStatus)}
+	for _, p := range b.Creators {
+		ok, err := p.VerifySelfSignature()
+		if err != nil {
+			return nil, fmt.Errorf("creator '%s' %s", p.Domain, err.Error())
+		}
+		if ok == false {
+			return nil, fmt.Errorf(
+				"creator '%s' signature is not valid",
+				p.Domain)
+		}
+		d := normalizeDomain(p.Domain)
+		v.cryptos[d], err = NewCryptoVerifyOnly(p.PublicKeySPKI)
+		if err != nil {
+			return nil, fmt.Errorf("creator '%s' %s", p.Domain, err.Error())
+		}
+		v.statuses[d], err = parseCreatorStatus(p.Status)
+		if err != nil {
+			return nil, fmt.Errorf("creator '%s' %s", p.Domain, err.Error())
+		}
+	}
+	return &v, nil
+}
+
+// Bundle returns the bundle used by the verifier.
+func (v *BundleVerifier) Bundle() *Bundle { return v.bundle }
+
+// Verify returns true if the OWID and any others were signed by the creator
+// for the OWID's domain. An error is returned if the domain is not in the
+// bundle, or if its creator is suspended or pending.
+func (v *BundleVerifier) Verify(o *OWID, others ...*OWID) (bool, error) {
+	d := normalizeDomain(o.Domain)
+	c := v.cryptos[d]
+	if c == nil {
+		return false, fmt.Errorf("domain '%s' not in bundle", o.Domain)
+	}
+	switch v.statuses[d] {
+	case CreatorSuspended:
+		return false, fmt.Errorf("domain '%s' creator is suspended", o.Domain)
+	case CreatorPending:
+		return false, fmt.Errorf("domain '%s' creator is pending", o.Domain)
+	}
+	return o.VerifyWithCrypto(c, others)
+}
+
+// VerifySignature returns true if the bundle was signed with the private key
+// associated with the public key in PEM format provided.
+func (b *Bundle) VerifySignature(public string) (bool, error) {
+	c, err := NewCryptoVerifyOnly(public)
+	if err != nil {
+		return false, err
+	}
+	d, err := b.signingData()
+	if err != nil {
+		return false, err
+	}
+	return c.VerifyByteArray(d, b.Signature)
+}
+
+// newBundle creates a bundle of the creators provided signed by the creator c
+// and dated at the time provided. Creators whose public information can't be
+// created are logged and omitted.
+func newBundle(
+	c *Creator,
+	cs map[string]*Creator,
+	now time.Time) (*Bundle, error) {
+	b := Bundle{Domain: c.domain, Created: now.UTC()}
+	for d, i := range cs {
+		p, err := publicCreator(i)
+		if err != nil {
+			log.Printf("bundle creator '%s' omitted: %s", d, err.Error())
+			continue
+		}
+		b.Creators = append(b.Creators, p)
+	}
+	sort.Slice(b.Creators, func(i, j int) bool {
+		return b.Creators[i].Domain < b.Creators[j].Domain
+	})
+	x, err := c.NewCryptoSignOnly()
+	if err != nil {
+		return nil, err
+	}
+	d, err := b.signingData()
+	if err != nil {
+		return nil, err
+	}
+	b.Signature, err = x.SignByteArray(d)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// bundleCache holds the signed bundle JSON for each signing domain so that the
+// creators are not signed for every request.
+type bundleCache struct {
+	mutex   sync.Mutex
+	bundles map[string]*cachedBundle
+}
+
+// cachedBundle is a signed bundle as JSON and the time it expires.
+type cachedBundle struct {
+	json    []byte
+	expires time.Time
+}
+
+// get returns the bundle JSON for the creator c, creating and signing a new
+// bundle if there is none or the cached one has expired.
+func (b *bundleCache) get(
+	c *Creator,
+	cs map[string]*Creator,
+	now time.Time) ([]byte, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if v, ok := b.bundles[c.domain]; ok && now.Before(v.expires) {
+		return v.json, nil
+	}
+	n, err := newBundle(c, cs, now)
+	if err != nil {
+		return nil, err
+	}
+	j, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	if b.bundles == nil {
+		b.bundles = make(map[string]*cachedBundle)
+	}
+	b.bundles[c.domain] = &cachedBundle{
+		json:    j,
+		expires: now.Add(bundleCacheDuration)}
+	return j, nil
+}
+
+// signingData returns the fields that are signed as a byte array. Includes the
+// signed fields and signature of each creator.
+func (b *Bundle) signingData() ([]byte, error) {
+	var f bytes.Buffer
+	err := writeString(&f, b.Domain)
+	if err != nil {
+		return nil, err
+	}
+	err = writeString(&f, b.Created.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range b.Creators {
+		d, err := p.signingData()
+		if err != nil {
+			return nil, err
+		}
+		err = writeByteArray(&f, d)
+		if err != nil {
+			return nil, err
+		}
+		err = writeByteArray(&f, p.Signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.Bytes(), nil
+}
@@ -0,0 +1,127 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Methods a registering domain can use to prove it is controlled by the
+// registrant.
+const (
+	ChallengeHTTP = "http" // Token served at challengePath on the domain
+	ChallengeDNS  = "dns"  // Token in a TXT record at challengeDNSPrefix
+)
+
+// Path on the registering domain that must return the challenge token.
+const challengePath = "/.well-known/owid-challenge"
+
+// Prefix added to the registering domain for the TXT record that must contain
+// the challenge token.
+const challengeDNSPrefix = "_owid-challenge."
+
+// The maximum number of bytes read from the challenge path.
+const maxChallengeLength = 1024
+
+// lookupTXT is used to resolve TXT records and can be replaced in tests.
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// challengeToken returns the token the creator's domain must publish before
+// the creator is activated. Derived from the creator's new public key so that
+// each registration has a different token that doesn't need to be stored.
+func challengeToken(c *Creator) string {
+	h := sha256.Sum256([]byte("owid-challenge:" + c.domain + ":" + c.publicKey))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+// challengeLocation returns where the token must be published for the method
+// configured.
+func challengeLocation(c *Configuration, domain string) string {
+	if c.DomainChallenge == ChallengeDNS {
+		return challengeDNSPrefix + domain
+	}
+	return c.Scheme + "://" + domain + challengePath
+}
+
+// checkChallenge returns nil if the creator's domain publishes the challenge
+// token using the configured method, otherwise an error describing why not.
+func checkChallenge(c *Configuration, n *Creator) error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(c.ContractURLTimeout)*time.Second)
+	defer cancel()
+	t := challengeToken(n)
+	l := challengeLocation(c, n.domain)
+	switch c.DomainChallenge {
+	case ChallengeHTTP:
+		return checkChallengeHTTP(ctx, l, t)
+	case ChallengeDNS:
+		return checkChallengeDNS(ctx, l, t)
+	}
+	return fmt.Errorf("challenge '%s' not supported", c.DomainChallenge)
+}
+
+// checkChallengeHTTP returns nil if the body returned from the URL is the
+// token.
+func checkChallengeHTTP(ctx context.Context, u string, t string) error {
+	q, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	r, err := client.Do(q)
+	if err != nil {
+		return fmt.Errorf("challenge '%s' not reachable: %s", u, err.Error())
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf(
+			"challenge '%s' returned status '%d'",
+			u,
+			r.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxChallengeLength))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(b)) != t {
+		return fmt.Errorf("challenge '%s' does not contain the token", u)
+	}
+	return nil
+}
+
+// checkChallengeDNS returns nil if one of the TXT records for the name is the
+// token.
+func checkChallengeDNS(ctx context.Context, name string, t string) error {
+	rs, err := lookupTXT(ctx, name)
+	if err != nil {
+		return fmt.Errorf("challenge '%s' not found: %s", name, err.Error())
+	}
+	for _, r := range rs {
+		if strings.TrimSpace(r) == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("challenge '%s' does not contain the token", name)
+}
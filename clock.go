@@ -0,0 +1,136 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time. Creators, services, verifiers and access
+// tokens use the system clock unless another is set so that tests can control
+// key age, tolerance and expiry without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock that returns the system time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// timerClock is implemented by clocks that provide timers so that waits, such
+// as retry backoffs, follow the clock.
+type timerClock interface {
+	Clock
+
+	// newTimer returns a channel that receives the time once the duration has
+	// elapsed on the clock, and a function that stops the timer.
+	newTimer(d time.Duration) (<-chan time.Time, func())
+}
+
+// clockTimer returns a timer for the duration from the clock, or a system
+// timer if the clock does not provide timers.
+func clockTimer(c Clock, d time.Duration) (<-chan time.Time, func()) {
+	if t, ok := c.(timerClock); ok {
+		return t.newTimer(d)
+	}
+	t := time.NewTimer(d)
+	return t.C, func() { t.Stop() }
+}
+
+// clockNow returns the time from the clock, or the system time if nil.
+func clockNow(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
+// ManualClock is a Clock that only changes when set or advanced. Timers fire
+// when the clock reaches their time. Safe for use from multiple goroutines.
+type ManualClock struct {
+	mutex  sync.Mutex
+	t      time.Time
+	timers map[*manualTimer]bool // Timers that have not yet fired
+}
+
+// manualTimer fires when the manual clock reaches the time.
+type manualTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewManualClock creates a clock that returns the time provided until it is
+// changed.
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{t: t}
+}
+
+// Now returns the time the clock is set to.
+func (m *ManualClock) Now() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.t
+}
+
+// Set changes the time the clock returns.
+func (m *ManualClock) Set(t time.Time) {
+	m.mutex.Lock()
+	m.t = t
+	m.fire()
+	m.mutex.Unlock()
+}
+
+// Advance moves the time the clock returns forward by the duration.
+func (m *ManualClock) Advance(d time.Duration) {
+	m.mutex.Lock()
+	m.t = m.t.Add(d)
+	m.fire()
+	m.mutex.Unlock()
+}
+
+// newTimer returns a timer that fires once the clock has been moved on by the
+// duration.
+func (m *ManualClock) newTimer(d time.Duration) (<-chan time.Time, func()) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	t := &manualTimer{at: m.t.Add(d), c: make(chan time.Time, 1)}
+	if m.timers == nil {
+		m.timers = make(map[*manualTimer]bool)
+	}
+	m.timers[t] = true
+	m.fire()
+	return t.c, func() {
+		m.mutex.Lock()
+		delete(m.timers, t)
+		m.mutex.Unlock()
+	}
+}
+
+// fire sends the time to the timers that have been reached. The mutex must be
+// held.
+func (m *ManualClock) fire() {
+	for t := range m.timers {
+		if t.at.After(m.t) == false {
+			t.c <- m.t
+			delete(m.timers, t)
+		}
+	}
+}
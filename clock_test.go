@@ -0,0 +1,104 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestClockCreator checks OWIDs are dated with the creator's clock.
+func TestClockCreator(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := NewManualClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	c = c.WithClock(k)
+	k.Advance(time.Hour)
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Date.Equal(k.Now()) == false {
+		t.Fatalf("OWID date '%s' not '%s'", o.Date, k.Now())
+	}
+}
+
+// TestClockVerifier checks the future tolerance and minimum key age use the
+// verifier's clock so that no sleeping is needed.
+func TestClockVerifier(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	k := NewManualClock(time.Now().UTC())
+	s.SetClock(k)
+	k.Advance(time.Hour)
+	o, err := s.Sign(u.Host, []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(u.Scheme, &VerificationPolicy{MinimumKeyAge: 2 * time.Hour})
+	_, err = v.Verify(o)
+	if err == nil {
+		t.Fatal("future dated OWID should error with the system clock")
+	}
+	v.SetClock(k)
+	_, err = v.Verify(o)
+	if err == nil {
+		t.Fatal("recently created key should error")
+	}
+	k.Advance(2 * time.Hour)
+	r, err := v.Verify(o)
+	if err != nil || r == false {
+		t.Fatalf("OWID not verified once key old enough '%v'", err)
+	}
+}
+
+// TestClockAccessHMAC checks token expiry uses the clock.
+func TestClockAccessHMAC(t *testing.T) {
+	a := NewAccessHMAC([]byte("secret"))
+	k := NewManualClock(time.Now())
+	a.SetClock(k)
+	n := a.NewToken(ScopeAdmin, k.Now().Add(time.Minute))
+	v, err := a.GetAllowed(n)
+	if err != nil || v == false {
+		t.Fatalf("token not allowed '%v'", err)
+	}
+	k.Advance(2 * time.Minute)
+	v, _ = a.GetAllowed(n)
+	if v {
+		t.Fatal("expired token allowed")
+	}
+}
@@ -0,0 +1,36 @@
+//go:build js && wasm
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+// Command owid-wasm exports OWID verification to JavaScript when built as
+// WebAssembly.
+//
+// Usage:
+//
+//	GOOS=js GOARCH=wasm go build -o owid.wasm ./cmd/owid-wasm
+//
+// Load owid.wasm with the wasm_exec.js file from the Go distribution. Once
+// running the global functions owidVerify(owidB64, dataB64, spkiPem) and
+// owidDecode(owidB64) are available.
+package main
+
+import "github.com/SWAN-community/owid-go"
+
+func main() {
+	owid.RegisterJS()
+	select {}
+}
@@ -0,0 +1,343 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+// Command owid performs administrative operations on OWID stores.
+//
+// Usage:
+//
+//	owid migrate -src appsettings.src.json -dst appsettings.dst.json
+//	owid export -config appsettings.json -domain example.com -out backup.json
+//	owid import -config appsettings.json -in backup.json
+//	owid rotation-preview -config appsettings.json -domain example.com -max-age 720h
+//	owid self-test -config appsettings.json
+//	owid migrate-schema -config appsettings.json
+//	owid edge-manifest -config appsettings.json -out keys.bin
+//	owid bulk-import -config appsettings.json -in creators.csv
+//
+// The migrate subcommand copies all creators and keys from the store in the
+// source configuration to the store in the destination configuration. Private
+// keys are copied as stored and are not re-encrypted.
+//
+// The export subcommand writes the creator for the domain encrypted with the
+// passphrase in the OWID_PASSPHRASE environment variable. The import
+// subcommand adds the creator from such a backup to the store.
+//
+// The rotation-preview subcommand prints as JSON what retiring the creator's
+// current key would do given the maximum age of OWIDs that must still verify.
+// Retired keys are included if an archive file is provided with -archive.
+//
+// The self-test subcommand signs and verifies a probe payload with every
+// creator in the store, printing any that fail and exiting with status 1.
+//
+// The migrate-schema subcommand applies any schema migrations the store needs
+// to be used by this version.
+//
+// The edge-manifest subcommand writes the compact key manifest used to verify
+// OWIDs at CDN edges. Retired keys are included if an archive file is
+// provided with -archive.
+//
+// The bulk-import subcommand registers the creators in a CSV file with a
+// header row, or a JSON array if the file name ends in .json, and prints the
+// outcome for each as JSON. It exits with status 1 if any could not be
+// registered.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SWAN-community/owid-go"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "migrate":
+		migrate(os.Args[2:])
+	case "export":
+		export(os.Args[2:])
+	case "import":
+		restore(os.Args[2:])
+	case "rotation-preview":
+		rotationPreview(os.Args[2:])
+	case "self-test":
+		selfTest(os.Args[2:])
+	case "migrate-schema":
+		migrateSchema(os.Args[2:])
+	case "edge-manifest":
+		edgeManifest(os.Args[2:])
+	case "bulk-import":
+		bulkImport(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: owid migrate -src <config> -dst <config>")
+	fmt.Fprintln(os.Stderr, "       owid export -config <config> -domain <domain> -out <file>")
+	fmt.Fprintln(os.Stderr, "       owid import -config <config> -in <file>")
+	fmt.Fprintln(os.Stderr, "       owid rotation-preview -config <config> -domain <domain> -max-age <duration> [-archive <file>]")
+	fmt.Fprintln(os.Stderr, "       owid self-test -config <config>")
+	fmt.Fprintln(os.Stderr, "       owid migrate-schema -config <config>")
+	fmt.Fprintln(os.Stderr, "       owid edge-manifest -config <config> -out <file> [-archive <file>]")
+	fmt.Fprintln(os.Stderr, "       owid bulk-import -config <config> -in <file>")
+	os.Exit(2)
+}
+
+func migrate(args []string) {
+	f := flag.NewFlagSet("migrate", flag.ExitOnError)
+	src := f.String("src", "", "configuration file for the source store")
+	dst := f.String("dst", "", "configuration file for the destination store")
+	f.Parse(args)
+	if *src == "" || *dst == "" {
+		usage()
+	}
+	s, err := newStore(*src)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d, err := newStore(*dst)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = owid.MigrateStore(s, d)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("OWID:migrated '%d' creators", len(s.GetCreators()))
+}
+
+func export(args []string) {
+	f := flag.NewFlagSet("export", flag.ExitOnError)
+	config := f.String("config", "", "configuration file for the store")
+	domain := f.String("domain", "", "domain of the creator to export")
+	out := f.String("out", "", "file to write the encrypted backup to")
+	f.Parse(args)
+	if *config == "" || *domain == "" || *out == "" {
+		usage()
+	}
+	s, err := newStore(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, err := s.GetCreator(*domain)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if c == nil {
+		log.Fatalf("OWID:domain '%s' not found", *domain)
+	}
+	b, err := c.ExportEncrypted(passphrase())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile(*out, b, 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func restore(args []string) {
+	f := flag.NewFlagSet("import", flag.ExitOnError)
+	config := f.String("config", "", "configuration file for the store")
+	in := f.String("in", "", "file containing the encrypted backup")
+	f.Parse(args)
+	if *config == "" || *in == "" {
+		usage()
+	}
+	s, err := newStore(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = owid.RestoreCreator(s, b, passphrase())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func rotationPreview(args []string) {
+	f := flag.NewFlagSet("rotation-preview", flag.ExitOnError)
+	config := f.String("config", "", "configuration file for the store")
+	domain := f.String("domain", "", "domain of the creator")
+	maxAge := f.Duration("max-age", 0, "maximum age of OWIDs that must verify")
+	archive := f.String("archive", "", "optional key archive file")
+	f.Parse(args)
+	if *config == "" || *domain == "" {
+		usage()
+	}
+	s, err := newStore(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, err := s.GetCreator(*domain)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if c == nil {
+		log.Fatalf("OWID:domain '%s' not found", *domain)
+	}
+	var a owid.KeyArchive
+	if *archive != "" {
+		a = owid.NewKeyArchiveFile(*archive)
+	}
+	p, err := owid.PreviewRotation(c, a, *maxAge, time.Now().UTC())
+	if err != nil {
+		log.Fatal(err)
+	}
+	j, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(j))
+}
+
+func selfTest(args []string) {
+	f := flag.NewFlagSet("self-test", flag.ExitOnError)
+	config := f.String("config", "", "configuration file for the store")
+	f.Parse(args)
+	if *config == "" {
+		usage()
+	}
+	s, err := newStore(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	e := owid.SelfTestStore(s)
+	for _, err := range e {
+		fmt.Println(err.Error())
+	}
+	if len(e) > 0 {
+		os.Exit(1)
+	}
+	log.Printf("OWID:'%d' creators passed self-test", len(s.GetCreators()))
+}
+
+func migrateSchema(args []string) {
+	f := flag.NewFlagSet("migrate-schema", flag.ExitOnError)
+	config := f.String("config", "", "configuration file for the store")
+	f.Parse(args)
+	if *config == "" {
+		usage()
+	}
+	s, err := newStore(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = owid.MigrateSchema(s)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func edgeManifest(args []string) {
+	f := flag.NewFlagSet("edge-manifest", flag.ExitOnError)
+	config := f.String("config", "", "configuration file for the store")
+	out := f.String("out", "", "file to write the manifest to")
+	archive := f.String("archive", "", "optional key archive file")
+	f.Parse(args)
+	if *config == "" || *out == "" {
+		usage()
+	}
+	s, err := newStore(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var a owid.KeyArchive
+	if *archive != "" {
+		a = owid.NewKeyArchiveFile(*archive)
+	}
+	m, err := owid.NewEdgeManifest(s.GetCreators(), a)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile(*out, b, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("OWID:wrote keys for '%d' domains", len(m))
+}
+
+func bulkImport(args []string) {
+	f := flag.NewFlagSet("bulk-import", flag.ExitOnError)
+	config := f.String("config", "", "configuration file for the store")
+	in := f.String("in", "", "CSV or JSON file of creators")
+	f.Parse(args)
+	if *config == "" || *in == "" {
+		usage()
+	}
+	c := owid.NewConfig(*config)
+	s, err := owid.NewStoreWithError(&c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r, err := os.Open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+	var cs []*owid.BulkCreator
+	if strings.HasSuffix(strings.ToLower(*in), ".json") {
+		cs, err = owid.ParseBulkJSON(r)
+	} else {
+		cs, err = owid.ParseBulkCSV(r)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	rs := owid.ImportCreators(s, &c, cs)
+	j, err := json.MarshalIndent(rs, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(j))
+	for _, v := range rs {
+		if v.Status != owid.BulkCreated && v.Status != owid.BulkExists {
+			os.Exit(1)
+		}
+	}
+}
+
+// passphrase returns the backup passphrase from the environment so that it
+// does not appear in the command history.
+func passphrase() string {
+	p := os.Getenv("OWID_PASSPHRASE")
+	if p == "" {
+		log.Fatal("OWID:OWID_PASSPHRASE environment variable not set")
+	}
+	return p
+}
+
+func newStore(file string) (owid.Store, error) {
+	c := owid.NewConfig(file)
+	return owid.NewStoreWithError(&c)
+}
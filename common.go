@@ -18,27 +18,122 @@ package owid
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // common is a partial implementation of sws.Store for use with other more
 // complex implementations, and the test methods.
 type common struct {
-	creators map[string]*Creator // Map of domain names to nodes
-	mutex    *sync.Mutex         // mutual-exclusion lock used for refresh
+	creators atomic.Pointer[map[string]*Creator] // Map of domain names to creators, replaced and never modified
+	mutex    *sync.Mutex                         // mutual-exclusion lock used for refresh
+	selfTest bool                                // True to self test creators when refreshed
+	public   bool                                // True to remove private keys when creators are loaded
+
+	refreshed    time.Time     // When the creators were last loaded
+	refreshErr   error         // Error from the last refresh, or nil
+	maxStaleness time.Duration // Time creators are served after refreshes fail, or zero for no limit
+
+	negativeTTL   time.Duration        // Time missing domains are remembered, zero for the default
+	misses        map[string]time.Time // Expiry times of domains known to be missing
+	missRefreshed time.Time            // When a missing domain last caused a refresh
+
+	quarantined  map[string]*QuarantinedCreator // Creators that can't be loaded
+	onQuarantine func(q *QuarantinedCreator)    // Called for new quarantines
 }
 
 func (c *common) init() {
-	c.creators = make(map[string]*Creator)
+	c.creators.Store(&map[string]*Creator{})
 	c.mutex = &sync.Mutex{}
 }
 
-// GetCreators return a map of all the known creators keyed on domain.
+// GetCreators return a map of all the known creators keyed on domain. The map
+// is a snapshot that the store never modifies, so it can be read while the
+// store is refreshed, and must not be modified by the caller.
 func (c *common) GetCreators() map[string]*Creator {
-	return c.creators
+	p := c.creators.Load()
+	if p == nil {
+		return map[string]*Creator{}
+	}
+	return *p
+}
+
+// setSelfTest sets whether creators are self tested when refreshed.
+func (c *common) setSelfTest(enabled bool) { c.selfTest = enabled }
+
+// setPublicOnly removes the private keys from the creators already loaded and
+// from those loaded by later refreshes so that verify only processes never
+// hold them in memory.
+func (c *common) setPublicOnly() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.public = true
+	c.creators.Store(publicCreators(c.GetCreators()))
+}
+
+// publicCreators returns a new map with the creators without private keys.
+func publicCreators(cs map[string]*Creator) *map[string]*Creator {
+	p := make(map[string]*Creator, len(cs))
+	for k, v := range cs {
+		n := v.withoutPrivateKey()
+		n.warm()
+		p[k] = n
+	}
+	return &p
+}
+
+// setCreators replaces the creators with those provided. The Crypto instances
+// for each creator are created before the creators are used so that the PEM
+// keys are not parsed on the first signing or verification request. Creators
+// with keys that can't be used, or that fail the self test if enabled, are
+// quarantined along with the records that failed to load so that one corrupt
+// record does not stop the others being used. If public only the private keys
+// are then removed.
+func (c *common) setCreators(
+	cs map[string]*Creator,
+	failed map[string]error) {
+	for _, v := range cs {
+		v.warm()
+	}
+	c.quarantine(cs, failed)
+	if c.public {
+		cs = *publicCreators(cs)
+	}
+	c.mutex.Lock()
+	c.creators.Store(&cs)
+	c.refreshed = time.Now()
+	c.refreshErr = nil
+	c.mutex.Unlock()
+}
+
+// putCreator adds or replaces the creator in a copy of the creators map so
+// that maps already returned from GetCreators are not modified.
+func (c *common) putCreator(n *Creator) {
+	if c.public {
+		n = n.withoutPrivateKey()
+	}
+	n.warm()
+	c.mutex.Lock()
+	o := c.GetCreators()
+	cs := make(map[string]*Creator, len(o)+1)
+	for k, v := range o {
+		cs[k] = v
+	}
+	cs[n.domain] = n
+	c.creators.Store(&cs)
+	delete(c.misses, n.domain)
+	c.mutex.Unlock()
 }
 
-// getCreator takes a domain name and returns the associated creator. If a
-// creator does not exist then nil is returned.
+// getCreator takes a domain name or host and returns the associated creator.
+// If it is not found as provided then any port is removed and the domain is
+// normalized as it is when registered so that a request's host, or the
+// Unicode form of a domain, finds the creator. If a creator does not exist
+// then nil is returned.
 func (c *common) getCreator(domain string) (*Creator, error) {
-	return c.creators[domain], nil
+	cs := c.GetCreators()
+	if v, ok := cs[domain]; ok {
+		return v, nil
+	}
+	return cs[hostDomain(domain)], nil
 }
@@ -0,0 +1,85 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestCommonConcurrent reads creators while the store is refreshed and
+// changed from other goroutines. Run with -race to detect data races.
+func TestCommonConcurrent(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "creators.json")
+	l, err := NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cs []*Creator
+	for _, d := range []string{"a.com", "b.com", "c.com", "d.com"} {
+		c, err := newTestCreator(d, testOrgName, registerContractURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs = append(cs, c)
+	}
+	err = l.setCreator(cs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		w.Add(2)
+		go func() {
+			defer w.Done()
+			for j := 0; j < 50; j++ {
+				c, err := l.GetCreator("a.com")
+				if err != nil || c == nil {
+					t.Error("a.com should always be found")
+					return
+				}
+				for d, c := range l.GetCreators() {
+					if c.domain != d {
+						t.Error("creator keyed on the wrong domain")
+						return
+					}
+				}
+			}
+		}()
+		go func(c *Creator) {
+			defer w.Done()
+			for j := 0; j < 10; j++ {
+				err := l.refresh()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			err := l.setCreator(c)
+			if err != nil {
+				t.Error(err)
+			}
+		}(cs[i])
+	}
+	w.Wait()
+	if len(l.GetCreators()) != len(cs) {
+		t.Fatalf("expected '%d' creators but got '%d'",
+			len(cs),
+			len(l.GetCreators()))
+	}
+}
@@ -0,0 +1,180 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Equal returns true if all the fields of the OWID are the same as the other
+// OWID.
+func (o *OWID) Equal(other *OWID) bool {
+	return len(o.Diff(other)) == 0
+}
+
+// Diff returns a description of each field that differs between the OWID and
+// the other OWID, or an empty slice if they are equal.
+func (o *OWID) Diff(other *OWID) []string {
+	var d []string
+	if o.Version != other.Version {
+		d = append(d, fmt.Sprintf(
+			"version '%d' != '%d'",
+			o.Version,
+			other.Version))
+	}
+	if o.Domain != other.Domain {
+		d = append(d, fmt.Sprintf(
+			"domain '%s' != '%s'",
+			o.Domain,
+			other.Domain))
+	}
+	if o.Date.Equal(other.Date) == false {
+		d = append(d, fmt.Sprintf(
+			"date '%s' != '%s'",
+			o.Date,
+			other.Date))
+	}
+	if bytes.Equal(o.Payload, other.Payload) == false {
+		d = append(d, fmt.Sprintf(
+			"payload '%x' != '%x'",
+			o.Payload,
+			other.Payload))
+	}
+	if equalConstantTime(o.Signature, other.Signature) == false {
+		d = append(d, fmt.Sprintf(
+			"signature '%x' != '%x'",
+			o.Signature,
+			other.Signature))
+	}
+	if len(o.CoSignatures) != len(other.CoSignatures) {
+		d = append(d, fmt.Sprintf(
+			"co-signatures '%d' != '%d'",
+			len(o.CoSignatures),
+			len(other.CoSignatures)))
+	} else {
+		for i, s := range o.CoSignatures {
+			t := other.CoSignatures[i]
+			if s.Domain != t.Domain ||
+				equalConstantTime(s.Signature, t.Signature) == false {
+				d = append(d, fmt.Sprintf(
+					"co-signature '%d' '%s' != '%s'",
+					i,
+					s.Domain,
+					t.Domain))
+			}
+		}
+	}
+	return d
+}
+
+// Equal returns true if all the fields of the creator, including the keys, are
+// the same as the other creator.
+func (c *Creator) Equal(other *Creator) bool {
+	return len(c.Diff(other)) == 0
+}
+
+// Diff returns a description of each field that differs between the creator
+// and the other creator, or an empty slice if they are equal. Key values are
+// not included in the descriptions.
+func (c *Creator) Diff(other *Creator) []string {
+	var d []string
+	if c.domain != other.domain {
+		d = append(d, fmt.Sprintf(
+			"domain '%s' != '%s'",
+			c.domain,
+			other.domain))
+	}
+	if c.name != other.name {
+		d = append(d, fmt.Sprintf("name '%s' != '%s'", c.name, other.name))
+	}
+	if c.contractURL != other.contractURL {
+		d = append(d, fmt.Sprintf(
+			"contractURL '%s' != '%s'",
+			c.contractURL,
+			other.contractURL))
+	}
+	if c.created.Equal(other.created) == false {
+		d = append(d, fmt.Sprintf(
+			"created '%s' != '%s'",
+			c.created,
+			other.created))
+	}
+	if c.contact != other.contact {
+		d = append(d, fmt.Sprintf(
+			"contact '%v' != '%v'",
+			c.contact,
+			other.contact))
+	}
+	if equalConstantTime(
+		[]byte(c.privateKey),
+		[]byte(other.privateKey)) == false {
+		d = append(d, "privateKey differs")
+	}
+	if c.publicKey != other.publicKey {
+		d = append(d, "publicKey differs")
+	}
+	return d
+}
+
+// Equal returns true if all the fields of the public creator are the same as
+// the other public creator.
+func (p *PublicCreator) Equal(other *PublicCreator) bool {
+	return len(p.Diff(other)) == 0
+}
+
+// Diff returns a description of each field that differs between the public
+// creator and the other public creator, or an empty slice if they are equal.
+func (p *PublicCreator) Diff(other *PublicCreator) []string {
+	var d []string
+	if p.Domain != other.Domain {
+		d = append(d, fmt.Sprintf(
+			"domain '%s' != '%s'",
+			p.Domain,
+			other.Domain))
+	}
+	if p.Name != other.Name {
+		d = append(d, fmt.Sprintf("name '%s' != '%s'", p.Name, other.Name))
+	}
+	if p.PublicKeySPKI != other.PublicKeySPKI {
+		d = append(d, "publicKeySPKI differs")
+	}
+	if p.ContractURL != other.ContractURL {
+		d = append(d, fmt.Sprintf(
+			"contractURL '%s' != '%s'",
+			p.ContractURL,
+			other.ContractURL))
+	}
+	if p.Created.Equal(other.Created) == false {
+		d = append(d, fmt.Sprintf(
+			"created '%s' != '%s'",
+			p.Created,
+			other.Created))
+	}
+	for _, f := range [][3]string{
+		{"email", p.Email, other.Email},
+		{"dpoURL", p.DpoURL, other.DpoURL},
+		{"jurisdiction", p.Jurisdiction, other.Jurisdiction}} {
+		if f[1] != f[2] {
+			d = append(d, fmt.Sprintf("%s '%s' != '%s'", f[0], f[1], f[2]))
+		}
+	}
+	if equalConstantTime(p.Signature, other.Signature) == false {
+		d = append(d, "signature differs")
+	}
+	return d
+}
@@ -0,0 +1,206 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// KeyStore holds creators' private keys apart from the rest of their
+// information, for example in a secrets manager such as Vault. Implement it
+// to use a secrets manager with CompositeStore.
+type KeyStore interface {
+
+	// GetPrivateKey returns the PEM private key for the domain, or an empty
+	// string if there is not one.
+	GetPrivateKey(domain string) (string, error)
+
+	// SetPrivateKey adds or replaces the PEM private key for the domain.
+	SetPrivateKey(domain string, privateKey string) error
+}
+
+// CompositeStore is an implementation of owid.Store that keeps creators
+// without their private keys in a metadata store and the private keys in a key
+// store. Creators returned have both joined together. Used where the store for
+// public information is not approved for secrets.
+type CompositeStore struct {
+	metadata Store      // Creators without private keys
+	keys     KeyStore   // Private keys keyed on domain
+	mutex    sync.Mutex // Guards cache
+	cache    map[string]*project
+}
+
+// NewCompositeStore returns a store that joins the creators in the metadata
+// store with the private keys in the key store.
+func NewCompositeStore(metadata Store, keys KeyStore) *CompositeStore {
+	return &CompositeStore{
+		metadata: metadata,
+		keys:     keys,
+		cache:    make(map[string]*project)}
+}
+
+// GetCreator returns the creator for the domain with its private key, or nil
+// if the metadata store has no creator.
+func (s *CompositeStore) GetCreator(domain string) (*Creator, error) {
+	c, err := s.metadata.GetCreator(domain)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	return s.join(c)
+}
+
+// GetCreators returns all the creators in the metadata store with their
+// private keys. Creators whose key can't be fetched are logged and returned
+// without a private key so they can still verify.
+func (s *CompositeStore) GetCreators() map[string]*Creator {
+	cs := make(map[string]*Creator)
+	for d, c := range s.metadata.GetCreators() {
+		j, err := s.join(c)
+		if err != nil {
+			log.Printf("OWID:key store for '%s' %s", d, err.Error())
+			j = c.withoutPrivateKey()
+		}
+		cs[d] = j
+	}
+	return cs
+}
+
+// setCreator writes the private key to the key store before the rest of the
+// creator is written to the metadata store so that the metadata never refers
+// to a key that has not been stored.
+func (s *CompositeStore) setCreator(c *Creator) error {
+	if c.privateKey != "" {
+		err := s.keys.SetPrivateKey(c.domain, c.privateKey)
+		if err != nil {
+			return fmt.Errorf("key store for '%s' %s", c.domain, err.Error())
+		}
+	}
+	return s.metadata.setCreator(c.withoutPrivateKey())
+}
+
+// join returns the creator with the private key from the key store. Joined
+// creators are cached until the metadata creator or the key changes.
+func (s *CompositeStore) join(c *Creator) (*Creator, error) {
+	k, err := s.keys.GetPrivateKey(c.domain)
+	if err != nil {
+		return nil, err
+	}
+	if k == "" {
+		return c, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	v, ok := s.cache[c.domain]
+	if ok && v.source == c && v.result.privateKey == k {
+		return v.result, nil
+	}
+	j := c.withPrivateKey(k)
+	x, err := j.NewCryptoSignOnly()
+	if err != nil {
+		return nil, fmt.Errorf("private key invalid: %s", err.Error())
+	}
+	p, err := c.NewCryptoVerifyOnly()
+	if err != nil {
+		return nil, fmt.Errorf("public key invalid: %s", err.Error())
+	}
+	if x.privateKey.PublicKey.Equal(p.publicKey) == false {
+		return nil, fmt.Errorf(
+			"private key for '%s' does not match public key",
+			c.domain)
+	}
+	s.cache[c.domain] = &project{source: c, result: j}
+	return j, nil
+}
+
+// withPrivateKey returns a copy of the creator with the private key.
+func (c *Creator) withPrivateKey(k string) *Creator {
+	n := newCreator(
+		c.domain,
+		k,
+		c.publicKey,
+		c.name,
+		c.contractURL,
+		c.created,
+		c.contact,
+		c.status)
+	n.clock = c.clock
+	n.version = c.version
+	n.modified = c.modified
+	return n
+}
+
+// health returns the health of the metadata store.
+func (s *CompositeStore) health() StoreHealth { return storeHealth(s.metadata) }
+
+// setMaxStaleness sets the maximum staleness of the metadata store.
+func (s *CompositeStore) setMaxStaleness(d time.Duration) {
+	if v, ok := s.metadata.(staleReporter); ok {
+		v.setMaxStaleness(d)
+	}
+}
+
+// setNegativeTTL sets the time missing domains are remembered by the metadata
+// store.
+func (s *CompositeStore) setNegativeTTL(d time.Duration) {
+	if v, ok := s.metadata.(negativeCacher); ok {
+		v.setNegativeTTL(d)
+	}
+}
+
+// keyStoreAdapter uses a Store to hold private keys, for example a local file
+// or a separate cloud account with stricter access.
+type keyStoreAdapter struct {
+	store Store
+}
+
+// NewKeyStoreFromStore returns a KeyStore that holds each private key as a
+// creator in the store containing only the domain and keys.
+func NewKeyStoreFromStore(s Store) KeyStore {
+	return &keyStoreAdapter{store: s}
+}
+
+func (a *keyStoreAdapter) GetPrivateKey(domain string) (string, error) {
+	c, err := a.store.GetCreator(domain)
+	if err != nil || c == nil {
+		return "", err
+	}
+	return c.privateKey, nil
+}
+
+func (a *keyStoreAdapter) SetPrivateKey(domain string, privateKey string) error {
+	x, err := NewCryptoSignOnly(privateKey)
+	if err != nil {
+		return err
+	}
+	p, err := (&Crypto{publicKey: &x.privateKey.PublicKey}).
+		publicKeyToPemString()
+	if err != nil {
+		return err
+	}
+	return a.store.setCreator(newCreator(
+		domain,
+		privateKey,
+		p,
+		"",
+		"",
+		time.Now().UTC(),
+		Contact{},
+		CreatorActive))
+}
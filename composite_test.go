@@ -0,0 +1,89 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestCompositeStore registers a creator through a composite store and checks
+// the private key is only in the key store and the joined creator can sign.
+func TestCompositeStore(t *testing.T) {
+	d := t.TempDir()
+	m, err := NewLocalStore(filepath.Join(d, "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewLocalStore(filepath.Join(d, "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewCompositeStore(m, NewKeyStoreFromStore(k))
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == nil || v.privateKey != "" || v.publicKey != c.publicKey {
+		t.Fatal("metadata store should hold the creator without private key")
+	}
+	p, err := k.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.privateKey != c.privateKey || p.name != "" {
+		t.Fatal("key store should only hold the keys")
+	}
+	j, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := j.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := c.Verify(o)
+	if err != nil || ok == false {
+		t.Fatalf("OWID signed by joined creator not valid '%v'", err)
+	}
+	if s.GetCreators()[testDomain] != j {
+		t.Error("joined creator should be cached")
+	}
+	n, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.setCreator(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.GetCreator(testDomain)
+	if err == nil {
+		t.Error("mismatched private key should error")
+	}
+	if s.GetCreators()[testDomain].privateKey != "" {
+		t.Error("creator with mismatched key should only verify")
+	}
+}
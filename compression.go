@@ -0,0 +1,84 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Content encodings that responses can be compressed with.
+const encodingGzip = "gzip"
+
+// acceptsEncoding returns true if the Accept-Encoding header value allows the
+// encoding. The encoding is allowed if named explicitly, or matched by *, with
+// a quality value greater than zero.
+func acceptsEncoding(header string, encoding string) bool {
+	a := false
+	for _, v := range strings.Split(header, ",") {
+		n, p, _ := strings.Cut(strings.TrimSpace(v), ";")
+		n = strings.TrimSpace(n)
+		if strings.EqualFold(n, encoding) == false && n != "*" {
+			continue
+		}
+		q := 1.0
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "q=") {
+			f, err := strconv.ParseFloat(strings.TrimPrefix(p, "q="), 64)
+			if err == nil {
+				q = f
+			}
+		}
+		if strings.EqualFold(n, encoding) {
+			return q > 0
+		}
+		a = q > 0
+	}
+	return a
+}
+
+// writeResponse writes the content with the status code to the response
+// compressed with gzip if the request accepts it and the content is at least
+// the configured minimum size. All OWID endpoints respond via this method so
+// that compression is consistent.
+func (s *Services) writeResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	contentType string,
+	status int,
+	b []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+	if len(b) < s.Config().CompressionMinSize ||
+		acceptsEncoding(r.Header.Get("Accept-Encoding"), encodingGzip) == false {
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		w.WriteHeader(status)
+		_, err := w.Write(b)
+		return err
+	}
+	w.Header().Set("Content-Encoding", encodingGzip)
+	w.WriteHeader(status)
+	g := gzip.NewWriter(w)
+	_, err := g.Write(b)
+	if err != nil {
+		g.Close()
+		return err
+	}
+	return g.Close()
+}
@@ -0,0 +1,85 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	for h, e := range map[string]bool{
+		"":                     false,
+		"gzip":                 true,
+		"GZIP":                 true,
+		"deflate, gzip;q=0.5":  true,
+		"br, deflate":          false,
+		"gzip;q=0":             false,
+		"*":                    true,
+		"*;q=0.1":              true,
+		"*, gzip;q=0":          false,
+		"identity, *;q=0":      false,
+		"br;q=1.0, gzip;q=0.8": true} {
+		if acceptsEncoding(h, encodingGzip) != e {
+			t.Errorf("header '%s' expected '%t'", h, e)
+		}
+	}
+}
+
+// TestWriteResponse checks responses are only compressed when the request
+// accepts gzip and the content is not smaller than the configured minimum.
+func TestWriteResponse(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := *s.Config()
+	f.CompressionMinSize = 100
+	s.SetConfig(f)
+	for _, c := range []struct {
+		accept     string
+		size       int
+		compressed bool
+	}{
+		{"gzip", 100, true},
+		{"gzip", 99, false},
+		{"", 1000, false},
+		{"br", 1000, false}} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", c.accept)
+		rr := httptest.NewRecorder()
+		b := bytes.Repeat([]byte("a"), c.size)
+		sendResponse(s, rr, r, "text/plain", b)
+		if (rr.Header().Get("Content-Encoding") == encodingGzip) !=
+			c.compressed {
+			t.Errorf("accept '%s' size '%d' expected compressed '%t'",
+				c.accept,
+				c.size,
+				c.compressed)
+		}
+		if decompressAsString(t, rr) != string(b) {
+			t.Errorf("accept '%s' size '%d' content differs",
+				c.accept,
+				c.size)
+		}
+		if rr.Header().Get("Vary") != "Accept-Encoding" {
+			t.Error("Vary header missing")
+		}
+	}
+}
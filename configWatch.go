@@ -0,0 +1,69 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// WatchConfig checks the configuration file for changes every interval until
+// the context is done. When the file changes the configuration is loaded,
+// validated and applied with SetConfig so settings such as debug, CORS and
+// webhooks change without restarting the service. Invalid configurations are
+// logged and ignored. Usually called in a separate goroutine.
+func (s *Services) WatchConfig(
+	ctx context.Context,
+	file string,
+	interval time.Duration) {
+	m := configModTime(file)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n := configModTime(file)
+			if n.Equal(m) {
+				continue
+			}
+			m = n
+			c := NewConfig(file)
+			c.SetTemplateFS(s.Config().templates)
+			err := c.Validate()
+			if err != nil {
+				log.Printf("OWID:configuration '%s' ignored: %s", file, err)
+				continue
+			}
+			s.SetConfig(c)
+			log.Printf("OWID:configuration '%s' reloaded", file)
+		}
+	}
+}
+
+// configModTime returns the modification time of the file, or the zero time
+// if the file can't be read.
+func configModTime(file string) time.Time {
+	i, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return i.ModTime()
+}
@@ -0,0 +1,65 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfig(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "appsettings.json")
+	w := func(c string, m time.Time) {
+		err := os.WriteFile(f, []byte(c), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes(f, m, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	n := time.Now()
+	w(`{"backgroundColor":"white","messageColor":"black","owidFile":"a.json"}`,
+		n.Add(-time.Hour))
+	s := NewServices(NewConfig(f), newTestStore(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.WatchConfig(ctx, f, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	// An invalid configuration is ignored.
+	w(`{"backgroundColor":"white","owidFile":"a.json"}`, n.Add(-time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if s.Config().MessageColor != "black" {
+		t.Fatal("invalid configuration applied")
+	}
+
+	// A valid configuration is applied.
+	w(`{"backgroundColor":"white","messageColor":"black","owidFile":"a.json",`+
+		`"cors":{"allowedOrigins":["https://allowed.com"]}}`, n)
+	d := time.Now().Add(5 * time.Second)
+	for len(s.Config().Cors.AllowedOrigins) == 0 {
+		if time.Now().After(d) {
+			t.Fatal("configuration not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -18,23 +18,109 @@ package owid
 
 import (
 	"fmt"
+	"html/template"
+	"io/fs"
 	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
 
 	"github.com/SWAN-community/config-go"
+	"github.com/spf13/viper"
 )
 
 // Configuration details from appsettings.json for access to the AWS or Azure
 // storage.
 type Configuration struct {
-	config.Common   `mapstructure:",squash"`
-	Scheme          string `mapstructure:"scheme"` // The scheme to use for requests
-	BackgroundColor string `mapstructure:"backgroundColor"`
-	MessageColor    string `mapstructure:"messageColor"`
-	Debug           bool   `mapstructure:"debug"`
-	OwidFile        string `mapstructure:"owidFile"`
-	OwidStore       string `mapstructure:"owidStore"`
+	config.Common          `mapstructure:",squash"`
+	Scheme                 string             `mapstructure:"scheme"` // The scheme to use for requests
+	BackgroundColor        string             `mapstructure:"backgroundColor"`
+	MessageColor           string             `mapstructure:"messageColor"`
+	Debug                  bool               `mapstructure:"debug"`
+	OwidFile               string             `mapstructure:"owidFile"`
+	OwidStore              string             `mapstructure:"owidStore"`
+	OwidReplicaFile        string             `mapstructure:"owidReplicaFile"` // Local file replica of the store, or empty for none
+	Cors                   Cors               `mapstructure:"cors"`
+	Webhooks               []Webhook          `mapstructure:"webhooks"`               // Notified when creators change
+	AllowedRegisterHosts   []string           `mapstructure:"allowedRegisterHosts"`   // Hosts such as localhost or IP addresses that can register despite failing domain validation
+	RegisterTemplateFile   string             `mapstructure:"registerTemplateFile"`   // Custom register page template, or empty for the default
+	CheckContractURL       bool               `mapstructure:"checkContractURL"`       // True to require the contract URL to respond over HTTPS from the registering domain
+	ContractURLTimeout     int                `mapstructure:"contractURLTimeout"`     // Seconds to wait for the contract URL or domain challenge to respond
+	DomainChallenge        string             `mapstructure:"domainChallenge"`        // Empty, http or dns to require new creators to prove control of their domain
+	CompressionMinSize     int                `mapstructure:"compressionMinSize"`     // Responses smaller than this many bytes are not compressed
+	Curve                  string             `mapstructure:"curve"`                  // Curve for new creators' keys, P-256 if empty, P-384 or P-521
+	SelfTestOnRefresh      bool               `mapstructure:"selfTestOnRefresh"`      // True to sign and verify a probe with every creator when the store is refreshed
+	AzureConnectionString  string             `mapstructure:"azureConnectionString"`  // Azure storage connection string used instead of the account and key
+	AwsEndpoint            string             `mapstructure:"awsEndpoint"`            // DynamoDB endpoint URL, for example DynamoDB Local, or empty for AWS
+	AwsRegion              string             `mapstructure:"awsRegion"`              // AWS region, or empty for the default
+	AwsAccessKeyID         string             `mapstructure:"awsAccessKeyID"`         // AWS access key ID, or empty for the default credentials
+	AwsSecretAccessKey     string             `mapstructure:"awsSecretAccessKey"`     // AWS secret access key used with AwsAccessKeyID
+	AwsSkipCreateTable     bool               `mapstructure:"awsSkipCreateTable"`     // True if the DynamoDB table must already exist
+	AwsTablePrefix         string             `mapstructure:"awsTablePrefix"`         // Prefix for DynamoDB table names to share an account between environments
+	AwsPointInTimeRecovery bool               `mapstructure:"awsPointInTimeRecovery"` // True to enable DynamoDB point in time recovery
+	AwsTTLAttribute        string             `mapstructure:"awsTTLAttribute"`        // DynamoDB attribute holding item expiry times, or empty to not enable TTL
+	Mode                   string             `mapstructure:"mode"`                   // Empty for full, sign for sign only or verify for verify only
+	SignDomains            []string           `mapstructure:"signDomains"`            // Domains a sign only service signs for
+	GcpCredentialsFile     string             `mapstructure:"gcpCredentialsFile"`     // Google service account JSON file, or empty for the default credentials
+	MaxStaleness           int                `mapstructure:"maxStaleness"`           // Seconds creators are served after store refreshes start failing, or zero for no limit
+	NegativeCacheTTL       int                `mapstructure:"negativeCacheTTL"`       // Seconds unregistered domains are remembered, zero for the default or negative to disable
+	MaxPayloadLength       int                `mapstructure:"maxPayloadLength"`       // Maximum payload bytes signed or verified by the service, or zero for the format limit
+	SellersSources         []string           `mapstructure:"sellersSources"`         // sellers.json or ads.txt URLs that must list new creators' domains, or empty for no check
+	store                  Store              // Store provided with SetStore, or nil
+	keyStore               KeyStore           // Store for private keys provided with SetKeyStore, or nil
+	templates              fs.FS              // File system for custom templates, or nil for the OS
+	registerTemplate       *template.Template // Custom register template loaded by Validate, or nil
 }
 
+// Webhook configuration for a URL notified when creators change. The body is
+// signed with HMAC SHA-256 using the shared secret.
+type Webhook struct {
+	URL    string `mapstructure:"url"`    // URL the notification is posted to
+	Secret string `mapstructure:"secret"` // Shared secret used to sign the body
+}
+
+// Cors configuration for cross-origin resource sharing with the API end
+// points.
+type Cors struct {
+	AllowedOrigins []string `mapstructure:"allowedOrigins"` // Origins allowed to access the API, or all origins if empty
+	AllowedMethods []string `mapstructure:"allowedMethods"` // Methods allowed for the API
+	MaxAge         int      `mapstructure:"maxAge"`         // Seconds the preflight response can be cached for
+}
+
+// isOriginAllowed returns true if the origin is in the list of allowed origins,
+// or the list contains the wildcard.
+func (c *Cors) isOriginAllowed(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStore sets the store returned by NewStoreWithError instead of creating
+// one from the configuration. Used to provide a store already constructed by
+// the application or a test.
+func (c *Configuration) SetStore(s Store) { c.store = s }
+
+// SetKeyStore sets a separate store for creators' private keys. The store
+// created by NewStoreWithError then only holds the rest of the creators'
+// information and is joined with the key store by a CompositeStore.
+func (c *Configuration) SetKeyStore(k KeyStore) { c.keyStore = k }
+
+// SetTemplateFS sets the file system, for example an embed.FS, that
+// RegisterTemplateFile is read from instead of the operating system's.
+func (c *Configuration) SetTemplateFS(f fs.FS) { c.templates = f }
+
+// The default scheme used for requests if none is configured.
+const defaultScheme = "https"
+
+// The default seconds to wait for a contract URL to respond if none is
+// configured.
+const defaultContractURLTimeout = 5
+
 // NewConfig creates a new instance of configuration from the file provided. If
 // the file does not contain a value for some important fields then the
 // environment is checked to see if there is corresponding value present there.
@@ -44,9 +130,41 @@ func NewConfig(file string) Configuration {
 	if err != nil {
 		fmt.Println(err.Error())
 	}
+	c.setDefaults()
 	return c
 }
 
+// NewConfigFromEnv creates a new instance of configuration from environment
+// variables only for deployments without a configuration file. The variable
+// names are the upper case field names with underscores between words, for
+// example OWID_FILE.
+func NewConfigFromEnv() (Configuration, error) {
+	var c Configuration
+	v := viper.New()
+	for _, n := range configFields(reflect.TypeOf(c)) {
+		err := v.BindEnv(n, configEnvName(n))
+		if err != nil {
+			return c, err
+		}
+	}
+	err := v.Unmarshal(&c)
+	if err != nil {
+		return c, err
+	}
+	c.setDefaults()
+	return c, nil
+}
+
+// setDefaults sets explicit defaults for fields that have not been configured.
+func (c *Configuration) setDefaults() {
+	if c.Scheme == "" {
+		c.Scheme = defaultScheme
+	}
+	if c.ContractURLTimeout == 0 {
+		c.ContractURLTimeout = defaultContractURLTimeout
+	}
+}
+
 // Validate confirms that the configuration is usable.
 func (c *Configuration) Validate() error {
 	var err error
@@ -65,5 +183,190 @@ func (c *Configuration) Validate() error {
 			err = fmt.Errorf("OWID MessageColor missing in config")
 		}
 	}
+	if err == nil && c.Scheme != "http" && c.Scheme != "https" {
+		err = fmt.Errorf("OWID Scheme '%s' must be http or https", c.Scheme)
+	}
+	if err == nil {
+		err = c.validateStore()
+	}
+	if err == nil && c.ContractURLTimeout < 0 {
+		err = fmt.Errorf(
+			"OWID ContractURLTimeout '%d' must not be negative",
+			c.ContractURLTimeout)
+	}
+	if err == nil &&
+		c.DomainChallenge != "" &&
+		c.DomainChallenge != ChallengeHTTP &&
+		c.DomainChallenge != ChallengeDNS {
+		err = fmt.Errorf(
+			"OWID DomainChallenge '%s' must be empty, %s or %s",
+			c.DomainChallenge,
+			ChallengeHTTP,
+			ChallengeDNS)
+	}
+	if err == nil && c.CompressionMinSize < 0 {
+		err = fmt.Errorf(
+			"OWID CompressionMinSize '%d' must not be negative",
+			c.CompressionMinSize)
+	}
+	if err == nil {
+		_, err = CurveByName(c.Curve)
+		if err != nil {
+			err = fmt.Errorf("OWID Curve '%s' must be P-256, P-384 or P-521",
+				c.Curve)
+		}
+	}
+	if err == nil && (c.AwsAccessKeyID == "") != (c.AwsSecretAccessKey == "") {
+		err = fmt.Errorf(
+			"OWID AwsAccessKeyID and AwsSecretAccessKey must be used together")
+	}
+	if err == nil && validAWSTableName(c.AwsTablePrefix) == false {
+		err = fmt.Errorf(
+			"OWID AwsTablePrefix '%s' must only contain letters, digits, "+
+				"underscores, hyphens and full stops",
+			c.AwsTablePrefix)
+	}
+	if err == nil &&
+		(c.MaxPayloadLength < 0 || c.MaxPayloadLength > maxPayloadLength) {
+		err = fmt.Errorf(
+			"OWID MaxPayloadLength '%d' must be between 0 and '%d'",
+			c.MaxPayloadLength,
+			maxPayloadLength)
+	}
+	if err == nil && c.MaxStaleness < 0 {
+		err = fmt.Errorf(
+			"OWID MaxStaleness '%d' must not be negative",
+			c.MaxStaleness)
+	}
+	if err == nil && c.GcpCredentialsFile != "" {
+		_, err = os.Stat(c.GcpCredentialsFile)
+		if err != nil {
+			err = fmt.Errorf(
+				"OWID GcpCredentialsFile '%s' not found",
+				c.GcpCredentialsFile)
+		}
+	}
+	if err == nil &&
+		c.Mode != ModeFull &&
+		c.Mode != ModeSign &&
+		c.Mode != ModeVerify {
+		err = fmt.Errorf(
+			"OWID Mode '%s' must be empty, %s or %s",
+			c.Mode,
+			ModeSign,
+			ModeVerify)
+	}
+	if err == nil && c.Mode == ModeSign && len(c.SignDomains) == 0 {
+		err = fmt.Errorf("OWID Mode '%s' requires SignDomains", c.Mode)
+	}
+	if err == nil && c.Cors.MaxAge < 0 {
+		err = fmt.Errorf("OWID Cors MaxAge '%d' must not be negative", c.Cors.MaxAge)
+	}
+	for _, s := range c.SellersSources {
+		u, e := url.Parse(s)
+		if err == nil && (e != nil || u.Scheme == "" || u.Host == "") {
+			err = fmt.Errorf("OWID SellersSources '%s' must be a URL", s)
+		}
+	}
+	for i, w := range c.Webhooks {
+		if err == nil && (w.URL == "" || w.Secret == "") {
+			err = fmt.Errorf("OWID Webhook '%d' requires url and secret", i)
+		}
+	}
+	if err == nil {
+		err = c.loadTemplates()
+	}
 	return err
 }
+
+// validAWSTableName returns true if the characters can be used in a DynamoDB
+// table name.
+func validAWSTableName(n string) bool {
+	for _, r := range n {
+		if (r < 'a' || r > 'z') &&
+			(r < 'A' || r > 'Z') &&
+			(r < '0' || r > '9') &&
+			r != '_' && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// azureConfigured returns true if any of the Azure storage settings are
+// present.
+func (c *Configuration) azureConfigured() bool {
+	return len(c.AzureStorageAccount) > 0 ||
+		len(c.AzureStorageAccessKey) > 0 ||
+		c.AzureConnectionString != ""
+}
+
+// validateStore checks that the credentials needed for the selected store are
+// present.
+func (c *Configuration) validateStore() error {
+	azure := c.azureConfigured()
+	if len(c.AzureStorageAccessKey) > 0 && len(c.AzureStorageAccount) == 0 {
+		return fmt.Errorf("OWID Azure AzureStorageAccessKey requires " +
+			"AzureStorageAccount")
+	}
+	switch c.OwidStore {
+	case "":
+		if c.store == nil &&
+			azure == false &&
+			c.GcpProject == "" &&
+			c.OwidFile == "" &&
+			c.AwsEnabled == false {
+			return fmt.Errorf("OWID no store configured")
+		}
+	case "azure":
+		if azure == false {
+			return fmt.Errorf("OWID OwidStore 'azure' requires Azure settings")
+		}
+	case "gcp":
+		if c.GcpProject == "" {
+			return fmt.Errorf("OWID OwidStore 'gcp' requires GcpProject")
+		}
+	case "local":
+		if c.OwidFile == "" {
+			return fmt.Errorf("OWID OwidStore 'local' requires OwidFile")
+		}
+	case "aws":
+		if c.AwsEnabled == false {
+			return fmt.Errorf("OWID OwidStore 'aws' requires AwsEnabled")
+		}
+	default:
+		return fmt.Errorf("OWID OwidStore '%s' not supported", c.OwidStore)
+	}
+	return nil
+}
+
+// configFields returns the names of the exported fields in the structure
+// including those of embedded and nested structures.
+func configFields(t reflect.Type) []string {
+	var a []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() == false {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct {
+			a = append(a, configFields(f.Type)...)
+		} else {
+			a = append(a, f.Name)
+		}
+	}
+	return a
+}
+
+// configEnvName returns the environment variable name for the field name.
+// For example OwidFile becomes OWID_FILE.
+func configEnvName(s string) string {
+	b := strings.Builder{}
+	for i, c := range s {
+		if unicode.IsUpper(c) && i != 0 {
+			b.WriteString("_")
+		}
+		b.WriteRune(unicode.ToUpper(c))
+	}
+	return b.String()
+}
@@ -18,6 +18,7 @@ package owid
 
 import (
 	"testing"
+	"testing/fstest"
 )
 
 func TestLocalConfigurationSettings(t *testing.T) {
@@ -44,6 +45,9 @@ func TestAwsConfigurationSettings(t *testing.T) {
 		t.Error("AWS Enabled not set")
 		return
 	}
+	if c.AwsTablePrefix != "test-" || c.AwsPointInTimeRecovery == false {
+		t.Error("AWS table options not set")
+	}
 }
 
 func TestAwsConfigurationEnvironment(t *testing.T) {
@@ -92,3 +96,122 @@ func TestAzureConfigurationEnvironment(t *testing.T) {
 		return
 	}
 }
+
+func TestNewStoreWithErrorNoStore(t *testing.T) {
+	c := NewConfig("appsettings.test.none.json")
+	c.OwidFile = ""
+	c.AwsEnabled = false
+	c.GcpProject = ""
+	c.AzureStorageAccount = ""
+	c.AzureStorageAccessKey = ""
+	_, err := NewStoreWithError(&c)
+	if err == nil {
+		t.Error("missing store configuration should error")
+		return
+	}
+	c.AzureStorageAccessKey = "key"
+	_, err = NewStoreWithError(&c)
+	if err == nil {
+		t.Error("partial Azure configuration should error")
+		return
+	}
+}
+
+func TestNewStoreWithErrorSetStore(t *testing.T) {
+	c := NewConfig("appsettings.test.none.json")
+	e := newTestStore()
+	c.SetStore(e)
+	s, err := NewStoreWithError(&c)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if s != e {
+		t.Error("store provided with SetStore not returned")
+		return
+	}
+}
+
+func TestConfigurationFromEnv(t *testing.T) {
+	t.Setenv("OWID_FILE", "owid.json")
+	t.Setenv("BACKGROUND_COLOR", "white")
+	t.Setenv("MESSAGE_COLOR", "black")
+	c, err := NewConfigFromEnv()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if c.OwidFile != "owid.json" || c.BackgroundColor != "white" {
+		t.Error("environment values not set")
+		return
+	}
+	if c.Scheme != defaultScheme {
+		t.Errorf("scheme '%s' not default", c.Scheme)
+		return
+	}
+	err = c.Validate()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestConfigurationValidate(t *testing.T) {
+	v := Configuration{
+		BackgroundColor: "white",
+		MessageColor:    "black",
+		Scheme:          defaultScheme,
+		OwidFile:        "owid.json"}
+	for _, c := range []struct {
+		name   string
+		change func(c *Configuration)
+	}{
+		{"scheme", func(c *Configuration) { c.Scheme = "ftp" }},
+		{"no store", func(c *Configuration) { c.OwidFile = "" }},
+		{"unknown store", func(c *Configuration) { c.OwidStore = "disk" }},
+		{"gcp store", func(c *Configuration) { c.OwidStore = "gcp" }},
+		{"partial azure", func(c *Configuration) {
+			c.AzureStorageAccessKey = "key"
+		}},
+		{"webhook", func(c *Configuration) {
+			c.Webhooks = []Webhook{{URL: "https://example.com"}}
+		}},
+		{"aws table prefix", func(c *Configuration) {
+			c.AwsTablePrefix = "dev/"
+		}},
+		{"gcp credentials", func(c *Configuration) {
+			c.GcpCredentialsFile = "missing.json"
+		}},
+		{"aws credentials", func(c *Configuration) {
+			c.AwsAccessKeyID = "local"
+		}},
+		{"sellers source", func(c *Configuration) {
+			c.SellersSources = []string{"sellers.json"}
+		}}} {
+		i := v
+		c.change(&i)
+		if i.Validate() == nil {
+			t.Errorf("'%s' should not be valid", c.name)
+		}
+	}
+	err := v.Validate()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestConfigurationRegisterTemplate checks that a missing or invalid custom
+// register template fails validation.
+func TestConfigurationRegisterTemplate(t *testing.T) {
+	c := NewConfig("appsettings.test.local.json")
+	c.RegisterTemplateFile = "missing.html"
+	if c.Validate() == nil {
+		t.Error("missing template should fail validation")
+	}
+	c.RegisterTemplateFile = "register.html"
+	c.SetTemplateFS(fstest.MapFS{"register.html": &fstest.MapFile{
+		Data: []byte(`{{ .Domain `)}})
+	if c.Validate() == nil {
+		t.Error("invalid template should fail validation")
+	}
+}
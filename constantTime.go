@@ -0,0 +1,41 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import "crypto/subtle"
+
+// equalConstantTime returns true if the byte arrays are equal. The time taken
+// depends only on the lengths, which are not secret, and not on the position
+// of the first difference. Use for signatures, MACs and other values where an
+// attacker could learn a valid value by timing comparisons.
+func equalConstantTime(a []byte, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// containsConstantTime returns true if the key is in the set. Every key in the
+// set is compared so that the time taken does not reveal how much of a valid
+// key a guess matched.
+func containsConstantTime(set map[string]bool, key string) bool {
+	k := []byte(key)
+	f := 0
+	for v, ok := range set {
+		if ok {
+			f |= subtle.ConstantTimeCompare([]byte(v), k)
+		}
+	}
+	return f == 1
+}
@@ -0,0 +1,107 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+// TestEqualConstantTime checks a difference at every position of a signature
+// is detected.
+func TestEqualConstantTime(t *testing.T) {
+	a := make([]byte, signatureLength)
+	_, err := rand.Read(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := append([]byte{}, a...)
+	if equalConstantTime(a, b) == false {
+		t.Fatal("equal signatures not equal")
+	}
+	for i := range b {
+		b[i] ^= 0x01
+		if equalConstantTime(a, b) {
+			t.Fatalf("difference at '%d' not detected", i)
+		}
+		b[i] ^= 0x01
+	}
+	if equalConstantTime(a, b[:halfSignatureLength]) {
+		t.Fatal("different lengths should not be equal")
+	}
+}
+
+// TestAccessSimpleConstantTime checks keys that are prefixes or extensions of
+// valid keys are refused.
+func TestAccessSimpleConstantTime(t *testing.T) {
+	a := NewAccessSimpleScoped(map[Scope][]string{ScopeAdmin: {"admin"}})
+	for k, e := range map[string]bool{
+		"admin":  true,
+		"admin1": false,
+		"admi":   false,
+		"":       false} {
+		v, err := a.GetAllowedScope(k, ScopeAdmin)
+		if err != nil || v != e {
+			t.Fatalf("key '%s' allowed '%t' not '%t'", k, v, e)
+		}
+	}
+}
+
+// minDuration returns the fastest of many timings of the function so that
+// scheduling noise is removed.
+func minDuration(f func()) time.Duration {
+	m := time.Duration(1<<63 - 1)
+	for i := 0; i < 200; i++ {
+		s := time.Now()
+		for j := 0; j < 1000; j++ {
+			f()
+		}
+		if d := time.Since(s); d < m {
+			m = d
+		}
+	}
+	return m
+}
+
+// TestTimingSignatures hammers the comparisons whose timing could reveal how
+// much of a signature or MAC was guessed. The fastest timing for a difference
+// in the first byte must be close to that for a difference in the last byte.
+func TestTimingSignatures(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test skipped in short mode")
+	}
+	s, err := NewCryptoHMAC(make([]byte, minHMACSecretLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := make([]byte, 32)
+	v := s.hmacSignature(h)
+	first := append([]byte{}, v...)
+	first[0] ^= 0xff
+	last := append([]byte{}, v...)
+	last[halfSignatureLength-1] ^= 0xff
+	for n, f := range map[string]func([]byte){
+		"equalConstantTime": func(b []byte) { equalConstantTime(v, b) },
+		"verifyHash":        func(b []byte) { s.verifyHash(h, b) }} {
+		a := minDuration(func() { f(first) })
+		b := minDuration(func() { f(last) })
+		if a > 2*b || b > 2*a {
+			t.Errorf("%s timing depends on difference '%s' '%s'", n, a, b)
+		}
+	}
+}
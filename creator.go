@@ -19,6 +19,9 @@ package owid
 import (
 	"encoding/json"
 	"fmt"
+	"net/mail"
+	"net/url"
+	"sync"
 	"time"
 )
 
@@ -27,21 +30,113 @@ type Creator struct {
 	domain      string // The registered domain name and key fields
 	privateKey  string
 	publicKey   string
-	name        string // The name of the entity associated with the domain
-	contractURL string // URL with the T&Cs associated with the creation of data
-	sign        *Crypto
-	verify      *Crypto
+	name        string        // The name of the entity associated with the domain
+	contractURL string        // URL with the T&Cs associated with the creation of data
+	created     time.Time     // The date and time the creator and keys were created
+	contact     Contact       // Optional details used to contact the creator
+	status      CreatorStatus // Active or suspended
+	sign        cryptoOnce    // Crypto for signing created on first use
+	verify      cryptoOnce    // Crypto for verifying created on first use
+	clock       Clock         // Clock used to date OWIDs, or nil for the system clock
+	version     byte          // Preferred version of new OWIDs, or zero for the default
+	signature   []byte        // Signature of the public information when the private key is not held
+	modified    time.Time     // The date and time the public information last changed, or zero if unchanged since created
+}
+
+// Contact contains optional details that downstream parties use to contact a
+// creator, for example when signatures start failing.
+type Contact struct {
+	Email        string // Email address for the creator
+	DpoURL       string // URL to contact the data protection officer
+	Jurisdiction string // Legal jurisdiction the creator operates under
+}
+
+// isEmpty returns true if none of the contact details are present.
+func (c Contact) isEmpty() bool {
+	return c.Email == "" && c.DpoURL == "" && c.Jurisdiction == ""
+}
+
+// validate returns an error if the email address or DPO URL are present but
+// invalid.
+func (c Contact) validate() error {
+	if c.Email != "" {
+		_, err := mail.ParseAddress(c.Email)
+		if err != nil {
+			return fmt.Errorf("email '%s' invalid", c.Email)
+		}
+	}
+	if c.DpoURL != "" {
+		u, err := url.Parse(c.DpoURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("DPO URL '%s' invalid", c.DpoURL)
+		}
+	}
+	return nil
+}
+
+// cryptoOnce creates a Crypto instance once even when accessed from multiple
+// goroutines concurrently. Creators are shared across HTTP handlers.
+type cryptoOnce struct {
+	once   sync.Once
+	crypto *Crypto
+	err    error
+}
+
+// get returns the Crypto instance creating it with the function provided on
+// the first call.
+func (o *cryptoOnce) get(f func() (*Crypto, error)) (*Crypto, error) {
+	o.once.Do(func() {
+		o.crypto, o.err = f()
+	})
+	return o.crypto, o.err
 }
 
 // CreateOWID returns a new unsigned OWID from the creator containing the
-// payload provided.
+// payload provided. The creator's preferred version is used if set. Version 5
+// is used if the creator's key is on a curve other than P-256 and the version
+// can't carry the signature.
 func (c *Creator) CreateOWID(payload []byte) (*OWID, error) {
-	return NewOwid(c.domain, time.Now(), payload)
+	return c.createOWIDAt(payload, clockNow(c.clock))
 }
 
-// Sign the OWID by updating the signature field.
+// createOWIDAt returns a new unsigned OWID dated at the time provided.
+func (c *Creator) createOWIDAt(payload []byte, t time.Time) (*OWID, error) {
+	o, err := NewOwid(c.domain, t, payload)
+	if err != nil {
+		return nil, err
+	}
+	if c.version != 0 {
+		o.Version = c.version
+	}
+	c.upgradeVersion(o)
+	return o, nil
+}
+
+// upgradeVersion sets the OWID to version 5 if the creator's signatures can't
+// be carried by the OWID's version. If the creator's key can't be used the
+// error is returned when signing.
+func (c *Creator) upgradeVersion(o *OWID) {
+	x, err := c.NewCryptoSignOnly()
+	if err == nil &&
+		signatureLengthValid(o.Version, x.signatureLength()) == false {
+		o.Version = owidVersion5
+	}
+}
+
+// Sign the OWID by updating the signature field. A SuspendedError is returned
+// if the creator is suspended, or a PendingError if the domain challenge has
+// not been met.
 func (c *Creator) Sign(o *OWID, others ...*OWID) error {
-	if c.domain != o.Domain {
+	if c.status == CreatorSuspended {
+		return &SuspendedError{Domain: c.domain}
+	}
+	if c.status == CreatorPending {
+		return &PendingError{Domain: c.domain}
+	}
+	if c.privateKey == "" {
+		return fmt.Errorf("creator '%s' is verify only", c.domain)
+	}
+	if sameDomain(c.domain, o.Domain) == false {
 		return fmt.Errorf(
 			"can't use creator '%s' to sign OWID for domain '%s'",
 			c.domain,
@@ -71,7 +166,7 @@ func (c *Creator) CreateOWIDandSign(
 
 // Verify the OWID and any other OWIDs are valid for this creator.
 func (c *Creator) Verify(o *OWID, others ...*OWID) (bool, error) {
-	if c.domain != o.Domain {
+	if sameDomain(c.domain, o.Domain) == false {
 		return false, fmt.Errorf(
 			"Can't use creator '%s' to verify OWID for domain '%s'",
 			c.domain,
@@ -84,30 +179,29 @@ func (c *Creator) Verify(o *OWID, others ...*OWID) (bool, error) {
 	return o.VerifyWithCrypto(x, others)
 }
 
-// NewCryptoSignOnly creates a new instance of the Crypto structure
-// for signing OWIDs only.
+// NewCryptoSignOnly returns the instance of the Crypto structure for signing
+// OWIDs only. The instance is created on first use and is safe to use from
+// multiple goroutines.
 func (c *Creator) NewCryptoSignOnly() (*Crypto, error) {
-	if c.sign == nil {
-		var err error
-		c.sign, err = NewCryptoSignOnly(c.privateKey)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return c.sign, nil
+	return c.sign.get(func() (*Crypto, error) {
+		return NewCryptoSignOnly(c.privateKey)
+	})
 }
 
-// NewCryptoVerifyOnly creates a new instance of the Crypto structure
-// for Verifying OWIDs only.
+// NewCryptoVerifyOnly returns the instance of the Crypto structure for
+// verifying OWIDs only. The instance is created on first use and is safe to
+// use from multiple goroutines.
 func (c *Creator) NewCryptoVerifyOnly() (*Crypto, error) {
-	if c.verify == nil {
-		var err error
-		c.verify, err = NewCryptoVerifyOnly(c.publicKey)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return c.verify, nil
+	return c.verify.get(func() (*Crypto, error) {
+		return NewCryptoVerifyOnly(c.publicKey)
+	})
+}
+
+// warm creates the Crypto instances used for signing and verifying. Any errors
+// are retained and returned when the instances are next used.
+func (c *Creator) warm() {
+	c.NewCryptoSignOnly()
+	c.NewCryptoVerifyOnly()
 }
 
 // SubjectPublicKeyInfo returns the public key in SPKI form.
@@ -122,33 +216,107 @@ func (c *Creator) SubjectPublicKeyInfo() (string, error) {
 // Domain associated with the creator.
 func (c *Creator) Domain() string { return c.domain }
 
-// MarshalJSON marshals a node to JSON without having to expose the fields in
-// the node struct. This is achieved by converting a node to a map.
+// Created returns the date and time the creator and its keys were created. The
+// zero time is returned if the creator was stored before the date was
+// recorded.
+func (c *Creator) Created() time.Time { return c.created }
+
+// Contact returns the optional details used to contact the creator.
+func (c *Creator) Contact() Contact { return c.contact }
+
+// Status returns whether the creator is active or suspended.
+func (c *Creator) Status() CreatorStatus { return c.status }
+
+// PreferredVersion returns the version used for new OWIDs, or zero if the
+// default version is used.
+func (c *Creator) PreferredVersion() byte { return c.version }
+
+// withVersion returns a copy of the creator with the preferred version
+// provided. Zero restores the default version.
+func (c *Creator) withVersion(v byte) *Creator {
+	n := c.withStatus(c.status)
+	n.version = v
+	return n
+}
+
+// withStatus returns a copy of the creator with the status provided.
+func (c *Creator) withStatus(status CreatorStatus) *Creator {
+	n := newCreator(
+		c.domain,
+		c.privateKey,
+		c.publicKey,
+		c.name,
+		c.contractURL,
+		c.created,
+		c.contact,
+		status)
+	n.clock = c.clock
+	n.version = c.version
+	n.modified = c.modified
+	return n
+}
+
+// lastModified returns the time the public information last changed.
+func (c *Creator) lastModified() time.Time {
+	if c.modified.After(c.created) {
+		return c.modified
+	}
+	return c.created
+}
+
+// WithClock returns a copy of the creator that dates the OWIDs it creates
+// using the clock provided.
+func (c *Creator) WithClock(k Clock) *Creator {
+	n := c.withStatus(c.status)
+	n.clock = k
+	return n
+}
+
+// MarshalJSON marshals a creator to JSON without the private key so that a
+// creator included in a response or log by mistake can't expose it. Stores and
+// backups use MarshalStorageJSON.
 func (c *Creator) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]interface{}{
+	return json.Marshal(c.jsonMap(false))
+}
+
+// MarshalStorageJSON marshals a creator to JSON including the private key. Only
+// use when persisting the creator to a store or backup.
+func (c *Creator) MarshalStorageJSON() ([]byte, error) {
+	return json.Marshal(c.jsonMap(true))
+}
+
+// jsonMap converts the creator to a map so that the fields in the creator
+// struct don't need to be exposed. The private key is only included if
+// requested.
+func (c *Creator) jsonMap(private bool) map[string]interface{} {
+	m := map[string]interface{}{
 		"domain":       c.domain,
-		"privateKey":   c.privateKey,
 		"publicKey":    c.publicKey,
 		"name":         c.name,
-		"contractURL:": c.contractURL})
+		"contractURL":  c.contractURL,
+		"created":      c.created,
+		"email":        c.contact.Email,
+		"dpoURL":       c.contact.DpoURL,
+		"jurisdiction": c.contact.Jurisdiction,
+		"status":       c.status}
+	if c.version != 0 {
+		m["preferredVersion"] = c.version
+	}
+	if c.modified.IsZero() == false {
+		m["modified"] = c.modified
+	}
+	if private {
+		m["privateKey"] = c.privateKey
+	}
+	return m
 }
 
-// UnmarshalJSON called by json.Unmarshall unmarshals a node from JSON and turns
-// it into a new node. As the node is marshalled to JSON by converting it to a
-// map, the unmarshalling from JSON needs to handle the type of each field
-// correctly.
+// UnmarshalJSON called by json.Unmarshall unmarshals a creator from JSON using
+// the package level JSONStrict setting. As the creator is marshalled to JSON by
+// converting it to a map, the unmarshalling from JSON needs to handle the type
+// of each field explicitly.
 func (c *Creator) UnmarshalJSON(b []byte) error {
-	var d map[string]string
-	err := json.Unmarshal(b, &d)
-	if err != nil {
-		return err
-	}
-	c.domain = d["domain"]
-	c.privateKey = d["privateKey"]
-	c.publicKey = d["publicKey"]
-	c.name = d["name"]
-	c.contractURL = d["contractURL"]
-	return nil
+	return c.unmarshalJSON(b, JSONStrict)
 }
 
 func newCreator(
@@ -156,12 +324,21 @@ func newCreator(
 	privateKey string,
 	publicKey string,
 	name string,
-	contractURL string) *Creator {
+	contractURL string,
+	created time.Time,
+	contact Contact,
+	status CreatorStatus) *Creator {
 	var c Creator
 	c.domain = domain
 	c.privateKey = privateKey
 	c.publicKey = publicKey
 	c.name = name
 	c.contractURL = contractURL
+	c.created = created
+	c.contact = contact
+	c.status = status
+	if c.status == "" {
+		c.status = CreatorActive
+	}
 	return &c
 }
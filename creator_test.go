@@ -15,3 +15,147 @@
  * ***************************************************************************/
 
 package owid
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCreatorJSON checks that all the fields of a creator survive marshalling
+// to and from JSON as used by the local store.
+func TestCreatorJSON(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.contact = Contact{Email: "a@" + testDomain, Jurisdiction: "GB"}
+	c.version = owidVersion2
+	c.modified = c.created.Add(time.Hour)
+	j, err := c.MarshalStorageJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Creator
+	err = json.Unmarshal(j, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.domain != c.domain ||
+		d.name != c.name ||
+		d.privateKey != c.privateKey ||
+		d.publicKey != c.publicKey ||
+		d.contractURL != c.contractURL ||
+		d.created.Equal(c.created) == false ||
+		d.contact != c.contact ||
+		d.version != c.version ||
+		d.modified.Equal(c.modified) == false {
+		t.Error("creator fields changed after JSON round trip")
+	}
+	err = json.Unmarshal(
+		[]byte(strings.Replace(string(j), `"preferredVersion":2`,
+			`"preferredVersion":9`, 1)),
+		&d)
+	if err == nil {
+		t.Error("preferred version '9' should be an error")
+	}
+}
+
+// TestCreatorJSONPrivateKey checks that the private key is only included when
+// the creator is marshalled for storage.
+func TestCreatorJSONPrivateKey(t *testing.T) {
+	ts := newTestStore()
+	err := ts.addCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(ts.GetCreators())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(j), "privateKey") ||
+		strings.Contains(string(j), "PRIVATE KEY") {
+		t.Fatal("private key should not be marshalled")
+	}
+	j, err = ts.GetCreators()[testDomain].MarshalStorageJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(j), "PRIVATE KEY") == false {
+		t.Fatal("private key should be marshalled for storage")
+	}
+}
+
+// TestCreatorConcurrent signs and verifies with a shared creator from many
+// goroutines. Run with -race to detect unsynchronised access to the cached
+// Crypto instances.
+func TestCreatorConcurrent(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o, err := c.CreateOWIDandSign([]byte(testPayload))
+			if err != nil {
+				errs <- err
+				return
+			}
+			v, err := c.Verify(o)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if v == false {
+				errs <- fmt.Errorf("OWID did not pass verification")
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	a, _ := c.NewCryptoSignOnly()
+	b, _ := c.NewCryptoSignOnly()
+	if a != b {
+		t.Error("sign Crypto instance not reused")
+	}
+}
+
+func TestCreatorDiff(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := newCreator(
+		c.domain,
+		c.privateKey,
+		c.publicKey,
+		c.name,
+		c.contractURL,
+		c.created,
+		c.contact,
+		c.status)
+	if c.Equal(n) == false {
+		t.Fatalf("copy differs '%v'", c.Diff(n))
+	}
+	n.name = "other"
+	n.privateKey = "other"
+	d := c.Diff(n)
+	if len(d) != 2 || d[1] != "privateKey differs" {
+		t.Fatalf("differences '%v' not expected", d)
+	}
+	for _, i := range d {
+		if strings.Contains(i, c.privateKey) {
+			t.Fatal("difference contains the private key")
+		}
+	}
+}
@@ -19,12 +19,14 @@ package owid
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 )
 
@@ -33,17 +35,70 @@ import (
  * Nothing to do with the web or HTTP.
  */
 
+// Algorithm identifies the signature algorithm used by a Crypto instance.
+type Algorithm string
+
+// Signature algorithms supported by Crypto. The names are those used by JSON
+// Web Algorithms where one exists.
+const (
+	// AlgorithmES256 is ECDSA with the P-256 curve and SHA-256. Signatures
+	// can be verified by anyone with the public key.
+	AlgorithmES256 Algorithm = "ES256"
+
+	// AlgorithmHS256 is HMAC with SHA-256 and a secret shared between the
+	// signer and verifier. Much faster than ECDSA but only parties with the
+	// secret can verify so only suitable for internal traffic.
+	AlgorithmHS256 Algorithm = "HS256"
+
+	// AlgorithmP384 is ECDSA with the P-384 curve and SHA-256. OWIDs always
+	// sign a SHA-256 hash so this is not the JSON Web Algorithm ES384.
+	AlgorithmP384 Algorithm = "ECDSA-P384-SHA256"
+
+	// AlgorithmP521 is ECDSA with the P-521 curve and SHA-256.
+	AlgorithmP521 Algorithm = "ECDSA-P521-SHA256"
+)
+
+// CurveByName returns the elliptic curve for the name P-256, P-384 or P-521.
+// An empty name returns P-256.
+func CurveByName(name string) (elliptic.Curve, error) {
+	switch name {
+	case "", "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("curve '%s' not supported", name)
+	}
+}
+
+// The minimum length of an HMAC shared secret in bytes.
+const minHMACSecretLength = sha256.Size
+
 // Crypto structure containing the public and private keys
 type Crypto struct {
-	publicKey  *ecdsa.PublicKey
-	privateKey *ecdsa.PrivateKey
+	publicKey     *ecdsa.PublicKey
+	privateKey    *ecdsa.PrivateKey
+	deterministic bool   // True if RFC 6979 deterministic signing is used
+	secret        []byte // Shared secret for AlgorithmHS256, otherwise nil
 }
 
 // NewCrypto creates an new instance of the Crypto structure and generates
 // a public / private key pair used to sign and verify OWIDs
 func NewCrypto() (*Crypto, error) {
+	return NewCryptoWithCurve(elliptic.P256())
+}
+
+// NewCryptoWithCurve creates a new instance of the Crypto structure and
+// generates a key pair on the curve provided. Curves other than P-256 have
+// longer signatures that can only be carried by version 5 OWIDs.
+func NewCryptoWithCurve(curve elliptic.Curve) (*Crypto, error) {
+	if _, err := CurveByName(curve.Params().Name); err != nil {
+		return nil, err
+	}
 	var c Crypto
-	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	k, err := ecdsa.GenerateKey(curve, rand.Reader)
 	if err != nil {
 		return nil, err
 	}
@@ -82,56 +137,197 @@ func NewCryptoVerifyOnly(publicPemKey string) (*Crypto, error) {
 	if err != nil {
 		return nil, err
 	}
-	c.publicKey = publicKey.(*ecdsa.PublicKey)
+	var ok bool
+	c.publicKey, ok = publicKey.(*ecdsa.PublicKey)
+	if ok == false {
+		return nil, fmt.Errorf("public key type '%T' not supported", publicKey)
+	}
+	return &c, nil
+}
+
+// NewCryptoHMAC creates a new instance of the Crypto structure that signs and
+// verifies OWIDs with HMAC SHA-256 using the secret shared with a partner. The
+// secret must be at least 32 bytes.
+func NewCryptoHMAC(secret []byte) (*Crypto, error) {
+	if len(secret) < minHMACSecretLength {
+		return nil, fmt.Errorf(
+			"HMAC secret length '%d' less than '%d'",
+			len(secret),
+			minHMACSecretLength)
+	}
+	var c Crypto
+	c.secret = append([]byte{}, secret...)
 	return &c, nil
 }
 
+// Algorithm returns the signature algorithm used by the instance.
+func (c *Crypto) Algorithm() Algorithm {
+	if c.secret != nil {
+		return AlgorithmHS256
+	}
+	switch c.curve() {
+	case elliptic.P384():
+		return AlgorithmP384
+	case elliptic.P521():
+		return AlgorithmP521
+	default:
+		return AlgorithmES256
+	}
+}
+
+// curve returns the curve of the keys, or nil for HMAC instances.
+func (c *Crypto) curve() elliptic.Curve {
+	if c.privateKey != nil {
+		return c.privateKey.Curve
+	}
+	if c.publicKey != nil {
+		return c.publicKey.Curve
+	}
+	return nil
+}
+
+// signatureLength returns the length in bytes of the signatures created with
+// the instance. ECDSA signatures are the two integers each padded to the size
+// of the curve.
+func (c *Crypto) signatureLength() int {
+	k := c.curve()
+	if k == nil {
+		return signatureLength
+	}
+	return 2 * ((k.Params().BitSize + 7) / 8)
+}
+
+// SetDeterministic sets whether signatures are generated deterministically
+// using RFC 6979. When true signing the same data with the same key always
+// results in the same signature which is useful for reproducible tests and
+// audit replay. When false, the default, a random value is used.
+func (c *Crypto) SetDeterministic(deterministic bool) {
+	c.deterministic = deterministic
+}
+
 // SignByteArray signs the byte array with the private key of the crypto
 // provider.
 func (c *Crypto) SignByteArray(data []byte) ([]byte, error) {
-	if c.privateKey == nil && c.publicKey != nil {
+	if c.privateKey == nil && c.secret == nil && c.publicKey != nil {
 		return nil, errors.New(
 			"instance of Crypto cannot be used to generate a signature")
 	}
 	h := sha256.Sum256(data)
-	r, s, err := ecdsa.Sign(
-		rand.Reader,
-		c.privateKey,
-		h[:])
-	if err != nil {
-		return nil, err
+	return c.signHash(h[:])
+}
+
+// signHash signs the SHA-256 hash provided with the private key of the crypto
+// provider.
+func (c *Crypto) signHash(h []byte) ([]byte, error) {
+	if c.secret != nil {
+		return c.hmacSignature(h), nil
 	}
-	signature := make([]byte, signatureLength)
-	for i, b := range r.Bytes() {
-		signature[i] = b
+	if c.privateKey == nil {
+		return nil, errors.New(
+			"instance of Crypto cannot be used to generate a signature")
 	}
-	for i, b := range s.Bytes() {
-		signature[i+halfSignatureLength] = b
+	var r, s *big.Int
+	var err error
+	if c.deterministic {
+		r, s, err = signDeterministic(c.privateKey, h)
+	} else {
+		r, s, err = ecdsa.Sign(rand.Reader, c.privateKey, h)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The integers are right aligned within each half of the signature so
+	// that values with leading zero bytes are encoded correctly.
+	signature := make([]byte, c.signatureLength())
+	r.FillBytes(signature[:len(signature)/2])
+	s.FillBytes(signature[len(signature)/2:])
 	return signature, nil
 }
 
 // VerifyByteArray returns true if the signature is valid for the data.
 func (c *Crypto) VerifyByteArray(data []byte, sig []byte) (bool, error) {
-	if c.publicKey == nil {
+	if c.publicKey == nil && c.secret == nil {
 		return false, errors.New(
 			"instance of Crypto cannot be used to verify a signature")
 	}
 	h := sha256.Sum256(data)
+	return c.verifyHash(h[:], sig)
+}
+
+// verifyHash returns true if the signature is valid for the SHA-256 hash.
+// Only the length of the signature, which is public, causes an early return.
+// HMAC signatures are compared in constant time. ECDSA verification only
+// branches on the public key, hash and signature so reveals nothing secret.
+func (c *Crypto) verifyHash(h []byte, sig []byte) (bool, error) {
+	if len(sig) != c.signatureLength() {
+		return false, &SignatureLengthError{len(sig)}
+	}
+	if c.secret != nil {
+		return hmac.Equal(c.hmacSignature(h), sig), nil
+	}
+	if c.publicKey == nil {
+		return false, errors.New(
+			"instance of Crypto cannot be used to verify a signature")
+	}
 	var r, s big.Int
-	r.SetBytes(sig[:32])
-	s.SetBytes(sig[32:])
+	r.SetBytes(sig[:len(sig)/2])
+	s.SetBytes(sig[len(sig)/2:])
 	return ecdsa.Verify(
 		c.publicKey,
-		h[:],
+		h,
 		&r,
 		&s), nil
 }
 
+// hmacSignature returns the HMAC SHA-256 of the hash in the first half of a
+// signature. The second half is zero so that the OWID format is unchanged.
+func (c *Crypto) hmacSignature(h []byte) []byte {
+	m := hmac.New(sha256.New, c.secret)
+	m.Write(h)
+	s := make([]byte, signatureLength)
+	copy(s, m.Sum(nil))
+	return s
+}
+
+// SignReader signs all the data read from the reader with the private key of
+// the crypto provider. The data is hashed as it is read so it does not need to
+// fit in memory. The signature is the same as SignByteArray for the same data.
+func (c *Crypto) SignReader(r io.Reader) ([]byte, error) {
+	h, err := hashReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.signHash(h)
+}
+
+// VerifyReader returns true if the signature is valid for all the data read
+// from the reader.
+func (c *Crypto) VerifyReader(r io.Reader, sig []byte) (bool, error) {
+	h, err := hashReader(r)
+	if err != nil {
+		return false, err
+	}
+	return c.verifyHash(h, sig)
+}
+
+// hashReader returns the SHA-256 hash of all the data read from the reader.
+func hashReader(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	_, err := io.Copy(h, r)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 // getSubjectPublicKeyInfo returns the public key in SPKI format for use with
 // JavaScript SubtleCrypto.importKey() method or other methods that require
 // SPKI format public keys.
 func (c *Crypto) getSubjectPublicKeyInfo() (string, error) {
+	if c.publicKey == nil {
+		return "", errors.New("instance of Crypto has no public key")
+	}
 	spki, err := x509.MarshalPKIXPublicKey(c.publicKey)
 	if err != nil {
 		return "", err
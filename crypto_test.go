@@ -17,7 +17,18 @@
 package owid
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newCrypto() (*Crypto, error) {
@@ -42,6 +53,45 @@ func TestInvalidPrivatePem(t *testing.T) {
 	}
 }
 
+func TestVerifyShortSignature(t *testing.T) {
+	c, err := newCrypto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.VerifyByteArray([]byte("data"), make([]byte, halfSignatureLength))
+	if err == nil {
+		t.Fatal("short signature should error")
+	}
+}
+
+func TestCryptoReader(t *testing.T) {
+	c, err := newCrypto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := bytes.Repeat([]byte("data"), 1024)
+	s, err := c.SignReader(bytes.NewReader(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.VerifyByteArray(d, s)
+	if err != nil || v == false {
+		t.Fatal("reader signature should verify with the byte array")
+	}
+	s, err = c.SignByteArray(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = c.VerifyReader(bytes.NewReader(d), s)
+	if err != nil || v == false {
+		t.Fatal("byte array signature should verify with the reader")
+	}
+	v, err = c.VerifyReader(bytes.NewReader(d[1:]), s)
+	if err != nil || v {
+		t.Fatal("altered data should not verify")
+	}
+}
+
 func TestCrypto(t *testing.T) {
 	c, err := newCrypto()
 	if err != nil {
@@ -75,3 +125,118 @@ func TestCrypto(t *testing.T) {
 		t.Errorf("signature was invalid")
 	}
 }
+
+// TestCryptoDeterministic checks deterministic signing against the RFC 6979
+// appendix A.2.5 test vector for P-256 with SHA-256 and the message "sample".
+func TestCryptoDeterministic(t *testing.T) {
+	d, _ := new(big.Int).SetString(
+		"C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721",
+		16)
+	var k ecdsa.PrivateKey
+	k.Curve = elliptic.P256()
+	k.D = d
+	k.PublicKey.X, k.PublicKey.Y = k.Curve.ScalarBaseMult(d.Bytes())
+	c := Crypto{privateKey: &k, publicKey: &k.PublicKey}
+	c.SetDeterministic(true)
+	a, err := c.SignByteArray([]byte("sample"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716" +
+		"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"
+	if strings.ToUpper(hex.EncodeToString(a)) != e {
+		t.Errorf("signature '%x' does not match RFC 6979 vector", a)
+	}
+	b, err := c.SignByteArray([]byte("sample"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) == false {
+		t.Error("deterministic signatures differ")
+	}
+	v, err := c.VerifyByteArray([]byte("sample"), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Error("deterministic signature was invalid")
+	}
+}
+
+// TestCryptoHMAC signs and verifies an OWID with a shared secret and checks a
+// different secret or a short secret are refused.
+func TestCryptoHMAC(t *testing.T) {
+	_, err := NewCryptoHMAC([]byte("short"))
+	if err == nil {
+		t.Fatal("short secret should be refused")
+	}
+	s := bytes.Repeat([]byte{1}, minHMACSecretLength)
+	c, err := NewCryptoHMAC(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Algorithm() != AlgorithmHS256 {
+		t.Fatalf("algorithm '%s' not '%s'", c.Algorithm(), AlgorithmHS256)
+	}
+	o, err := NewOwid(testDomain, time.Now().UTC(), []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Sign(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.Signature) != signatureLength {
+		t.Fatalf("signature length '%d'", len(o.Signature))
+	}
+	b, err := o.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := FromByteArray(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := p.VerifyWithCrypto(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Error("HMAC signature was invalid")
+	}
+	d, err := NewCryptoHMAC(bytes.Repeat([]byte{2}, minHMACSecretLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = p.VerifyWithCrypto(d, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v {
+		t.Error("HMAC signature valid with a different secret")
+	}
+}
+
+// TestCryptoVerifyOnlyKeyType checks public keys that are not ECDSA are
+// refused with an error.
+func TestCryptoVerifyOnlyKeyType(t *testing.T) {
+	_, err := NewCryptoVerifyOnly(newEd25519PublicPem(t))
+	if err == nil {
+		t.Fatal("Ed25519 public key accepted")
+	}
+}
+
+// newEd25519PublicPem returns a new Ed25519 public key in PEM SPKI form.
+func newEd25519PublicPem(t *testing.T) string {
+	k, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := x509.MarshalPKIXPublicKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: b}))
+}
@@ -0,0 +1,142 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/elliptic"
+	"testing"
+	"time"
+)
+
+// newCurveCreator returns a creator with a key on the curve provided.
+func newCurveCreator(t *testing.T, domain string, k elliptic.Curve) *Creator {
+	x, err := NewCryptoWithCurve(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := x.privateKeyToPemString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := x.publicKeyToPemString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newCreator(
+		domain,
+		p,
+		u,
+		testOrgName,
+		registerContractURL,
+		time.Now().UTC(),
+		Contact{},
+		CreatorActive)
+}
+
+// TestCurves signs OWIDs with each supported curve and checks they survive a
+// round trip through the binary format and verify.
+func TestCurves(t *testing.T) {
+	for _, n := range []string{"P-256", "P-384", "P-521"} {
+		k, err := CurveByName(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := newCurveCreator(t, testDomain, k)
+		o, err := c.CreateOWIDandSign([]byte(testPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == "P-256" && o.Version != owidVersion3 {
+			t.Fatalf("P-256 OWID version '%d' not expected", o.Version)
+		}
+		if n != "P-256" && o.Version != owidVersion5 {
+			t.Fatalf("%s OWID version '%d' not expected", n, o.Version)
+		}
+		b, err := o.AsBase64()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := FromBase64(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.AsString() != b {
+			t.Fatalf("%s OWID changed by round trip", n)
+		}
+		v, err := c.Verify(r)
+		if err != nil || v == false {
+			t.Fatalf("%s OWID not verified '%v'", n, err)
+		}
+	}
+	_, err := CurveByName("P-224")
+	if err == nil {
+		t.Fatal("unsupported curve should error")
+	}
+}
+
+// TestCurveVersion checks that signatures that can't be carried by the OWID
+// version are refused.
+func TestCurveVersion(t *testing.T) {
+	c := newCurveCreator(t, testDomain, elliptic.P384())
+	x, err := c.NewCryptoSignOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Algorithm() != AlgorithmP384 {
+		t.Fatalf("algorithm '%s' not expected", x.Algorithm())
+	}
+	o, err := NewOwid(testDomain, time.Now(), []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Sign(x, nil) == nil {
+		t.Fatal("P-384 signature in version 3 OWID should error")
+	}
+	m, err := newTestCreator("other.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = m.CreateMultiSignatureOWID([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.CoSign(o) == nil {
+		t.Fatal("P-384 co-signature in version 4 OWID should error")
+	}
+	o.Version = owidVersion5
+	err = m.Sign(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.CoSign(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := o.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := FromByteArray(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.CoSignatures) != 1 ||
+		len(r.CoSignatures[0].Signature) != 96 ||
+		r.AsString() != o.AsString() {
+		t.Fatal("version 5 OWID with co-signature changed by round trip")
+	}
+}
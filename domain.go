@@ -0,0 +1,100 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// normalizeDomain returns the domain in lower case ASCII form with
+// internationalized labels converted to punycode. If the domain can't be
+// converted, for example because it contains a port, then the lower case
+// domain is returned.
+func normalizeDomain(domain string) string {
+	a, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return strings.ToLower(domain)
+	}
+	return a
+}
+
+// sameDomain returns true if the domains are the same after normalization so
+// that Unicode and punycode forms, and different cases, are equal.
+func sameDomain(a string, b string) bool {
+	return a == b || normalizeDomain(a) == normalizeDomain(b)
+}
+
+// hostName returns the host without any port, IPv6 brackets or trailing dot.
+func hostName(host string) string {
+	d := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		d = h
+	}
+	return strings.TrimSuffix(strings.Trim(d, "[]"), ".")
+}
+
+// hostDomain returns the normalized domain for the host without any port so
+// that creators are found with the domain registrationDomain registered them
+// under.
+func hostDomain(host string) string {
+	return normalizeDomain(hostName(host))
+}
+
+// registrationDomain returns the canonical domain to register for the host
+// provided. Any port is removed and the domain is normalized. An error is
+// returned if the domain is an IP address, a public suffix or has no
+// registrable part unless it is in the allowed list.
+func registrationDomain(host string, allowed []string) (string, error) {
+	d := hostName(host)
+	if d == "" {
+		return "", fmt.Errorf("domain missing")
+	}
+	a, err := idna.Registration.ToASCII(strings.ToLower(d))
+	if err != nil {
+		return "", fmt.Errorf("domain '%s' invalid: %s", host, err.Error())
+	}
+	for _, n := range allowed {
+		if normalizeDomain(n) == a {
+			return a, nil
+		}
+	}
+	if net.ParseIP(a) != nil {
+		return "", fmt.Errorf("domain '%s' is an IP address", host)
+	}
+	_, err = publicsuffix.EffectiveTLDPlusOne(a)
+	if err != nil {
+		return "", fmt.Errorf("domain '%s' not registrable: %s", host, err.Error())
+	}
+	return a, nil
+}
+
+// registrableDomain returns the public suffix plus one label for the domain,
+// or the normalized domain if it has no registrable part, for example an IP
+// address or localhost.
+func registrableDomain(domain string) string {
+	d := normalizeDomain(domain)
+	r, err := publicsuffix.EffectiveTLDPlusOne(d)
+	if err != nil {
+		return d
+	}
+	return r
+}
@@ -0,0 +1,83 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NotRegisteredError is returned when a domain has no creator.
+type NotRegisteredError struct {
+	Domain string // Domain without a creator
+}
+
+func (e *NotRegisteredError) Error() string {
+	return fmt.Sprintf("domain '%s' not registered", e.Domain)
+}
+
+// AlreadyRegisteredError is returned when a creator is registered for a
+// domain that already has one.
+type AlreadyRegisteredError struct {
+	Domain string // Domain with an existing creator
+}
+
+func (e *AlreadyRegisteredError) Error() string {
+	return fmt.Sprintf("domain '%s' already registered", e.Domain)
+}
+
+// domainLocks serializes changes to the creator for each domain so that
+// changes that read the creator before writing it don't overwrite each other,
+// without changes to different domains waiting for one another. Locks are
+// only held within a single process.
+type domainLocks struct {
+	mutex sync.Mutex
+	locks map[string]*domainLock
+}
+
+// domainLock is the lock for a single domain and the number of callers using
+// it so that it can be discarded when no longer needed.
+type domainLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks the domain and returns the function that unlocks it.
+func (d *domainLocks) lock(domain string) func() {
+	domain = normalizeDomain(domain)
+	d.mutex.Lock()
+	if d.locks == nil {
+		d.locks = make(map[string]*domainLock)
+	}
+	l := d.locks[domain]
+	if l == nil {
+		l = &domainLock{}
+		d.locks[domain] = l
+	}
+	l.refs++
+	d.mutex.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		d.mutex.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(d.locks, domain)
+		}
+		d.mutex.Unlock()
+	}
+}
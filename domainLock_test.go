@@ -0,0 +1,110 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDomainLocks checks a locked domain blocks only other callers for the
+// same domain and that unused locks are discarded.
+func TestDomainLocks(t *testing.T) {
+	var d domainLocks
+	u := d.lock("a.com")
+	l := make(chan bool)
+	go func() {
+		d.lock("b.com")()
+		l <- true
+	}()
+	select {
+	case <-l:
+	case <-time.After(time.Second):
+		t.Fatal("b.com should not wait for a.com")
+	}
+	go func() {
+		d.lock("A.com")()
+		l <- true
+	}()
+	select {
+	case <-l:
+		t.Fatal("a.com should wait for the lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	u()
+	<-l
+	if len(d.locks) != 0 {
+		t.Fatalf("expected no locks but got '%d'", len(d.locks))
+	}
+}
+
+// TestStoreCreatorConcurrent registers the same domain from several
+// goroutines and checks only one succeeds.
+func TestStoreCreatorConcurrent(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w sync.WaitGroup
+	var m sync.Mutex
+	n := 0
+	for i := 0; i < 8; i++ {
+		w.Add(1)
+		go func() {
+			defer w.Done()
+			d := Register{
+				Services:    s,
+				Domain:      "new.com",
+				Name:        testOrgName,
+				ContractURL: registerContractURL}
+			err := storeCreator(s, &d)
+			var a *AlreadyRegisteredError
+			if err != nil && errors.As(err, &a) == false {
+				t.Error(err)
+			}
+			if err == nil {
+				m.Lock()
+				n++
+				m.Unlock()
+			}
+		}()
+	}
+	w.Wait()
+	if n != 1 {
+		t.Fatalf("expected one registration but got '%d'", n)
+	}
+}
+
+// TestNotRegisteredError checks signing for a missing domain returns the typed
+// error.
+func TestNotRegisteredError(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Sign("missing.com", []byte(testPayload))
+	var e *NotRegisteredError
+	if errors.As(err, &e) == false || e.Domain != "missing.com" {
+		t.Fatalf("expected NotRegisteredError but got '%v'", err)
+	}
+	c, err := s.SetCreatorStatus("missing.com", CreatorSuspended, "key1")
+	if err != nil || c != nil {
+		t.Fatal("missing domain should return nil")
+	}
+}
@@ -0,0 +1,173 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	for _, c := range []struct{ domain, expected string }{
+		{"bücher.de", "xn--bcher-kva.de"},
+		{"BÜCHER.de", "xn--bcher-kva.de"},
+		{"xn--bcher-kva.de", "xn--bcher-kva.de"},
+		{"Example.COM", "example.com"},
+		{"Localhost:8080", "localhost:8080"}} {
+		if n := normalizeDomain(c.domain); n != c.expected {
+			t.Errorf("'%s' normalized to '%s' not '%s'", c.domain, n, c.expected)
+		}
+	}
+	if sameDomain("bücher.de", "xn--bcher-kva.de") == false {
+		t.Error("Unicode and punycode forms should be the same domain")
+	}
+	if sameDomain("bücher.de", "bucher.de") {
+		t.Error("different domains should not be the same")
+	}
+}
+
+// TestCreatorIDN signs an OWID with a creator registered with the Unicode
+// form of the domain and verifies it with the punycode form.
+func TestCreatorIDN(t *testing.T) {
+	c, err := newTestCreator("bücher.de", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Domain != "xn--bcher-kva.de" {
+		t.Fatalf("domain '%s' not normalized", o.Domain)
+	}
+	p := newCreator(
+		"xn--bcher-kva.de",
+		c.privateKey,
+		c.publicKey,
+		c.name,
+		c.contractURL,
+		c.created,
+		c.contact,
+		c.status)
+	v, err := p.Verify(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID should verify with the punycode creator")
+	}
+}
+
+// TestServicesIDN checks the services find the creator stored with the
+// punycode form of the domain when given the Unicode form.
+func TestServicesIDN(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator("xn--bcher-kva.de", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.store.setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := s.GetCreator("Bücher.de")
+	if err != nil || g != c {
+		t.Fatal("creator not found with the Unicode domain")
+	}
+	o, err := s.Sign("bücher.de", []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Verify("bücher.de", o)
+	if err != nil || v == false {
+		t.Fatalf("OWID not verified with the Unicode domain '%v'", err)
+	}
+}
+
+// TestServicesHostPort checks a creator registered for a domain is found when
+// requests are made to a host with a port, a different case or a trailing dot.
+func TestServicesHostPort(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator("example.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.store.setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []string{"Example.com:8080", "example.com.", "example.com"} {
+		var p PublicCreator
+		err = json.Unmarshal([]byte(decompressAsString(
+			t,
+			send(t, HandlerCreator(s), h, wellKnownPath, url.Values{}))),
+			&p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Domain != "example.com" {
+			t.Fatalf("host '%s' found creator '%s'", h, p.Domain)
+		}
+	}
+}
+
+// TestRegistrationDomain checks hosts are reduced to their canonical domain and
+// that hosts which can't be registered are refused unless allowed.
+func TestRegistrationDomain(t *testing.T) {
+	valid := map[string]string{
+		"Example.COM":       "example.com",
+		"example.com:8080":  "example.com",
+		"example.com.":      "example.com",
+		"bücher.example":    "xn--bcher-kva.example",
+		"sub.example.co.uk": "sub.example.co.uk"}
+	for h, e := range valid {
+		d, err := registrationDomain(h, nil)
+		if err != nil {
+			t.Errorf("host '%s' refused with '%s'", h, err)
+		} else if d != e {
+			t.Errorf("host '%s' expected '%s', found '%s'", h, e, d)
+		}
+	}
+	for _, h := range []string{
+		"",
+		"localhost:8080",
+		"127.0.0.1",
+		"[::1]:443",
+		"co.uk",
+		"com",
+		"bad domain.com"} {
+		_, err := registrationDomain(h, nil)
+		if err == nil {
+			t.Errorf("host '%s' should be refused", h)
+		}
+	}
+	d, err := registrationDomain("localhost:8080", []string{"localhost"})
+	if err != nil || d != "localhost" {
+		t.Errorf("allowed host refused with '%v'", err)
+	}
+	d, err = registrationDomain("127.0.0.1:80", []string{"127.0.0.1"})
+	if err != nil || d != "127.0.0.1" {
+		t.Errorf("allowed IP refused with '%v'", err)
+	}
+}
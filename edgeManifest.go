@@ -0,0 +1,239 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// The version of the edge manifest format written by MarshalBinary.
+const edgeManifestVersion = 1
+
+// The length of the longest raw public key, an uncompressed P-521 point.
+const maxRawPublicKeyLength = 133
+
+// EdgeKey is a public key in an edge manifest.
+type EdgeKey struct {
+	Created   time.Time // When the key was created, to the minute
+	PublicKey []byte    // Uncompressed elliptic curve point
+}
+
+// EdgeManifest maps domains to the public keys that could have signed their
+// OWIDs. It is written in a compact binary form for key value stores at CDN
+// edges where verification runs without access to the OWID store.
+//
+// The binary form is a version byte, the number of domains as a uint32, and
+// for each domain the null terminated domain, the number of keys as a byte,
+// and for each key the created minute as a uint32 and the raw public key as
+// a uint32 length and bytes. Numbers are little endian as in OWIDs.
+type EdgeManifest map[string][]*EdgeKey
+
+// NewEdgeManifest returns the manifest for the creators with their current
+// keys and, if the archive is not nil, their archived keys.
+func NewEdgeManifest(cs map[string]*Creator, a KeyArchive) (EdgeManifest, error) {
+	m := make(EdgeManifest)
+	for _, c := range cs {
+		x, err := c.NewCryptoVerifyOnly()
+		if err != nil {
+			return nil, err
+		}
+		k, err := newEdgeKey(x, c.created)
+		if err != nil {
+			return nil, err
+		}
+		d := normalizeDomain(c.domain)
+		m[d] = append(m[d], k)
+		if a == nil {
+			continue
+		}
+		ks, err := a.GetArchivedKeys(c.domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range ks {
+			x, err := NewCryptoVerifyOnly(v.PublicKeySPKI)
+			if err != nil {
+				return nil, err
+			}
+			k, err := newEdgeKey(x, v.Created)
+			if err != nil {
+				return nil, err
+			}
+			m[d] = append(m[d], k)
+		}
+	}
+	return m, nil
+}
+
+func newEdgeKey(x *Crypto, created time.Time) (*EdgeKey, error) {
+	p, err := x.publicKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return &EdgeKey{
+		Created:   created.Truncate(time.Minute),
+		PublicKey: p.Bytes()}, nil
+}
+
+// MarshalBinary returns the manifest in the compact binary form with the
+// domains in order so the same keys always give the same bytes.
+func (m EdgeManifest) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	d := make([]string, 0, len(m))
+	for k := range m {
+		d = append(d, k)
+	}
+	sort.Strings(d)
+	err := writeByte(&b, edgeManifestVersion)
+	if err == nil {
+		err = writeUint32(&b, uint32(len(d)))
+	}
+	for _, k := range d {
+		if err != nil {
+			return nil, err
+		}
+		if len(m[k]) > 255 {
+			return nil, fmt.Errorf("domain '%s' has too many keys", k)
+		}
+		err = writeString(&b, k)
+		if err == nil {
+			err = writeByte(&b, byte(len(m[k])))
+		}
+		for _, v := range m[k] {
+			if err == nil {
+				err = writeDateV2(&b, v.Created)
+			}
+			if err == nil {
+				err = writeByteArray(&b, v.PublicKey)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the manifest with the one in the compact binary
+// form. The keys are checked to be valid points.
+func (m *EdgeManifest) UnmarshalBinary(data []byte) error {
+	b := bytes.NewBuffer(data)
+	v, err := readByte(b)
+	if err != nil {
+		return err
+	}
+	if v != edgeManifestVersion {
+		return fmt.Errorf("edge manifest version '%d' not supported", v)
+	}
+	n, err := readUint32(b)
+	if err != nil {
+		return err
+	}
+	r := make(EdgeManifest)
+	for i := uint32(0); i < n; i++ {
+		d, err := readString(b, maxDomainLength)
+		if err != nil {
+			return err
+		}
+		c, err := readByte(b)
+		if err != nil {
+			return err
+		}
+		for j := byte(0); j < c; j++ {
+			t, err := readDateV2(b)
+			if err != nil {
+				return err
+			}
+			p, err := readByteArray(b, maxRawPublicKeyLength)
+			if err != nil {
+				return err
+			}
+			_, err = newCryptoRawPublicKey(p)
+			if err != nil {
+				return fmt.Errorf("domain '%s' %s", d, err.Error())
+			}
+			r[d] = append(r[d], &EdgeKey{
+				Created:   t,
+				PublicKey: append([]byte{}, p...)})
+		}
+	}
+	if b.Len() != 0 {
+		return fmt.Errorf("'%d' bytes after edge manifest", b.Len())
+	}
+	*m = r
+	return nil
+}
+
+// Verify returns true if the OWID was signed with one of the keys for its
+// domain created at or before the OWID's date. Keys are not retired in the
+// manifest so all earlier keys are tried.
+func (m EdgeManifest) Verify(o *OWID, others ...*OWID) (bool, error) {
+	ks, ok := m[normalizeDomain(o.Domain)]
+	if ok == false {
+		return false, &NotRegisteredError{Domain: o.Domain}
+	}
+	for _, k := range ks {
+		if o.Date.Before(k.Created) {
+			continue
+		}
+		x, err := newCryptoRawPublicKey(k.PublicKey)
+		if err != nil {
+			return false, err
+		}
+		v, err := o.VerifyWithCrypto(x, others)
+		if err != nil {
+			return false, err
+		}
+		if v {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newCryptoRawPublicKey returns a Crypto for verifying with the uncompressed
+// point. The curve is found from the length of the point.
+func newCryptoRawPublicKey(p []byte) (*Crypto, error) {
+	var e ecdh.Curve
+	var k elliptic.Curve
+	switch len(p) {
+	case 65:
+		e, k = ecdh.P256(), elliptic.P256()
+	case 97:
+		e, k = ecdh.P384(), elliptic.P384()
+	case 133:
+		e, k = ecdh.P521(), elliptic.P521()
+	default:
+		return nil, fmt.Errorf("public key length '%d' not supported", len(p))
+	}
+	_, err := e.NewPublicKey(p)
+	if err != nil {
+		return nil, err
+	}
+	n := (len(p) - 1) / 2
+	return &Crypto{publicKey: &ecdsa.PublicKey{
+		Curve: k,
+		X:     new(big.Int).SetBytes(p[1 : 1+n]),
+		Y:     new(big.Int).SetBytes(p[1+n:])}}, nil
+}
@@ -0,0 +1,89 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestEdgeManifest writes the manifest for a creator with an archived key,
+// reads it back and checks OWIDs signed with either key verify.
+func TestEdgeManifest(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.created = c.created.Add(-time.Hour)
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewKeyArchiveFile(filepath.Join(t.TempDir(), "archive.jsonl"))
+	err = ArchiveCreatorKey(a, c, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := n.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewEdgeManifest(map[string]*Creator{testDomain: n}, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r EdgeManifest
+	err = r.UnmarshalBinary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r[testDomain]) != 2 || len(r[testDomain][0].PublicKey) != 65 {
+		t.Fatalf("unexpected keys '%v'", r[testDomain])
+	}
+	for _, v := range []*OWID{o, p} {
+		ok, err := r.Verify(v)
+		if err != nil || ok == false {
+			t.Fatalf("OWID not verified '%v'", err)
+		}
+	}
+	o.Payload = []byte("changed")
+	ok, err := r.Verify(o)
+	if err != nil || ok {
+		t.Fatalf("changed OWID verified '%v'", err)
+	}
+	o.Domain = "unknown.com"
+	_, err = r.Verify(o)
+	var e *NotRegisteredError
+	if errors.As(err, &e) == false {
+		t.Fatalf("expected not registered error but got '%v'", err)
+	}
+	for _, v := range [][]byte{nil, {2}, b[:len(b)-1], append(b, 0)} {
+		if r.UnmarshalBinary(v) == nil {
+			t.Errorf("'%v' should not be valid", v)
+		}
+	}
+}
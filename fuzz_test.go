@@ -0,0 +1,133 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// newFuzzOWID returns a signed OWID and the Crypto instance that can verify it
+// for use as the seed corpus of the fuzz targets.
+func newFuzzOWID(f *testing.F) (*OWID, *Crypto) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		f.Fatal(err)
+	}
+	o, err := newOWID(c)
+	if err != nil {
+		f.Fatal(err)
+	}
+	v, err := c.NewCryptoVerifyOnly()
+	if err != nil {
+		f.Fatal(err)
+	}
+	return o, v
+}
+
+// FuzzFromByteArray checks that corrupted binary OWIDs never cause a panic
+// when decoded, encoded again or verified.
+func FuzzFromByteArray(f *testing.F) {
+	o, v := newFuzzOWID(f)
+	b, err := o.AsByteArray()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(b)
+	f.Add([]byte{})
+	f.Add([]byte{owidEmpty})
+	f.Add(b[:len(b)/2])
+	f.Fuzz(func(t *testing.T, b []byte) {
+		o, err := FromByteArray(b)
+		if err != nil {
+			return
+		}
+		var f bytes.Buffer
+		o.ToBuffer(&f)
+		o.VerifyWithCrypto(v, nil)
+	})
+}
+
+// FuzzFromBase64 checks that corrupted base 64 OWIDs never cause a panic.
+func FuzzFromBase64(f *testing.F) {
+	o, v := newFuzzOWID(f)
+	s, err := o.AsBase64()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(s)
+	f.Add("")
+	f.Add(s[:len(s)/2])
+	f.Fuzz(func(t *testing.T, s string) {
+		o, err := FromBase64(s)
+		if err != nil {
+			return
+		}
+		o.AsString()
+		o.VerifyWithCrypto(v, nil)
+	})
+}
+
+// FuzzFromJSON checks that corrupted JSON OWIDs never cause a panic in either
+// strict or lenient mode.
+func FuzzFromJSON(f *testing.F) {
+	o, v := newFuzzOWID(f)
+	j, err := json.Marshal(o)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(j)
+	f.Add([]byte("{}"))
+	f.Add([]byte(`{"version":1,"signature":"AA=="}`))
+	f.Fuzz(func(t *testing.T, j []byte) {
+		for _, s := range []bool{false, true} {
+			var o OWID
+			err := o.unmarshalJSON(j, s)
+			if err != nil {
+				continue
+			}
+			o.AsString()
+			o.VerifyWithCrypto(v, nil)
+		}
+	})
+}
+
+// FuzzCreatorJSON checks that corrupted JSON creators never cause a panic.
+func FuzzCreatorJSON(f *testing.F) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		f.Fatal(err)
+	}
+	j, err := c.MarshalStorageJSON()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(j)
+	f.Add([]byte("{}"))
+	f.Fuzz(func(t *testing.T, j []byte) {
+		for _, s := range []bool{false, true} {
+			var c Creator
+			err := c.unmarshalJSON(j, s)
+			if err != nil {
+				continue
+			}
+			c.NewCryptoVerifyOnly()
+			c.NewCryptoSignOnly()
+		}
+	})
+}
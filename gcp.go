@@ -1,3 +1,5 @@
+//go:build !js
+
 /* ****************************************************************************
  * Copyright 2020 51 Degrees Mobile Experts Limited (51degrees.com)
  *
@@ -18,50 +20,83 @@ package owid
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"os"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/firestore"
-	firebase "firebase.google.com/go"
+	gcs "cloud.google.com/go/storage"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Connect to GCP Firebase. Concrete implementation of store.go
 
 // Firebase is a implementation of owid.Store for GCP's Firebase.
 type Firebase struct {
-	timestamp time.Time         // The last time the maps were refreshed
-	client    *firestore.Client // Firebase app
+	client *firestore.Client // Firebase app
 	common
 }
 
 // Fireitem is the Firestore table item representation of a Creator
 type Fireitem struct {
-	Domain      string
-	PrivateKey  string
-	PublicKey   string
-	Name        string
-	ContractURL string
+	Domain       string
+	PrivateKey   string
+	PublicKey    string
+	Name         string
+	ContractURL  string
+	Created      time.Time
+	Email        string
+	DpoURL       string
+	Jurisdiction string
+	Status       string
+	Version      int       // Preferred OWID version, or zero for the default
+	Modified     time.Time // Time the public information last changed, or zero
 }
 
-// NewFirebase creates a new instance of the Firebase structure
-func NewFirebase(project string) (*Firebase, error) {
-	var f Firebase
+// Environment variable set to the host and port of the Firestore emulator. The
+// Firestore client connects to the emulator without credentials when it is
+// set.
+const firestoreEmulatorHost = "FIRESTORE_EMULATOR_HOST"
 
-	ctx := context.Background()
-	conf := &firebase.Config{ProjectID: project}
+// FirebaseOptions control how the Firestore client authenticates.
+type FirebaseOptions struct {
+	CredentialsFile string // Service account JSON file, or empty for the default credentials
+}
+
+// newFirebase returns the Firebase store for the configuration.
+func (c *Configuration) newFirebase() (*Firebase, error) {
+	return NewFirebaseWithOptions(
+		c.GcpProject,
+		FirebaseOptions{CredentialsFile: c.GcpCredentialsFile})
+}
 
-	app, err := firebase.NewApp(ctx, conf)
+// NewFirebase creates a new instance of the Firebase structure
+func NewFirebase(project string) (*Firebase, error) {
+	return NewFirebaseWithOptions(project, FirebaseOptions{})
+}
 
-	client, err := app.Firestore(ctx)
+// NewFirebaseWithOptions creates a new instance of the Firebase structure
+// using the options provided. The credentials file is ignored when the
+// emulator is used.
+func NewFirebaseWithOptions(
+	project string,
+	o FirebaseOptions) (*Firebase, error) {
+	var f Firebase
+	var opts []option.ClientOption
+	if h := os.Getenv(firestoreEmulatorHost); h != "" {
+		log.Printf("OWID:Using Firestore emulator '%s'", h)
+	} else if o.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(o.CredentialsFile))
+	}
+	client, err := firestore.NewClient(context.Background(), project, opts...)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
-
 	f.client = client
-
 	f.mutex = &sync.Mutex{}
 	err = f.refresh()
 	if err != nil {
@@ -70,54 +105,107 @@ func NewFirebase(project string) (*Firebase, error) {
 	return &f, nil
 }
 
+// setCreator writes the creator in a transaction so that concurrent writers
+// for the same domain are serialized and a failed write leaves the existing
+// document unchanged.
 func (f *Firebase) setCreator(creator *Creator) error {
-	ctx := context.Background()
-	c := Fireitem{
-		Domain:      creator.domain,
-		PrivateKey:  creator.privateKey,
-		PublicKey:   creator.publicKey,
-		Name:        creator.name,
-		ContractURL: creator.contractURL,
+	d := f.client.Collection(creatorsTableName).Doc(creator.domain)
+	err := f.client.RunTransaction(
+		context.Background(),
+		func(ctx context.Context, t *firestore.Transaction) error {
+			_, err := t.Get(d)
+			if err != nil && status.Code(err) != codes.NotFound {
+				return err
+			}
+			return t.Set(d, newFireitem(creator))
+		})
+	if err != nil {
+		return err
 	}
-	a, err := f.client.Collection(creatorsTableName).Doc(creator.domain).Set(ctx, c)
-	fmt.Println(a)
-	return err
+	f.putCreator(creator)
+	return nil
+}
+
+// newFireitem returns the Firestore document for the creator.
+func newFireitem(c *Creator) *Fireitem {
+	return &Fireitem{
+		Domain:       c.domain,
+		PrivateKey:   c.privateKey,
+		PublicKey:    c.publicKey,
+		Name:         c.name,
+		ContractURL:  c.contractURL,
+		Created:      c.created,
+		Email:        c.contact.Email,
+		DpoURL:       c.contact.DpoURL,
+		Jurisdiction: c.contact.Jurisdiction,
+		Status:       string(c.status),
+		Version:      int(c.version),
+		Modified:     c.modified}
+}
+
+// creator returns the creator held in the Firestore document.
+func (i *Fireitem) creator() *Creator {
+	c := newCreator(
+		i.Domain,
+		i.PrivateKey,
+		i.PublicKey,
+		i.Name,
+		i.ContractURL,
+		i.Created,
+		Contact{
+			Email:        i.Email,
+			DpoURL:       i.DpoURL,
+			Jurisdiction: i.Jurisdiction},
+		CreatorStatus(i.Status))
+	c.version = byte(i.Version)
+	c.modified = i.Modified
+	return c
 }
 
 // GetCreator gets creator for domain from internal map, updating the internal
-// map if the creator is not in the map.
+// map if the creator is not in the map. Domains that are not found are
+// remembered for a short time so that they don't cause a refresh per request.
+// If the update fails the creators already held are used until they are
+// older than the maximum staleness.
 func (f *Firebase) GetCreator(domain string) (*Creator, error) {
 	c, err := f.common.getCreator(domain)
 	if err != nil {
 		return nil, err
 	}
-	if c == nil {
-		err = f.refresh()
+	if c == nil && f.shouldRefresh(domain) {
+		err = f.refreshError(f.refresh())
 		if err != nil {
 			return nil, err
 		}
 		c, err = f.common.getCreator(domain)
+		if c == nil {
+			f.addMiss(domain)
+		}
 	}
 	return c, err
 }
 
 func (f *Firebase) refresh() error {
 	// Fetch the creators
-	cs, err := f.fetchCreators()
+	cs, e, err := f.fetchCreators()
 	if err != nil {
 		return err
 	}
 	// In a single atomic operation update the reference to the creators.
-	f.mutex.Lock()
-	f.creators = cs
-	f.mutex.Unlock()
+	f.setCreators(cs, e)
 
 	return nil
 }
 
-func (f *Firebase) fetchCreators() (map[string]*Creator, error) {
+// fetchCreators reads the creators collection. Documents that can't be read
+// are returned with their errors keyed on document ID.
+func (f *Firebase) fetchCreators() (
+	map[string]*Creator,
+	map[string]error,
+	error) {
 	ctx := context.Background()
 	cs := make(map[string]*Creator)
+	e := make(map[string]error)
 
 	iter := f.client.Collection(creatorsTableName).Documents(ctx)
 	for {
@@ -126,19 +214,81 @@ func (f *Firebase) fetchCreators() (map[string]*Creator, error) {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		var item Fireitem
 		err = doc.DataTo(&item)
 		if err != nil {
-			return nil, err
+			e[doc.Ref.ID] = err
+			continue
 		}
-		cs[item.Domain] = newCreator(
-			item.Domain,
-			item.PrivateKey,
-			item.PublicKey,
-			item.Name,
-			item.ContractURL)
+		cs[item.Domain] = item.creator()
+	}
+	return cs, e, nil
+}
+
+// schemaDoc is the Firestore document that holds the schema version in its own
+// collection.
+type schemaDoc struct {
+	Version int
+}
+
+func (f *Firebase) schemaBackend() string { return schemaBackendGCP }
+
+// getSchemaVersion reads the schema document, or zero if there is none.
+func (f *Firebase) getSchemaVersion() (int, error) {
+	d, err := f.client.Collection(schemaTableName).Doc(schemaRowKey).Get(
+		context.Background())
+	if status.Code(err) == codes.NotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var s schemaDoc
+	err = d.DataTo(&s)
+	if err != nil {
+		return 0, err
+	}
+	return s.Version, nil
+}
+
+// setSchemaVersion writes the schema document.
+func (f *Firebase) setSchemaVersion(v int) error {
+	_, err := f.client.Collection(schemaTableName).Doc(schemaRowKey).Set(
+		context.Background(),
+		schemaDoc{Version: v})
+	return err
+}
+
+// PublisherGCS publishes public information to a Google Cloud Storage bucket.
+type PublisherGCS struct {
+	bucket *gcs.BucketHandle
+}
+
+// NewPublisherGCS creates a publisher for the bucket using the default
+// credentials.
+func NewPublisherGCS(bucket string) (*PublisherGCS, error) {
+	c, err := gcs.NewClient(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &PublisherGCS{bucket: c.Bucket(bucket)}, nil
+}
+
+// Put writes the data to the object with the name.
+func (p *PublisherGCS) Put(
+	name string,
+	contentType string,
+	cacheControl string,
+	data []byte) error {
+	w := p.bucket.Object(name).NewWriter(context.Background())
+	w.ContentType = contentType
+	w.CacheControl = cacheControl
+	_, err := w.Write(data)
+	if err != nil {
+		w.Close()
+		return err
 	}
-	return cs, nil
+	return w.Close()
 }
@@ -0,0 +1,70 @@
+//go:build !js
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"os"
+	"testing"
+)
+
+// TestFireitem checks a creator survives conversion to and from the Firestore
+// document.
+func TestFireitem(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.contact = Contact{Email: "dpo@" + testDomain, Jurisdiction: "GB"}
+	n := newFireitem(c).creator()
+	if n.domain != c.domain ||
+		n.privateKey != c.privateKey ||
+		n.publicKey != c.publicKey ||
+		n.created.Equal(c.created) == false ||
+		n.contact != c.contact ||
+		n.status != c.status {
+		t.Fatal("creator changed by Firestore document")
+	}
+}
+
+// TestFirebaseEmulator stores and reads a creator using the Firestore
+// emulator. Set FIRESTORE_EMULATOR_HOST to run.
+func TestFirebaseEmulator(t *testing.T) {
+	if os.Getenv(firestoreEmulatorHost) == "" {
+		t.Skip(firestoreEmulatorHost + " not set")
+	}
+	f, err := NewFirebase("owid-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, _, err := f.fetchCreators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs[testDomain] == nil || cs[testDomain].SelfTest() != nil {
+		t.Fatal("creator not read back from the emulator")
+	}
+}
@@ -0,0 +1,168 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// TestGrpcSignVerify signs an OWID using the gRPC client and then verifies it
+// with both the gRPC client and the public key returned by GetSigner.
+func TestGrpcSignVerify(t *testing.T) {
+	c := newTestGrpcClient(t)
+	o, err := c.Sign(
+		context.Background(),
+		"key1",
+		testDomain,
+		[]byte(testPayload),
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.Verify(context.Background(), o, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID did not pass verification")
+	}
+	p, err := c.GetSigner(context.Background(), testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = o.VerifyWithPublicKey(p.PublicKeySPKI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID did not pass verification with signer public key")
+	}
+}
+
+// TestGrpcAccessDenied checks that signing and registering require a valid
+// access key.
+func TestGrpcAccessDenied(t *testing.T) {
+	c := newTestGrpcClient(t)
+	_, err := c.Sign(
+		context.Background(),
+		"invalid",
+		testDomain,
+		[]byte(testPayload),
+		nil)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected permission denied, found '%v'", err)
+	}
+	_, err = c.Register(
+		context.Background(),
+		"invalid",
+		registerDomain,
+		registerName,
+		registerContractURL)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected permission denied, found '%v'", err)
+	}
+}
+
+// TestGrpcRegister registers a new domain and checks it can then sign.
+func TestGrpcRegister(t *testing.T) {
+	c := newTestGrpcClient(t)
+	p, err := c.Register(
+		context.Background(),
+		"key1",
+		registerDomain,
+		registerName,
+		registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Domain != registerDomain || p.Name != registerName {
+		t.Fatalf("unexpected signer '%s' '%s'", p.Domain, p.Name)
+	}
+	_, err = c.Register(
+		context.Background(),
+		"key1",
+		registerDomain,
+		registerName,
+		registerContractURL)
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected already exists, found '%v'", err)
+	}
+	_, err = c.Sign(
+		context.Background(),
+		"key1",
+		registerDomain,
+		[]byte(testPayload),
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGrpcRegisterInvalid checks the gRPC server refuses registrations that
+// the HTTP handler refuses.
+func TestGrpcRegisterInvalid(t *testing.T) {
+	c := newTestGrpcClient(t)
+	_, err := c.Register(
+		context.Background(),
+		"key1",
+		registerDomain,
+		"short",
+		registerContractURL)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected invalid argument, found '%v'", err)
+	}
+	_, err = c.Register(
+		context.Background(),
+		"key1",
+		registerDomain,
+		registerName,
+		"http://[invalid")
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected invalid argument, found '%v'", err)
+	}
+}
+
+func newTestGrpcClient(t *testing.T) *GrpcClient {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := bufconn.Listen(1024 * 1024)
+	g := grpc.NewServer()
+	AddGrpcServer(g, s)
+	go g.Serve(l)
+	t.Cleanup(g.Stop)
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return l.Dial()
+		}),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewGrpcClient(conn)
+}
@@ -0,0 +1,133 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"time"
+
+	"github.com/SWAN-community/owid-go/owidpb"
+	"google.golang.org/grpc"
+)
+
+// GrpcClient is used by internal services to sign and verify OWIDs using a
+// remote GrpcServer.
+type GrpcClient struct {
+	client owidpb.OWIDClient // The generated gRPC client
+}
+
+// NewGrpcClient creates a new instance of the gRPC client using the connection
+// provided.
+func NewGrpcClient(conn grpc.ClientConnInterface) *GrpcClient {
+	var c GrpcClient
+	c.client = owidpb.NewOWIDClient(conn)
+	return &c
+}
+
+// Sign returns a new signed OWID containing the payload for the domain. The
+// parent is optional and can be nil.
+func (c *GrpcClient) Sign(
+	ctx context.Context,
+	accessKey string,
+	domain string,
+	payload []byte,
+	parent *OWID) (*OWID, error) {
+	r := owidpb.SignRequest{
+		AccessKey: accessKey,
+		Domain:    domain,
+		Payload:   payload}
+	if parent != nil {
+		var err error
+		r.Parent, err = parent.AsByteArray()
+		if err != nil {
+			return nil, err
+		}
+	}
+	s, err := c.client.Sign(ctx, &r)
+	if err != nil {
+		return nil, err
+	}
+	return FromByteArray(s.Owid)
+}
+
+// Verify returns true if the OWID, and optional parent, are valid.
+func (c *GrpcClient) Verify(
+	ctx context.Context,
+	o *OWID,
+	parent *OWID) (bool, error) {
+	var r owidpb.VerifyRequest
+	var err error
+	r.Owid, err = o.AsByteArray()
+	if err != nil {
+		return false, err
+	}
+	if parent != nil {
+		r.Parent, err = parent.AsByteArray()
+		if err != nil {
+			return false, err
+		}
+	}
+	v, err := c.client.Verify(ctx, &r)
+	if err != nil {
+		return false, err
+	}
+	return v.Valid, nil
+}
+
+// GetSigner returns the public information associated with the domain.
+func (c *GrpcClient) GetSigner(
+	ctx context.Context,
+	domain string) (*PublicCreator, error) {
+	s, err := c.client.GetSigner(ctx, &owidpb.GetSignerRequest{Domain: domain})
+	if err != nil {
+		return nil, err
+	}
+	return grpcPublicCreator(s), nil
+}
+
+// Register a new creator for the domain returning the public information.
+func (c *GrpcClient) Register(
+	ctx context.Context,
+	accessKey string,
+	domain string,
+	name string,
+	contractURL string) (*PublicCreator, error) {
+	s, err := c.client.Register(ctx, &owidpb.RegisterRequest{
+		AccessKey:   accessKey,
+		Domain:      domain,
+		Name:        name,
+		ContractUrl: contractURL})
+	if err != nil {
+		return nil, err
+	}
+	return grpcPublicCreator(s), nil
+}
+
+func grpcPublicCreator(s *owidpb.Signer) *PublicCreator {
+	created, _ := time.Parse(time.RFC3339Nano, s.Created)
+	return &PublicCreator{
+		Domain:        s.Domain,
+		Name:          s.Name,
+		PublicKeySPKI: s.PublicKeySpki,
+		ContractURL:   s.ContractUrl,
+		Created:       created,
+		Email:         s.Email,
+		DpoURL:        s.DpoUrl,
+		Jurisdiction:  s.Jurisdiction,
+		Status:        s.Status,
+		Signature:     s.Signature}
+}
@@ -0,0 +1,220 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SWAN-community/owid-go/owidpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcServer implements the owidpb.OWIDServer interface using the services
+// provided. Used by internal services that need lower latency and strong
+// typing compared to the HTTP handlers.
+type GrpcServer struct {
+	owidpb.UnimplementedOWIDServer
+	services *Services // Services used to access the store and access keys
+}
+
+// NewGrpcServer creates a new instance of the gRPC server for the services.
+func NewGrpcServer(s *Services) *GrpcServer {
+	var g GrpcServer
+	g.services = s
+	return &g
+}
+
+// AddGrpcServer registers the OWID gRPC service with the gRPC server provided.
+func AddGrpcServer(g *grpc.Server, s *Services) {
+	owidpb.RegisterOWIDServer(g, NewGrpcServer(s))
+}
+
+// Sign creates a new OWID for the domain in the request and signs it with the
+// creator's private key.
+func (g *GrpcServer) Sign(
+	ctx context.Context,
+	r *owidpb.SignRequest) (*owidpb.SignResponse, error) {
+	err := g.getAccessAllowed(r.AccessKey, ScopeSign)
+	if err != nil {
+		return nil, err
+	}
+	err = checkPayload(r.Payload, g.services.Config().MaxPayloadLength)
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	c, err := g.getCreator(r.Domain)
+	if err != nil {
+		return nil, err
+	}
+	var others []*OWID
+	if len(r.Parent) > 0 {
+		p, err := FromByteArray(r.Parent)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		others = append(others, p)
+	}
+	o, err := c.CreateOWIDandSign(r.Payload, others...)
+	if err != nil {
+		var e *SuspendedError
+		var p *PendingError
+		if errors.As(err, &e) || errors.As(err, &p) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	b, err := o.AsByteArray()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &owidpb.SignResponse{Owid: b}, nil
+}
+
+// Verify returns true if the OWID, and optional parent, in the request are
+// valid for the creator associated with the OWID's domain.
+func (g *GrpcServer) Verify(
+	ctx context.Context,
+	r *owidpb.VerifyRequest) (*owidpb.VerifyResponse, error) {
+	o, err := FromByteArray(r.Owid)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = checkPayload(o.Payload, g.services.Config().MaxPayloadLength)
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	var p *OWID
+	if len(r.Parent) > 0 {
+		p, err = FromByteArray(r.Parent)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	c, err := g.getCreator(o.Domain)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.Verify(o, p)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &owidpb.VerifyResponse{Valid: v}, nil
+}
+
+// GetSigner returns the public information for the domain in the request.
+func (g *GrpcServer) GetSigner(
+	ctx context.Context,
+	r *owidpb.GetSignerRequest) (*owidpb.Signer, error) {
+	c, err := g.getCreator(r.Domain)
+	if err != nil {
+		return nil, err
+	}
+	return grpcSigner(c)
+}
+
+// Register creates a new creator for the domain in the request.
+func (g *GrpcServer) Register(
+	ctx context.Context,
+	r *owidpb.RegisterRequest) (*owidpb.Signer, error) {
+	err := g.getAccessAllowed(r.AccessKey, ScopeRegister)
+	if err != nil {
+		return nil, err
+	}
+	h, err := registrationDomain(
+		r.Domain,
+		g.services.Config().AllowedRegisterHosts)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	d := Register{
+		Services:    g.services,
+		Domain:      h,
+		Name:        r.Name,
+		ContractURL: r.ContractUrl,
+		AccessKey:   r.AccessKey}
+	err = d.validate(g.services.Config())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	c, err := g.services.store.GetCreator(h)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if c != nil {
+		return nil, status.Errorf(
+			codes.AlreadyExists,
+			"domain '%s' already registered",
+			r.Domain)
+	}
+	err = storeCreator(g.services, &d)
+	var a *AlreadyRegisteredError
+	if errors.As(err, &a) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	c, err = g.getCreator(h)
+	if err != nil {
+		return nil, err
+	}
+	return grpcSigner(c)
+}
+
+func (g *GrpcServer) getAccessAllowed(accessKey string, scope Scope) error {
+	v, err := g.services.getAllowed(accessKey, scope)
+	if v == false || err != nil {
+		return status.Error(codes.PermissionDenied, "Access denied")
+	}
+	return nil
+}
+
+func (g *GrpcServer) getCreator(domain string) (*Creator, error) {
+	c, err := g.services.store.GetCreator(domain)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if c == nil {
+		return nil, status.Error(
+			codes.NotFound,
+			fmt.Sprintf("domain '%s' not registered", domain))
+	}
+	return c, nil
+}
+
+func grpcSigner(c *Creator) (*owidpb.Signer, error) {
+	p, err := publicCreator(c)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &owidpb.Signer{
+		Domain:        p.Domain,
+		Name:          p.Name,
+		PublicKeySpki: p.PublicKeySPKI,
+		ContractUrl:   p.ContractURL,
+		Created:       p.Created.UTC().Format(time.RFC3339Nano),
+		Email:         p.Email,
+		DpoUrl:        p.DpoURL,
+		Jurisdiction:  p.Jurisdiction,
+		Status:        p.Status,
+		Signature:     p.Signature}, nil
+}
@@ -0,0 +1,51 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+)
+
+// HandlerBundle returns the public information for all the creators known to
+// the service signed by the creator for the requesting host. The bundle can be
+// used with NewBundleVerifier to verify OWIDs without network access. The
+// signed bundle is reused for a minute so that the creators are not signed
+// for every request.
+func HandlerBundle(s *Services) http.HandlerFunc {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		c, err := s.store.GetCreator(r.Host)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: r.Host},
+				http.StatusNotFound)
+			return
+		}
+		u, err := s.bundles.get(c, s.store.GetCreators(), s.now())
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		sendResponse(s, w, r, "application/json; charset=utf-8", u)
+	})
+}
@@ -17,45 +17,78 @@
 package owid
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 )
 
-// PublicCreator used by a supply chain partner to cache the publicKey
-// associated with the domain so that they do not need to call the end points to
-// verify a signature. For example; a request is received with OWIDs and those
-// OWIDs need to be verified before the bid is processed.
-type PublicCreator struct {
-	Domain        string `json:"domain"`        // The domain that the name and key relate to
-	Name          string `json:"name"`          // Common name of the creator
-	PublicKeySPKI string `json:"publicKeySPKI"` // The public key in SPKI form
-	ContractURL   string `json:"contractURL"`   // URL with the T&Cs associated with the creation of the data in the OWID
-}
-
 // HandlerCreator Returns the public information associated with the creator.
 func HandlerCreator(s *Services) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
 		c, err := s.store.GetCreator(r.Host)
 		if err != nil {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
 			return
 		}
-		pc, err := publicCreator(c)
+		if c == nil {
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: r.Host},
+				http.StatusNotFound)
+			return
+		}
+		pc, err := newPublicCreator(c)
 		if err != nil {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
 			return
 		}
-		u, err := json.Marshal(pc)
+		// The signature varies for each request so the ETag is derived from
+		// the signed fields only. Conditional requests are answered before
+		// the public information is signed.
+		e, err := pc.signingData()
 		if err != nil {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Cache-Control", "max-age=60")
-		sendResponse(s, w, "application/json; charset=utf-8", u)
-	}
+		if isNotModified(w, r, newETag(e), c.lastModified()) {
+			return
+		}
+		err = pc.sign(c)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		u, err := json.Marshal(pc)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		sendResponse(s, w, r, "application/json; charset=utf-8", u)
+	})
 }
 
+// publicCreator returns the public information for the creator signed with
+// the creator's private key. Creators that only verify use the signature
+// created when the private key was removed.
 func publicCreator(c *Creator) (*PublicCreator, error) {
+	p, err := newPublicCreator(c)
+	if err != nil {
+		return nil, err
+	}
+	err = p.sign(c)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newPublicCreator returns the public information for the creator without a
+// signature.
+func newPublicCreator(c *Creator) (*PublicCreator, error) {
 	var err error
 	var p PublicCreator
 	p.PublicKeySPKI, err = c.SubjectPublicKeyInfo()
@@ -65,5 +98,87 @@ func publicCreator(c *Creator) (*PublicCreator, error) {
 	p.Domain = c.domain
 	p.Name = c.name
 	p.ContractURL = c.contractURL
+	p.Created = c.created
+	p.Email = c.contact.Email
+	p.DpoURL = c.contact.DpoURL
+	p.Jurisdiction = c.contact.Jurisdiction
+	p.Status = string(c.status)
 	return &p, nil
 }
+
+// sign sets the signature of the public information using the creator's
+// private key, or the signature created when the private key was removed for
+// creators that only verify.
+func (p *PublicCreator) sign(c *Creator) error {
+	if c.privateKey == "" {
+		if c.signature == nil {
+			return fmt.Errorf(
+				"creator '%s' has no signature for its public information",
+				c.domain)
+		}
+		p.Signature = c.signature
+		return nil
+	}
+	x, err := c.NewCryptoSignOnly()
+	if err != nil {
+		return err
+	}
+	b, err := p.signingData()
+	if err != nil {
+		return err
+	}
+	p.Signature, err = x.SignByteArray(b)
+	return err
+}
+
+// VerifySignature returns true if the signature was created with the private
+// key associated with the public key in PEM format provided. Used with a
+// public key obtained previously from a trusted source to detect the public
+// information being altered in transit.
+func (p *PublicCreator) VerifySignature(public string) (bool, error) {
+	c, err := NewCryptoVerifyOnly(public)
+	if err != nil {
+		return false, err
+	}
+	b, err := p.signingData()
+	if err != nil {
+		return false, err
+	}
+	return c.VerifyByteArray(b, p.Signature)
+}
+
+// VerifySelfSignature returns true if the signature was created with the
+// private key associated with the public key in the public information. This
+// only confirms the party that provided the information has the private key.
+func (p *PublicCreator) VerifySelfSignature() (bool, error) {
+	return p.VerifySignature(p.PublicKeySPKI)
+}
+
+// signingData returns the fields that are signed as a byte array. The contact
+// fields and a status other than active are only included when present so
+// that signatures over public information without them are unchanged.
+func (p *PublicCreator) signingData() ([]byte, error) {
+	var b bytes.Buffer
+	f := []string{
+		p.Domain,
+		p.Name,
+		p.PublicKeySPKI,
+		p.ContractURL,
+		p.Created.UTC().Format(time.RFC3339Nano)}
+	if p.Email != "" ||
+		p.DpoURL != "" ||
+		p.Jurisdiction != "" ||
+		p.isActive() == false {
+		f = append(f, p.Email, p.DpoURL, p.Jurisdiction)
+	}
+	if p.isActive() == false {
+		f = append(f, p.Status)
+	}
+	for _, s := range f {
+		err := writeString(&b, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
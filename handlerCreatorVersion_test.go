@@ -0,0 +1,104 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// TestCreatorVersionHandler sets the preferred version of a creator and checks
+// that new OWIDs use it until the default is restored.
+func TestCreatorVersionHandler(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range owidVersions {
+		data := url.Values{}
+		data.Set("preferredVersion", strconv.Itoa(int(v)))
+		sendPost(
+			t,
+			HandlerCreatorVersion(s),
+			testDomain,
+			"/owid/api/v3/preferred-version",
+			data)
+		c, err := s.GetCreator(testDomain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.PreferredVersion() != v {
+			t.Fatalf("expected preferred version '%d', found '%d'",
+				v,
+				c.PreferredVersion())
+		}
+		o, err := c.CreateOWIDandSign([]byte(testPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.Version != v {
+			t.Fatalf("expected OWID version '%d', found '%d'", v, o.Version)
+		}
+		b, err := o.AsByteArray()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := FromByteArray(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := c.Verify(n)
+		if err != nil || ok == false {
+			t.Fatalf("version '%d' OWID not verified", v)
+		}
+	}
+	data := url.Values{}
+	data.Set("preferredVersion", "0")
+	sendPost(
+		t,
+		HandlerCreatorVersion(s),
+		testDomain,
+		"/owid/api/v3/preferred-version",
+		data)
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWID([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PreferredVersion() != 0 || o.Version != owidVersion3 {
+		t.Fatalf("expected default version, found '%d'", o.Version)
+	}
+	for _, v := range []string{"6", "256", "x"} {
+		rr := post(
+			t,
+			HandlerCreatorVersion(s),
+			testDomain,
+			"/owid/api/v3/preferred-version",
+			url.Values{"preferredVersion": {v}})
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("version '%s' expected status '%d', found '%d'",
+				v,
+				http.StatusBadRequest,
+				rr.Code)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HandlerDecode returns the fields of the base 64 OWID in the owid parameter
+// without verifying it, for use by debugging tools. The payload and any other
+// OWIDs signed with it are not needed.
+func HandlerDecode(s *Services) http.HandlerFunc {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		limitRequestBody(s, w, r, 1)
+		err := r.ParseForm()
+		if err != nil {
+			returnAPIError(
+				s,
+				w,
+				err,
+				payloadErrorStatus(err, http.StatusBadRequest))
+			return
+		}
+		if r.FormValue("owid") == "" {
+			returnAPIError(
+				s,
+				w,
+				fmt.Errorf("owid parameter must be provided"),
+				http.StatusBadRequest)
+			return
+		}
+		o, err := FromBase64(r.FormValue("owid"))
+		if err == nil {
+			err = checkPayload(o.Payload, s.Config().MaxPayloadLength)
+		}
+		if err != nil {
+			returnAPIError(
+				s,
+				w,
+				err,
+				payloadErrorStatus(err, http.StatusBadRequest))
+			return
+		}
+		j, err := json.Marshal(newDecodeResponse(o, s.now()))
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		sendResponse(s, w, r, "application/json; charset=utf-8", j)
+	})
+}
+
+// newDecodeResponse returns the fields of the OWID with the age in minutes at
+// the time provided.
+func newDecodeResponse(o *OWID, now time.Time) *DecodeResponse {
+	return &DecodeResponse{
+		Version:       int(o.Version),
+		Domain:        o.Domain,
+		Date:          o.Date,
+		Age:           int(now.Sub(o.Date).Minutes()),
+		PayloadLength: len(o.Payload),
+		Signature:     o.Signature}
+}
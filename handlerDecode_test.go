@@ -0,0 +1,73 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestHandlerDecode decodes an OWID and checks the fields returned, and that
+// invalid OWIDs are refused.
+func TestHandlerDecode(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := NewManualClock(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	s.SetClock(k)
+	o, err := s.Sign(testDomain, []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := o.AsBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Advance(90 * time.Minute)
+	rr := send(t, HandlerDecode(s), "other.com", "/owid/api/v3/decode",
+		url.Values{"owid": {v}})
+	if rr == nil {
+		t.FailNow()
+	}
+	var d DecodeResponse
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != int(o.Version) ||
+		d.Domain != testDomain ||
+		d.Date.Equal(o.Date) == false ||
+		d.Age != 90 ||
+		d.PayloadLength != len(testPayload) ||
+		bytes.Equal(d.Signature, o.Signature) == false {
+		t.Fatalf("unexpected response '%v'", d)
+	}
+	for _, q := range []string{"", "owid=invalid", "owid=CQ%3D%3D"} {
+		r := httptest.NewRequest("GET", "/owid/api/v3/decode?"+q, nil)
+		w := httptest.NewRecorder()
+		HandlerDecode(s)(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("'%s' expected 400 but got '%d'", q, w.Code)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+// The maximum number of bytes in a bulk import request body.
+const maxImportLength = 1 << 24
+
+// HandlerImport registers the creators in the request body and returns the
+// outcome for each as JSON. The body is CSV if the content type is text/csv,
+// otherwise a JSON array. Requires an access key with the admin scope.
+func HandlerImport(s *Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportLength)
+		if s.getAccessAllowed(w, r, ScopeAdmin) == false {
+			return
+		}
+		var cs []*BulkCreator
+		var err error
+		t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if t == "text/csv" {
+			cs, err = ParseBulkCSV(r.Body)
+		} else {
+			cs, err = ParseBulkJSON(r.Body)
+		}
+		if err != nil {
+			returnAPIError(
+				s,
+				w,
+				err,
+				payloadErrorStatus(err, http.StatusBadRequest))
+			return
+		}
+		j, err := json.Marshal(s.ImportCreators(cs, r.FormValue("accesskey")))
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		sendResponse(s, w, r, "application/json; charset=utf-8", j)
+	}
+}
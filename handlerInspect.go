@@ -0,0 +1,87 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// HandlerInspect returns an HTML page that decodes the base 64 OWID provided
+// in the owid parameter and displays the fields along with the result of
+// verifying the OWID, and the optional parent, using the creator's public key.
+func HandlerInspect(s *Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var d Inspect
+		d.Services = s
+		err := r.ParseForm()
+		if err != nil {
+			returnServerError(s, w, err)
+			return
+		}
+		d.Value = strings.TrimSpace(r.FormValue("owid"))
+		d.Parent = strings.TrimSpace(r.FormValue("parent"))
+		if d.Value != "" {
+			inspectOWID(r.Context(), s, &d)
+		}
+		sendHTMLTemplate(s, w, r, inspectTemplate, &d)
+	}
+}
+
+func inspectOWID(ctx context.Context, s *Services, d *Inspect) {
+	o, err := FromBase64(d.Value)
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	var p *OWID
+	if d.Parent != "" {
+		p, err = FromBase64(d.Parent)
+		if err != nil {
+			d.Error = err.Error()
+			return
+		}
+	}
+	d.OWID = o
+	d.Valid, err = inspectVerify(ctx, s, o, p)
+	if err != nil {
+		d.VerifyError = err.Error()
+	}
+}
+
+// inspectVerify verifies the OWID using the creator from the store if the
+// domain is registered with this service, otherwise fetching the public key
+// from the OWID's domain.
+func inspectVerify(
+	ctx context.Context,
+	s *Services,
+	o *OWID,
+	p *OWID) (bool, error) {
+	c, err := s.store.GetCreator(o.Domain)
+	if err != nil {
+		return false, err
+	}
+	if c != nil {
+		return c.Verify(o, p)
+	}
+	k, err := o.fetchPublicKey(ctx, s.Config().Scheme)
+	if err != nil {
+		return false, err
+	}
+	return o.VerifyWithPublicKey(k, p)
+}
@@ -0,0 +1,92 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HandlerJWKS returns the public key associated with the creator as a JSON Web
+// Key Set so that standard JOSE libraries can verify signatures.
+func HandlerJWKS(s *Services) http.HandlerFunc {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		c, err := s.store.GetCreator(r.Host)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: r.Host},
+				http.StatusNotFound)
+			return
+		}
+		x, err := c.NewCryptoVerifyOnly()
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		k, err := x.jwk()
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		j, err := json.Marshal(&JWKSet{Keys: []*JWK{k}})
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		if isNotModified(w, r, newETag(j), c.created) {
+			return
+		}
+		sendResponse(s, w, r, "application/json; charset=utf-8", j)
+	})
+}
+
+// jwk returns the public key as a JSON Web Key with the RFC 7638 thumbprint as
+// the key id.
+func (c *Crypto) jwk() (*JWK, error) {
+	if c.publicKey == nil {
+		return nil, errors.New("instance of Crypto has no public key")
+	}
+	n := (c.publicKey.Curve.Params().BitSize + 7) / 8
+	e := base64.RawURLEncoding
+	k := JWK{
+		Kty: "EC",
+		Crv: c.publicKey.Curve.Params().Name,
+		X:   e.EncodeToString(c.publicKey.X.FillBytes(make([]byte, n))),
+		Y:   e.EncodeToString(c.publicKey.Y.FillBytes(make([]byte, n))),
+		Use: "sig",
+		Alg: string(c.Algorithm())}
+	t := fmt.Sprintf(
+		`{"crv":"%s","kty":"%s","x":"%s","y":"%s"}`,
+		k.Crv,
+		k.Kty,
+		k.X,
+		k.Y)
+	h := sha256.Sum256([]byte(t))
+	k.Kid = base64.RawURLEncoding.EncodeToString(h[:])
+	return &k, nil
+}
@@ -0,0 +1,39 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:generate go run openapiGenerate.go
+
+// openAPI is the OpenAPI 3 specification for the HTTP end points. The request
+// and response structures in openapiTypes.go are generated from it.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// HandlerOpenAPI returns the OpenAPI 3 specification for the OWID end points
+// so that clients in other languages can be generated automatically.
+func HandlerOpenAPI(s *Services) http.HandlerFunc {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		sendResponse(s, w, r, "application/json; charset=utf-8", openAPI)
+	})
+}
@@ -18,26 +18,107 @@ package owid
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-// HandlerNodesJSON is a handler that returns a list of all the alive nodes
-// which is then used to serialize to JSON.
+// Header containing the number of creators that matched the prefix before
+// the offset and limit were applied.
+const owidsTotalHeader = "X-Total-Count"
+
+// owidsQuery selects the creators returned by HandlerOwidsJSON.
+type owidsQuery struct {
+	offset     int    // Number of matching domains to skip
+	limit      int    // Maximum number of creators, or zero for all
+	prefix     string // Domains must start with the prefix
+	publicOnly bool   // True to return only public information
+}
+
+// HandlerOwidsJSON is a handler that returns the creators keyed on domain in
+// domain order. The offset and limit parameters page through the creators,
+// the prefix parameter returns only domains that start with it, and
+// publicOnly=true returns the public information without the private keys.
 func HandlerOwidsJSON(s *Services) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		j, err := getJSON(s)
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		q, err := newOwidsQuery(r)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusBadRequest)
+			return
+		}
+		j, t, err := getJSON(s, q)
 		if err != nil {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
 			return
 		}
-		sendResponse(s, w, "application/json", j)
+		w.Header().Set(owidsTotalHeader, strconv.Itoa(t))
+		sendResponse(s, w, r, "application/json", j)
+	})
+}
+
+// newOwidsQuery returns the query from the request parameters.
+func newOwidsQuery(r *http.Request) (*owidsQuery, error) {
+	var q owidsQuery
+	var err error
+	if v := r.FormValue("offset"); v != "" {
+		q.offset, err = strconv.Atoi(v)
+		if err != nil || q.offset < 0 {
+			return nil, fmt.Errorf("offset '%s' must be a positive number", v)
+		}
+	}
+	if v := r.FormValue("limit"); v != "" {
+		q.limit, err = strconv.Atoi(v)
+		if err != nil || q.limit < 0 {
+			return nil, fmt.Errorf("limit '%s' must be a positive number", v)
+		}
 	}
+	if v := r.FormValue("publicOnly"); v != "" {
+		q.publicOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("publicOnly '%s' must be true or false", v)
+		}
+	}
+	q.prefix = r.FormValue("prefix")
+	return &q, nil
 }
 
-func getJSON(s *Services) ([]byte, error) {
-	j, err := json.Marshal(s.store.GetCreators())
+// getJSON returns the creators selected by the query as JSON together with
+// the number of creators that matched the prefix.
+func getJSON(s *Services, q *owidsQuery) ([]byte, int, error) {
+	cs := s.store.GetCreators()
+	var ds []string
+	for d := range cs {
+		if strings.HasPrefix(d, q.prefix) {
+			ds = append(ds, d)
+		}
+	}
+	sort.Strings(ds)
+	t := len(ds)
+	if q.offset < len(ds) {
+		ds = ds[q.offset:]
+	} else {
+		ds = nil
+	}
+	if q.limit > 0 && q.limit < len(ds) {
+		ds = ds[:q.limit]
+	}
+	m := make(map[string]interface{}, len(ds))
+	for _, d := range ds {
+		if q.publicOnly {
+			p, err := publicCreator(cs[d])
+			if err != nil {
+				return nil, 0, err
+			}
+			m[d] = p
+		} else {
+			m[d] = cs[d]
+		}
+	}
+	j, err := json.Marshal(m)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return j, nil
+	return j, t, nil
 }
@@ -0,0 +1,101 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+// TestOwidsHandlerPaging checks the creators are returned in domain order and
+// filtered by the offset, limit and prefix parameters.
+func TestOwidsHandlerPaging(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := s.store.(*testStore)
+	for _, d := range []string{"a.com", "b.com", "b.org", "c.com"} {
+		err = ts.addCreator(d, testOrgName, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rr := send(
+		t,
+		HandlerOwidsJSON(s),
+		testDomain,
+		"/owid/api/v1/owids",
+		url.Values{"offset": {"1"}, "limit": {"2"}})
+	m := make(map[string]*Creator)
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["a.com"] == nil || m["b.com"] == nil {
+		t.Fatalf("unexpected creators '%v'", m)
+	}
+	if rr.Header().Get(owidsTotalHeader) != "5" {
+		t.Fatalf("expected total of 5 but got '%s'",
+			rr.Header().Get(owidsTotalHeader))
+	}
+	rr = send(
+		t,
+		HandlerOwidsJSON(s),
+		testDomain,
+		"/owid/api/v1/owids",
+		url.Values{"prefix": {"b."}})
+	m = make(map[string]*Creator)
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || rr.Header().Get(owidsTotalHeader) != "2" {
+		t.Fatalf("expected two creators with prefix but got '%v'", m)
+	}
+}
+
+// TestOwidsHandlerPublicOnly checks private keys are not returned when only
+// public information is requested.
+func TestOwidsHandlerPublicOnly(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := send(
+		t,
+		HandlerOwidsJSON(s),
+		testDomain,
+		"/owid/api/v1/owids",
+		url.Values{"publicOnly": {"true"}})
+	var m map[string]map[string]interface{}
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := m[testDomain]
+	if p == nil {
+		t.Fatal("creator missing")
+	}
+	if _, ok := p["privateKey"]; ok {
+		t.Fatal("private key should not be returned")
+	}
+	if p["publicKeySPKI"] == nil {
+		t.Fatal("public key expected")
+	}
+}
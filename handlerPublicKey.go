@@ -17,43 +17,141 @@
 package owid
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
-// HandlerPublicKey returns the public key associated with the creator.
+// Formats of the public key returned by HandlerPublicKey.
+const (
+	publicKeyPKCS = "pkcs" // PEM public key as stored by the creator
+	publicKeySPKI = "spki" // PEM subject public key info, for example for SubtleCrypto
+	publicKeyJWK  = "jwk"  // JSON Web Key
+)
+
+// HandlerPublicKey returns the public key associated with the creator in the
+// format parameter, pkcs, spki or jwk. The optional created parameter selects
+// the key created at that time, either the current key or a retired key from
+// the key archive, so that OWIDs signed with earlier keys can be verified.
 func HandlerPublicKey(s *Services) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
 		c, err := s.store.GetCreator(r.Host)
 		if err != nil {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
 			return
 		}
 		if c == nil {
-			returnAPIError(s, w, err, http.StatusInternalServerError)
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: r.Host},
+				http.StatusNotFound)
 			return
 		}
 		err = r.ParseForm()
 		if err != nil {
-			returnAPIError(s, w, err, http.StatusInternalServerError)
+			returnAPIError(s, w, err, http.StatusBadRequest)
+			return
+		}
+		f := r.Form.Get("format")
+		if f != publicKeyPKCS && f != publicKeySPKI && f != publicKeyJWK {
+			returnAPIError(
+				s,
+				w,
+				fmt.Errorf("format parameter 'spki', 'pkcs' or 'jwk' must be "+
+					"provided"),
+				http.StatusBadRequest)
+			return
+		}
+		k, t, err := publicKeyGeneration(s, c, r.Form.Get("created"))
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusBadRequest)
 			return
 		}
-		var p string
-		switch r.Form.Get("format") {
-		case "pkcs":
-			p = c.publicKey
-		case "spki":
-			p, err = c.SubjectPublicKeyInfo()
-			break
-		default:
-			err = fmt.Errorf(
-				"format parameter 'spki' or 'pkcs' must be provided")
+		if k == "" {
+			returnAPIError(
+				s,
+				w,
+				fmt.Errorf(
+					"no key for '%s' created at '%s'",
+					c.domain,
+					r.Form.Get("created")),
+				http.StatusNotFound)
+			return
 		}
+		b, m, err := publicKeyFormat(k, f)
 		if err != nil {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Cache-Control", "max-age=60")
-		sendResponse(s, w, "text/plain; charset=utf-8", []byte(p))
+		if isNotModified(w, r, newETag(b), t) {
+			return
+		}
+		sendResponse(s, w, r, m, b)
+	})
+}
+
+// publicKeyGeneration returns the PEM public key of the creator created at the
+// RFC 3339 time, or the current key if the time is empty, along with the time
+// the key was created. An empty key is returned if there is no key created at
+// the time.
+func publicKeyGeneration(
+	s *Services,
+	c *Creator,
+	created string) (string, time.Time, error) {
+	if created == "" {
+		return c.publicKey, c.created, nil
+	}
+	t, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf(
+			"created '%s' must be an RFC 3339 date and time",
+			created)
+	}
+	if sameSecond(t, c.created) {
+		return c.publicKey, c.created, nil
+	}
+	if s.archive == nil {
+		return "", time.Time{}, nil
+	}
+	a, err := s.archive.GetArchivedKeys(c.domain)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	for _, k := range a {
+		if sameSecond(t, k.Created) {
+			return k.PublicKeySPKI, k.Created, nil
+		}
+	}
+	return "", time.Time{}, nil
+}
+
+// sameSecond returns true if the times are within the same second, allowing
+// for created times provided without fractions of a second.
+func sameSecond(a time.Time, b time.Time) bool {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+// publicKeyFormat returns the PEM public key in the format along with the
+// content type.
+func publicKeyFormat(k string, format string) ([]byte, string, error) {
+	if format == publicKeyPKCS {
+		return []byte(k), "text/plain; charset=utf-8", nil
+	}
+	x, err := NewCryptoVerifyOnly(k)
+	if err != nil {
+		return nil, "", err
+	}
+	if format == publicKeySPKI {
+		p, err := x.getSubjectPublicKeyInfo()
+		return []byte(p), "text/plain; charset=utf-8", err
+	}
+	j, err := x.jwk()
+	if err != nil {
+		return nil, "", err
 	}
+	b, err := json.Marshal(j)
+	return b, "application/jwk+json", err
 }
@@ -0,0 +1,124 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// getPublicKey calls the public key handler for the test domain with the query
+// and returns the response.
+func getPublicKey(s *Services, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/owid/api/v3/public-key?"+query, nil)
+	r.Host = testDomain
+	w := httptest.NewRecorder()
+	HandlerPublicKey(s)(w, r)
+	return w
+}
+
+// TestHandlerPublicKeyFormats checks each format returns the creator's key.
+func TestHandlerPublicKeyFormats(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.store.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := getPublicKey(s, "format=pkcs")
+	if w.Code != http.StatusOK || w.Body.String() != c.publicKey {
+		t.Fatalf("unexpected pkcs response '%d'", w.Code)
+	}
+	k, err := c.SubjectPublicKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = getPublicKey(s, "format=spki")
+	if w.Code != http.StatusOK || w.Body.String() != k {
+		t.Fatalf("unexpected spki response '%d'", w.Code)
+	}
+	w = getPublicKey(s, "format=jwk")
+	var j JWK
+	err = json.Unmarshal(w.Body.Bytes(), &j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Kty != "EC" || j.Crv != "P-256" ||
+		w.Header().Get("Content-Type") != "application/jwk+json" {
+		t.Fatalf("unexpected jwk response '%s'", w.Body.String())
+	}
+	for _, q := range []string{"", "format=der", "format=pkcs&created=now"} {
+		if w = getPublicKey(s, q); w.Code != http.StatusBadRequest {
+			t.Errorf("'%s' expected 400 but got '%d'", q, w.Code)
+		}
+	}
+}
+
+// TestHandlerPublicKeyCreated checks the created parameter selects the current
+// key or an archived key.
+func TestHandlerPublicKeyCreated(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.store.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewKeyArchiveFile(filepath.Join(t.TempDir(), "archive.jsonl"))
+	oc := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	k, err := o.SubjectPublicKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.ArchiveKey(&ArchivedKey{
+		Domain:        testDomain,
+		PublicKeySPKI: k,
+		Created:       oc,
+		Retired:       oc.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := "format=spki&created="
+	w := getPublicKey(s, q+oc.Format(time.RFC3339))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without an archive but got '%d'", w.Code)
+	}
+	s.SetKeyArchive(a)
+	w = getPublicKey(s, q+oc.Format(time.RFC3339))
+	if w.Code != http.StatusOK || w.Body.String() != k {
+		t.Fatalf("archived key not returned '%d'", w.Code)
+	}
+	w = getPublicKey(s, "format=pkcs&created="+c.created.Format(time.RFC3339))
+	if w.Code != http.StatusOK || w.Body.String() != c.publicKey {
+		t.Fatalf("current key not returned '%d'", w.Code)
+	}
+	w = getPublicKey(s, q+"2020-01-01T00:00:00Z")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 but got '%d'", w.Code)
+	}
+}
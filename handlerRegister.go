@@ -17,26 +17,52 @@
 package owid
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 // HandlerRegister - Handler for the registering of a domain.
 func HandlerRegister(s *Services) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
+		// Check the caller is allowed to register domains.
+		if s.getAccessAllowed(w, r, ScopeRegister) == false {
+			return
+		}
+
+		// Get the canonical domain for the host refusing any that can't be
+		// registered.
+		h, err := registrationDomain(r.Host, s.Config().AllowedRegisterHosts)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusBadRequest)
+			return
+		}
+
 		var d Register
 		d.Services = s
-		d.Domain = r.Host
+		d.Domain = h
 		d.Name = ""
+		d.AccessKey = r.FormValue("accesskey")
 
 		// Check that the domain has not already been registered.
-		n, err := s.store.GetCreator(r.Host)
+		n, err := s.store.GetCreator(h)
 		if err != nil {
 			returnServerError(s, w, err)
 			return
 		}
 		if n != nil {
+			// A pending creator is activated once the domain challenge is
+			// met.
+			if n.status == CreatorPending {
+				verifyChallenge(s, &d, n)
+				sendHTMLTemplate(s, w, r, s.Config().getRegisterTemplate(), &d)
+			}
 			return
 		}
 
@@ -46,25 +72,21 @@ func HandlerRegister(s *Services) http.HandlerFunc {
 			returnServerError(s, w, err)
 			return
 		}
-		d.DisplayErrors = len(r.Form) > 0
+		// The access key is always present so only display errors if other
+		// values have been provided.
+		d.DisplayErrors = len(r.Form) > 1
 
-		// Get the OWID creator legal name.
+		// Get the OWID creator legal name, the contract URL used for the
+		// creation of data, and the optional contact details.
 		d.Name = r.FormValue("name")
-		if len(d.Name) <= 5 {
-			d.NameError = "Name must be longer than 5 characters"
-		} else if len(d.Name) > 20 {
-			d.NameError = "Name can not be longer than 20 characters"
-		}
-
-		// Get the OWID creater contract URL use for the creation of data.
 		d.ContractURL = r.FormValue("contractURL")
-		_, err = url.Parse(d.ContractURL)
-		if err != nil {
-			d.ContractURLError = err.Error()
-		}
+		d.Contact = Contact{
+			Email:        strings.TrimSpace(r.FormValue("email")),
+			DpoURL:       strings.TrimSpace(r.FormValue("dpoURL")),
+			Jurisdiction: strings.TrimSpace(r.FormValue("jurisdiction"))}
 
 		// If the form data is valid then store the new node.
-		if d.NameError == "" {
+		if d.validate(s.Config()) == nil {
 			err := storeCreator(s, &d)
 			if err != nil {
 				returnServerError(s, w, err)
@@ -72,14 +94,222 @@ func HandlerRegister(s *Services) http.HandlerFunc {
 		}
 
 		// Return the HTML page.
-		sendHTMLTemplate(s, w, registerTemplate, &d)
+		sendHTMLTemplate(s, w, r, s.Config().getRegisterTemplate(), &d)
+	}
+}
+
+// HandlerRegisterAPI registers a creator for the requesting host from the
+// name, contractURL and optional contact parameters in the form body. Only POST
+// is accepted and an access key with the register scope is required in the
+// access key header or the form body. Values that are not valid are refused
+// with 400 and domains that are already registered with 409. Returns the
+// public information for the new creator.
+func HandlerRegisterAPI(s *Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		k, ok := s.getAccessAllowedPost(w, r, ScopeRegister)
+		if ok == false {
+			return
+		}
+		h, err := registrationDomain(r.Host, s.Config().AllowedRegisterHosts)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusBadRequest)
+			return
+		}
+		d := Register{
+			Services:    s,
+			Domain:      h,
+			Name:        r.PostFormValue("name"),
+			ContractURL: r.PostFormValue("contractURL"),
+			Contact: Contact{
+				Email:        strings.TrimSpace(r.PostFormValue("email")),
+				DpoURL:       strings.TrimSpace(r.PostFormValue("dpoURL")),
+				Jurisdiction: strings.TrimSpace(r.PostFormValue("jurisdiction"))},
+			AccessKey: k}
+		err = d.validate(s.Config())
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusBadRequest)
+			return
+		}
+		err = storeCreator(s, &d)
+		var a *AlreadyRegisteredError
+		if errors.As(err, &a) {
+			returnAPIError(s, w, err, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		c, err := s.store.GetCreator(h)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		p, err := publicCreator(c)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		u, err := json.Marshal(p)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		sendResponse(s, w, r, "application/json; charset=utf-8", u)
 	}
 }
 
+// validate checks the values to register, setting the error fields for any
+// that are invalid. Used by every registration path so that they all accept
+// the same creators. Returns the first error found, or nil if all the values
+// are valid.
+func (d *Register) validate(c *Configuration) error {
+	d.NameError = creatorNameError(d.Name)
+
+	// Check the contract URL is valid and optionally that it responds.
+	d.ContractURLError = ""
+	_, err := url.Parse(d.ContractURL)
+	if err != nil {
+		d.ContractURLError = err.Error()
+	} else if c.CheckContractURL {
+		err = checkContractURL(c, d.Domain, d.ContractURL)
+		if err != nil {
+			d.ContractURLError = err.Error()
+		}
+	}
+
+	// Check the optional contact details for the creator.
+	d.ContactError = ""
+	err = d.Contact.validate()
+	if err != nil {
+		d.ContactError = err.Error()
+	}
+
+	// Check the domain is a known seller if sources are configured.
+	d.Error = ""
+	err = checkSellers(c, d.Domain)
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	for _, e := range []string{
+		d.NameError,
+		d.ContractURLError,
+		d.ContactError,
+		d.Error} {
+		if e != "" {
+			return errors.New(e)
+		}
+	}
+	return nil
+}
+
+// creatorNameError returns the reason the creator's name is invalid, or an
+// empty string if it is valid.
+func creatorNameError(name string) string {
+	if len(name) <= 5 {
+		return "Name must be longer than 5 characters"
+	} else if len(name) > 20 {
+		return "Name can not be longer than 20 characters"
+	}
+	return ""
+}
+
+// checkContractURL returns an error if the contract URL does not use HTTPS,
+// is not for the same registrable domain as the domain being registered, or
+// does not respond with 200 OK to a HEAD request within the configured
+// timeout.
+func checkContractURL(
+	c *Configuration,
+	domain string,
+	contractURL string) error {
+	u, err := url.Parse(contractURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("contract URL '%s' must use https", contractURL)
+	}
+	if registrableDomain(u.Hostname()) != registrableDomain(domain) {
+		return fmt.Errorf(
+			"contract URL '%s' not for domain '%s'",
+			contractURL,
+			domain)
+	}
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(c.ContractURLTimeout)*time.Second)
+	defer cancel()
+	q, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	r, err := client.Do(q)
+	if err != nil {
+		return fmt.Errorf(
+			"contract URL '%s' not reachable: %s",
+			contractURL,
+			err.Error())
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf(
+			"contract URL '%s' returned status '%d'",
+			contractURL,
+			r.StatusCode)
+	}
+	return nil
+}
+
+// verifyChallenge checks whether the pending creator's domain now publishes the
+// challenge token and activates the creator if it does. The template data is
+// updated with the outcome.
+func verifyChallenge(s *Services, d *Register, n *Creator) {
+	d.Name = n.name
+	d.ContractURL = n.contractURL
+	d.Contact = n.contact
+	d.ReadOnly = true
+	d.DisplayErrors = true
+	d.Pending = true
+	d.ChallengeToken = challengeToken(n)
+	d.ChallengeLocation = challengeLocation(s.Config(), n.domain)
+	err := checkChallenge(s.Config(), n)
+	if err != nil {
+		d.ChallengeError = err.Error()
+		return
+	}
+	_, err = s.SetCreatorStatus(n.domain, CreatorActive, d.AccessKey)
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	d.Pending = false
+}
+
+// storeCreator creates a new creator for the domain in the template data and
+// adds it to the store. An AlreadyRegisteredError is returned if another
+// request registered the domain first.
 func storeCreator(s *Services, d *Register) error {
+	defer s.locks.lock(d.Domain)()
+	e, err := s.store.GetCreator(d.Domain)
+	if err != nil {
+		d.Error = err.Error()
+		return err
+	}
+	if e != nil {
+		err = &AlreadyRegisteredError{Domain: d.Domain}
+		d.Error = err.Error()
+		return err
+	}
 
 	// Create the new node ready to have it's secret added and stored.
-	cry, err := NewCrypto()
+	k, err := CurveByName(s.Config().Curve)
+	if err != nil {
+		d.Error = err.Error()
+		return err
+	}
+	cry, err := NewCryptoWithCurve(k)
 	if err != nil {
 		d.Error = err.Error()
 		return err
@@ -94,12 +324,19 @@ func storeCreator(s *Services, d *Register) error {
 		d.Error = err.Error()
 		return err
 	}
+	t := CreatorActive
+	if s.Config().DomainChallenge != "" {
+		t = CreatorPending
+	}
 	c := newCreator(
 		d.Domain,
 		privateKey,
 		publicKey,
 		d.Name,
-		d.ContractURL)
+		d.ContractURL,
+		s.now().UTC(),
+		d.Contact,
+		t)
 	if err != nil {
 		d.Error = err.Error()
 		return err
@@ -113,7 +350,14 @@ func storeCreator(s *Services, d *Register) error {
 		return err
 	} else {
 		d.ReadOnly = true
+		if c.status == CreatorPending {
+			d.Pending = true
+			d.ChallengeToken = challengeToken(c)
+			d.ChallengeLocation = challengeLocation(s.Config(), c.domain)
+		}
 	}
+	s.audit(AuditRegister, c.domain, d.AccessKey, nil, c)
+	s.notify(AuditRegister, c)
 
 	return nil
 }
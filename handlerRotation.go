@@ -0,0 +1,71 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HandlerRotationPreview reports what retiring the creator's current key
+// would do without changing anything. The domain is taken from the domain
+// parameter, or the request host if not provided, and the maximum age of
+// OWIDs that must still verify from the maxAge parameter in minutes. Requires
+// an access key with the admin scope.
+func HandlerRotationPreview(s *Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.getAccessAllowed(w, r, ScopeAdmin) == false {
+			return
+		}
+		d := r.FormValue("domain")
+		if d == "" {
+			d = r.Host
+		}
+		m, err := strconv.Atoi(r.FormValue("maxAge"))
+		if err != nil || m < 0 {
+			returnAPIError(
+				s,
+				w,
+				fmt.Errorf("maxAge must be a number of minutes"),
+				http.StatusBadRequest)
+			return
+		}
+		p, err := s.PreviewRotation(d, time.Duration(m)*time.Minute)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		if p == nil {
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: d},
+				http.StatusNotFound)
+			return
+		}
+		j, err := json.Marshal(p)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		sendResponse(s, w, r, "application/json; charset=utf-8", j)
+	}
+}
@@ -0,0 +1,78 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// HandlerCreatorStatus changes the status of a creator to suspend or resume
+// signing. The domain is taken from the domain parameter, or the request host
+// if not provided, and the new status from the status parameter. Only POST is
+// accepted and an access key with the admin scope is required in the access
+// key header or the form body. Returns the updated public information.
+func HandlerCreatorStatus(s *Services) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		k, ok := s.getAccessAllowedPost(w, r, ScopeAdmin)
+		if ok == false {
+			return
+		}
+		d := r.PostFormValue("domain")
+		if d == "" {
+			d = r.Host
+		}
+		p := r.PostFormValue("status")
+		n, err := parseCreatorStatus(p)
+		if err != nil || n == CreatorPending || p == "" {
+			returnAPIError(
+				s,
+				w,
+				fmt.Errorf("status must be '%s' or '%s'",
+					CreatorActive,
+					CreatorSuspended),
+				http.StatusBadRequest)
+			return
+		}
+		c, err := s.SetCreatorStatus(d, n, k)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: d},
+				http.StatusNotFound)
+			return
+		}
+		pc, err := publicCreator(c)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		u, err := json.Marshal(pc)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		sendResponse(s, w, r, "application/json; charset=utf-8", u)
+	}
+}
@@ -18,26 +18,52 @@ package owid
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
-type verify struct {
-	Valid bool `json:"valid"`
-}
-
 // HandlerVerify verifies the signature in the incoming OWID. If the method is
 // POST and the content is binary data then the OWID is created using the
 // FromByteArray method. Otherwise the OWID is constructed form the base 64
 // encoded string in the owid parameter.
-// Returns true if the OWID is valid, otherwise false.
+// If the remote parameter is true then the services' verifier is used to
+// verify OWIDs from any domain, applying its policy and preloaded public
+// information. Otherwise the creator for the requesting host is used.
+// Returns true if the OWID is valid, otherwise false. Requests or payloads
+// larger than the configured maximum payload length are refused with 413.
 func HandlerVerify(s *Services) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var v verify
-		p, o, err := verifyGetOWIDs(r)
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		var v VerifyResponse
+		limitRequestBody(s, w, r, 2)
+		p, o, err := verifyGetOWIDs(r, s.Config().MaxPayloadLength)
 		if err != nil {
-			returnAPIError(s, w, err, http.StatusBadRequest)
+			returnAPIError(
+				s,
+				w,
+				err,
+				payloadErrorStatus(err, http.StatusBadRequest))
+			return
+		}
+		remote := false
+		if x := r.FormValue("remote"); x != "" {
+			remote, err = strconv.ParseBool(x)
+			if err != nil {
+				returnAPIError(
+					s,
+					w,
+					fmt.Errorf("remote '%s' must be true or false", x),
+					http.StatusBadRequest)
+				return
+			}
+		}
+		if remote {
+			verifyRemote(s, w, r, o, p)
 			return
 		}
 		c, err := getCreatorFromRequest(s, r)
@@ -45,6 +71,14 @@ func HandlerVerify(s *Services) http.HandlerFunc {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
 			return
 		}
+		if c == nil {
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: r.Host},
+				http.StatusNotFound)
+			return
+		}
 		v.Valid, err = c.Verify(o, p)
 		if err != nil && strings.Contains(err.Error(), "verification error") {
 			returnAPIError(s, w, err, http.StatusInternalServerError)
@@ -56,11 +90,137 @@ func HandlerVerify(s *Services) http.HandlerFunc {
 			return
 		}
 		w.Header().Set("Cache-Control", "no-cache")
-		sendResponse(s, w, "application/json; charset=utf-8", j)
+		sendResponse(s, w, r, "application/json; charset=utf-8", j)
+	})
+}
+
+// remoteTimeout is the longest remote verification waits for a response
+// from a domain.
+const remoteTimeout = 10 * time.Second
+
+// verifyRemote responds with the result of verifying the OWID using the
+// services' verifier. OWIDs refused by the verifier's policy are not valid.
+// Domains the services must not fetch from, or that connect to internal
+// addresses, are refused with 403 and failures to fetch the public
+// information are returned with 502.
+func verifyRemote(
+	s *Services,
+	w http.ResponseWriter,
+	r *http.Request,
+	o *OWID,
+	p *OWID) {
+	if s.verifier == nil {
+		returnAPIError(
+			s,
+			w,
+			fmt.Errorf("remote verification not enabled"),
+			http.StatusNotImplemented)
+		return
 	}
+	var a *remoteRefusedError
+	err := remoteAllowed(s.verifier.policy, o.Domain)
+	if err != nil {
+		returnAPIError(s, w, err, http.StatusForbidden)
+		return
+	}
+	var v VerifyResponse
+	rp, err := s.verifier.VerifyWithReportContext(r.Context(), o, p)
+	if errors.As(err, &a) {
+		returnAPIError(s, w, err, http.StatusForbidden)
+		return
+	}
+	if err != nil && temporaryError(err) {
+		returnAPIError(s, w, err, http.StatusBadGateway)
+		return
+	}
+	v.Valid = err == nil && rp.Valid
+	j, err := json.Marshal(v)
+	if err != nil {
+		returnAPIError(s, w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	sendResponse(s, w, r, "application/json; charset=utf-8", j)
 }
 
-func verifyGetOWIDs(r *http.Request) (*OWID, *OWID, error) {
+// remoteRefusedError is returned when public information must not be fetched
+// from a domain.
+type remoteRefusedError struct {
+	domain string
+	reason string
+}
+
+func (e *remoteRefusedError) Error() string {
+	return fmt.Sprintf("remote domain '%s' refused: %s", e.domain, e.reason)
+}
+
+// remoteAllowed returns an error if public information must not be fetched
+// from the domain. If the policy trusts domains then only those are allowed.
+// Otherwise IP addresses and localhost are refused. The addresses other
+// domains resolve to are checked by the remote client when connecting.
+func remoteAllowed(policy *VerificationPolicy, domain string) error {
+	if policy != nil && len(policy.TrustedDomains) > 0 {
+		if containsDomain(policy.TrustedDomains, domain) == false {
+			return &remoteRefusedError{domain, "not trusted by policy"}
+		}
+		return nil
+	}
+	h := normalizeDomain(domain)
+	if n, _, err := net.SplitHostPort(h); err == nil {
+		h = n
+	}
+	if net.ParseIP(strings.Trim(h, "[]")) != nil {
+		return &remoteRefusedError{domain, "IP address"}
+	}
+	if h == "localhost" || strings.HasSuffix(h, ".localhost") {
+		return &remoteRefusedError{domain, "localhost"}
+	}
+	return nil
+}
+
+// newRemoteClient returns the HTTP client used for remote verification. The
+// client does not follow redirects and times out after remoteTimeout. Unless
+// private is true connections to loopback, private, link local or unspecified
+// addresses are refused. The address is checked when connecting so that a
+// domain can't resolve to a different address after it has been checked.
+func newRemoteClient(private bool) *http.Client {
+	d := &net.Dialer{Timeout: remoteTimeout}
+	if private == false {
+		d.Control = remoteControl
+	}
+	return &http.Client{
+		Timeout: remoteTimeout,
+		Transport: &http.Transport{
+			DialContext:         d.DialContext,
+			TLSHandshakeTimeout: remoteTimeout,
+			ForceAttemptHTTP2:   true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+}
+
+// remoteControl refuses connections to addresses remote verification must
+// not use.
+func remoteControl(network, address string, c syscall.RawConn) error {
+	h, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(h)
+	if ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() {
+		return &remoteRefusedError{address, "internal address"}
+	}
+	return nil
+}
+
+// verifyGetOWIDs returns the optional parent and the OWID from the request
+// refusing payloads longer than the limit.
+func verifyGetOWIDs(r *http.Request, limit int) (*OWID, *OWID, error) {
 	err := r.ParseForm()
 	if err != nil {
 		return nil, nil, err
@@ -74,10 +234,18 @@ func verifyGetOWIDs(r *http.Request) (*OWID, *OWID, error) {
 		if err != nil {
 			return nil, nil, err
 		}
+		err = checkPayload(p.Payload, limit)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 	o, err := FromBase64(r.FormValue("owid"))
 	if err != nil {
 		return nil, nil, err
 	}
+	err = checkPayload(o.Payload, limit)
+	if err != nil {
+		return nil, nil, err
+	}
 	return p, o, nil
 }
@@ -0,0 +1,248 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// verifyJSKey is a public key embedded in the JavaScript verification module
+// with the period OWIDs could have been signed with it.
+type verifyJSKey struct {
+	SPKI    string     `json:"spki"`    // Base 64 DER subject public key info
+	Curve   string     `json:"curve"`   // Named curve for WebCrypto
+	Created time.Time  `json:"created"` // When the key was created
+	Retired *time.Time `json:"retired"` // When the key was retired, or null if current
+}
+
+// verifyJSTemplate is the JavaScript module served by HandlerVerifyJS. The
+// domain and keys are inserted as JSON.
+var verifyJSTemplate = template.Must(template.New("verify.js").Parse(
+	`// OWID verification for {{.Name}} generated by the OWID service. The module
+// changes when the creator's keys change.
+const domain = {{.Domain}};
+const keys = {{.Keys}};
+const base = Date.UTC(2020, 0, 1);
+const imported = new Map();
+
+function fromBase64(s) {
+  const b = atob(s);
+  const a = new Uint8Array(b.length);
+  for (let i = 0; i < b.length; i++) {
+    a[i] = b.charCodeAt(i);
+  }
+  return a;
+}
+
+// decode returns the fields of the OWID in the bytes and the number of bytes
+// at the start of the OWID that are signed.
+function decode(b) {
+  const v = new DataView(b.buffer, b.byteOffset, b.byteLength);
+  const version = b[0];
+  if (version < 1 || version > 5) {
+    throw new Error("OWID version '" + version + "' not supported");
+  }
+  let i = b.indexOf(0, 1);
+  if (i < 0) {
+    throw new Error("OWID domain not terminated");
+  }
+  const d = new TextDecoder().decode(b.subarray(1, i));
+  i++;
+  let date;
+  if (version === 1) {
+    date = base + v.getUint16(i, false) * 86400000;
+    i += 2;
+  } else {
+    date = base + v.getUint32(i, true) * 60000;
+    i += 4;
+  }
+  i += 4 + v.getUint32(i, true);
+  const signed = i;
+  let l = 64;
+  if (version === 5) {
+    l = b[i];
+    i++;
+  }
+  const signature = b.subarray(i, i + l);
+  if (signature.length !== l) {
+    throw new Error("OWID truncated");
+  }
+  return { version: version, domain: d, date: date, signed: signed,
+    signature: signature };
+}
+
+// keysFor returns the keys that could have signed an OWID dated at the time.
+// OWIDs are dated to the minute so keys are valid from the minute created.
+function keysFor(date) {
+  return keys.filter(function (k) {
+    const c = Math.floor(Date.parse(k.created) / 60000) * 60000;
+    return date >= c && (k.retired === null || date <= Date.parse(k.retired));
+  });
+}
+
+function importKey(k) {
+  if (imported.has(k.spki) === false) {
+    imported.set(k.spki, crypto.subtle.importKey(
+      "spki",
+      fromBase64(k.spki),
+      { name: "ECDSA", namedCurve: k.curve },
+      false,
+      ["verify"]));
+  }
+  return imported.get(k.spki);
+}
+
+// verifyOWID returns true if the base 64 OWID was signed by the creator. Data
+// is optional and is the base 64 string, or array of strings, of any other
+// OWIDs that were signed with the OWID.
+export async function verifyOWID(base64Owid, data) {
+  const b = fromBase64(base64Owid);
+  const o = decode(b);
+  if (o.domain.toLowerCase() !== domain) {
+    return false;
+  }
+  let others = [];
+  if (data !== undefined && data !== null) {
+    others = Array.isArray(data) ? data : [data];
+  }
+  const parts = [b.subarray(0, o.signed)].concat(others.map(fromBase64));
+  const m = new Uint8Array(parts.reduce(function (t, p) {
+    return t + p.length;
+  }, 0));
+  let i = 0;
+  for (const p of parts) {
+    m.set(p, i);
+    i += p.length;
+  }
+  for (const k of keysFor(o.date)) {
+    const v = await crypto.subtle.verify(
+      { name: "ECDSA", hash: "SHA-256" },
+      await importKey(k),
+      o.signature,
+      m);
+    if (v) {
+      return true;
+    }
+  }
+  return false;
+}
+`))
+
+// HandlerVerifyJS returns a JavaScript module for the creator associated with
+// the requesting host that embeds the creator's current and archived public
+// keys and exports verifyOWID(base64Owid, data). Publisher pages can import it
+// to verify OWIDs in the browser with WebCrypto without fetching the keys. The
+// module is generated for each request so it changes when keys are rotated.
+func HandlerVerifyJS(s *Services) http.HandlerFunc {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		c, err := s.store.GetCreator(r.Host)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		if c == nil {
+			returnAPIError(
+				s,
+				w,
+				&NotRegisteredError{Domain: r.Host},
+				http.StatusNotFound)
+			return
+		}
+		b, err := verifyJS(s, c)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		if isNotModified(w, r, newETag(b), c.created) {
+			return
+		}
+		sendResponse(s, w, r, "text/javascript; charset=utf-8", b)
+	})
+}
+
+// verifyJS returns the JavaScript verification module for the creator.
+func verifyJS(s *Services, c *Creator) ([]byte, error) {
+	k, err := newVerifyJSKey(c.publicKey, c.created, nil)
+	if err != nil {
+		return nil, err
+	}
+	ks := []*verifyJSKey{k}
+	if s.archive != nil {
+		a, err := s.archive.GetArchivedKeys(c.domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range a {
+			r := v.Retired
+			k, err = newVerifyJSKey(v.PublicKeySPKI, v.Created, &r)
+			if err != nil {
+				return nil, err
+			}
+			ks = append(ks, k)
+		}
+	}
+	d, err := json.Marshal(normalizeDomain(c.domain))
+	if err != nil {
+		return nil, err
+	}
+	j, err := json.Marshal(ks)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	err = verifyJSTemplate.Execute(&b, struct {
+		Name   string
+		Domain string
+		Keys   string
+	}{c.domain, string(d), string(j)})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// newVerifyJSKey returns the PEM public key in the form used by WebCrypto.
+func newVerifyJSKey(
+	p string,
+	created time.Time,
+	retired *time.Time) (*verifyJSKey, error) {
+	x, err := NewCryptoVerifyOnly(p)
+	if err != nil {
+		return nil, err
+	}
+	s, err := x.getSubjectPublicKeyInfo()
+	if err != nil {
+		return nil, err
+	}
+	k, _ := pem.Decode([]byte(s))
+	if k == nil {
+		return nil, errors.New("public key not valid PEM")
+	}
+	return &verifyJSKey{
+		SPKI:    base64.StdEncoding.EncodeToString(k.Bytes),
+		Curve:   x.publicKey.Curve.Params().Name,
+		Created: created,
+		Retired: retired}, nil
+}
@@ -0,0 +1,115 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHandlerVerifyJS checks the module embeds the creator's domain and key
+// and that unregistered domains are not found.
+func TestHandlerVerifyJS(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := send(t, HandlerVerifyJS(s), testDomain, "/owid/api/v3/verify.js",
+		url.Values{})
+	if rr == nil {
+		t.FailNow()
+	}
+	if strings.HasPrefix(rr.Header().Get("Content-Type"),
+		"text/javascript") == false {
+		t.Errorf("content type '%s'", rr.Header().Get("Content-Type"))
+	}
+	j := decompressAsString(t, rr)
+	for _, e := range []string{
+		`const domain = "` + testDomain + `"`,
+		`"curve":"P-256"`,
+		"export async function verifyOWID"} {
+		if strings.Contains(j, e) == false {
+			t.Errorf("module missing '%s'", e)
+		}
+	}
+	r := httptest.NewRequest("GET", "/owid/api/v3/verify.js", nil)
+	r.Host = "unknown.com"
+	w := httptest.NewRecorder()
+	HandlerVerifyJS(s)(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 but got '%d'", w.Code)
+	}
+}
+
+// TestHandlerVerifyJSNode runs the module with Node to verify a signed OWID
+// and refuse a changed one. Skipped if Node is not installed.
+func TestHandlerVerifyJSNode(t *testing.T) {
+	n, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.store.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := verifyJS(s, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := s.Sign(testDomain, []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := o.AsBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Payload = []byte("changed")
+	x, err := o.AsBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := t.TempDir()
+	err = os.WriteFile(filepath.Join(d, "verify.mjs"), b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := filepath.Join(d, "test.mjs")
+	err = os.WriteFile(m, []byte(`import { verifyOWID } from "./verify.mjs";
+console.log(await verifyOWID("`+v+`"), await verifyOWID("`+x+`"));
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := exec.Command(n, m).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err, r)
+	}
+	if strings.TrimSpace(string(r)) != "true false" {
+		t.Errorf("unexpected output '%s'", r)
+	}
+}
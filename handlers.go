@@ -17,24 +17,110 @@
 package owid
 
 import (
-	"compress/gzip"
-	"fmt"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
 	"html/template"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // AddHandlers to the http default mux for shared web state.
 func AddHandlers(s *Services) {
 	http.HandleFunc("/owid/register", HandlerRegister(s))
-	for i := owidVersion1; i <= owidVersion3; i++ {
-		b := fmt.Sprintf("/owid/api/v%d/", i)
-		http.HandleFunc(b+"public-key", HandlerPublicKey(s))
-		http.HandleFunc(b+"creator", HandlerCreator(s))
-		http.HandleFunc(b+"verify", HandlerVerify(s))
-		if s.config.Debug {
-			http.HandleFunc(b+"owids", HandlerOwidsJSON(s))
+	http.HandleFunc("/owid/inspect", HandlerInspect(s))
+	http.HandleFunc("/owid/api/openapi.json", HandlerOpenAPI(s))
+	http.HandleFunc(wellKnownPath, HandlerCreator(s))
+	addAPIHandlers(s, http.DefaultServeMux)
+}
+
+// handlerCors wraps the handler setting the cross-origin resource sharing
+// headers from the configuration and responding to preflight requests.
+func handlerCors(s *Services, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCorsHeaders(s, w, r)
+		if r.Method == http.MethodOptions &&
+			r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// setCorsHeaders sets the cross-origin resource sharing headers for the
+// response. If no allowed origins are configured then all origins are allowed.
+func setCorsHeaders(s *Services, w http.ResponseWriter, r *http.Request) {
+	c := &s.Config().Cors
+	if len(c.AllowedOrigins) == 0 {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		o := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if c.isOriginAllowed(o) == false {
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", o)
+	}
+	if len(c.AllowedMethods) > 0 {
+		w.Header().Set(
+			"Access-Control-Allow-Methods",
+			strings.Join(c.AllowedMethods, ", "))
+	}
+	if c.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(c.MaxAge))
+	}
+}
+
+// isNotModified sets the ETag and Last-Modified headers for the response. If
+// the request's conditional headers show the client already has the current
+// version then a 304 response is sent and true is returned, otherwise false.
+// The modified time is ignored if it is the zero time.
+func isNotModified(
+	w http.ResponseWriter,
+	r *http.Request,
+	etag string,
+	modified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if modified.IsZero() == false {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	n := false
+	if m := r.Header.Get("If-None-Match"); m != "" {
+		n = etagMatch(m, etag)
+	} else if m := r.Header.Get("If-Modified-Since"); m != "" &&
+		modified.IsZero() == false {
+		t, err := http.ParseTime(m)
+		n = err == nil && modified.Truncate(time.Second).After(t) == false
+	}
+	if n {
+		w.WriteHeader(http.StatusNotModified)
+	}
+	return n
+}
+
+// etagMatch returns true if the ETag is in the If-None-Match header value.
+// Weak comparison is used as required for If-None-Match.
+func etagMatch(header string, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" ||
+			strings.TrimPrefix(v, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
 		}
 	}
+	return false
+}
+
+// newETag returns a strong ETag for the content provided.
+func newETag(b []byte) string {
+	h := sha256.Sum256(b)
+	return `"` + base64.RawURLEncoding.EncodeToString(h[:16]) + `"`
 }
 
 func returnAPIError(
@@ -45,19 +131,19 @@ func returnAPIError(
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	http.Error(w, err.Error(), code)
-	if s.config.Debug {
+	if s.Config().Debug {
 		println(err.Error())
 	}
 }
 
 func returnServerError(s *Services, w http.ResponseWriter, err error) {
 	w.Header().Set("Cache-Control", "no-cache")
-	if s.config.Debug {
+	if s.Config().Debug {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	} else {
 		http.Error(w, "", http.StatusInternalServerError)
 	}
-	if s.config.Debug {
+	if s.Config().Debug {
 		println(err.Error())
 	}
 }
@@ -73,46 +159,43 @@ func getCreatorFromRequest(s *Services, r *http.Request) (*Creator, error) {
 	return c, nil
 }
 
-// getWriter creates a new compressed writer for the content type provided.
-func getWriter(w http.ResponseWriter, c string) *gzip.Writer {
-	g := gzip.NewWriter(w)
-	w.Header().Set("Content-Encoding", "gzip")
-	w.Header().Set("Content-Type", c)
-	return g
-}
-
+// sendHTMLTemplate executes the template before sending the HTML so that any
+// error can be returned in place of a partial page.
 func sendHTMLTemplate(s *Services,
 	w http.ResponseWriter,
+	r *http.Request,
 	t *template.Template,
 	m interface{}) {
 	w.Header().Set("Cache-Control", "no-cache")
-	g := getWriter(w, "text/html; charset=utf-8")
-	defer g.Close()
-	err := t.Execute(g, m)
+	var b bytes.Buffer
+	err := t.Execute(&b, m)
 	if err != nil {
 		returnServerError(s, w, err)
+		return
 	}
+	sendResponse(s, w, r, "text/html; charset=utf-8", b.Bytes())
 }
 
 func sendResponse(
 	s *Services,
 	w http.ResponseWriter,
+	r *http.Request,
 	c string,
 	b []byte) {
-	g := getWriter(w, c)
-	defer g.Close()
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	l, err := g.Write(b)
-	if err != nil {
-		returnAPIError(s, w, err, http.StatusInternalServerError)
-		return
-	}
-	if l != len(b) {
-		returnAPIError(
-			s,
-			w,
-			fmt.Errorf("Byte count mismatch"),
-			http.StatusInternalServerError)
-		return
+	sendResponseStatus(s, w, r, c, http.StatusOK, b)
+}
+
+// sendResponseStatus is sendResponse for responses with a status code other
+// than OK.
+func sendResponseStatus(
+	s *Services,
+	w http.ResponseWriter,
+	r *http.Request,
+	c string,
+	status int,
+	b []byte) {
+	err := s.writeResponse(w, r, c, status, b)
+	if err != nil && s.Config().Debug {
+		println(err.Error())
 	}
 }
@@ -18,17 +18,23 @@ package owid
 
 import (
 	"compress/gzip"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 const (
-	registerDomain      = testDomain + " register"
+	registerDomain      = "register." + testDomain
 	registerName        = testOrgName + "register"
 	registerContractURL = "https://test.com/" + testOrgName
 )
@@ -133,8 +139,35 @@ func TestCreatorHandler(t *testing.T) {
 		return
 	}
 
+	// Check the signature is valid for the creator's public key and that
+	// altering the information invalidates it.
+	var p PublicCreator
+	p.Domain = d["domain"]
+	p.Name = d["name"]
+	p.PublicKeySPKI = d["publicKeySPKI"]
+	p.ContractURL = d["contractURL"]
+	p.Created, err = time.Parse(time.RFC3339Nano, d["created"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Signature, err = base64.StdEncoding.DecodeString(d["signature"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := p.VerifySignature(expected.publicKey)
+	if err != nil || v == false {
+		t.Errorf("signature not valid")
+		return
+	}
+	p.Name = "altered"
+	v, err = p.VerifySelfSignature()
+	if err != nil || v == true {
+		t.Errorf("signature valid for altered information")
+		return
+	}
+
 	// Check no additional information has been returned.
-	if len(d) != 4 {
+	if len(d) != 10 {
 		t.Errorf("too many keys returned")
 		return
 	}
@@ -154,9 +187,10 @@ func send(
 		return nil
 	}
 	req.Host = d
+	req.Header.Set("Accept-Encoding", encodingGzip)
 
 	// Add the access key for verification.
-	q.Set("accessKey", "key1")
+	q.Set("accesskey", "key1")
 	req.URL.RawQuery = q.Encode()
 
 	// Call the handler function.
@@ -173,17 +207,52 @@ func send(
 	return rr
 }
 
+// sendPost posts the form to the state changing end point with the access key
+// in the access key header and checks the response is OK.
+func sendPost(
+	t *testing.T,
+	f http.HandlerFunc,
+	d string,
+	p string,
+	q url.Values) *httptest.ResponseRecorder {
+	rr := post(t, f, d, p, q)
+	if rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			rr.Code, http.StatusOK)
+		return nil
+	}
+	return rr
+}
+
+// post posts the form to the handler with the access key in the access key
+// header and returns the response whatever the status code.
+func post(
+	t *testing.T,
+	f http.HandlerFunc,
+	d string,
+	p string,
+	q url.Values) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(
+		http.MethodPost,
+		p,
+		strings.NewReader(q.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = d
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept-Encoding", encodingGzip)
+	req.Header.Set(accessKeyHeader, "key1")
+	rr := httptest.NewRecorder()
+	f.ServeHTTP(rr, req)
+	return rr
+}
+
 func decompressAsMap(
 	t *testing.T,
 	rr *httptest.ResponseRecorder) map[string]string {
 	var d map[string]string
-	br, err := gzip.NewReader(rr.Body)
-	if err != nil {
-		t.Errorf("error '%s' decompressing", err)
-		return nil
-	}
-	b, _ := io.ReadAll(br)
-	err = json.Unmarshal(b, &d)
+	err := json.Unmarshal([]byte(decompressAsString(t, rr)), &d)
 	if err != nil {
 		t.Errorf("error '%s' unmarshalling response to json", err)
 		return nil
@@ -194,6 +263,9 @@ func decompressAsMap(
 func decompressAsString(
 	t *testing.T,
 	rr *httptest.ResponseRecorder) string {
+	if rr.Header().Get("Content-Encoding") != encodingGzip {
+		return rr.Body.String()
+	}
 	br, err := gzip.NewReader(rr.Body)
 	if err != nil {
 		t.Errorf("error '%s' decompressing", err)
@@ -209,3 +281,805 @@ func getServices() (*Services, error) {
 	ts.addCreator(testDomain, testOrgName, registerContractURL)
 	return NewServices(c, ts, a), nil
 }
+
+// TestOpenAPIHandler verifies that the handler returns the OpenAPI
+// specification and that the schemas it contains are in the generated types.
+func TestOpenAPIHandler(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := send(
+		t,
+		HandlerOpenAPI(s),
+		testDomain,
+		"/owid/api/openapi.json",
+		url.Values{})
+	var d struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(d.OpenAPI, "3.") == false {
+		t.Errorf("expected OpenAPI 3, found '%s'", d.OpenAPI)
+	}
+	if _, ok := d.Components.Schemas["PublicCreator"]; ok == false {
+		t.Error("PublicCreator schema missing")
+	}
+	if _, ok := d.Components.Schemas["Bundle"]; ok == false {
+		t.Error("Bundle schema missing")
+	}
+	if _, ok := d.Components.Schemas["VerifyResponse"]; ok == false {
+		t.Error("VerifyResponse schema missing")
+	}
+}
+
+// TestCorsHeaders verifies that the CORS headers reflect the configuration.
+func TestCorsHeaders(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := HandlerCreator(s)
+	if o := sendCors(t, h, "https://any.com").Get(
+		"Access-Control-Allow-Origin"); o != "*" {
+		t.Errorf("expected '*' origin, found '%s'", o)
+	}
+	c := *s.Config()
+	c.Cors = Cors{
+		AllowedOrigins: []string{"https://allowed.com"},
+		AllowedMethods: []string{"GET"},
+		MaxAge:         600}
+	s.SetConfig(c)
+	a := sendCors(t, h, "https://allowed.com")
+	if o := a.Get("Access-Control-Allow-Origin"); o != "https://allowed.com" {
+		t.Errorf("expected allowed origin, found '%s'", o)
+	}
+	if m := a.Get("Access-Control-Allow-Methods"); m != "GET" {
+		t.Errorf("expected 'GET' methods, found '%s'", m)
+	}
+	if m := a.Get("Access-Control-Max-Age"); m != "600" {
+		t.Errorf("expected '600' max age, found '%s'", m)
+	}
+	if o := sendCors(t, h, "https://denied.com").Get(
+		"Access-Control-Allow-Origin"); o != "" {
+		t.Errorf("expected no origin, found '%s'", o)
+	}
+}
+
+func sendCors(t *testing.T, h http.HandlerFunc, origin string) http.Header {
+	req, err := http.NewRequest("OPTIONS", "/owid/api/v1/creator", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = testDomain
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status '%d', found '%d'",
+			http.StatusNoContent,
+			rr.Code)
+	}
+	return rr.Header()
+}
+
+// TestCreatorHandlerNotModified verifies that conditional requests using the
+// ETag or Last-Modified values returned receive a 304 response.
+func TestCreatorHandlerNotModified(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := send(t, HandlerCreator(s), testDomain, "", url.Values{})
+	e := rr.Header().Get("ETag")
+	if e == "" {
+		t.Fatal("ETag missing")
+	}
+	m := rr.Header().Get("Last-Modified")
+	if m == "" {
+		t.Fatal("Last-Modified missing")
+	}
+	if c := sendConditional(t, s, "If-None-Match", e); c != http.StatusNotModified {
+		t.Errorf("If-None-Match expected '%d', found '%d'",
+			http.StatusNotModified, c)
+	}
+	if c := sendConditional(t, s, "If-None-Match", `"other"`); c != http.StatusOK {
+		t.Errorf("If-None-Match expected '%d', found '%d'",
+			http.StatusOK, c)
+	}
+	if c := sendConditional(t, s, "If-Modified-Since", m); c != http.StatusNotModified {
+		t.Errorf("If-Modified-Since expected '%d', found '%d'",
+			http.StatusNotModified, c)
+	}
+
+	// Changing the status changes the Last-Modified time so that clients
+	// revalidating with the earlier time receive the new information.
+	s.SetClock(NewManualClock(time.Now().Add(time.Hour)))
+	_, err = s.SetCreatorStatus(testDomain, CreatorSuspended, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := sendConditional(t, s, "If-Modified-Since", m); c != http.StatusOK {
+		t.Errorf("If-Modified-Since after status change expected '%d', "+
+			"found '%d'", http.StatusOK, c)
+	}
+	rr = send(t, HandlerCreator(s), testDomain, "", url.Values{})
+	if rr.Header().Get("Last-Modified") == m {
+		t.Error("Last-Modified not changed by status change")
+	}
+}
+
+func sendConditional(t *testing.T, s *Services, h string, v string) int {
+	req, err := http.NewRequest("GET", "/owid/api/v1/creator", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = testDomain
+	req.Header.Set(h, v)
+	rr := httptest.NewRecorder()
+	HandlerCreator(s).ServeHTTP(rr, req)
+	return rr.Code
+}
+
+// TestInspectHandler checks the inspect page decodes and verifies an OWID
+// created by a registered creator.
+func TestInspectHandler(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.store.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := url.Values{}
+	data.Set("owid", o.AsString())
+	rr := send(t, HandlerInspect(s), testDomain, "/owid/inspect", data)
+	v := decompressAsString(t, rr)
+	if strings.Contains(v, testDomain) == false {
+		t.Error("domain missing from inspect page")
+	}
+	if strings.Contains(v, "<p>true</p>") == false {
+		t.Error("OWID not shown as verified")
+	}
+}
+
+// TestBundleHandler verifies that the bundle is signed by the creator for the
+// host and can be used to verify OWIDs without network access.
+func TestBundleHandler(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.store.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := send(
+		t,
+		HandlerBundle(s),
+		testDomain,
+		"/owid/api/v3/bundle",
+		url.Values{})
+	b, err := BundleFromJSON([]byte(decompressAsString(t, rr)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := b.VerifySignature(c.publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok == false {
+		t.Fatal("bundle signature should be valid")
+	}
+	v, err := NewBundleVerifier(b, c.publicKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newOWID(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err = v.Verify(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok == false {
+		t.Fatal("OWID should verify with the bundle")
+	}
+	o.Domain = "unknown.com"
+	_, err = v.Verify(o)
+	if err == nil {
+		t.Fatal("OWID for a domain not in the bundle should error")
+	}
+	b.Creators = b.Creators[1:]
+	ok, err = b.VerifySignature(c.publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("altered bundle signature should not be valid")
+	}
+	_, err = NewBundleVerifier(b, c.publicKey, 0)
+	if err == nil {
+		t.Fatal("altered bundle should be refused")
+	}
+}
+
+// TestBundleVerifierTrust checks bundles are only trusted if signed by the
+// trusted key and recent, that suspended creators are refused and that the
+// signed bundle is reused.
+func TestBundleVerifierTrust(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := NewManualClock(time.Now().UTC())
+	s.SetClock(k)
+	c, err := s.store.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := newTestCreator("other.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(x.withStatus(CreatorSuspended))
+	j := decompressAsString(t, send(
+		t,
+		HandlerBundle(s),
+		testDomain,
+		"/owid/api/v3/bundle",
+		url.Values{}))
+	b, err := BundleFromJSON([]byte(j))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewBundleVerifier(b, x.publicKey, 0)
+	if err == nil {
+		t.Fatal("bundle signed by an untrusted key should be refused")
+	}
+	v, err := NewBundleVerifier(b, c.publicKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := x.CreateOWID([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = x.Sign(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = v.Verify(o)
+	if err == nil {
+		t.Fatal("OWID from a suspended creator should be refused")
+	}
+	k.Advance(time.Second)
+	r := decompressAsString(t, send(
+		t,
+		HandlerBundle(s),
+		testDomain,
+		"/owid/api/v3/bundle",
+		url.Values{}))
+	if r != j {
+		t.Fatal("signed bundle should be reused")
+	}
+	b, err = newBundle(
+		c,
+		s.store.GetCreators(),
+		time.Now().Add(-2*defaultBundleMaxAge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewBundleVerifier(b, c.publicKey, 0)
+	if err == nil {
+		t.Fatal("expired bundle should be refused")
+	}
+}
+
+// TestRegisterHandlerTemplate checks that a custom register template provided
+// from a file system is used in place of the default.
+func TestRegisterHandlerTemplate(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := *s.Config()
+	c.RegisterTemplateFile = "register.html"
+	c.SetTemplateFS(fstest.MapFS{"register.html": &fstest.MapFile{
+		Data: []byte(`<html>Example Ltd {{ .Domain }}</html>`)}})
+	err = c.loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetConfig(c)
+	rr := send(
+		t,
+		HandlerRegister(s),
+		registerDomain,
+		"/owid/register",
+		url.Values{})
+	v := decompressAsString(t, rr)
+	if v != "<html>Example Ltd "+registerDomain+"</html>" {
+		t.Errorf("custom template not used, found '%s'", v)
+	}
+}
+
+// TestCheckContractURL checks contract URLs must use HTTPS, be for the same
+// registrable domain and respond with 200 OK.
+func TestCheckContractURL(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodHead || r.URL.Path != "/terms" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer ts.Close()
+	c := client
+	client = ts.Client()
+	defer func() { client = c }()
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewConfig("appsettings.test.none.json")
+	h := u.Hostname()
+	err = checkContractURL(&f, h, ts.URL+"/terms")
+	if err != nil {
+		t.Errorf("valid contract URL refused with '%s'", err)
+	}
+	for _, v := range []struct{ domain, url string }{
+		{h, ts.URL + "/missing"},
+		{h, "http://" + u.Host + "/terms"},
+		{testDomain, ts.URL + "/terms"}} {
+		if checkContractURL(&f, v.domain, v.url) == nil {
+			t.Errorf("contract URL '%s' for '%s' should be refused",
+				v.url,
+				v.domain)
+		}
+	}
+	if registrableDomain("www.example.co.uk") !=
+		registrableDomain("terms.EXAMPLE.co.uk") {
+		t.Error("subdomains should share the registrable domain")
+	}
+}
+
+// TestRegisterHandlerContact checks that contact details from the register
+// form are stored and included in the signed public information.
+func TestRegisterHandlerContact(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := url.Values{}
+	data.Set("name", registerName)
+	data.Set("email", "bad email")
+	send(t, HandlerRegister(s), registerDomain, "/owid/register", data)
+	c, err := s.store.GetCreator(registerDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != nil {
+		t.Fatal("creator registered with invalid email")
+	}
+	e := Contact{
+		Email:        "privacy@" + registerDomain,
+		DpoURL:       "https://" + registerDomain + "/dpo",
+		Jurisdiction: "GB"}
+	data.Set("email", e.Email)
+	data.Set("dpoURL", e.DpoURL)
+	data.Set("jurisdiction", e.Jurisdiction)
+	send(t, HandlerRegister(s), registerDomain, "/owid/register", data)
+	c, err = s.store.GetCreator(registerDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil || c.Contact() != e {
+		t.Fatal("contact details not stored")
+	}
+	p, err := publicCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Email != e.Email ||
+		p.DpoURL != e.DpoURL ||
+		p.Jurisdiction != e.Jurisdiction {
+		t.Fatal("contact details not in public information")
+	}
+	v, err := p.VerifySelfSignature()
+	if err != nil || v == false {
+		t.Fatal("signature not valid")
+	}
+	p.Email = "other@" + registerDomain
+	v, err = p.VerifySelfSignature()
+	if err != nil || v {
+		t.Fatal("signature valid for altered contact")
+	}
+}
+
+// TestRegisterHandlerChallenge checks that a creator registered when a domain
+// challenge is configured can't sign until the token is published.
+func TestRegisterHandlerChallenge(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := *s.Config()
+	f.DomainChallenge = ChallengeDNS
+	s.SetConfig(f)
+	var txt []string
+	l := lookupTXT
+	lookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		if name != challengeDNSPrefix+registerDomain {
+			return nil, fmt.Errorf("name '%s' not found", name)
+		}
+		return txt, nil
+	}
+	defer func() { lookupTXT = l }()
+	data := url.Values{}
+	data.Set("name", registerName)
+	send(t, HandlerRegister(s), registerDomain, "/owid/register", data)
+	c, err := s.store.GetCreator(registerDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil || c.Status() != CreatorPending {
+		t.Fatal("creator should be pending")
+	}
+	_, err = c.CreateOWIDandSign([]byte(testPayload))
+	var e *PendingError
+	if errors.As(err, &e) == false {
+		t.Fatalf("pending creator signed with error '%v'", err)
+	}
+	txt = []string{"wrong"}
+	send(t, HandlerRegister(s), registerDomain, "/owid/register", data)
+	c, err = s.store.GetCreator(registerDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status() != CreatorPending {
+		t.Fatal("creator activated without the token")
+	}
+	txt = []string{"other", challengeToken(c)}
+	send(t, HandlerRegister(s), registerDomain, "/owid/register", data)
+	c, err = s.store.GetCreator(registerDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status() != CreatorActive {
+		t.Fatal("creator not activated with the token")
+	}
+	_, err = c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCheckChallengeHTTP checks the token must be returned from the well known
+// challenge path.
+func TestCheckChallengeHTTP(t *testing.T) {
+	token := "token"
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != challengePath {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(token + "\n"))
+		}))
+	defer ts.Close()
+	ctx := context.Background()
+	err := checkChallengeHTTP(ctx, ts.URL+challengePath, "token")
+	if err != nil {
+		t.Errorf("valid challenge refused with '%s'", err)
+	}
+	if checkChallengeHTTP(ctx, ts.URL+challengePath, "other") == nil {
+		t.Error("wrong token should be refused")
+	}
+	if checkChallengeHTTP(ctx, ts.URL+"/missing", "token") == nil {
+		t.Error("missing challenge should be refused")
+	}
+}
+
+// TestCreatorStatusHandler suspends and resumes a creator checking that the
+// suspended creator refuses to sign and that the status is advertised.
+func TestCreatorStatusHandler(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := url.Values{}
+	data.Set("status", string(CreatorSuspended))
+	rr := sendPost(
+		t,
+		HandlerCreatorStatus(s),
+		testDomain,
+		"/owid/api/v1/status",
+		data)
+	var p PublicCreator
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.isSuspended() == false {
+		t.Fatalf("status '%s' not expected", p.Status)
+	}
+	v, err := p.VerifySelfSignature()
+	if err != nil || v == false {
+		t.Fatal("signature not valid")
+	}
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CreateOWIDandSign([]byte(testPayload))
+	var e *SuspendedError
+	if errors.As(err, &e) == false || e.Domain != testDomain {
+		t.Fatalf("expected suspended error, found '%v'", err)
+	}
+	data.Set("status", string(CreatorActive))
+	sendPost(
+		t,
+		HandlerCreatorStatus(s),
+		testDomain,
+		"/owid/api/v1/status",
+		data)
+	c, err = s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Set("status", "deleted")
+	rr = post(
+		t,
+		HandlerCreatorStatus(s),
+		testDomain,
+		"/owid/api/v1/status",
+		data)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status '%d', found '%d'",
+			http.StatusBadRequest,
+			rr.Code)
+	}
+}
+
+// TestCreatorStatusHandlerGet checks that the state changing end points refuse
+// GET requests, even with a valid access key, and leave the creator unchanged.
+func TestCreatorStatusHandlerGet(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct {
+		p string
+		h http.HandlerFunc
+	}{
+		{"/owid/api/v1/status?status=suspended&accesskey=key1",
+			HandlerCreatorStatus(s)},
+		{"/owid/api/v3/preferred-version?preferredVersion=1&accesskey=key1",
+			HandlerCreatorVersion(s)}} {
+		p, h := e.p, e.h
+		req, err := http.NewRequest(http.MethodGet, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = testDomain
+		req.Header.Set(accessKeyHeader, "key1")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("'%s' expected status '%d', found '%d'",
+				p,
+				http.StatusMethodNotAllowed,
+				rr.Code)
+		}
+		if rr.Header().Get("Allow") != http.MethodPost {
+			t.Fatalf("'%s' expected allow header '%s'", p, http.MethodPost)
+		}
+	}
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Status() != CreatorActive || c.PreferredVersion() != 0 {
+		t.Fatal("creator changed by GET request")
+	}
+}
+
+// TestVerifyHandlerRemote verifies an OWID from another domain using the
+// services' verifier, checking remote verification must be enabled, that only
+// trusted or public domains are fetched and that the verifier's policy is
+// applied.
+func TestVerifyHandlerRemote(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(r))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := url.Values{}
+	data.Set("owid", o.AsString())
+	data.Set("remote", "true")
+	verify := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(
+			"GET",
+			"/owid/api/v1/verify?"+data.Encode(),
+			nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = testDomain
+		rr := httptest.NewRecorder()
+		HandlerVerify(s).ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := verify(); rr.Code != http.StatusNotImplemented {
+		t.Fatalf("expected status '%d', found '%d'",
+			http.StatusNotImplemented,
+			rr.Code)
+	}
+	s.SetVerifier(NewVerifier(u.Scheme, nil))
+	if rr := verify(); rr.Code != http.StatusForbidden {
+		t.Fatalf("IP address expected status '%d', found '%d'",
+			http.StatusForbidden,
+			rr.Code)
+	}
+	s.SetVerifier(NewVerifier(u.Scheme, &VerificationPolicy{
+		TrustedDomains: []string{u.Host}}))
+	var v VerifyResponse
+	err = json.Unmarshal([]byte(decompressAsString(
+		t,
+		send(t, HandlerVerify(s), testDomain, "/owid/api/v1/verify", data))),
+		&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Valid == false {
+		t.Fatal("remote OWID should be valid")
+	}
+	s.SetVerifier(NewVerifier(u.Scheme, &VerificationPolicy{
+		TrustedDomains: []string{u.Host},
+		BlockedDomains: []string{u.Host}}))
+	rr := verify()
+	err = json.Unmarshal(rr.Body.Bytes(), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusOK || v.Valid {
+		t.Fatal("OWID refused by policy should not be valid")
+	}
+	data.Set("remote", "maybe")
+	if rr := verify(); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status '%d', found '%d'",
+			http.StatusBadRequest,
+			rr.Code)
+	}
+}
+
+// TestVerifyHandlerNotRegistered checks verifying with a host that has no
+// creator, and requesting its bundle, responds not found.
+func TestVerifyHandlerNotRegistered(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := url.Values{}
+	data.Set("owid", o.AsString())
+	for _, h := range []http.HandlerFunc{HandlerVerify(s), HandlerBundle(s)} {
+		req, err := http.NewRequest(
+			"GET",
+			"/owid/api/v1/verify?"+data.Encode(),
+			nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = "unregistered.com"
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected '404', found '%d'", rr.Code)
+		}
+	}
+}
+
+// TestRemoteAllowed checks IP addresses and localhost are refused unless the
+// policy trusts them, and that connections to internal addresses are refused.
+func TestRemoteAllowed(t *testing.T) {
+	for d, e := range map[string]bool{
+		"example.com":      true,
+		"localhost":        false,
+		"a.localhost:8080": false,
+		"127.0.0.1":        false,
+		"[::1]:443":        false,
+		"169.254.169.254":  false} {
+		err := remoteAllowed(nil, d)
+		if (err == nil) != e {
+			t.Errorf("domain '%s' allowed '%t' not '%t'", d, err == nil, e)
+		}
+	}
+	p := &VerificationPolicy{TrustedDomains: []string{"internal.example.com"}}
+	if remoteAllowed(p, "internal.example.com") != nil {
+		t.Error("trusted domain should be allowed")
+	}
+	if remoteAllowed(p, "example.com") == nil {
+		t.Error("domain not trusted by policy should be refused")
+	}
+	for a, e := range map[string]bool{
+		"93.184.216.34:443":   true,
+		"10.0.0.1:443":        false,
+		"127.0.0.1:80":        false,
+		"[::1]:443":           false,
+		"169.254.169.254:80":  false,
+		"0.0.0.0:80":          false,
+		"[fe80::1%25eth0]:80": false} {
+		err := remoteControl("tcp", a, nil)
+		if (err == nil) != e {
+			t.Errorf("address '%s' allowed '%t' not '%t'", a, err == nil, e)
+		}
+	}
+}
+
+// TestRemoteClient checks the remote client refuses to connect to internal
+// addresses and does not follow redirects.
+func TestRemoteClient(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/other", http.StatusFound)
+		}))
+	defer h.Close()
+	_, err := newRemoteClient(false).Get(h.URL)
+	var a *remoteRefusedError
+	if errors.As(err, &a) == false {
+		t.Fatalf("expected connection refused, found '%v'", err)
+	}
+	r, err := newRemoteClient(true).Get(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if r.StatusCode != http.StatusFound {
+		t.Fatalf("redirect followed to status '%d'", r.StatusCode)
+	}
+}
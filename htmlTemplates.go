@@ -17,7 +17,11 @@
 package owid
 
 import (
+	"fmt"
 	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -42,11 +46,15 @@ var registerTemplate = newHTMLTemplate("register", `
     justify-content: center;
     align-items: center;">
     <form action="register" method="GET">
+    <input type="hidden" name="accesskey" value="{{ .AccessKey }}">
     <table style="text-align: left;">
         <tr>
             <td colspan="3">
                 {{ if not .ReadOnly }}
                 <p>Register creator '{{ .Domain }}' to a organization.</p>
+                {{ else if .Pending }}
+                <p>Creator '{{ .Domain }}' registered to organization name '{{ .Name }}' pending verification of the domain.</p>
+                <p>Publish the token '{{ .ChallengeToken }}' at '{{ .ChallengeLocation }}' and then verify.</p>
                 {{ else }}
                 <p>Success. Creator '{{ .Domain }}' registered to organization name '{{ .Name }}'.</p>
                 {{ end }}
@@ -78,10 +86,42 @@ var registerTemplate = newHTMLTemplate("register", `
                 {{ end }}
             </td>
         </tr>
+        <tr>
+            <td>
+                <p><label for="email">Contact Email</label></p>
+            </td>
+            <td>
+                <p><input type="text" maxlength="200" id="email" name="email" value="{{ .Contact.Email }}" {{ if .ReadOnly }}disabled{{ end }}></p>
+            </td>
+            <td>
+                {{ if .DisplayErrors }}
+                <p>{{ .ContactError }}</p>
+                {{ end }}
+            </td>
+        </tr>
+        <tr>
+            <td>
+                <p><label for="dpoURL">DPO URL</label></p>
+            </td>
+            <td>
+                <p><input type="text" maxlength="200" id="dpoURL" name="dpoURL" value="{{ .Contact.DpoURL }}" {{ if .ReadOnly }}disabled{{ end }}></p>
+            </td>
+            <td></td>
+        </tr>
+        <tr>
+            <td>
+                <p><label for="jurisdiction">Jurisdiction</label></p>
+            </td>
+            <td>
+                <p><input type="text" maxlength="50" id="jurisdiction" name="jurisdiction" value="{{ .Contact.Jurisdiction }}" {{ if .ReadOnly }}disabled{{ end }}></p>
+            </td>
+            <td></td>
+        </tr>
         <tr>
             <td colspan="3">
                 {{ if .DisplayErrors }}
                 <p>{{ .Error }}</p>
+                <p>{{ .ChallengeError }}</p>
                 {{ end }}
             </td>
         </tr>        
@@ -90,6 +130,10 @@ var registerTemplate = newHTMLTemplate("register", `
             <td colspan="3" style="text-align: center;">
                 <input type="submit">
             </td>
+            {{ else if .Pending }}
+            <td colspan="3" style="text-align: center;">
+                <input type="submit" value="Verify">
+            </td>
             {{ end }}
         </tr>        
     </table>
@@ -97,6 +141,133 @@ var registerTemplate = newHTMLTemplate("register", `
 </body>
 </html>`)
 
+var inspectTemplate = newHTMLTemplate("inspect", `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8" />
+    <title>Open Web Id - Inspect</title>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <link rel="icon" href="data:;base64,=">
+</head>
+<body style="margin: 0;
+    padding: 0;
+    font-family: nunito, sans-serif;
+    font-size: 16px;
+    font-weight: 600;
+    background-color: {{ .Services.Config.BackgroundColor }};
+    color: {{ .Services.Config.MessageColor }};
+    height: 100vh;
+    display: flex;
+    justify-content: center;
+    align-items: center;">
+    <form action="inspect" method="POST">
+    <table style="text-align: left; max-width: 80em;">
+        <tr>
+            <td>
+                <p><label for="owid">OWID</label></p>
+            </td>
+            <td>
+                <p><textarea id="owid" name="owid" rows="4" cols="80">{{ .Value }}</textarea></p>
+            </td>
+        </tr>
+        <tr>
+            <td>
+                <p><label for="parent">Parent OWID (optional)</label></p>
+            </td>
+            <td>
+                <p><textarea id="parent" name="parent" rows="4" cols="80">{{ .Parent }}</textarea></p>
+            </td>
+        </tr>
+        {{ if .Error }}
+        <tr>
+            <td colspan="2">
+                <p>{{ .Error }}</p>
+            </td>
+        </tr>
+        {{ end }}
+        {{ if .OWID }}
+        <tr>
+            <td><p>Version</p></td>
+            <td><p>{{ .OWID.Version }}</p></td>
+        </tr>
+        <tr>
+            <td><p>Domain</p></td>
+            <td><p>{{ .OWID.Domain }}</p></td>
+        </tr>
+        <tr>
+            <td><p>Date</p></td>
+            <td><p>{{ .OWID.Date.Format "2006-01-02T15:04:05Z07:00" }}</p></td>
+        </tr>
+        <tr>
+            <td><p>Age (minutes)</p></td>
+            <td><p>{{ .OWID.Age }}</p></td>
+        </tr>
+        <tr>
+            <td><p>Payload</p></td>
+            <td><p style="word-break: break-all;">{{ .OWID.PayloadAsPrintable }}</p></td>
+        </tr>
+        <tr>
+            <td><p>Signature</p></td>
+            <td><p style="word-break: break-all;">{{ .SignatureHex }}</p></td>
+        </tr>
+        <tr>
+            <td><p>Verified</p></td>
+            <td><p>{{ if .VerifyError }}{{ .VerifyError }}{{ else }}{{ .Valid }}{{ end }}</p></td>
+        </tr>
+        {{ end }}
+        <tr>
+            <td colspan="2" style="text-align: center;">
+                <input type="submit" value="Inspect">
+            </td>
+        </tr>
+    </table>
+    </form>
+</body>
+</html>`)
+
+// loadTemplates reads and parses any custom templates in the configuration.
+// The templates are passed the exported data structures, for example Register,
+// used by the default templates.
+func (c *Configuration) loadTemplates() error {
+	c.registerTemplate = nil
+	if c.RegisterTemplateFile == "" {
+		return nil
+	}
+	var b []byte
+	var err error
+	if c.templates != nil {
+		b, err = fs.ReadFile(c.templates, c.RegisterTemplateFile)
+	} else {
+		b, err = os.ReadFile(c.RegisterTemplateFile)
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"OWID RegisterTemplateFile '%s' not read: %s",
+			c.RegisterTemplateFile,
+			err.Error())
+	}
+	t, err := template.New(filepath.Base(c.RegisterTemplateFile)).Parse(
+		string(b))
+	if err != nil {
+		return fmt.Errorf(
+			"OWID RegisterTemplateFile '%s' invalid: %s",
+			c.RegisterTemplateFile,
+			err.Error())
+	}
+	c.registerTemplate = t
+	return nil
+}
+
+// getRegisterTemplate returns the custom register template if one has been
+// loaded, otherwise the default.
+func (c *Configuration) getRegisterTemplate() *template.Template {
+	if c.registerTemplate != nil {
+		return c.registerTemplate
+	}
+	return registerTemplate
+}
+
 func newHTMLTemplate(n string, h string) *template.Template {
 	c := removeHTMLWhiteSpace(h)
 	return template.Must(template.New(n).Parse(c))
@@ -0,0 +1,360 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Header field that carries the OWID in a request signed with an HTTP message
+// signature.
+const HTTPSignatureField = "Owid"
+
+// The label of the signature in the Signature and Signature-Input fields, and
+// the tag parameter identifying OWID signatures.
+const httpSignatureLabel = "owid"
+
+// The RFC 9421 algorithm name for ECDSA P-256 with SHA-256.
+const httpSignatureAlgorithm = "ecdsa-p256-sha256"
+
+// DefaultHTTPComponents are the components covered by the signature if none
+// are provided. The OWID field covers the payload.
+var DefaultHTTPComponents = []string{"@method", "@target-uri", "owid"}
+
+// SignHTTPRequest adds the OWID to the request and signs the components of
+// the request, including the OWID field, as an RFC 9421 HTTP message
+// signature. The OWID's domain is the key id and its date the created time so
+// that gateways that understand message signatures can verify the request
+// using the creator's public key.
+func SignHTTPRequest(
+	r *http.Request,
+	c *Crypto,
+	o *OWID,
+	components []string) error {
+	if c.Algorithm() != AlgorithmES256 {
+		return fmt.Errorf(
+			"algorithm '%s' not supported for message signatures",
+			c.Algorithm())
+	}
+	if len(components) == 0 {
+		components = DefaultHTTPComponents
+	}
+	v, err := o.AsBase64()
+	if err != nil {
+		return err
+	}
+
+	// The created time is taken from the OWID as it will be read by the
+	// verifier as the precision of the date depends on the OWID version.
+	w, err := FromBase64(v)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(HTTPSignatureField, v)
+	p := httpSignatureParams{
+		components: components,
+		created:    w.Date.Unix(),
+		keyID:      o.Domain,
+		alg:        httpSignatureAlgorithm,
+		tag:        httpSignatureLabel}
+	b, err := httpSignatureBase(r, &p)
+	if err != nil {
+		return err
+	}
+	s, err := c.SignByteArray(b)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(
+		"Signature-Input",
+		httpSignatureLabel+"="+p.String())
+	r.Header.Set(
+		"Signature",
+		httpSignatureLabel+"=:"+base64.StdEncoding.EncodeToString(s)+":")
+	return nil
+}
+
+// VerifyHTTPRequest verifies the OWID message signature of the request using
+// the Crypto instance and returns the OWID carried in the request. The OWID's
+// own signature is not verified. An error is returned if the signature is
+// missing, does not cover the OWID field, or is not valid.
+func VerifyHTTPRequest(r *http.Request, c *Crypto) (*OWID, error) {
+	p, err := parseHTTPSignatureInput(
+		r.Header.Get("Signature-Input"),
+		httpSignatureLabel)
+	if err != nil {
+		return nil, err
+	}
+	if p.alg != "" && p.alg != httpSignatureAlgorithm {
+		return nil, fmt.Errorf("algorithm '%s' not supported", p.alg)
+	}
+	if p.covers(strings.ToLower(HTTPSignatureField)) == false {
+		return nil, errors.New("message signature does not cover the OWID")
+	}
+	s, err := parseHTTPSignature(r.Header.Get("Signature"), httpSignatureLabel)
+	if err != nil {
+		return nil, err
+	}
+	b, err := httpSignatureBase(r, p)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.VerifyByteArray(b, s)
+	if err != nil {
+		return nil, err
+	}
+	if v == false {
+		return nil, errors.New("message signature is not valid")
+	}
+	o, err := FromBase64(r.Header.Get(HTTPSignatureField))
+	if err != nil {
+		return nil, err
+	}
+	if sameDomain(o.Domain, p.keyID) == false {
+		return nil, fmt.Errorf(
+			"key id '%s' does not match OWID domain '%s'",
+			p.keyID,
+			o.Domain)
+	}
+	if o.Date.Unix() != p.created {
+		return nil, errors.New("created time does not match OWID date")
+	}
+	return o, nil
+}
+
+// httpSignatureParams are the signature parameters of an RFC 9421 signature.
+type httpSignatureParams struct {
+	components []string // Covered component identifiers
+	created    int64    // Unix time the signature was created
+	keyID      string   // Identifies the key used
+	alg        string   // Algorithm used
+	tag        string   // Application the signature is for
+}
+
+// String returns the parameters serialized as a structured field inner list
+// as used for both the Signature-Input field and the signature base.
+func (p *httpSignatureParams) String() string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, c := range p.components {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strconv.Quote(c))
+	}
+	b.WriteByte(')')
+	fmt.Fprintf(&b, ";created=%d", p.created)
+	if p.keyID != "" {
+		fmt.Fprintf(&b, ";keyid=%s", strconv.Quote(p.keyID))
+	}
+	if p.alg != "" {
+		fmt.Fprintf(&b, ";alg=%s", strconv.Quote(p.alg))
+	}
+	if p.tag != "" {
+		fmt.Fprintf(&b, ";tag=%s", strconv.Quote(p.tag))
+	}
+	return b.String()
+}
+
+// covers returns true if the component is covered by the signature.
+func (p *httpSignatureParams) covers(component string) bool {
+	for _, c := range p.components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// httpSignatureBase returns the RFC 9421 signature base for the covered
+// components of the request.
+func httpSignatureBase(r *http.Request, p *httpSignatureParams) ([]byte, error) {
+	var b strings.Builder
+	for _, c := range p.components {
+		v, err := httpComponentValue(r, c)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%s: %s\n", strconv.Quote(c), v)
+	}
+	fmt.Fprintf(&b, "\"@signature-params\": %s", p.String())
+	return []byte(b.String()), nil
+}
+
+// httpComponentValue returns the value of the derived component or header
+// field from the request.
+func httpComponentValue(r *http.Request, c string) (string, error) {
+	switch c {
+	case "@method":
+		return r.Method, nil
+	case "@authority":
+		return strings.ToLower(httpRequestHost(r)), nil
+	case "@scheme":
+		return httpRequestScheme(r), nil
+	case "@target-uri":
+		return httpRequestScheme(r) + "://" +
+			strings.ToLower(httpRequestHost(r)) +
+			r.URL.RequestURI(), nil
+	case "@path":
+		return r.URL.EscapedPath(), nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	}
+	if strings.HasPrefix(c, "@") {
+		return "", fmt.Errorf("component '%s' not supported", c)
+	}
+	if c != strings.ToLower(c) {
+		return "", fmt.Errorf("component '%s' must be lowercase", c)
+	}
+	vs := r.Header.Values(c)
+	if len(vs) == 0 {
+		return "", fmt.Errorf("component '%s' not in request", c)
+	}
+	for i, v := range vs {
+		vs[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(vs, ", "), nil
+}
+
+// httpRequestHost returns the host of the request for both client and server
+// requests.
+func httpRequestHost(r *http.Request) string {
+	if r.Host != "" {
+		return r.Host
+	}
+	return r.URL.Host
+}
+
+// httpRequestScheme returns the scheme of the request for both client and
+// server requests.
+func httpRequestScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return r.URL.Scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// httpDictionaryMember returns the value of the member with the label from a
+// structured field dictionary, or an error if not present.
+func httpDictionaryMember(field string, label string) (string, error) {
+	q := false
+	d := 0
+	s := 0
+	for i := 0; i <= len(field); i++ {
+		if i < len(field) {
+			switch field[i] {
+			case '\\':
+				if q {
+					i++
+				}
+				continue
+			case '"':
+				q = !q
+				continue
+			case '(':
+				if q == false {
+					d++
+				}
+				continue
+			case ')':
+				if q == false {
+					d--
+				}
+				continue
+			case ',':
+				if q || d > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		m := strings.TrimSpace(field[s:i])
+		if k, v, ok := strings.Cut(m, "="); ok && k == label {
+			return v, nil
+		}
+		s = i + 1
+	}
+	return "", fmt.Errorf("signature '%s' not found", label)
+}
+
+// parseHTTPSignatureInput returns the parameters of the signature with the
+// label from the Signature-Input field.
+func parseHTTPSignatureInput(
+	field string,
+	label string) (*httpSignatureParams, error) {
+	v, err := httpDictionaryMember(field, label)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(v, "(") == false {
+		return nil, fmt.Errorf("signature input '%s' not an inner list", v)
+	}
+	e := strings.Index(v, ")")
+	if e < 0 {
+		return nil, fmt.Errorf("signature input '%s' not an inner list", v)
+	}
+	var p httpSignatureParams
+	for _, c := range strings.Fields(v[1:e]) {
+		s, err := strconv.Unquote(c)
+		if err != nil {
+			return nil, fmt.Errorf("component '%s' not a string", c)
+		}
+		p.components = append(p.components, s)
+	}
+	for _, i := range strings.Split(v[e+1:], ";")[1:] {
+		k, a, _ := strings.Cut(strings.TrimSpace(i), "=")
+		switch k {
+		case "created":
+			p.created, err = strconv.ParseInt(a, 10, 64)
+		case "keyid":
+			p.keyID, err = strconv.Unquote(a)
+		case "alg":
+			p.alg, err = strconv.Unquote(a)
+		case "tag":
+			p.tag, err = strconv.Unquote(a)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s' not valid", k)
+		}
+	}
+	if p.created == 0 {
+		return nil, errors.New("signature input has no created time")
+	}
+	return &p, nil
+}
+
+// parseHTTPSignature returns the bytes of the signature with the label from
+// the Signature field.
+func parseHTTPSignature(field string, label string) ([]byte, error) {
+	v, err := httpDictionaryMember(field, label)
+	if err != nil {
+		return nil, err
+	}
+	if len(v) < 2 || v[0] != ':' || v[len(v)-1] != ':' {
+		return nil, fmt.Errorf("signature '%s' not a byte sequence", label)
+	}
+	return base64.StdEncoding.DecodeString(v[1 : len(v)-1])
+}
@@ -0,0 +1,109 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHTTPSignature signs a client request, verifies it as a server request,
+// and checks that changes to covered components are detected.
+func TestHTTPSignature(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.NewCryptoSignOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.NewCryptoVerifyOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := http.NewRequest(
+		http.MethodPost,
+		"https://partner.com/bid?id=1",
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Header.Set(
+		"Content-Digest",
+		"sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:")
+	err = SignHTTPRequest(q, s, o, []string{
+		"@method",
+		"@target-uri",
+		"content-digest",
+		"owid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := q.Header.Get("Signature-Input")
+	e := `owid=("@method" "@target-uri" "content-digest" "owid");created=`
+	if strings.HasPrefix(i, e) == false ||
+		strings.Contains(i, `;keyid="`+testDomain+`"`) == false ||
+		strings.Contains(i, `;alg="ecdsa-p256-sha256"`) == false {
+		t.Fatalf("signature input '%s' not expected", i)
+	}
+
+	// Verify the request as received by a server with another signature
+	// present.
+	newServerRequest := func() *http.Request {
+		r := httptest.NewRequest(q.Method, q.URL.String(), nil)
+		for k, v := range q.Header {
+			r.Header[k] = append([]string{}, v...)
+		}
+		r.Header.Set("Signature-Input", `other=("@method");created=1, `+i)
+		r.Header.Set("Signature", `other=:AAAA:, `+q.Header.Get("Signature"))
+		return r
+	}
+	r := newServerRequest()
+	p, err := VerifyHTTPRequest(r, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Domain != o.Domain || string(p.Payload) != testPayload {
+		t.Fatal("OWID in the request not expected")
+	}
+	b, err := c.Verify(p)
+	if err != nil || b == false {
+		t.Fatal("OWID in the request not valid")
+	}
+	r = newServerRequest()
+	r.Method = http.MethodGet
+	if _, err = VerifyHTTPRequest(r, v); err == nil {
+		t.Error("changed method should not verify")
+	}
+	r = newServerRequest()
+	r.Header.Set("Content-Digest", "sha-256=:changed:")
+	if _, err = VerifyHTTPRequest(r, v); err == nil {
+		t.Error("changed content digest should not verify")
+	}
+	r = newServerRequest()
+	r.Header.Del("Signature")
+	if _, err = VerifyHTTPRequest(r, v); err == nil {
+		t.Error("missing signature should not verify")
+	}
+}
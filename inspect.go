@@ -0,0 +1,38 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import "encoding/hex"
+
+// Inspect contains HTML template data used to display the fields of an OWID
+type Inspect struct {
+	Services    *Services
+	Value       string // The OWID as a base 64 string
+	Parent      string // The optional parent OWID as a base 64 string
+	OWID        *OWID  // The decoded OWID, or nil if not valid
+	Error       string // Error decoding the OWIDs
+	Valid       bool   // True if the OWID passed verification
+	VerifyError string // Error verifying the OWID
+}
+
+// SignatureHex returns the signature of the OWID as a hex string.
+func (i *Inspect) SignatureHex() string {
+	if i.OWID == nil {
+		return ""
+	}
+	return hex.EncodeToString(i.OWID.Signature)
+}
@@ -26,47 +26,127 @@ import (
 // The base year for all dates encoded with the io time methods.
 var ioDateBase = time.Date(2020, time.Month(1), 1, 0, 0, 0, 0, time.UTC)
 
-// The maximum length of an OWID signature in bytes.
+// The length of a P-256 OWID signature in bytes. Versions 1 to 4 only support
+// signatures of this length.
 const signatureLength = 64
 const halfSignatureLength = signatureLength / 2
 
-func readString(b *bytes.Buffer) (string, error) {
-	s, err := b.ReadBytes(0)
-	if err == nil {
-		return string(s[0 : len(s)-1]), err
+// The lengths of P-256, P-384 and P-521 signatures supported by version 5.
+var signatureLengths = []int{signatureLength, 96, 132}
+
+// The maximum length of a domain in bytes. Matches the maximum length of a DNS
+// name.
+const maxDomainLength = 253
+
+// The maximum length of a payload in bytes.
+const maxPayloadLength = 1 << 16
+
+// SignatureLengthError is returned when a signature is not exactly
+// signatureLength bytes.
+type SignatureLengthError struct {
+	Length int // The length of the signature found
+}
+
+func (e *SignatureLengthError) Error() string {
+	return fmt.Sprintf(
+		"signature length '%d' not compaitable with '%d' OWID signature "+
+			"length",
+		e.Length,
+		signatureLength)
+}
+
+func readString(b *bytes.Buffer, max int) (string, error) {
+	i := bytes.IndexByte(b.Bytes(), 0)
+	if i < 0 {
+		return "", fmt.Errorf("string not terminated")
+	}
+	if i > max {
+		return "", fmt.Errorf("string length '%d' exceeds '%d'", i, max)
 	}
-	return "", err
+	s := b.Next(i + 1)
+	return string(s[:i]), nil
 }
 
 func readSignature(b *bytes.Buffer) ([]byte, error) {
 	v := b.Next(int(signatureLength))
 	if len(v) != signatureLength {
-		return nil, fmt.Errorf(
-			"signature length '%d' not compaitable with '%d' OWID signature "+
-				"length",
-			len(v),
-			signatureLength)
+		return nil, &SignatureLengthError{len(v)}
 	}
 	return v, nil
 }
 
 func writeSignature(b *bytes.Buffer, v []byte) error {
 	if len(v) != signatureLength {
-		return fmt.Errorf(
-			"provided signature length '%d' not compaitable with '%d' "+
-				"OWID signature length",
-			len(v),
-			signatureLength)
+		return &SignatureLengthError{len(v)}
 	}
 	return writeByteArrayNoLength(b, v)
 }
 
-func readByteArray(b *bytes.Buffer) ([]byte, error) {
+// signatureLengthValid returns true if a signature of the length can be
+// carried by an OWID of the version.
+func signatureLengthValid(v byte, l int) bool {
+	if v < owidVersion5 {
+		return l == signatureLength
+	}
+	for _, s := range signatureLengths {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// readSignatureVersion reads a signature in the layout used by the version.
+// Version 5 signatures are prefixed with a single byte length.
+func readSignatureVersion(b *bytes.Buffer, v byte) ([]byte, error) {
+	if v < owidVersion5 {
+		return readSignature(b)
+	}
+	l, err := readByte(b)
+	if err != nil {
+		return nil, err
+	}
+	if signatureLengthValid(v, int(l)) == false {
+		return nil, &SignatureLengthError{int(l)}
+	}
+	s := b.Next(int(l))
+	if len(s) != int(l) {
+		return nil, &SignatureLengthError{len(s)}
+	}
+	return s, nil
+}
+
+// writeSignatureVersion writes the signature in the layout used by the
+// version.
+func writeSignatureVersion(b *bytes.Buffer, v byte, s []byte) error {
+	if v < owidVersion5 {
+		return writeSignature(b, s)
+	}
+	if signatureLengthValid(v, len(s)) == false {
+		return &SignatureLengthError{len(s)}
+	}
+	err := writeByte(b, byte(len(s)))
+	if err != nil {
+		return err
+	}
+	return writeByteArrayNoLength(b, s)
+}
+
+func readByteArray(b *bytes.Buffer, max uint32) ([]byte, error) {
 	l, err := readUint32(b)
 	if err != nil {
 		return nil, err
 	}
-	return b.Next(int(l)), err
+	if l > max {
+		return nil, fmt.Errorf("byte array length '%d' exceeds '%d'", l, max)
+	}
+	if uint64(l) > uint64(b.Len()) {
+		return nil, fmt.Errorf(
+			"byte array length '%d' exceeds '%d' remaining bytes",
+			l,
+			b.Len())
+	}
+	return b.Next(int(l)), nil
 }
 
 func writeByteArray(b *bytes.Buffer, v []byte) error {
@@ -90,30 +170,13 @@ func writeByteArrayNoLength(b *bytes.Buffer, v []byte) error {
 	return err
 }
 
-func readTime(b *bytes.Buffer) (time.Time, error) {
-	var t time.Time
-	d, err := readByteArray(b)
-	if err == nil {
-		t.GobDecode(d)
-	}
-	return t, err
-}
-
-func writeTime(b *bytes.Buffer, t time.Time) error {
-	d, err := t.GobEncode()
-	if err != nil {
-		return err
-	}
-	return writeByteArray(b, d)
-}
-
 func readDate(b *bytes.Buffer, v byte) (time.Time, error) {
 	switch v {
 	case owidVersion1:
 		return readDateV1(b)
 	case owidVersion2:
 		return readDateV2(b)
-	case owidVersion3:
+	case owidVersion3, owidVersion4, owidVersion5:
 		return readDateV2(b)
 	default:
 		return time.Time{}, fmt.Errorf("Date version '%d' is invalid", v)
@@ -147,7 +210,7 @@ func writeDate(b *bytes.Buffer, t time.Time, v byte) error {
 		return writeDateV1(b, t)
 	case owidVersion2:
 		return writeDateV2(b, t)
-	case owidVersion3:
+	case owidVersion3, owidVersion4, owidVersion5:
 		return writeDateV2(b, t)
 	default:
 		return fmt.Errorf("date version '%d' is invalid", v)
@@ -18,7 +18,9 @@ package owid
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -55,3 +57,38 @@ func testCompareDate(t *testing.T, a time.Time, b time.Time) {
 		t.Fail()
 	}
 }
+
+func TestReadSignatureShort(t *testing.T) {
+	_, err := readSignature(bytes.NewBuffer(make([]byte, halfSignatureLength)))
+	var e *SignatureLengthError
+	if errors.As(err, &e) == false {
+		t.Fatalf("expected SignatureLengthError but found '%v'", err)
+	}
+	if e.Length != halfSignatureLength {
+		t.Fatalf("length '%d' not '%d'", e.Length, halfSignatureLength)
+	}
+}
+
+func TestReadStringTooLong(t *testing.T) {
+	var b bytes.Buffer
+	err := writeString(&b, strings.Repeat("a", maxDomainLength+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readString(&b, maxDomainLength)
+	if err == nil {
+		t.Fatal("string longer than the maximum should error")
+	}
+}
+
+func TestReadByteArrayTooLong(t *testing.T) {
+	var b bytes.Buffer
+	err := writeUint32(&b, maxPayloadLength+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readByteArray(&b, maxPayloadLength)
+	if err == nil {
+		t.Fatal("byte array longer than the maximum should error")
+	}
+}
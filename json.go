@@ -0,0 +1,265 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONStrict when true causes OWID and Creator JSON unmarshalling to reject
+// unknown fields, missing fields and invalid values. Use FromJSON or
+// FromJSONStrict to select the behaviour for a single call.
+var JSONStrict = false
+
+// owidJSON is the explicit JSON representation of an OWID. Pointers are used
+// so that missing fields can be identified in strict mode.
+type owidJSON struct {
+	Version   *byte      `json:"version"`
+	Domain    *string    `json:"domain"`
+	Date      *time.Time `json:"date"`
+	Payload   *[]byte    `json:"payload"`
+	Signature *[]byte    `json:"signature"`
+
+	CoSignatures []*CoSignature `json:"coSignatures"`
+}
+
+// creatorJSON is the explicit JSON representation of a Creator.
+type creatorJSON struct {
+	Domain       *string `json:"domain"`
+	PrivateKey   *string `json:"privateKey"`
+	PublicKey    *string `json:"publicKey"`
+	Name         *string `json:"name"`
+	ContractURL  *string `json:"contractURL"`
+	Created      *string `json:"created"`
+	Email        *string `json:"email"`
+	DpoURL       *string `json:"dpoURL"`
+	Jurisdiction *string `json:"jurisdiction"`
+	Status       *string `json:"status"`
+	Modified     *string `json:"modified"`
+
+	PreferredVersion *byte `json:"preferredVersion"`
+}
+
+// FromJSON creates a single OWID from the JSON using the package level
+// JSONStrict setting.
+func FromJSON(j []byte) (*OWID, error) {
+	var o OWID
+	err := o.unmarshalJSON(j, JSONStrict)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// FromJSONStrict creates a single OWID from the JSON rejecting unknown fields,
+// missing fields and invalid values irrespective of the JSONStrict setting.
+func FromJSONStrict(j []byte) (*OWID, error) {
+	var o OWID
+	err := o.unmarshalJSON(j, true)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// UnmarshalJSON called by json.Unmarshal unmarshals an OWID from JSON using
+// the package level JSONStrict setting.
+func (o *OWID) UnmarshalJSON(b []byte) error {
+	return o.unmarshalJSON(b, JSONStrict)
+}
+
+func (o *OWID) unmarshalJSON(b []byte, strict bool) error {
+	var d owidJSON
+	err := decodeJSON(b, &d, strict, "OWID")
+	if err != nil {
+		return err
+	}
+	if strict {
+		err = requireJSONFields("OWID", map[string]bool{
+			"version":   d.Version != nil,
+			"domain":    d.Domain != nil,
+			"date":      d.Date != nil,
+			"payload":   d.Payload != nil,
+			"signature": d.Signature != nil})
+		if err != nil {
+			return err
+		}
+	}
+	var n OWID
+	if d.Version != nil {
+		n.Version = *d.Version
+	}
+	if d.Domain != nil {
+		n.Domain = *d.Domain
+	}
+	if d.Date != nil {
+		n.Date = *d.Date
+	}
+	if d.Payload != nil {
+		n.Payload = *d.Payload
+	}
+	if d.Signature != nil {
+		n.Signature = *d.Signature
+	}
+	n.CoSignatures = d.CoSignatures
+	if strict {
+		if n.Version < owidVersion1 || n.Version > owidVersion5 {
+			return fmt.Errorf(
+				"OWID field 'version' value '%d' not supported",
+				n.Version)
+		}
+		if n.Domain == "" {
+			return errors.New("OWID field 'domain' must not be empty")
+		}
+		if signatureLengthValid(n.Version, len(n.Signature)) == false {
+			return fmt.Errorf(
+				"OWID field 'signature' length '%d' not valid for version '%d'",
+				len(n.Signature),
+				n.Version)
+		}
+		for _, s := range n.CoSignatures {
+			if s == nil ||
+				signatureLengthValid(n.Version, len(s.Signature)) == false {
+				return errors.New(
+					"OWID field 'coSignatures' contains an invalid signature")
+			}
+		}
+	}
+	*o = n
+	return nil
+}
+
+func (c *Creator) unmarshalJSON(b []byte, strict bool) error {
+	var d creatorJSON
+	err := decodeJSON(b, &d, strict, "Creator")
+	if err != nil {
+		return err
+	}
+	if strict {
+		err = requireJSONFields("Creator", map[string]bool{
+			"domain":     d.Domain != nil,
+			"privateKey": d.PrivateKey != nil,
+			"publicKey":  d.PublicKey != nil,
+			"name":       d.Name != nil})
+		if err != nil {
+			return err
+		}
+	}
+	var created time.Time
+	if d.Created != nil && *d.Created != "" {
+		created, err = time.Parse(time.RFC3339Nano, *d.Created)
+		if err != nil {
+			return fmt.Errorf("Creator field 'created' %s", err.Error())
+		}
+	}
+	var modified time.Time
+	if d.Modified != nil && *d.Modified != "" {
+		modified, err = time.Parse(time.RFC3339Nano, *d.Modified)
+		if err != nil {
+			return fmt.Errorf("Creator field 'modified' %s", err.Error())
+		}
+	}
+	status, err := parseCreatorStatus(stringOrEmpty(d.Status))
+	if err != nil {
+		return fmt.Errorf("Creator field 'status' %s", err.Error())
+	}
+	var version byte
+	if d.PreferredVersion != nil && *d.PreferredVersion != 0 {
+		version = *d.PreferredVersion
+		err = validOWIDVersion(version)
+		if err != nil {
+			return fmt.Errorf(
+				"Creator field 'preferredVersion' %s",
+				err.Error())
+		}
+	}
+	c.domain = stringOrEmpty(d.Domain)
+	c.privateKey = stringOrEmpty(d.PrivateKey)
+	c.publicKey = stringOrEmpty(d.PublicKey)
+	c.name = stringOrEmpty(d.Name)
+	c.contractURL = stringOrEmpty(d.ContractURL)
+	c.created = created
+	c.contact = Contact{
+		Email:        stringOrEmpty(d.Email),
+		DpoURL:       stringOrEmpty(d.DpoURL),
+		Jurisdiction: stringOrEmpty(d.Jurisdiction)}
+	c.status = status
+	c.modified = modified
+	c.version = version
+	c.sign = cryptoOnce{}
+	c.verify = cryptoOnce{}
+	return nil
+}
+
+// decodeJSON decodes the JSON into the value converting errors into messages
+// that name the offending field.
+func decodeJSON(b []byte, v interface{}, strict bool, t string) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	if strict {
+		d.DisallowUnknownFields()
+	}
+	err := d.Decode(v)
+	if err != nil {
+		var te *json.UnmarshalTypeError
+		if errors.As(err, &te) {
+			return fmt.Errorf(
+				"%s field '%s' expected '%s' but found '%s'",
+				t,
+				te.Field,
+				te.Type,
+				te.Value)
+		}
+		if f := strings.TrimPrefix(
+			err.Error(),
+			"json: unknown field "); f != err.Error() {
+			return fmt.Errorf(
+				"%s field '%s' unknown",
+				t,
+				strings.Trim(f, `"`))
+		}
+		return fmt.Errorf("%s JSON %s", t, err.Error())
+	}
+	return nil
+}
+
+// requireJSONFields returns an error naming the first missing field.
+func requireJSONFields(t string, fields map[string]bool) error {
+	var m []string
+	for k, v := range fields {
+		if v == false {
+			m = append(m, k)
+		}
+	}
+	if len(m) > 0 {
+		sort.Strings(m)
+		return fmt.Errorf("%s field '%s' missing", t, m[0])
+	}
+	return nil
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
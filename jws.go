@@ -0,0 +1,165 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jwsHeader is the protected header of an OWID JWS.
+type jwsHeader struct {
+	Alg     string `json:"alg"`  // Always ES256
+	Typ     string `json:"typ"`  // Always JWT
+	Kid     string `json:"kid"`  // Domain of the OWID creator
+	Version byte   `json:"owid"` // Version of the OWID
+}
+
+// jwsClaims is the payload of an OWID JWS.
+type jwsClaims struct {
+	Iss       string `json:"iss"` // Domain of the OWID creator
+	Iat       int64  `json:"iat"` // Date of the OWID as a Unix time
+	Hash      string `json:"hsh"` // SHA-256 of the OWID payload
+	Payload   string `json:"pld"` // OWID payload
+	Signature string `json:"sig"` // OWID signature
+}
+
+// ToJWS returns the OWID as a compact JWS signed with the Crypto instance so
+// that it can be consumed by standard JWT libraries. The protected header
+// carries the domain as the key id and the OWID version. The claims carry the
+// date, the payload and its SHA-256 hash, and the OWID's own signature so
+// that the OWID can be recreated with FromJWS.
+func (o *OWID) ToJWS(c *Crypto) (string, error) {
+	if c.Algorithm() != AlgorithmES256 {
+		return "", fmt.Errorf(
+			"algorithm '%s' not supported for JWS",
+			c.Algorithm())
+	}
+	h, err := json.Marshal(&jwsHeader{
+		Alg:     string(AlgorithmES256),
+		Typ:     "JWT",
+		Kid:     o.Domain,
+		Version: o.Version})
+	if err != nil {
+		return "", err
+	}
+	s := sha256.Sum256(o.Payload)
+	e := base64.RawURLEncoding
+	p, err := json.Marshal(&jwsClaims{
+		Iss:       o.Domain,
+		Iat:       o.Date.Unix(),
+		Hash:      e.EncodeToString(s[:]),
+		Payload:   e.EncodeToString(o.Payload),
+		Signature: e.EncodeToString(o.Signature)})
+	if err != nil {
+		return "", err
+	}
+	i := e.EncodeToString(h) + "." + e.EncodeToString(p)
+	g, err := c.SignByteArray([]byte(i))
+	if err != nil {
+		return "", err
+	}
+	return i + "." + e.EncodeToString(g), nil
+}
+
+// FromJWS returns the OWID from a compact JWS created with ToJWS. The JWS
+// signature is verified with the Crypto instance for the key id from the keys
+// provided, which are keyed on domain. The token is refused if the key's
+// algorithm is not the one in the header. The OWID's own signature is not
+// verified.
+func FromJWS(token string, keys map[string]*Crypto) (*OWID, error) {
+	t := strings.Split(token, ".")
+	if len(t) != 3 {
+		return nil, errors.New("JWS must have three parts")
+	}
+	e := base64.RawURLEncoding
+	var h jwsHeader
+	err := jwsDecode(t[0], &h)
+	if err != nil {
+		return nil, err
+	}
+	if h.Alg != string(AlgorithmES256) {
+		return nil, fmt.Errorf("JWS algorithm '%s' not supported", h.Alg)
+	}
+	c := keys[normalizeDomain(h.Kid)]
+	if c == nil {
+		c = keys[h.Kid]
+	}
+	if c == nil {
+		return nil, fmt.Errorf("JWS key id '%s' not known", h.Kid)
+	}
+	if c.Algorithm() != Algorithm(h.Alg) {
+		return nil, fmt.Errorf(
+			"JWS algorithm '%s' does not match key algorithm '%s'",
+			h.Alg,
+			c.Algorithm())
+	}
+	g, err := e.DecodeString(t[2])
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.VerifyByteArray([]byte(t[0]+"."+t[1]), g)
+	if err != nil {
+		return nil, err
+	}
+	if v == false {
+		return nil, errors.New("JWS signature is not valid")
+	}
+	var p jwsClaims
+	err = jwsDecode(t[1], &p)
+	if err != nil {
+		return nil, err
+	}
+	if sameDomain(p.Iss, h.Kid) == false {
+		return nil, fmt.Errorf(
+			"JWS issuer '%s' does not match key id '%s'",
+			p.Iss,
+			h.Kid)
+	}
+	d, err := e.DecodeString(p.Payload)
+	if err != nil {
+		return nil, err
+	}
+	s := sha256.Sum256(d)
+	if p.Hash != e.EncodeToString(s[:]) {
+		return nil, errors.New("JWS payload hash does not match")
+	}
+	o, err := NewOwid(p.Iss, time.Unix(p.Iat, 0).UTC(), d)
+	if err != nil {
+		return nil, err
+	}
+	o.Version = h.Version
+	o.Signature, err = e.DecodeString(p.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// jwsDecode unmarshals the base 64 URL encoded JSON into v.
+func jwsDecode(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
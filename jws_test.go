@@ -0,0 +1,114 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestJWS converts an OWID to a JWS and back checking the recreated OWID is
+// still valid and that a changed token is refused.
+func TestJWS(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Use the OWID as it would be read so the date has the same precision.
+	b, err := o.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = FromByteArray(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := c.NewCryptoSignOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := o.ToJWS(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := base64.RawURLEncoding.DecodeString(strings.Split(j, ".")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(h, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["alg"] != "ES256" || m["kid"] != testDomain {
+		t.Fatalf("header '%s' not expected", h)
+	}
+	v, err := c.NewCryptoVerifyOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := map[string]*Crypto{testDomain: v}
+	p, err := FromJWS(j, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := p.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) == false {
+		t.Fatal("OWID from JWS differs")
+	}
+	r, err := c.Verify(p)
+	if err != nil || r == false {
+		t.Fatal("OWID from JWS not valid")
+	}
+	if _, err = FromJWS(j, map[string]*Crypto{}); err == nil {
+		t.Error("unknown key id should be refused")
+	}
+	for _, f := range []func() (*Crypto, error){
+		func() (*Crypto, error) {
+			return NewCryptoWithCurve(elliptic.P384())
+		},
+		func() (*Crypto, error) {
+			return NewCryptoHMAC(bytes.Repeat([]byte{1}, minHMACSecretLength))
+		}} {
+		w, err := f()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = FromJWS(j, map[string]*Crypto{testDomain: w})
+		if err == nil ||
+			strings.Contains(err.Error(), "does not match key") == false {
+			t.Errorf("'%s' key should be refused", w.Algorithm())
+		}
+	}
+	x := strings.Split(j, ".")
+	x[1] = x[1][:len(x[1])-2] + "AA"
+	if _, err = FromJWS(strings.Join(x, "."), k); err == nil {
+		t.Error("changed claims should be refused")
+	}
+}
@@ -21,15 +21,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
-	"time"
 )
 
 // Local store implementation for OWID - data is stored in maps in memory and
 // persisted on disk using JSON files.
 type Local struct {
-	timestamp time.Time // The last time the maps were refreshed
-	file      string    // file path
+	file  string     // file path
+	write sync.Mutex // Serializes changes to the file
 	common
 }
 
@@ -47,13 +48,29 @@ func NewLocalStore(file string) (*Local, error) {
 	return &l, nil
 }
 
-// setCreator adds a new Creator to the local store.
+// setCreator adds a new Creator to the local store. Only the creator's record
+// in the file is changed so that quarantined records are kept.
 func (l *Local) setCreator(creator *Creator) error {
-	l.mutex.Lock()
-	l.creators[creator.domain] = creator
-	l.mutex.Unlock()
+	l.write.Lock()
+	defer l.write.Unlock()
 
-	data, err := json.MarshalIndent(&l.creators, "", "\t")
+	m := make(map[string]json.RawMessage)
+	data, err := readLocalStore(l.file)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		err = json.Unmarshal(data, &m)
+		if err != nil {
+			return err
+		}
+	}
+	m[creator.domain], err = creator.MarshalStorageJSON()
+	if err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(m, "", "\t")
 	if err != nil {
 		return err
 	}
@@ -62,23 +79,30 @@ func (l *Local) setCreator(creator *Creator) error {
 	if err != nil {
 		return err
 	}
+	l.putCreator(creator)
 
 	return nil
 }
 
 // GetCreator gets creator for domain from internal map, updating the internal
-// map if the creator is not in the map.
+// map if the creator is not in the map. Domains that are not found are
+// remembered for a short time so that they don't cause a refresh per request.
+// If the update fails the creators already held are used until they are
+// older than the maximum staleness.
 func (l *Local) GetCreator(domain string) (*Creator, error) {
 	c, err := l.common.getCreator(domain)
 	if err != nil {
 		return nil, err
 	}
-	if c == nil {
-		err = l.refresh()
+	if c == nil && l.shouldRefresh(domain) {
+		err = l.refreshError(l.refresh())
 		if err != nil {
 			return nil, err
 		}
 		c, err = l.common.getCreator(domain)
+		if c == nil {
+			l.addMiss(domain)
+		}
 	}
 	return c, err
 }
@@ -87,34 +111,31 @@ func (l *Local) GetCreator(domain string) (*Creator, error) {
 // storage instance.
 func (l *Local) refresh() error {
 	// Fetch the creators
-	cs, err := l.fetchCreators()
+	cs, f, err := l.fetchCreators()
 	if err != nil {
 		return err
 	}
 	// In a single atomic operation update the reference to the creators.
-	l.mutex.Lock()
-	l.creators = cs
-	l.mutex.Unlock()
-	l.timestamp = time.Now()
+	l.setCreators(cs, f)
 
 	return nil
 }
 
 // fetch creators reads the Creators from the persistent JSON files and
-// converts them from a map of storage items to a map of Creators.
-func (l *Local) fetchCreators() (map[string]*Creator, error) {
-	cs := make(map[string]*Creator)
+// converts them from a map of storage items to a map of Creators. Creators
+// that can't be unmarshalled are returned with their errors.
+func (l *Local) fetchCreators() (
+	map[string]*Creator,
+	map[string]error,
+	error) {
 	data, err := readLocalStore(l.file)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	err = json.Unmarshal(data, &cs)
-	if err != nil && len(data) > 0 {
-		return nil, err
+	if len(data) == 0 {
+		return make(map[string]*Creator), nil, nil
 	}
-
-	return cs, nil
+	return unmarshalCreators(data)
 }
 
 // readLocalStore reads the contents of a file and returns the binary data.
@@ -132,18 +153,31 @@ func readLocalStore(file string) ([]byte, error) {
 	return data, nil
 }
 
-// writeLocalStore writes binary data to a file.
+// writeLocalStore writes binary data to a file. The data is written to a
+// temporary file that then replaces the file so that a refresh never reads a
+// partly written file.
 func writeLocalStore(file string, data []byte) error {
 	err := createLocalStore(file)
 	if err != nil {
 		return err
 	}
 
-	err = ioutil.WriteFile(file, data, 0644)
+	t, err := os.CreateTemp(path.Dir(file), path.Base(file)+".*")
 	if err != nil {
 		return err
 	}
-	return nil
+	defer os.Remove(t.Name())
+	_, err = t.Write(data)
+	if err == nil {
+		err = t.Chmod(0644)
+	}
+	if cerr := t.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(t.Name(), file)
 }
 
 // createLocalStore creates the persistent JSON file and any parents specified
@@ -162,3 +196,31 @@ func createLocalStore(file string) error {
 	}
 	return nil
 }
+
+// Suffix added to the file name to form the file that contains the schema
+// version. A separate file is used so the creators file format is unchanged.
+const localSchemaSuffix = ".schema"
+
+func (l *Local) schemaBackend() string { return schemaBackendLocal }
+
+// getSchemaVersion reads the schema version from the file next to the
+// creators file, or zero if there is no such file.
+func (l *Local) getSchemaVersion() (int, error) {
+	b, err := ioutil.ReadFile(l.file + localSchemaSuffix)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// setSchemaVersion writes the schema version to the file next to the creators
+// file.
+func (l *Local) setSchemaVersion(v int) error {
+	return ioutil.WriteFile(
+		l.file+localSchemaSuffix,
+		[]byte(strconv.Itoa(v)),
+		0644)
+}
@@ -0,0 +1,50 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+// Memory store holds creators in memory only. Changes are lost when the
+// process ends. Used by tests and mocks that should not touch the file
+// system, and provided to NewStoreWithError with Configuration.SetStore.
+type Memory struct {
+	common
+}
+
+// NewMemoryStore creates a new instance of Memory containing the creators.
+func NewMemoryStore(creators ...*Creator) *Memory {
+	var m Memory
+	m.init()
+	cs := make(map[string]*Creator, len(creators))
+	for _, c := range creators {
+		cs[c.domain] = c
+	}
+	m.setCreators(cs, nil)
+	return &m
+}
+
+// GetCreator returns the creator for the domain, or nil if there is no
+// creator.
+func (m *Memory) GetCreator(domain string) (*Creator, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.getCreator(domain)
+}
+
+// setCreator adds or replaces the creator.
+func (m *Memory) setCreator(c *Creator) error {
+	m.putCreator(c)
+	return nil
+}
@@ -0,0 +1,54 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"testing"
+)
+
+// TestMemoryStore checks creators provided and added are returned, and that
+// maps already returned are not modified.
+func TestMemoryStore(t *testing.T) {
+	a, err := newTestCreator(testDomain, testOrgName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMemoryStore(a)
+	c, err := m.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != a {
+		t.Fatal("expected creator provided")
+	}
+	cs := m.GetCreators()
+	b, err := newTestCreator("other."+testDomain, testOrgName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.setCreator(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 1 || len(m.GetCreators()) != 2 {
+		t.Fatal("expected original map unchanged")
+	}
+	c, err = m.GetCreator("missing." + testDomain)
+	if err != nil || c != nil {
+		t.Fatal("expected nil for missing creator")
+	}
+}
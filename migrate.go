@@ -0,0 +1,44 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import "fmt"
+
+// MigrateStore copies all the creators, including their private keys, from
+// the source store to the destination store. Creators that already exist in
+// the destination are not altered.
+//
+// Re-encrypting private keys during the copy is not supported. Stores hold
+// private keys as PEM and leave encryption at rest to the storage service, so
+// there is no package level encryption to change. To move the keys into a
+// secrets manager that encrypts them use a CompositeStore as the destination.
+func MigrateStore(src Store, dst Store) error {
+	for d, c := range src.GetCreators() {
+		e, err := dst.GetCreator(d)
+		if err != nil {
+			return fmt.Errorf("destination '%s' %s", d, err.Error())
+		}
+		if e != nil {
+			continue
+		}
+		err = dst.setCreator(c)
+		if err != nil {
+			return fmt.Errorf("destination '%s' %s", d, err.Error())
+		}
+	}
+	return nil
+}
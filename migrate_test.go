@@ -0,0 +1,81 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateStore(t *testing.T) {
+	src := newTestStore()
+	err := src.addCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := NewLocalStore(filepath.Join(t.TempDir(), "owid.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = MigrateStore(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := dst.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := src.GetCreators()[testDomain]
+	if c == nil || c.privateKey != e.privateKey || c.name != e.name {
+		t.Fatal("creator not migrated")
+	}
+	err = MigrateStore(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMigrateStoreComposite checks migrating to a composite store moves the
+// private keys into the key store and not the metadata store.
+func TestMigrateStoreComposite(t *testing.T) {
+	src := newTestStore()
+	err := src.addCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMemoryStore()
+	k := NewKeyStoreFromStore(NewMemoryStore())
+	err = MigrateStore(src, NewCompositeStore(m, k))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := src.GetCreators()[testDomain]
+	c, err := m.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil || c.privateKey != "" || c.name != e.name {
+		t.Fatal("metadata not migrated without the private key")
+	}
+	p, err := k.GetPrivateKey(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != e.privateKey {
+		t.Fatal("private key not migrated to the key store")
+	}
+}
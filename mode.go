@@ -0,0 +1,202 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Service modes that limit the key material a process can use.
+const (
+	ModeFull   = ""       // Sign and verify for every creator in the store
+	ModeSign   = "sign"   // Sign for the configured domains only
+	ModeVerify = "verify" // Verify only, private keys are never returned
+)
+
+// publicOnlyStore is implemented by stores that can remove private keys from
+// creators as they are loaded.
+type publicOnlyStore interface {
+	setPublicOnly()
+}
+
+// Projected store returns a projection of the creators in another store. Only
+// creators for the permitted domains are returned, and when public only the
+// creators are returned without their private keys. Used to run sign only
+// origins and verify only edge nodes from the same store as other services.
+type Projected struct {
+	store      Store
+	publicOnly bool                // True to remove private keys
+	domains    map[string]bool     // Permitted domains, or nil for all
+	mutex      sync.Mutex          // Guards cache
+	cache      map[string]*project // Projections keyed on domain
+}
+
+// project is a cached projection of a creator from the underlying store.
+type project struct {
+	source *Creator // Creator in the underlying store
+	result *Creator // Projection returned
+}
+
+// NewPublicStore returns a projection of the store that never returns private
+// keys. Creators can't be added or changed.
+func NewPublicStore(s Store) *Projected {
+	return &Projected{
+		store:      s,
+		publicOnly: true,
+		cache:      make(map[string]*project)}
+}
+
+// NewDomainStore returns a projection of the store that only returns creators
+// for the domains provided.
+func NewDomainStore(s Store, domains []string) *Projected {
+	p := Projected{
+		store:   s,
+		domains: make(map[string]bool, len(domains)),
+		cache:   make(map[string]*project)}
+	for _, d := range domains {
+		p.domains[normalizeDomain(d)] = true
+	}
+	return &p
+}
+
+// GetCreator returns the projection of the creator for the domain, or nil if
+// there is no creator or the domain is not permitted.
+func (p *Projected) GetCreator(domain string) (*Creator, error) {
+	if p.permitted(domain) == false {
+		return nil, nil
+	}
+	c, err := p.store.GetCreator(domain)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	return p.project(c), nil
+}
+
+// GetCreators returns a map of the projections of the permitted creators keyed
+// on domain.
+func (p *Projected) GetCreators() map[string]*Creator {
+	cs := make(map[string]*Creator)
+	for d, c := range p.store.GetCreators() {
+		if p.permitted(d) {
+			cs[d] = p.project(c)
+		}
+	}
+	return cs
+}
+
+// Quarantined returns the permitted creators excluded by the underlying store.
+func (p *Projected) Quarantined() []*QuarantinedCreator {
+	a := []*QuarantinedCreator{}
+	if q, ok := p.store.(quarantiner); ok {
+		for _, v := range q.Quarantined() {
+			if p.permitted(v.Domain) {
+				a = append(a, v)
+			}
+		}
+	}
+	return a
+}
+
+// setQuarantineHandler passes quarantines of permitted creators from the
+// underlying store to the function.
+func (p *Projected) setQuarantineHandler(f func(q *QuarantinedCreator)) {
+	if q, ok := p.store.(quarantiner); ok {
+		q.setQuarantineHandler(func(v *QuarantinedCreator) {
+			if p.permitted(v.Domain) {
+				f(v)
+			}
+		})
+	}
+}
+
+// setCreator adds or replaces a permitted creator in the underlying store. A
+// public only store can't be changed.
+func (p *Projected) setCreator(c *Creator) error {
+	if p.publicOnly {
+		return fmt.Errorf("can't set creator '%s' in a verify only store",
+			c.domain)
+	}
+	if p.permitted(c.domain) == false {
+		return fmt.Errorf("domain '%s' not permitted in this store", c.domain)
+	}
+	return p.store.setCreator(c)
+}
+
+// permitted returns true if creators for the domain can be returned.
+func (p *Projected) permitted(domain string) bool {
+	return p.domains == nil || p.domains[normalizeDomain(domain)]
+}
+
+// project returns the projection of the creator. Projections are cached so
+// that the Crypto instances are not recreated for every request.
+func (p *Projected) project(c *Creator) *Creator {
+	if p.publicOnly == false {
+		return c
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	v, ok := p.cache[c.domain]
+	if ok == false || v.source != c {
+		v = &project{source: c, result: c.withoutPrivateKey()}
+		p.cache[c.domain] = v
+	}
+	return v.result
+}
+
+// withoutPrivateKey returns a copy of the creator that can only verify. The
+// signature of the public information is created before the private key is
+// removed so that the public information can still be served.
+func (c *Creator) withoutPrivateKey() *Creator {
+	n := newCreator(
+		c.domain,
+		"",
+		c.publicKey,
+		c.name,
+		c.contractURL,
+		c.created,
+		c.contact,
+		c.status)
+	n.clock = c.clock
+	n.version = c.version
+	n.modified = c.modified
+	n.signature = c.signature
+	if c.privateKey != "" {
+		p, err := publicCreator(c)
+		if err == nil {
+			n.signature = p.Signature
+		}
+	}
+	return n
+}
+
+// projectStore applies the configured mode to the store. In verify mode stores
+// that support it also remove private keys as creators are loaded so that
+// they are not held in memory, and the projection removes them from any other
+// store.
+func (c *Configuration) projectStore(s Store) Store {
+	switch c.Mode {
+	case ModeVerify:
+		if p, ok := s.(publicOnlyStore); ok {
+			p.setPublicOnly()
+		}
+		return NewPublicStore(s)
+	case ModeSign:
+		return NewDomainStore(s, c.SignDomains)
+	}
+	return s
+}
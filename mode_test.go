@@ -0,0 +1,198 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+// TestModeVerify checks a verify only store never returns private keys, can
+// verify OWIDs and refuses to sign or add creators.
+func TestModeVerify(t *testing.T) {
+	ts := newTestStore()
+	err := ts.addCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ts.GetCreators()[testDomain]
+	c := Configuration{Mode: ModeVerify}
+	c.SetStore(ts)
+	s, err := NewStoreWithError(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.privateKey != "" {
+		t.Fatal("private key should not be returned")
+	}
+	for _, v := range s.GetCreators() {
+		if v.privateKey != "" {
+			t.Fatal("private key should not be returned")
+		}
+	}
+	q, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != q {
+		t.Fatal("projection should be cached")
+	}
+	o, err := a.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := p.Verify(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok == false {
+		t.Fatal("OWID should verify")
+	}
+	_, err = p.CreateOWIDandSign([]byte(testPayload))
+	if err == nil {
+		t.Fatal("verify only creator should not sign")
+	}
+	err = s.setCreator(ts.GetCreators()[testDomain])
+	if err == nil {
+		t.Fatal("verify only store should not change")
+	}
+}
+
+// TestModeVerifyLoad checks the underlying store does not hold private keys
+// for the creators loaded before or after the verify only store is created.
+func TestModeVerifyLoad(t *testing.T) {
+	ts := newTestStore()
+	err := ts.addCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Configuration{Mode: ModeVerify}
+	c.SetStore(ts)
+	_, err = NewStoreWithError(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ts.addCreator("other.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.GetCreators()) != 2 {
+		t.Fatal("expected two creators")
+	}
+	for d, v := range ts.GetCreators() {
+		if v.privateKey != "" {
+			t.Fatalf("private key for '%s' held by the store", d)
+		}
+		p, err := publicCreator(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ok, err := p.VerifySelfSignature()
+		if err != nil || ok == false {
+			t.Fatalf("public information for '%s' not valid", d)
+		}
+	}
+}
+
+// TestModeVerifyHandlerCreator checks the public information of a creator in
+// a verify only store is served with a valid signature.
+func TestModeVerifyHandlerCreator(t *testing.T) {
+	ts := newTestStore()
+	err := ts.addCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig("appsettings.test.none.json")
+	c.Mode = ModeVerify
+	c.SetStore(ts)
+	v, err := NewStoreWithError(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServices(c, v, NewAccessSimple([]string{"key1"}))
+	rr := send(t, HandlerCreator(s), testDomain, wellKnownPath, url.Values{})
+	if rr == nil {
+		t.Fatal("public information not returned in verify mode")
+	}
+	var p PublicCreator
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := p.VerifySelfSignature()
+	if err != nil || ok == false {
+		t.Fatal("signature not valid")
+	}
+}
+
+// TestModeSign checks a sign only store only returns creators for the
+// configured domains.
+func TestModeSign(t *testing.T) {
+	ts := newTestStore()
+	for _, d := range []string{testDomain, "other.com"} {
+		err := ts.addCreator(d, testOrgName, registerContractURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := Configuration{Mode: ModeSign, SignDomains: []string{testDomain}}
+	c.SetStore(ts)
+	s, err := NewStoreWithError(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.GetCreator("other.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != nil {
+		t.Fatal("creator for other domain should not be returned")
+	}
+	if len(s.GetCreators()) != 1 {
+		t.Fatal("expected one creator")
+	}
+}
+
+// TestModeValidate checks unknown modes and sign mode without domains are
+// refused.
+func TestModeValidate(t *testing.T) {
+	for _, c := range []Configuration{
+		{Mode: "edge"},
+		{Mode: ModeSign}} {
+		c.BackgroundColor = "white"
+		c.MessageColor = "black"
+		c.Scheme = "https"
+		c.OwidFile = "creators.json"
+		err := c.Validate()
+		if err == nil {
+			t.Fatalf("mode '%s' should be refused", c.Mode)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// The maximum number of co-signatures an OWID can carry.
+const maxCoSignatures = 16
+
+// CoSignature is a signature from another domain over the same data as the
+// OWID's own signature. For example a publisher and a CMP can both attest to
+// the same payload and date.
+type CoSignature struct {
+	Domain    string `json:"domain"`    // Domain of the co-signing creator
+	Signature []byte `json:"signature"` // Signature over the OWID's signed data
+}
+
+// NewMultiSignatureOwid creates a new unsigned OWID that can carry
+// co-signatures. The version is part of the signed data so must be set before
+// the OWID is signed.
+func NewMultiSignatureOwid(
+	domain string,
+	date time.Time,
+	payload []byte) (*OWID, error) {
+	o, err := NewOwid(domain, date, payload)
+	if err != nil {
+		return nil, err
+	}
+	o.Version = owidVersion4
+	return o, nil
+}
+
+// CreateMultiSignatureOWID returns a new unsigned OWID from the creator that
+// other creators can co-sign. Use NewMultiSignatureOwid and set the version to
+// 5 if co-signers use curves other than P-256.
+func (c *Creator) CreateMultiSignatureOWID(payload []byte) (*OWID, error) {
+	o, err := NewMultiSignatureOwid(c.domain, clockNow(c.clock), payload)
+	if err != nil {
+		return nil, err
+	}
+	c.upgradeVersion(o)
+	return o, nil
+}
+
+// CoSign adds a signature from the creator over the same data as the OWID's
+// own signature. The OWID must be a multi-signature OWID from another domain
+// that the creator has not already co-signed.
+func (c *Creator) CoSign(o *OWID, others ...*OWID) error {
+	if c.status == CreatorSuspended {
+		return &SuspendedError{Domain: c.domain}
+	}
+	if c.status == CreatorPending {
+		return &PendingError{Domain: c.domain}
+	}
+	if o.Version < owidVersion4 {
+		return fmt.Errorf(
+			"OWID version '%d' does not support co-signatures",
+			o.Version)
+	}
+	if sameDomain(c.domain, o.Domain) {
+		return fmt.Errorf(
+			"creator '%s' can't co-sign its own OWID",
+			c.domain)
+	}
+	for _, s := range o.CoSignatures {
+		if sameDomain(c.domain, s.Domain) {
+			return fmt.Errorf("OWID already co-signed by '%s'", c.domain)
+		}
+	}
+	if len(o.CoSignatures) >= maxCoSignatures {
+		return fmt.Errorf(
+			"OWID already has the maximum '%d' co-signatures",
+			maxCoSignatures)
+	}
+	x, err := c.NewCryptoSignOnly()
+	if err != nil {
+		return err
+	}
+	if signatureLengthValid(o.Version, x.signatureLength()) == false {
+		return fmt.Errorf(
+			"OWID version '%d' can't carry '%s' co-signatures",
+			o.Version,
+			x.Algorithm())
+	}
+	h, err := o.hashForCrypto(others)
+	if err != nil {
+		return err
+	}
+	s, err := x.signHash(h[:])
+	if err != nil {
+		return err
+	}
+	o.CoSignatures = append(o.CoSignatures, &CoSignature{
+		Domain:    c.domain,
+		Signature: s})
+	return nil
+}
+
+// coSigner returns a view of the OWID for the co-signature that has the
+// co-signer's domain and signature but verifies against the data signed by
+// the OWID.
+func (o *OWID) coSigner(s *CoSignature) *OWID {
+	return &OWID{
+		Version:   o.Version,
+		Domain:    s.Domain,
+		Date:      o.Date,
+		Payload:   o.Payload,
+		Signature: s.Signature,
+		cosigned:  o}
+}
+
+// signers returns the OWID followed by a view for each co-signature.
+func (o *OWID) signers() []*OWID {
+	a := []*OWID{o}
+	for _, s := range o.CoSignatures {
+		a = append(a, o.coSigner(s))
+	}
+	return a
+}
+
+// VerifyAll returns true if the OWID's signature and every co-signature were
+// signed by the creators of their domains. An error is returned for the first
+// signer that can't be verified.
+func (v *Verifier) VerifyAll(o *OWID, others ...*OWID) (bool, error) {
+	for _, s := range o.signers() {
+		r, err := v.Verify(s, others...)
+		if err != nil {
+			return false, err
+		}
+		if r == false {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// VerifyAny returns true if the OWID's signature or any co-signature was
+// signed by the creator of its domain. Errors are only returned if no
+// signature verified.
+func (v *Verifier) VerifyAny(o *OWID, others ...*OWID) (bool, error) {
+	var first error
+	for _, s := range o.signers() {
+		r, err := v.Verify(s, others...)
+		if r && err == nil {
+			return true, nil
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return false, first
+}
+
+func readCoSignatures(b *bytes.Buffer, v byte) ([]*CoSignature, error) {
+	n, err := readByte(b)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxCoSignatures {
+		return nil, fmt.Errorf(
+			"co-signatures '%d' exceeds '%d'",
+			n,
+			maxCoSignatures)
+	}
+	var a []*CoSignature
+	for i := byte(0); i < n; i++ {
+		var s CoSignature
+		s.Domain, err = readString(b, maxDomainLength)
+		if err != nil {
+			return nil, err
+		}
+		s.Signature, err = readSignatureVersion(b, v)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, &s)
+	}
+	return a, nil
+}
+
+func writeCoSignatures(b *bytes.Buffer, v byte, a []*CoSignature) error {
+	if len(a) > maxCoSignatures {
+		return fmt.Errorf(
+			"co-signatures '%d' exceeds '%d'",
+			len(a),
+			maxCoSignatures)
+	}
+	err := writeByte(b, byte(len(a)))
+	if err != nil {
+		return err
+	}
+	for _, s := range a {
+		err = writeString(b, s.Domain)
+		if err != nil {
+			return err
+		}
+		err = writeSignatureVersion(b, v, s.Signature)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
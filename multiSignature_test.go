@@ -0,0 +1,118 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestMultiSignature co-signs an OWID from a second domain and checks the
+// co-signature survives a round trip and is verified by VerifyAll and
+// VerifyAny.
+func TestMultiSignature(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := http.NewServeMux()
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	var cs []*Creator
+	for i := 0; i < 2; i++ {
+		h := httptest.NewServer(m)
+		defer h.Close()
+		u, err := url.Parse(h.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.store.setCreator(c)
+		cs = append(cs, c)
+	}
+	o, err := cs[0].CreateMultiSignatureOWID([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cs[0].Sign(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cs[1].CoSign(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs[1].CoSign(o) == nil {
+		t.Fatal("second co-signature from the same domain should error")
+	}
+	if cs[0].CoSign(o) == nil {
+		t.Fatal("co-signing own OWID should error")
+	}
+	b, err := o.AsBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = FromBase64(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.CoSignatures) != 1 || o.CoSignatures[0].Domain != cs[1].domain {
+		t.Fatal("co-signature not read")
+	}
+	v := NewVerifier("http", nil)
+	r, err := v.VerifyAll(o)
+	if err != nil || r == false {
+		t.Fatalf("OWID with co-signature not verified '%v'", err)
+	}
+	o.CoSignatures[0].Signature[0] ^= 0xff
+	r, err = v.VerifyAll(o)
+	if err != nil || r {
+		t.Fatalf("OWID with invalid co-signature verified '%v'", err)
+	}
+	r, err = v.VerifyAny(o)
+	if err != nil || r == false {
+		t.Fatalf("OWID with valid signature not verified '%v'", err)
+	}
+	o.Signature[0] ^= 0xff
+	r, _ = v.VerifyAny(o)
+	if r {
+		t.Fatal("OWID with no valid signatures verified")
+	}
+}
+
+// TestCoSignVersion checks OWIDs that can't carry co-signatures are refused.
+func TestCoSignVersion(t *testing.T) {
+	a, err := newTestCreator("a.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newTestCreator("b.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := a.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.CoSign(o) == nil {
+		t.Fatal("version 3 OWID should not accept co-signatures")
+	}
+}
@@ -0,0 +1,107 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import "time"
+
+// The default time a domain that is not in the store is remembered, and the
+// minimum time between refreshes caused by such domains.
+const defaultNegativeTTL = 30 * time.Second
+
+// The maximum number of domains remembered as not being in the store. The
+// entries are discarded when the limit is reached.
+const negativeCacheMax = 10000
+
+// negativeCacher is implemented by stores that remember domains that are not
+// in the store so that junk host headers can't cause a refresh per request.
+type negativeCacher interface {
+
+	// setNegativeTTL sets the time a missing domain is remembered. Zero uses
+	// the default and a negative value disables the cache.
+	setNegativeTTL(d time.Duration)
+}
+
+// setNegativeTTL sets the time a missing domain is remembered.
+func (c *common) setNegativeTTL(d time.Duration) { c.negativeTTL = d }
+
+// shouldRefresh returns true if the store should be refreshed to look for the
+// domain. False is returned if the domain was recently found to be missing,
+// or if a refresh for any missing domain happened within the TTL, so that a
+// stream of different unregistered domains causes at most one refresh per
+// TTL.
+func (c *common) shouldRefresh(domain string) bool {
+	t := c.negativeTTL
+	if t == 0 {
+		t = defaultNegativeTTL
+	}
+	if t < 0 {
+		return true
+	}
+	n := time.Now()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.misses[domain]; ok && n.Before(e) {
+		return false
+	}
+	if n.Sub(c.missRefreshed) < t {
+		c.addMissLocked(domain, n.Add(t))
+		return false
+	}
+	c.missRefreshed = n
+	return true
+}
+
+// addMiss remembers that the domain was not found after a refresh.
+func (c *common) addMiss(domain string) {
+	t := c.negativeTTL
+	if t == 0 {
+		t = defaultNegativeTTL
+	}
+	if t < 0 {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.addMissLocked(domain, time.Now().Add(t))
+}
+
+// addMissLocked remembers the domain is missing until the expiry time. The
+// mutex must be held.
+func (c *common) addMissLocked(domain string, expires time.Time) {
+	if c.misses == nil || len(c.misses) >= negativeCacheMax {
+		c.misses = make(map[string]time.Time)
+	}
+	c.misses[domain] = expires
+}
+
+// setNegativeTTL sets the time missing domains are remembered by the primary
+// and replicas.
+func (r *Replicated) setNegativeTTL(d time.Duration) {
+	for _, s := range append([]Store{r.primary}, r.replicas...) {
+		if v, ok := s.(negativeCacher); ok {
+			v.setNegativeTTL(d)
+		}
+	}
+}
+
+// setNegativeTTL sets the time missing domains are remembered by the
+// underlying store.
+func (p *Projected) setNegativeTTL(d time.Duration) {
+	if v, ok := p.store.(negativeCacher); ok {
+		v.setNegativeTTL(d)
+	}
+}
@@ -0,0 +1,70 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestNegativeCache checks that missing domains don't cause a refresh until
+// the TTL has passed, whether the same domain or a different one is requested.
+func TestNegativeCache(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "creators.json")
+	l, err := NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.setNegativeTTL(50 * time.Millisecond)
+	c, err := l.GetCreator("a.com")
+	if err != nil || c != nil {
+		t.Fatal("a.com should not be found")
+	}
+
+	// Add creators to the file with another store so that only a refresh
+	// finds them.
+	w, err := NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"a.com", "b.com"} {
+		n, err := newTestCreator(d, testOrgName, registerContractURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.setCreator(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, d := range []string{"a.com", "b.com"} {
+		c, err = l.GetCreator(d)
+		if err != nil || c != nil {
+			t.Fatalf("'%s' should not be refreshed within the TTL", d)
+		}
+	}
+	time.Sleep(60 * time.Millisecond)
+	c, err = l.GetCreator("a.com")
+	if err != nil || c == nil {
+		t.Fatal("a.com should be found after the TTL")
+	}
+	c, err = l.GetCreator("b.com")
+	if err != nil || c == nil {
+		t.Fatal("b.com should be found after the refresh")
+	}
+}
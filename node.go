@@ -19,6 +19,7 @@ package owid
 import (
 	"container/list"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 )
@@ -41,6 +42,24 @@ func (n *Node) GetOWID() (*OWID, error) {
 	return FromByteArray(n.OWID)
 }
 
+// VerifyOWID returns true if the OWID associated with the node was signed by
+// the creator for its domain. The OWID of the parent node, if present, is
+// included in the signed data as it is with HandlerVerify.
+func (n *Node) VerifyOWID(v *Verifier) (bool, error) {
+	o, err := n.GetOWID()
+	if err != nil {
+		return false, err
+	}
+	if n.parent == nil || len(n.parent.OWID) == 0 {
+		return v.Verify(o)
+	}
+	p, err := n.parent.GetOWID()
+	if err != nil {
+		return false, err
+	}
+	return v.Verify(o, p)
+}
+
 // GetOWIDAsString returns the OWID as a base 64 string.
 func (n *Node) GetOWIDAsString() string {
 	o, err := n.GetOWID()
@@ -113,6 +132,72 @@ func (n *Node) Find(condition func(n *Node) bool) *Node {
 	return nil
 }
 
+// FindAll returns all the Nodes that match the condition in breadth first
+// order.
+func (n *Node) FindAll(condition func(n *Node) bool) []*Node {
+	var r []*Node
+	q := list.New()
+	q.PushBack(n)
+	for q.Len() > 0 {
+		n = dequeue(q)
+		if condition(n) {
+			r = append(r, n)
+		}
+		for _, c := range n.Children {
+			q.PushBack(c)
+		}
+	}
+	return r
+}
+
+// WalkOrder determines when a node is visited relative to its children.
+type WalkOrder int
+
+const (
+	// WalkPreOrder visits a node before its children.
+	WalkPreOrder WalkOrder = iota
+
+	// WalkPostOrder visits a node after its children.
+	WalkPostOrder
+)
+
+// ErrStopWalk can be returned by the function passed to Walk to stop the walk
+// without Walk returning an error.
+var ErrStopWalk = errors.New("stop walk")
+
+// Walk visits this node and all the descendents depth first calling fn with
+// each node and its depth relative to this node. If fn returns an error the
+// walk stops and the error is returned, unless the error is ErrStopWalk.
+func (n *Node) Walk(order WalkOrder, fn func(n *Node, depth int) error) error {
+	err := n.walk(order, fn, 0)
+	if err == ErrStopWalk {
+		return nil
+	}
+	return err
+}
+
+func (n *Node) walk(
+	order WalkOrder,
+	fn func(n *Node, depth int) error,
+	depth int) error {
+	if order == WalkPreOrder {
+		err := fn(n, depth)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range n.Children {
+		err := c.walk(order, fn, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	if order == WalkPostOrder {
+		return fn(n, depth)
+	}
+	return nil
+}
+
 // AddChild adds the child to the children of this Node returning the index of
 // the child. Returns the index of the added child.
 func (n *Node) AddChild(child *Node) (uint32, error) {
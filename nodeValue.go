@@ -0,0 +1,150 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ValueCodec converts a Node value to and from JSON so that the value has the
+// same Go type after the tree is unmarshalled.
+type ValueCodec interface {
+
+	// Marshal returns the JSON for the value.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal returns the value from the JSON.
+	Unmarshal(b []byte) (interface{}, error)
+}
+
+// valueCodec is a registered codec and the name recorded in the JSON.
+type valueCodec struct {
+	name  string
+	codec ValueCodec
+}
+
+var valueCodecs = struct {
+	mutex  sync.RWMutex
+	byType map[reflect.Type]*valueCodec
+	byName map[string]*valueCodec
+}{
+	byType: make(map[reflect.Type]*valueCodec),
+	byName: make(map[string]*valueCodec)}
+
+// RegisterValueCodec registers the codec used for Node values of type t. The
+// name is recorded with the value in the JSON and must be unique.
+func RegisterValueCodec(name string, t reflect.Type, c ValueCodec) error {
+	valueCodecs.mutex.Lock()
+	defer valueCodecs.mutex.Unlock()
+	if _, ok := valueCodecs.byName[name]; ok {
+		return fmt.Errorf("value codec '%s' already registered", name)
+	}
+	v := &valueCodec{name: name, codec: c}
+	valueCodecs.byType[t] = v
+	valueCodecs.byName[name] = v
+	return nil
+}
+
+// RegisterValueType registers a codec that uses encoding/json for Node values
+// of type T.
+func RegisterValueType[T any](name string) error {
+	var v T
+	return RegisterValueCodec(name, reflect.TypeOf(v), jsonValueCodec[T]{})
+}
+
+// jsonValueCodec uses encoding/json to marshal values of type T.
+type jsonValueCodec[T any] struct{}
+
+func (jsonValueCodec[T]) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonValueCodec[T]) Unmarshal(b []byte) (interface{}, error) {
+	var v T
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func valueCodecForType(v interface{}) *valueCodec {
+	valueCodecs.mutex.RLock()
+	defer valueCodecs.mutex.RUnlock()
+	return valueCodecs.byType[reflect.TypeOf(v)]
+}
+
+func valueCodecForName(name string) *valueCodec {
+	valueCodecs.mutex.RLock()
+	defer valueCodecs.mutex.RUnlock()
+	return valueCodecs.byName[name]
+}
+
+// nodeJSON is the JSON representation of a Node. ValueType is only present if
+// the value has a registered codec.
+type nodeJSON struct {
+	OWID      []byte
+	Children  []*Node
+	Value     json.RawMessage
+	ValueType string `json:",omitempty"`
+}
+
+// MarshalJSON marshals the node using the registered codec for the value if
+// there is one.
+func (n *Node) MarshalJSON() ([]byte, error) {
+	d := nodeJSON{OWID: n.OWID, Children: n.Children}
+	var err error
+	if c := valueCodecForType(n.Value); c != nil {
+		d.ValueType = c.name
+		d.Value, err = c.codec.Marshal(n.Value)
+	} else {
+		d.Value, err = json.Marshal(n.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&d)
+}
+
+// UnmarshalJSON unmarshals the node using the registered codec for the value
+// type if there is one. Values without a registered codec are unmarshalled as
+// generic JSON values.
+func (n *Node) UnmarshalJSON(b []byte) error {
+	var d nodeJSON
+	err := json.Unmarshal(b, &d)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if len(d.Value) > 0 {
+		if c := valueCodecForName(d.ValueType); c != nil {
+			v, err = c.codec.Unmarshal(d.Value)
+		} else {
+			err = json.Unmarshal(d.Value, &v)
+		}
+		if err != nil {
+			return fmt.Errorf("node value '%s' %s", d.ValueType, err.Error())
+		}
+	}
+	n.OWID = d.OWID
+	n.Children = d.Children
+	n.Value = v
+	return nil
+}
@@ -0,0 +1,140 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"sync"
+)
+
+// NodeResult is the outcome of verifying the OWID of a single node.
+type NodeResult struct {
+	Valid bool  // True if the OWID is valid
+	Err   error // Error preventing verification, or nil
+}
+
+// nodeJob is a node waiting to be verified with its OWID and any parent OWID.
+type nodeJob struct {
+	index  string
+	owid   *OWID
+	others []*OWID // The parent OWID, or empty for the root
+}
+
+// VerifyTreeConcurrent verifies the OWIDs of this node and all descendents
+// using at most workers goroutines. Each OWID is verified as Verifier.Verify
+// does except that the public key for each domain is fetched once and reused
+// for every node with that domain. The results are keyed on
+// the node index as returned by GetIndexAsString. Nodes without an OWID are
+// not included.
+func (n *Node) VerifyTreeConcurrent(
+	ctx context.Context,
+	v *Verifier,
+	workers int) map[string]*NodeResult {
+	if workers < 1 {
+		workers = 1
+	}
+	r := make(map[string]*NodeResult)
+	var jobs []*nodeJob
+	n.Walk(WalkPreOrder, func(i *Node, d int) error {
+		if len(i.OWID) == 0 {
+			return nil
+		}
+		j, err := i.newNodeJob()
+		if err != nil {
+			r[i.GetIndexAsString()] = &NodeResult{Err: err}
+		} else {
+			jobs = append(jobs, j)
+		}
+		return nil
+	})
+
+	// Fetch the public key for each domain once.
+	keys := make(map[string]*domainKey)
+	for _, j := range jobs {
+		if keys[j.owid.Domain] == nil {
+			keys[j.owid.Domain] = &domainKey{verifier: v}
+		}
+	}
+
+	var m sync.Mutex
+	var w sync.WaitGroup
+	q := make(chan *nodeJob)
+	for i := 0; i < workers; i++ {
+		w.Add(1)
+		go func() {
+			defer w.Done()
+			for j := range q {
+				var s NodeResult
+				if err := ctx.Err(); err != nil {
+					s.Err = err
+				} else {
+					p, err := v.verifyWithReport(
+						ctx,
+						j.owid,
+						j.others,
+						keys[j.owid.Domain].fetch)
+					s.Valid = err == nil && p.Valid
+					s.Err = err
+				}
+				m.Lock()
+				r[j.index] = &s
+				m.Unlock()
+			}
+		}()
+	}
+	for _, j := range jobs {
+		q <- j
+	}
+	close(q)
+	w.Wait()
+	return r
+}
+
+// domainKey fetches the public key for a domain once.
+type domainKey struct {
+	verifier *Verifier
+	once     sync.Once
+	report   VerifyReport // Outcome of fetching the key
+	err      error
+}
+
+// fetch is a keyFetcher that fetches the key the first time it is called and
+// copies the outcome to the report every time.
+func (k *domainKey) fetch(ctx context.Context, o *OWID, r *VerifyReport) error {
+	k.once.Do(func() {
+		k.err = k.verifier.fetchPublicKeyWithRetry(ctx, o, &k.report)
+	})
+	r.setKey(&k.report)
+	return k.err
+}
+
+func (n *Node) newNodeJob() (*nodeJob, error) {
+	var err error
+	j := nodeJob{index: n.GetIndexAsString()}
+	j.owid, err = n.GetOWID()
+	if err != nil {
+		return nil, err
+	}
+	if n.parent != nil && len(n.parent.OWID) > 0 {
+		p, err := n.parent.GetOWID()
+		if err != nil {
+			return nil, err
+		}
+		j.others = []*OWID{p}
+	}
+	return &j, nil
+}
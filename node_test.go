@@ -0,0 +1,377 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testBid struct {
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+}
+
+func TestNodeValueCodec(t *testing.T) {
+	err := RegisterValueType[testBid]("testBid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RegisterValueType[testBid]("testBid")
+	if err == nil {
+		t.Fatal("registering the same name twice should error")
+	}
+	r := &Node{Value: "root"}
+	_, err = r.AddChild(&Node{Value: testBid{1.5, "GBP"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := r.AsJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := NodeFromJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Value != "root" {
+		t.Fatalf("root value '%v' not expected", n.Value)
+	}
+	b, ok := n.Children[0].Value.(testBid)
+	if ok == false {
+		t.Fatalf("child value type '%T' not expected", n.Children[0].Value)
+	}
+	if b.Price != 1.5 || b.Currency != "GBP" {
+		t.Fatal("child value not expected")
+	}
+	if n.Children[0].GetParent() != n {
+		t.Fatal("parent not set")
+	}
+}
+
+// newTestTree returns a tree where each node's value is its name.
+//
+//	a
+//	├── b
+//	│   └── d
+//	└── c
+func newTestTree(t *testing.T) *Node {
+	a := &Node{Value: "a"}
+	b := &Node{Value: "b"}
+	for _, p := range []struct{ p, c *Node }{
+		{a, b},
+		{a, &Node{Value: "c"}},
+		{b, &Node{Value: "d"}}} {
+		_, err := p.p.AddChild(p.c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a
+}
+
+func TestNodeWalk(t *testing.T) {
+	r := newTestTree(t)
+	for _, c := range []struct {
+		order    WalkOrder
+		expected string
+	}{
+		{WalkPreOrder, "a0b1d2c1"},
+		{WalkPostOrder, "d2b1c1a0"}} {
+		var s string
+		err := r.Walk(c.order, func(n *Node, d int) error {
+			s += fmt.Sprintf("%s%d", n.Value, d)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != c.expected {
+			t.Fatalf("walk '%s' not '%s'", s, c.expected)
+		}
+	}
+	var s string
+	err := r.Walk(WalkPreOrder, func(n *Node, d int) error {
+		s += n.Value.(string)
+		if n.Value == "d" {
+			return ErrStopWalk
+		}
+		return nil
+	})
+	if err != nil || s != "abd" {
+		t.Fatalf("stopped walk '%s' not 'abd'", s)
+	}
+}
+
+func TestNodeFindAll(t *testing.T) {
+	f := newTestTree(t).FindAll(func(n *Node) bool {
+		return n.Value != "b"
+	})
+	var s string
+	for _, n := range f {
+		s += n.Value.(string)
+	}
+	if s != "acd" {
+		t.Fatalf("found '%s' not 'acd'", s)
+	}
+}
+
+// TestNodeVerifyOWID verifies the OWID of a child node that was signed with
+// the parent's OWID.
+func TestNodeVerifyOWID(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	p, err := c.CreateOWIDandSign([]byte("parent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte("child"), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Node{}
+	r.OWID, err = p.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := r.AddOWID(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(u.Scheme, nil)
+	for _, i := range []*Node{r, n} {
+		ok, err := i.VerifyOWID(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok == false {
+			t.Fatalf("node '%s' OWID not valid", i.GetIndexAsString())
+		}
+	}
+}
+
+// TestNodeVerifyTreeConcurrent verifies a tree and checks the public
+// information is fetched once for the domain.
+func TestNodeVerifyTreeConcurrent(t *testing.T) {
+	var f int32
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	w := HandlerCreator(s)
+	m.HandleFunc(wellKnownPath, func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&f, 1)
+		w(rw, r)
+	})
+	p, err := c.CreateOWIDandSign([]byte("parent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Node{}
+	r.OWID, err = p.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		o, err := c.CreateOWIDandSign([]byte(fmt.Sprintf("child %d", i)), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = r.AddOWID(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Children[3].OWID[len(r.Children[3].OWID)-1]++
+	var n int32
+	x := NewVerifier(u.Scheme, nil)
+	x.SetVerifyHook(VerifyHookFunc(func(string, int, bool, error) {
+		atomic.AddInt32(&n, 1)
+	}))
+	v := r.VerifyTreeConcurrent(context.Background(), x, 4)
+	if len(v) != 11 {
+		t.Fatalf("'%d' results not 11", len(v))
+	}
+	for k, i := range v {
+		if i.Err != nil {
+			t.Fatal(i.Err)
+		}
+		if i.Valid != (k != "3") {
+			t.Fatalf("node '%s' valid '%t' not expected", k, i.Valid)
+		}
+	}
+	if f != 1 {
+		t.Fatalf("public information fetched '%d' times", f)
+	}
+	if n != 11 {
+		t.Fatalf("verify hook called '%d' times", n)
+	}
+}
+
+// TestNodeVerifyTreeConcurrentKeyType checks a domain that publishes a key
+// that is not ECDSA results in errors rather than a panic, and that the
+// verifier's checks are applied to each node.
+func TestNodeVerifyTreeConcurrentKeyType(t *testing.T) {
+	var u *url.URL
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			j, _ := json.Marshal(&PublicCreator{
+				Domain:        u.Host,
+				PublicKeySPKI: newEd25519PublicPem(t)})
+			w.Write(j)
+		}))
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.CreateOWIDandSign([]byte("parent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Node{}
+	r.OWID, err = p.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte("child"), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Date = time.Now().Add(time.Hour)
+	_, err = r.AddOWID(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := r.VerifyTreeConcurrent(context.Background(), NewVerifier(u.Scheme, nil), 2)
+	if v[""].Err == nil || v[""].Valid {
+		t.Fatal("OWID verified with an Ed25519 key")
+	}
+	if v["0"].Err == nil ||
+		strings.Contains(v["0"].Err.Error(), "future") == false {
+		t.Fatalf("future OWID not refused, found '%v'", v["0"].Err)
+	}
+}
+
+// benchmarkTrees runs the function as a sub-benchmark for trees of OWIDs with
+// different numbers of nodes.
+func benchmarkTrees(b *testing.B, f func(b *testing.B, n *Node)) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, s := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("nodes=%d", s), func(b *testing.B) {
+			r := &Node{Value: o}
+			q := []*Node{r}
+			for i := 1; i < s; i++ {
+				n, err := q[0].AddOWID(o)
+				if err != nil {
+					b.Fatal(err)
+				}
+				q = append(q, n)
+				if len(q[0].Children) == 4 {
+					q = q[1:]
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			f(b, r)
+		})
+	}
+}
+
+// BenchmarkNodeAsJSON measures the cost of encoding a tree as JSON.
+func BenchmarkNodeAsJSON(b *testing.B) {
+	benchmarkTrees(b, func(b *testing.B, n *Node) {
+		for i := 0; i < b.N; i++ {
+			_, err := n.AsJSON()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkNodeFromJSON measures the cost of decoding a tree from JSON.
+func BenchmarkNodeFromJSON(b *testing.B) {
+	benchmarkTrees(b, func(b *testing.B, n *Node) {
+		j, err := n.AsJSON()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err = NodeFromJSON(j)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkNodeFindAll measures the cost of visiting every node in a tree.
+func BenchmarkNodeFindAll(b *testing.B) {
+	benchmarkTrees(b, func(b *testing.B, n *Node) {
+		for i := 0; i < b.N; i++ {
+			n.FindAll(func(n *Node) bool { return n.Value != nil })
+		}
+	})
+}
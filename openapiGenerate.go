@@ -0,0 +1,205 @@
+//go:build ignore
+// +build ignore
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+// Generates the Go structures for the schemas in the OpenAPI specification.
+// Run using go generate.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+type schema struct {
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Description string             `json:"description"`
+	Properties  map[string]*schema `json:"properties"`
+	Items       *schema            `json:"items"`
+	Ref         string             `json:"$ref"`
+}
+
+type specification struct {
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// propertyOrder is used to retain the order of properties from the
+// specification when generating the fields of a structure.
+type propertyOrder struct {
+	Components struct {
+		Schemas map[string]struct {
+			Properties json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func main() {
+	j, err := ioutil.ReadFile("openapi.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var s specification
+	err = json.Unmarshal(j, &s)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var o propertyOrder
+	err = json.Unmarshal(j, &o)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h, err := ioutil.ReadFile("doc.go")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var b bytes.Buffer
+	b.Write(h[:bytes.Index(h, []byte("package"))])
+	b.WriteString("// Code generated by openapiGenerate.go. DO NOT EDIT.\n\n")
+	b.WriteString("package owid\n")
+	if usesTime(s.Components.Schemas) {
+		b.WriteString("\nimport \"time\"\n")
+	}
+	var n []string
+	for k := range s.Components.Schemas {
+		n = append(n, k)
+	}
+	sort.Strings(n)
+	for _, k := range n {
+		p, err := orderedKeys(o.Components.Schemas[k].Properties)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeStruct(&b, k, s.Components.Schemas[k], p)
+	}
+	f, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile("openapiTypes.go", f, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeStruct(b *bytes.Buffer, n string, s *schema, p []string) {
+	b.WriteString("\n")
+	if s.Description != "" {
+		writeComment(b, n+" "+lowerFirst(s.Description))
+	}
+	fmt.Fprintf(b, "type %s struct {\n", n)
+	for _, k := range p {
+		f := s.Properties[k]
+		fmt.Fprintf(b, "\t%s %s `json:\"%s\"`", upperFirst(k), goType(f), k)
+		if f.Description != "" {
+			fmt.Fprintf(b, " // %s", f.Description)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+}
+
+// writeComment writes the text as a Go comment wrapped at 80 characters.
+func writeComment(b *bytes.Buffer, t string) {
+	l := "//"
+	for _, w := range strings.Fields(t) {
+		if len(l)+len(w)+1 > 80 {
+			b.WriteString(l + "\n")
+			l = "//"
+		}
+		l += " " + w
+	}
+	b.WriteString(l + "\n")
+}
+
+// usesTime returns true if any of the properties are date times.
+func usesTime(m map[string]*schema) bool {
+	for _, s := range m {
+		for _, p := range s.Properties {
+			if p.Format == "date-time" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func goType(s *schema) string {
+	if s.Ref != "" {
+		return "*" + s.Ref[strings.LastIndex(s.Ref, "/")+1:]
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "byte" {
+			return "[]byte"
+		}
+		if s.Format == "date-time" {
+			return "time.Time"
+		}
+		return "string"
+	case "boolean":
+		return "bool"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "array":
+		return "[]" + goType(s.Items)
+	default:
+		return "interface{}"
+	}
+}
+
+// orderedKeys returns the keys of the JSON object in the order they appear.
+func orderedKeys(j json.RawMessage) ([]string, error) {
+	var k []string
+	d := json.NewDecoder(bytes.NewReader(j))
+	_, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		k = append(k, t.(string))
+		var v json.RawMessage
+		err = d.Decode(&v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func upperFirst(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,114 @@
+/* ****************************************************************************
+ * Copyright 2020 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+// Code generated by openapiGenerate.go. DO NOT EDIT.
+
+package owid
+
+import "time"
+
+// APIVersions contains the versions of the API supported by a service so that
+// clients can use the highest mutually supported version.
+type APIVersions struct {
+	Versions []int `json:"versions"` // Versions of the API supported
+	Latest   int   `json:"latest"`   // Highest version of the API supported
+}
+
+// BulkCreator contains a creator to register with a bulk import.
+type BulkCreator struct {
+	Domain       string `json:"domain"`       // Domain of the creator to register
+	Name         string `json:"name"`         // Common name of the creator
+	ContractURL  string `json:"contractURL"`  // URL with the T&Cs associated with the creation of the data in the OWIDs
+	Email        string `json:"email"`        // Optional email address to contact the creator
+	DpoURL       string `json:"dpoURL"`       // Optional URL to contact the creator's data protection officer
+	Jurisdiction string `json:"jurisdiction"` // Optional legal jurisdiction the creator operates under
+	PrivateKey   string `json:"privateKey"`   // Optional pre-generated private key in PEM form, or empty to generate keys
+	PublicKey    string `json:"publicKey"`    // Public key in PEM form matching the private key, or empty to generate keys
+}
+
+// BulkResult contains the outcome of importing a creator with a bulk import.
+type BulkResult struct {
+	Row    int    `json:"row"`    // Row of the creator in the import starting at 1
+	Domain string `json:"domain"` // Domain of the creator
+	Status string `json:"status"` // Outcome of importing the creator
+	Error  string `json:"error"`  // Reason the creator was not created, or empty
+}
+
+// Bundle contains the public information for all the creators known to a
+// service so that OWIDs can be verified without network access.
+type Bundle struct {
+	Domain    string           `json:"domain"`    // The domain of the creator that signed the bundle
+	Created   time.Time        `json:"created"`   // The date and time the bundle was created
+	Creators  []*PublicCreator `json:"creators"`  // Public information for each of the creators
+	Signature []byte           `json:"signature"` // Signature of the other fields using the private key of the creator that signed the bundle
+}
+
+// DecodeResponse contains the fields of an OWID decoded without verifying it.
+type DecodeResponse struct {
+	Version       int       `json:"version"`       // Version of the OWID
+	Domain        string    `json:"domain"`        // Domain of the creator
+	Date          time.Time `json:"date"`          // The date and time the OWID was created
+	Age           int       `json:"age"`           // Complete minutes since the OWID was created
+	PayloadLength int       `json:"payloadLength"` // Length of the payload in bytes
+	Signature     []byte    `json:"signature"`     // Signature of the OWID
+}
+
+// JWK contains the public key of a creator as a JSON Web Key.
+type JWK struct {
+	Kty string `json:"kty"` // Key type, always EC
+	Crv string `json:"crv"` // Elliptic curve of the key
+	X   string `json:"x"`   // X coordinate of the public key base 64 URL encoded
+	Y   string `json:"y"`   // Y coordinate of the public key base 64 URL encoded
+	Use string `json:"use"` // Intended use of the key, always sig
+	Alg string `json:"alg"` // Algorithm the key is used with
+	Kid string `json:"kid"` // RFC 7638 thumbprint of the key
+}
+
+// JWKSet contains the public keys of a creator as a JSON Web Key Set.
+type JWKSet struct {
+	Keys []*JWK `json:"keys"` // Public keys of the creator
+}
+
+// PublicCreator used by a supply chain partner to cache the publicKey
+// associated with the domain so that they do not need to call the end points to
+// verify a signature. For example; a request is received with OWIDs and those
+// OWIDs need to be verified before the bid is processed.
+type PublicCreator struct {
+	Domain        string    `json:"domain"`        // The domain that the name and key relate to
+	Name          string    `json:"name"`          // Common name of the creator
+	PublicKeySPKI string    `json:"publicKeySPKI"` // The public key in SPKI form
+	ContractURL   string    `json:"contractURL"`   // URL with the T&Cs associated with the creation of the data in the OWID
+	Created       time.Time `json:"created"`       // The date and time the creator and keys were created
+	Email         string    `json:"email"`         // Optional email address to contact the creator about the data it signs
+	DpoURL        string    `json:"dpoURL"`        // Optional URL to contact the creator's data protection officer
+	Jurisdiction  string    `json:"jurisdiction"`  // Optional legal jurisdiction the creator operates under
+	Status        string    `json:"status"`        // Status of the creator. Suspended creators can not sign OWIDs and pending creators have not yet proven control of their domain
+	Signature     []byte    `json:"signature"`     // Signature of the other fields using the creator's private key
+}
+
+// StoreHealth describes how current the creators held by a store are.
+type StoreHealth struct {
+	Status      string    `json:"status"`      // ok, stale or failed
+	LastRefresh time.Time `json:"lastRefresh"` // When the creators were last loaded
+	Staleness   int       `json:"staleness"`   // Seconds since the creators were last loaded
+	Creators    int       `json:"creators"`    // Number of creators held
+	LastError   string    `json:"lastError"`   // Error from the last failed refresh, only in debug mode
+}
+
+// VerifyResponse is the result of verifying an OWID.
+type VerifyResponse struct {
+	Valid bool `json:"valid"` // True if the OWID is valid, otherwise false
+}
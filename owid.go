@@ -18,11 +18,16 @@ package owid
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -31,10 +36,43 @@ const (
 	owidVersion1 byte = 1
 	owidVersion2 byte = 2
 	owidVersion3 byte = 3
+	owidVersion4 byte = 4 // Version 3 followed by co-signatures
+	owidVersion5 byte = 5 // Version 4 with length prefixed signatures
 )
 
+// owidVersions are the versions that OWIDs can be created with.
+var owidVersions = []byte{
+	owidVersion1,
+	owidVersion2,
+	owidVersion3,
+	owidVersion4,
+	owidVersion5}
+
+// validOWIDVersion returns an error if the version is not one of owidVersions.
+func validOWIDVersion(v byte) error {
+	for _, i := range owidVersions {
+		if i == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("OWID version '%d' not supported", v)
+}
+
+// The version of the SigningDataV2 layout.
+const signingDataVersion2 byte = 2
+
+// The well known URI path for the public information associated with the
+// creator.
+const wellKnownPath = "/.well-known/owid"
+
 var client *http.Client
 
+// bufferPool contains buffers reused when forming the data to be signed or
+// verified.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
 func init() {
 	client = &http.Client{}
 }
@@ -46,6 +84,11 @@ type OWID struct {
 	Date      time.Time `json:"date"`      // The date and time to the nearest minute in UTC of the creation.
 	Payload   []byte    `json:"payload"`   // Array of bytes that form the identifier.
 	Signature []byte    `json:"signature"` // Signature for this OWID and it's ancestor from the creator.
+
+	// Signatures from other domains over the same data. Version 4 only.
+	CoSignatures []*CoSignature `json:"coSignatures,omitempty"`
+
+	cosigned *OWID // OWID a co-signature view was taken from, or nil
 }
 
 // Age returns the number of complete minutes that have elapsed since the OWID
@@ -74,6 +117,17 @@ func NewOwid(
 	domain string,
 	date time.Time,
 	payload []byte) (*OWID, error) {
+	domain = normalizeDomain(domain)
+	if len(domain) > maxDomainLength {
+		return nil, fmt.Errorf(
+			"domain length '%d' exceeds '%d'",
+			len(domain),
+			maxDomainLength)
+	}
+	err := checkPayload(payload, 0)
+	if err != nil {
+		return nil, err
+	}
 	var o OWID
 	o.Version = owidVersion3
 	o.Domain = domain
@@ -84,11 +138,17 @@ func NewOwid(
 
 // Sign this OWID and and any other OWIDs using the Crypto instance provided.
 func (o *OWID) Sign(c *Crypto, others []*OWID) error {
-	b, err := o.dataForCrypto(others)
+	if signatureLengthValid(o.Version, c.signatureLength()) == false {
+		return fmt.Errorf(
+			"OWID version '%d' can't carry '%s' signatures",
+			o.Version,
+			c.Algorithm())
+	}
+	h, err := o.hashForCrypto(others)
 	if err != nil {
 		return err
 	}
-	o.Signature, err = c.SignByteArray(b)
+	o.Signature, err = c.signHash(h[:])
 	if err != nil {
 		return err
 	}
@@ -97,11 +157,11 @@ func (o *OWID) Sign(c *Crypto, others []*OWID) error {
 
 // VerifyWithCrypto this OWID and any other OWIDs are valid.
 func (o *OWID) VerifyWithCrypto(c *Crypto, others []*OWID) (bool, error) {
-	b, err := o.dataForCrypto(others)
+	h, err := o.hashForCrypto(others)
 	if err != nil {
 		return false, err
 	}
-	return c.VerifyByteArray(b, o.Signature)
+	return c.verifyHash(h[:], o.Signature)
 }
 
 // VerifyWithPublicKey this OWID and it's ancestors using the public key in PEM
@@ -117,31 +177,126 @@ func (o *OWID) VerifyWithPublicKey(
 }
 
 // Verify this OWID and it's ancestors by fetching the public key from the
-// domain associated with the OWID.
+// domain associated with the OWID. The well known URI is tried first and if
+// not available the versioned public key end point is used.
 func (o *OWID) Verify(scheme string) (bool, error) {
+	k, err := o.fetchPublicKey(context.Background(), scheme)
+	if err != nil {
+		return false, err
+	}
+	return o.VerifyWithPublicKey(k)
+}
+
+// fetchPublicKey returns the public key for the OWID's domain trying the well
+// known URI first and then the versioned public key end point.
+func (o *OWID) fetchPublicKey(
+	ctx context.Context,
+	scheme string) (string, error) {
+	p, err := o.getPublicCreator(ctx, client, scheme)
+	if err != nil {
+		k, _, err := o.getPublicKey(ctx, client, scheme)
+		return k, err
+	}
+	return p.PublicKeySPKI, nil
+}
+
+// getPublicCreator returns the creator information at the well known URI for
+// the OWID's domain.
+func (o *OWID) getPublicCreator(
+	ctx context.Context,
+	c *http.Client,
+	scheme string) (*PublicCreator, error) {
+	v, err := o.get(ctx, c, o.wellKnownURL(scheme))
+	if err != nil {
+		return nil, err
+	}
+	var p PublicCreator
+	err = json.Unmarshal(v, &p)
+	if err != nil {
+		return nil, err
+	}
+	if p.PublicKeySPKI == "" {
+		return nil, fmt.Errorf("Domain '%s' public key missing", o.Domain)
+	}
+	return &p, nil
+}
+
+// getPublicKey returns the public key and the URL it was fetched from using
+// the versioned public key end point for the OWID's domain. The latest API
+// version is tried first falling back to earlier versions if the domain does
+// not support it.
+func (o *OWID) getPublicKey(
+	ctx context.Context,
+	c *http.Client,
+	scheme string) (string, string, error) {
+	var err error
+	for i := apiVersionLatest; i >= apiVersionMin; i-- {
+		u := o.publicKeyURL(scheme, i)
+		var v []byte
+		v, err = o.get(ctx, c, u)
+		var s *statusError
+		if errors.As(err, &s) && s.code == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return "", u.String(), err
+		}
+		return string(v), u.String(), nil
+	}
+	return "", "", err
+}
+
+// wellKnownURL returns the URL of the creator information for the OWID's
+// domain.
+func (o *OWID) wellKnownURL(scheme string) *url.URL {
+	return &url.URL{
+		Scheme: scheme,
+		Host:   normalizeDomain(o.Domain),
+		Path:   wellKnownPath}
+}
+
+// publicKeyURL returns the URL of the public key end point for the API version
+// and the OWID's domain.
+func (o *OWID) publicKeyURL(scheme string, version int) *url.URL {
 	u := url.URL{
 		Scheme: scheme,
-		Host:   o.Domain,
-		Path:   fmt.Sprintf("/owid/api/v%d/public-key", o.Version)}
+		Host:   normalizeDomain(o.Domain),
+		Path:   apiPath(version, "public-key")}
 	q := u.Query()
 	q.Set("format", "pkcs")
 	u.RawQuery = q.Encode()
-	r, err := client.Get(u.String())
+	return &u
+}
+
+// get returns the body of the response from the URL provided using the HTTP
+// client. The request is cancelled if the context is done.
+func (o *OWID) get(
+	ctx context.Context,
+	c *http.Client,
+	u *url.URL) ([]byte, error) {
+	q, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	r, err := c.Do(q)
+	if err != nil {
+		return nil, err
 	}
 	defer r.Body.Close()
 	if r.StatusCode != http.StatusOK {
-		return false, fmt.Errorf(
-			"Domain '%s' return code '%d'",
-			o.Domain,
-			r.StatusCode)
-	}
-	v, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return false, err
+		return nil, &statusError{domain: o.Domain, code: r.StatusCode}
 	}
-	return o.VerifyWithPublicKey(string(v))
+	return ioutil.ReadAll(r.Body)
+}
+
+// statusError is returned when a domain responds with a status other than OK.
+type statusError struct {
+	domain string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("Domain '%s' return code '%d'", e.domain, e.code)
 }
 
 // ToBuffer appends the OWID to the buffer provided.
@@ -150,10 +305,13 @@ func (o *OWID) ToBuffer(f *bytes.Buffer) error {
 	if err != nil {
 		return err
 	}
-	err = writeSignature(f, o.Signature)
+	err = writeSignatureVersion(f, o.Version, o.Signature)
 	if err != nil {
 		return err
 	}
+	if o.Version >= owidVersion4 {
+		return writeCoSignatures(f, o.Version, o.CoSignatures)
+	}
 	return nil
 }
 
@@ -213,12 +371,20 @@ func FromBuffer(b *bytes.Buffer) (*OWID, error) {
 	switch o.Version {
 	case owidEmpty:
 		break
-	case owidVersion1:
-		fromBuffer(b, &o)
-	case owidVersion2:
-		fromBuffer(b, &o)
-	case owidVersion3:
-		fromBuffer(b, &o)
+	case owidVersion1, owidVersion2, owidVersion3:
+		err = fromBuffer(b, &o)
+		if err != nil {
+			return nil, err
+		}
+	case owidVersion4, owidVersion5:
+		err = fromBuffer(b, &o)
+		if err != nil {
+			return nil, err
+		}
+		o.CoSignatures, err = readCoSignatures(b, o.Version)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("version '%d' not supported", o.Version)
 	}
@@ -252,6 +418,86 @@ func FromForm(q *url.Values, n string) (*OWID, error) {
 	return o, nil
 }
 
+// SigningData returns the bytes that are signed to form the signature of the
+// OWID and any other OWIDs. This is the reference layout used by all current
+// OWID versions and other implementations must produce identical bytes.
+//
+// The layout is the OWID without the signature followed by each of the other
+// OWIDs including their signatures. Nil others are ignored. Co-signatures of
+// the OWID are not part of the data so that they can be added after signing.
+//
+//	byte    version
+//	string  domain (UTF-8 bytes followed by a null terminator)
+//	date    version 1 as a uint16 big endian count of days since 2020-01-01,
+//	        versions 2 to 4 as a uint32 little endian count of minutes since
+//	        2020-01-01 00:00 UTC
+//	uint32  payload length (little endian)
+//	bytes   payload
+//	bytes   others in order, each as returned by AsByteArray
+func (o *OWID) SigningData(others ...*OWID) ([]byte, error) {
+	return o.dataForCrypto(others)
+}
+
+// SigningDataV2 returns the bytes of a length prefixed and versioned layout of
+// the OWID and any other OWIDs. Unlike SigningData the layout does not rely on
+// null terminators and can be unambiguously parsed. Nil others are ignored.
+//
+//	byte    signing data layout version (2)
+//	byte    OWID version
+//	uint32  domain length (little endian)
+//	bytes   domain (UTF-8)
+//	uint32  minutes since 2020-01-01 00:00 UTC (little endian)
+//	uint32  payload length (little endian)
+//	bytes   payload
+//	uint32  count of others (little endian)
+//	for each other:
+//	uint32  other length (little endian)
+//	bytes   other as returned by AsByteArray
+func (o *OWID) SigningDataV2(others ...*OWID) ([]byte, error) {
+	var f bytes.Buffer
+	err := writeByte(&f, signingDataVersion2)
+	if err != nil {
+		return nil, err
+	}
+	err = writeByte(&f, o.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = writeByteArray(&f, []byte(o.Domain))
+	if err != nil {
+		return nil, err
+	}
+	err = writeDateV2(&f, o.Date)
+	if err != nil {
+		return nil, err
+	}
+	err = writeByteArray(&f, o.Payload)
+	if err != nil {
+		return nil, err
+	}
+	var a [][]byte
+	for _, p := range others {
+		if p != nil {
+			b, err := p.AsByteArray()
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, b)
+		}
+	}
+	err = writeUint32(&f, uint32(len(a)))
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range a {
+		err = writeByteArray(&f, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.Bytes(), nil
+}
+
 // dataForCrypto adds the fields from this OWID to the byte buffer without
 // the signature. Adds all the bytes of the others to the data.
 func (o *OWID) dataForCrypto(others []*OWID) ([]byte, error) {
@@ -271,9 +517,34 @@ func (o *OWID) dataForCrypto(others []*OWID) ([]byte, error) {
 	return f.Bytes(), nil
 }
 
+// hashForCrypto returns the SHA-256 hash of the data returned from
+// dataForCrypto using a pooled buffer to avoid allocations for each signing or
+// verification.
+func (o *OWID) hashForCrypto(others []*OWID) ([sha256.Size]byte, error) {
+	if o.cosigned != nil {
+		return o.cosigned.hashForCrypto(others)
+	}
+	f := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(f)
+	f.Reset()
+	err := o.toBufferNoSignature(f)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	for _, a := range others {
+		if a != nil {
+			err = a.ToBuffer(f)
+			if err != nil {
+				return [sha256.Size]byte{}, err
+			}
+		}
+	}
+	return sha256.Sum256(f.Bytes()), nil
+}
+
 func fromBuffer(b *bytes.Buffer, o *OWID) error {
 	var err error
-	o.Domain, err = readString(b)
+	o.Domain, err = readString(b, maxDomainLength)
 	if err != nil {
 		return err
 	}
@@ -281,11 +552,11 @@ func fromBuffer(b *bytes.Buffer, o *OWID) error {
 	if err != nil {
 		return err
 	}
-	o.Payload, err = readByteArray(b)
+	o.Payload, err = readPayload(b)
 	if err != nil {
 		return err
 	}
-	o.Signature, err = readSignature(b)
+	o.Signature, err = readSignatureVersion(b, o.Version)
 	if err != nil {
 		return err
 	}
@@ -18,8 +18,18 @@ package owid
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestOWIDVerify(t *testing.T) {
@@ -57,7 +67,7 @@ func TestOWIDBase64(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if o.compare(b) == false {
+	if o.Equal(b) == false {
 		t.Error("encode and decode failed")
 	}
 }
@@ -75,7 +85,7 @@ func TestOWIDString(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if o.compare(b) == false {
+	if o.Equal(b) == false {
 		t.Error("encode and decode failed")
 	}
 }
@@ -165,9 +175,297 @@ func corrupt(creator *Creator, a []byte, i int) error {
 	return err
 }
 
-func (o *OWID) compare(other *OWID) bool {
-	return o.Version == other.Version &&
-		o.Date == other.Date &&
-		bytes.Equal(o.Signature, other.Signature) &&
-		bytes.Equal(o.Payload, other.Payload)
+// TestOWIDVerifyWellKnown verifies an OWID by fetching the public key from the
+// well known URI.
+func TestOWIDVerifyWellKnown(t *testing.T) {
+	m := http.NewServeMux()
+	testOWIDVerifyRemote(t, m, func(s *Services) {
+		m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	})
+}
+
+// TestOWIDVerifyFallback verifies an OWID by fetching the public key from the
+// versioned public key end point when the well known URI is not available.
+func TestOWIDVerifyFallback(t *testing.T) {
+	m := http.NewServeMux()
+	testOWIDVerifyRemote(t, m, func(s *Services) {
+		m.HandleFunc("/owid/api/v3/public-key", HandlerPublicKey(s))
+	})
+}
+
+func testOWIDVerifyRemote(
+	t *testing.T,
+	m *http.ServeMux,
+	add func(s *Services)) {
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	add(s)
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := o.Verify(u.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID did not pass verification")
+	}
+}
+
+func TestOWIDJSON(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newOWID(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := FromJSONStrict(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Equal(n) == false {
+		t.Error("JSON round trip failed")
+	}
+
+	// Unknown fields are only rejected in strict mode.
+	u := append(j[:len(j)-1], []byte(`,"other":1}`)...)
+	_, err = FromJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testOWIDJSONError(t, u, "'other'")
+
+	// Missing and incorrectly typed fields are named in the error.
+	testOWIDJSONError(t, []byte(`{"version":3}`), "'date'")
+	testOWIDJSONError(t, []byte(`{"version":"3"}`), "'version'")
+}
+
+func testOWIDJSONError(t *testing.T, j []byte, field string) {
+	_, err := FromJSONStrict(j)
+	if err == nil {
+		t.Errorf("expected error for '%s'", j)
+	} else if strings.Contains(err.Error(), field) == false {
+		t.Errorf("error '%s' does not name field %s", err, field)
+	}
+}
+
+// TestSigningDataVectors checks the signing data layouts against the test
+// vectors in testdata/signingdata.json which other implementations can also use
+// to validate their layouts.
+func TestSigningDataVectors(t *testing.T) {
+	var vs []struct {
+		Description   string    `json:"description"`
+		Version       byte      `json:"version"`
+		Domain        string    `json:"domain"`
+		Date          time.Time `json:"date"`
+		Payload       string    `json:"payload"`
+		Others        []string  `json:"others"`
+		SigningData   string    `json:"signingData"`
+		SigningDataV2 string    `json:"signingDataV2"`
+	}
+	j, err := ioutil.ReadFile("testdata/signingdata.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(j, &vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vs {
+		p, err := hex.DecodeString(v.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := OWID{
+			Version: v.Version,
+			Domain:  v.Domain,
+			Date:    v.Date,
+			Payload: p}
+		var others []*OWID
+		for _, h := range v.Others {
+			b, err := hex.DecodeString(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := FromByteArray(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			others = append(others, a)
+		}
+		a, err := o.SigningData(others...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(a) != v.SigningData {
+			t.Errorf("'%s' signing data mismatch", v.Description)
+		}
+		b, err := o.SigningDataV2(others...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(b) != v.SigningDataV2 {
+			t.Errorf("'%s' signing data V2 mismatch", v.Description)
+		}
+	}
+}
+
+// benchmarkPayloadSizes are the payload lengths used by the sub-benchmarks.
+var benchmarkPayloadSizes = []int{16, 256, 4096, maxPayloadLength}
+
+// benchmarkOWIDs runs the function as a sub-benchmark for each payload size
+// with a signed OWID and the creator that signed it.
+func benchmarkOWIDs(b *testing.B, f func(b *testing.B, c *Creator, o *OWID)) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, n := range benchmarkPayloadSizes {
+		b.Run(fmt.Sprintf("payload=%d", n), func(b *testing.B) {
+			o, err := c.CreateOWIDandSign(bytes.Repeat([]byte{'x'}, n))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			f(b, c, o)
+		})
+	}
+}
+
+// BenchmarkOWIDSign measures the cost of signing an OWID with a Crypto
+// instance created before the benchmark starts.
+func BenchmarkOWIDSign(b *testing.B) {
+	benchmarkOWIDs(b, func(b *testing.B, c *Creator, o *OWID) {
+		s, err := c.NewCryptoSignOnly()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < b.N; i++ {
+			err = o.Sign(s, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkOWIDVerify measures the cost of verifying an OWID with a Crypto
+// instance created before the benchmark starts.
+func BenchmarkOWIDVerify(b *testing.B) {
+	benchmarkOWIDs(b, func(b *testing.B, c *Creator, o *OWID) {
+		v, err := c.NewCryptoVerifyOnly()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < b.N; i++ {
+			_, err = o.VerifyWithCrypto(v, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkOWIDAsByteArray measures the cost of the binary encoding.
+func BenchmarkOWIDAsByteArray(b *testing.B) {
+	benchmarkOWIDs(b, func(b *testing.B, c *Creator, o *OWID) {
+		for i := 0; i < b.N; i++ {
+			_, err := o.AsByteArray()
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkFromBase64 measures the cost of decoding an OWID from base 64.
+func BenchmarkFromBase64(b *testing.B) {
+	benchmarkOWIDs(b, func(b *testing.B, c *Creator, o *OWID) {
+		s, err := o.AsBase64()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, err = FromBase64(s)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestOWIDDiff(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newOWID(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := *o
+	if d := o.Diff(&n); len(d) != 0 {
+		t.Fatalf("copy differs '%v'", d)
+	}
+	n.Domain = "other.com"
+	n.Payload = []byte("other")
+	d := o.Diff(&n)
+	if len(d) != 2 ||
+		strings.HasPrefix(d[0], "domain") == false ||
+		strings.HasPrefix(d[1], "payload") == false {
+		t.Fatalf("differences '%v' not expected", d)
+	}
+	if o.Equal(&n) {
+		t.Fatal("different OWIDs should not be equal")
+	}
+}
+
+// TestOWIDGetContext checks fetching public information stops when the
+// context is cancelled.
+func TestOWIDGetContext(t *testing.T) {
+	d := make(chan struct{})
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-d:
+			}
+		}))
+	defer h.Close()
+	defer close(d)
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		100*time.Millisecond)
+	defer cancel()
+	o := OWID{Domain: u.Host}
+	_, err = o.getPublicCreator(ctx, client, u.Scheme)
+	if errors.Is(err, context.DeadlineExceeded) == false {
+		t.Fatalf("expected deadline exceeded, found '%v'", err)
+	}
 }
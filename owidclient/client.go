@@ -0,0 +1,320 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+// Package owidclient is a client for the HTTP API of a remote OWID service.
+// Requests are retried with exponential backoff when the service is
+// unavailable and failures are returned as typed errors.
+package owidclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SWAN-community/owid-go"
+)
+
+// The version of the API used for requests.
+const apiVersion = 3
+
+// Defaults for retrying requests.
+const (
+	defaultRetries = 2
+	defaultBackoff = 100 * time.Millisecond
+)
+
+// The maximum number of bytes read from an error response.
+const maxErrorLength = 1024
+
+// The HTTP header that carries the access key for requests that change state.
+const accessKeyHeader = "X-OWID-Access-Key"
+
+// ErrNotFound is matched by errors for domains that are not registered.
+var ErrNotFound = errors.New("not found")
+
+// ErrAccessDenied is matched by errors for requests with an access key that
+// is not allowed to perform the operation.
+var ErrAccessDenied = errors.New("access denied")
+
+// Error is returned when the service responds with a status other than OK.
+type Error struct {
+	StatusCode int    // HTTP status code of the response
+	Message    string // Body of the response
+	URL        string // URL of the request
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"request '%s' returned status '%d': %s",
+		e.URL,
+		e.StatusCode,
+		e.Message)
+}
+
+// Is returns true for ErrNotFound or ErrAccessDenied if the status code
+// matches.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrAccessDenied:
+		return e.StatusCode == http.StatusNetworkAuthenticationRequired ||
+			e.StatusCode == http.StatusForbidden ||
+			e.StatusCode == http.StatusUnauthorized
+	}
+	return false
+}
+
+// temporary returns true if the request might succeed if retried.
+func (e *Error) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= http.StatusInternalServerError
+}
+
+// Client calls the HTTP API of an OWID service. The domain of each request is
+// used as the host unless an endpoint is set, in which case requests are sent
+// to the endpoint with the domain as the Host header.
+type Client struct {
+	scheme    string        // Scheme used for requests
+	accessKey string        // Access key for operations that require one
+	endpoint  *url.URL      // Optional address of a central service
+	http      *http.Client  // Client used for requests
+	retries   int           // Number of times a request is retried
+	backoff   time.Duration // Delay before the first retry, then doubled
+}
+
+// New creates a client that uses the scheme and access key provided.
+func New(scheme string, accessKey string) *Client {
+	return &Client{
+		scheme:    scheme,
+		accessKey: accessKey,
+		http:      http.DefaultClient,
+		retries:   defaultRetries,
+		backoff:   defaultBackoff}
+}
+
+// SetEndpoint sends all requests to the URL with the domain as the Host
+// header. Used when many domains are served by one central service.
+func (c *Client) SetEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("endpoint '%s' must be an absolute URL", endpoint)
+	}
+	c.endpoint = u
+	return nil
+}
+
+// SetHTTPClient sets the HTTP client used for requests.
+func (c *Client) SetHTTPClient(h *http.Client) { c.http = h }
+
+// SetRetries sets the number of times a failed request is retried and the
+// delay before the first retry. The delay doubles for each further retry.
+func (c *Client) SetRetries(retries int, backoff time.Duration) {
+	c.retries = retries
+	c.backoff = backoff
+}
+
+// GetCreator returns the public information for the domain. The error matches
+// ErrNotFound if the domain is not registered.
+func (c *Client) GetCreator(
+	ctx context.Context,
+	domain string) (*owid.PublicCreator, error) {
+	b, err := c.get(ctx, domain, apiPath("creator"), nil)
+	if err != nil {
+		return nil, err
+	}
+	var p owid.PublicCreator
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Verify returns true if the OWID, and optional parent, were signed by the
+// creator for the OWID's domain.
+func (c *Client) Verify(
+	ctx context.Context,
+	o *owid.OWID,
+	parent *owid.OWID) (bool, error) {
+	q := url.Values{}
+	v, err := o.AsBase64()
+	if err != nil {
+		return false, err
+	}
+	q.Set("owid", v)
+	if parent != nil {
+		v, err = parent.AsBase64()
+		if err != nil {
+			return false, err
+		}
+		q.Set("parent", v)
+	}
+	b, err := c.get(ctx, o.Domain, apiPath("verify"), q)
+	if err != nil {
+		return false, err
+	}
+	var r owid.VerifyResponse
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return false, err
+	}
+	return r.Valid, nil
+}
+
+// Register a new creator for the domain returning the public information. The
+// access key is sent in a header. Values the service refuses are returned as
+// an Error with the service's status code and message.
+func (c *Client) Register(
+	ctx context.Context,
+	domain string,
+	name string,
+	contractURL string,
+	contact owid.Contact) (*owid.PublicCreator, error) {
+	f := url.Values{}
+	f.Set("name", name)
+	f.Set("contractURL", contractURL)
+	f.Set("email", contact.Email)
+	f.Set("dpoURL", contact.DpoURL)
+	f.Set("jurisdiction", contact.Jurisdiction)
+	b, err := c.send(ctx, http.MethodPost, domain, apiPath("register"), nil, f)
+	if err != nil {
+		return nil, err
+	}
+	var p owid.PublicCreator
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// apiPath returns the path of the end point for the API version used.
+func apiPath(name string) string {
+	return fmt.Sprintf("/owid/api/v%d/%s", apiVersion, name)
+}
+
+// get returns the body of the response to a GET request for the path and
+// query at the domain retrying failures that might be temporary.
+func (c *Client) get(
+	ctx context.Context,
+	domain string,
+	path string,
+	q url.Values) ([]byte, error) {
+	return c.send(ctx, http.MethodGet, domain, path, q, nil)
+}
+
+// send returns the body of the response to a request for the path and query
+// at the domain with the optional form body. GET requests are retried after
+// failures that might be temporary. Other methods change state so are not.
+func (c *Client) send(
+	ctx context.Context,
+	method string,
+	domain string,
+	path string,
+	q url.Values,
+	f url.Values) ([]byte, error) {
+	d := c.backoff
+	for i := 0; ; i++ {
+		b, err := c.do(ctx, method, domain, path, q, f)
+		if err == nil ||
+			i >= c.retries ||
+			method != http.MethodGet ||
+			retry(err) == false {
+			return b, err
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		d *= 2
+	}
+}
+
+// retry returns true if the request that failed with the error should be
+// retried.
+func retry(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.temporary()
+	}
+	if errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var n net.Error
+	return errors.As(err, &n)
+}
+
+// do sends a single request returning the body if the status is OK. If the
+// form is not nil it is sent as the body with the access key in a header.
+func (c *Client) do(
+	ctx context.Context,
+	method string,
+	domain string,
+	path string,
+	q url.Values,
+	f url.Values) ([]byte, error) {
+	u := url.URL{Scheme: c.scheme, Host: domain, Path: path}
+	if c.endpoint != nil {
+		u.Scheme = c.endpoint.Scheme
+		u.Host = c.endpoint.Host
+		u.Path = strings.TrimSuffix(c.endpoint.Path, "/") + path
+	}
+	u.RawQuery = q.Encode()
+	var body io.Reader
+	if f != nil {
+		body = strings.NewReader(f.Encode())
+	}
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	r.Host = domain
+	if f != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set(accessKeyHeader, c.accessKey)
+	}
+	s, err := c.http.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Body.Close()
+	if s.StatusCode != http.StatusOK {
+		// The query is omitted from the error as it might contain the access
+		// key.
+		m, _ := io.ReadAll(io.LimitReader(s.Body, maxErrorLength))
+		u.RawQuery = ""
+		return nil, &Error{
+			StatusCode: s.StatusCode,
+			Message:    strings.TrimSpace(string(m)),
+			URL:        u.String()}
+	}
+	return io.ReadAll(s.Body)
+}
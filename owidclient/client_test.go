@@ -0,0 +1,155 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owidclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SWAN-community/owid-go"
+)
+
+const (
+	testDomain      = "example.com"
+	testName        = "Example Org"
+	testContractURL = "https://example.com/terms"
+	testAccessKey   = "key1"
+)
+
+// newTestServer returns a server for the OWID API that hosts all domains.
+func newTestServer(t *testing.T) *httptest.Server {
+	l, err := owid.NewLocalStore(filepath.Join(t.TempDir(), "owids.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := owid.NewServices(
+		owid.NewConfig("../appsettings.test.none.json"),
+		l,
+		owid.NewAccessSimple([]string{testAccessKey}))
+	m := http.NewServeMux()
+	m.HandleFunc(apiPath("register"), owid.HandlerRegisterAPI(s))
+	m.HandleFunc(apiPath("creator"), owid.HandlerCreator(s))
+	m.HandleFunc(apiPath("verify"), owid.HandlerVerify(s))
+	h := httptest.NewServer(m)
+	t.Cleanup(h.Close)
+	return h
+}
+
+func TestClient(t *testing.T) {
+	h := newTestServer(t)
+	c := New("http", testAccessKey)
+	err := c.SetEndpoint(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err = c.GetCreator(ctx, testDomain)
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("unregistered domain returned '%v'", err)
+	}
+	p, err := c.Register(
+		ctx,
+		testDomain,
+		testName,
+		testContractURL,
+		owid.Contact{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Domain != testDomain || p.Name != testName {
+		t.Fatalf("registered creator '%s' '%s' not expected", p.Domain, p.Name)
+	}
+	v, err := p.VerifySelfSignature()
+	if err != nil || v == false {
+		t.Fatal("public information not valid")
+	}
+	o, err := owid.NewOwid(testDomain, time.Now().UTC(), []byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Signature = make([]byte, 64)
+	o.Signature[0] = 1
+	v, err = c.Verify(ctx, o, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v {
+		t.Fatal("OWID with an invalid signature verified")
+	}
+	d := New("http", "wrong")
+	d.SetEndpoint(h.URL)
+	_, err = d.Register(ctx, "other.com", testName, testContractURL, owid.Contact{})
+	if errors.Is(err, ErrAccessDenied) == false {
+		t.Fatalf("wrong access key returned '%v'", err)
+	}
+	_, err = c.Register(ctx, "other.com", "short", testContractURL, owid.Contact{})
+	var e *Error
+	if errors.As(err, &e) == false ||
+		e.StatusCode != http.StatusBadRequest ||
+		strings.Contains(e.Message, "Name") == false {
+		t.Fatalf("invalid name returned '%v'", err)
+	}
+	_, err = c.Register(ctx, testDomain, testName, testContractURL, owid.Contact{})
+	if errors.As(err, &e) == false || e.StatusCode != http.StatusConflict {
+		t.Fatalf("registered domain returned '%v'", err)
+	}
+}
+
+// TestClientRetry checks temporary failures are retried and others are not.
+func TestClientRetry(t *testing.T) {
+	var n atomic.Int32
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if n.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	defer h.Close()
+	c := New("http", testAccessKey)
+	c.SetEndpoint(h.URL)
+	c.SetRetries(5, time.Millisecond)
+	_, err := c.GetCreator(context.Background(), testDomain)
+	if errors.Is(err, ErrNotFound) == false {
+		t.Fatalf("expected not found, found '%v'", err)
+	}
+	if n.Load() != 3 {
+		t.Fatalf("expected '3' requests, found '%d'", n.Load())
+	}
+	n.Store(0)
+	c.SetRetries(1, time.Millisecond)
+	_, err = c.GetCreator(context.Background(), testDomain)
+	var e *Error
+	if errors.As(err, &e) == false ||
+		e.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected unavailable, found '%v'", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetCreator(ctx, testDomain)
+	if errors.Is(err, context.Canceled) == false {
+		t.Fatalf("expected cancelled, found '%v'", err)
+	}
+}
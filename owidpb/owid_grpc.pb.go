@@ -0,0 +1,221 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package owidpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// OWIDClient is the client API for OWID service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OWIDClient interface {
+	// Sign creates a new OWID for the domain containing the payload and signs
+	// it with the creator's private key.
+	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	// Verify returns true if the OWID, and optional parent, are valid for the
+	// creator associated with the OWID's domain.
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+	// GetSigner returns the public information associated with the domain.
+	GetSigner(ctx context.Context, in *GetSignerRequest, opts ...grpc.CallOption) (*Signer, error)
+	// Register a new creator for the domain generating a new key pair.
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*Signer, error)
+}
+
+type oWIDClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOWIDClient(cc grpc.ClientConnInterface) OWIDClient {
+	return &oWIDClient{cc}
+}
+
+func (c *oWIDClient) Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	err := c.cc.Invoke(ctx, "/owidpb.OWID/Sign", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oWIDClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, "/owidpb.OWID/Verify", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oWIDClient) GetSigner(ctx context.Context, in *GetSignerRequest, opts ...grpc.CallOption) (*Signer, error) {
+	out := new(Signer)
+	err := c.cc.Invoke(ctx, "/owidpb.OWID/GetSigner", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oWIDClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*Signer, error) {
+	out := new(Signer)
+	err := c.cc.Invoke(ctx, "/owidpb.OWID/Register", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OWIDServer is the server API for OWID service.
+// All implementations must embed UnimplementedOWIDServer
+// for forward compatibility
+type OWIDServer interface {
+	// Sign creates a new OWID for the domain containing the payload and signs
+	// it with the creator's private key.
+	Sign(context.Context, *SignRequest) (*SignResponse, error)
+	// Verify returns true if the OWID, and optional parent, are valid for the
+	// creator associated with the OWID's domain.
+	Verify(context.Context, *VerifyRequest) (*VerifyResponse, error)
+	// GetSigner returns the public information associated with the domain.
+	GetSigner(context.Context, *GetSignerRequest) (*Signer, error)
+	// Register a new creator for the domain generating a new key pair.
+	Register(context.Context, *RegisterRequest) (*Signer, error)
+	mustEmbedUnimplementedOWIDServer()
+}
+
+// UnimplementedOWIDServer must be embedded to have forward compatible implementations.
+type UnimplementedOWIDServer struct {
+}
+
+func (UnimplementedOWIDServer) Sign(context.Context, *SignRequest) (*SignResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sign not implemented")
+}
+func (UnimplementedOWIDServer) Verify(context.Context, *VerifyRequest) (*VerifyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedOWIDServer) GetSigner(context.Context, *GetSignerRequest) (*Signer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSigner not implemented")
+}
+func (UnimplementedOWIDServer) Register(context.Context, *RegisterRequest) (*Signer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedOWIDServer) mustEmbedUnimplementedOWIDServer() {}
+
+// UnsafeOWIDServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OWIDServer will
+// result in compilation errors.
+type UnsafeOWIDServer interface {
+	mustEmbedUnimplementedOWIDServer()
+}
+
+func RegisterOWIDServer(s grpc.ServiceRegistrar, srv OWIDServer) {
+	s.RegisterService(&OWID_ServiceDesc, srv)
+}
+
+func _OWID_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OWIDServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/owidpb.OWID/Sign",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OWIDServer).Sign(ctx, req.(*SignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OWID_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OWIDServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/owidpb.OWID/Verify",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OWIDServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OWID_GetSigner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSignerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OWIDServer).GetSigner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/owidpb.OWID/GetSigner",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OWIDServer).GetSigner(ctx, req.(*GetSignerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OWID_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OWIDServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/owidpb.OWID/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OWIDServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OWID_ServiceDesc is the grpc.ServiceDesc for OWID service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OWID_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "owidpb.OWID",
+	HandlerType: (*OWIDServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Sign",
+			Handler:    _OWID_Sign_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _OWID_Verify_Handler,
+		},
+		{
+			MethodName: "GetSigner",
+			Handler:    _OWID_GetSigner_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _OWID_Register_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "owid.proto",
+}
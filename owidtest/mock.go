@@ -0,0 +1,144 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owidtest
+
+import (
+	"sync"
+
+	"github.com/SWAN-community/owid-go"
+)
+
+// MockStore is an in memory owid.Store that records the domains requested.
+// Set GetCreatorFunc to override the creators returned, for example to return
+// an error.
+type MockStore struct {
+	*owid.Memory
+	GetCreatorFunc func(domain string) (*owid.Creator, error)
+	mutex          sync.Mutex
+	gets           []string
+}
+
+// NewMockStore returns a mock store containing the creators.
+func NewMockStore(creators ...*owid.Creator) *MockStore {
+	return &MockStore{Memory: owid.NewMemoryStore(creators...)}
+}
+
+// GetCreator records the domain and returns the result of GetCreatorFunc if
+// set, otherwise the creator from memory.
+func (m *MockStore) GetCreator(domain string) (*owid.Creator, error) {
+	m.mutex.Lock()
+	m.gets = append(m.gets, domain)
+	m.mutex.Unlock()
+	if m.GetCreatorFunc != nil {
+		return m.GetCreatorFunc(domain)
+	}
+	return m.Memory.GetCreator(domain)
+}
+
+// GetCreatorCalls returns the domains passed to GetCreator in order.
+func (m *MockStore) GetCreatorCalls() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string(nil), m.gets...)
+}
+
+// MockVerifier is an owid.OWIDVerifier that returns Valid and Err without
+// fetching public information. Set VerifyFunc to decide per OWID.
+type MockVerifier struct {
+	Valid      bool
+	Err        error
+	VerifyFunc func(o *owid.OWID, others ...*owid.OWID) (bool, error)
+	mutex      sync.Mutex
+	calls      []*owid.OWID
+}
+
+// Verify records the OWID and returns the result of VerifyFunc if set,
+// otherwise Valid and Err.
+func (m *MockVerifier) Verify(o *owid.OWID, others ...*owid.OWID) (bool, error) {
+	m.mutex.Lock()
+	m.calls = append(m.calls, o)
+	m.mutex.Unlock()
+	if m.VerifyFunc != nil {
+		return m.VerifyFunc(o, others...)
+	}
+	return m.Valid, m.Err
+}
+
+// VerifyWithReport returns a report for the OWID containing the outcome of
+// Verify.
+func (m *MockVerifier) VerifyWithReport(
+	o *owid.OWID,
+	others ...*owid.OWID) (*owid.VerifyReport, error) {
+	v, err := m.Verify(o, others...)
+	return &owid.VerifyReport{
+		Domain:        o.Domain,
+		KeySource:     "mock",
+		CreatorDomain: o.Domain,
+		DomainMatched: true,
+		Date:          o.Date,
+		Valid:         v}, err
+}
+
+// VerifyCalls returns the OWIDs passed to Verify in order.
+func (m *MockVerifier) VerifyCalls() []*owid.OWID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*owid.OWID(nil), m.calls...)
+}
+
+// MockSigningProvider is an owid.SigningProvider that signs OWIDs for any
+// domain deterministically with PrivateKey, so the result verifies with
+// PublicKey. Set Err to fail signing or SignFunc to replace it.
+type MockSigningProvider struct {
+	Err      error
+	SignFunc func(o *owid.OWID, others ...*owid.OWID) error
+	mutex    sync.Mutex
+	calls    []*owid.OWID
+}
+
+// Sign records the OWID and signs it unless Err or SignFunc are set.
+func (m *MockSigningProvider) Sign(o *owid.OWID, others ...*owid.OWID) error {
+	m.mutex.Lock()
+	m.calls = append(m.calls, o)
+	m.mutex.Unlock()
+	if m.SignFunc != nil {
+		return m.SignFunc(o, others...)
+	}
+	if m.Err != nil {
+		return m.Err
+	}
+	x, err := FixedCreator().NewCryptoSignOnly()
+	if err != nil {
+		return err
+	}
+	x.SetDeterministic(true)
+	return o.Sign(x, others)
+}
+
+// SignCalls returns the OWIDs passed to Sign in order.
+func (m *MockSigningProvider) SignCalls() []*owid.OWID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*owid.OWID(nil), m.calls...)
+}
+
+// Confirm the mocks satisfy the interfaces.
+var (
+	_ owid.Store           = (*MockStore)(nil)
+	_ owid.OWIDVerifier    = (*MockVerifier)(nil)
+	_ owid.SigningProvider = (*MockSigningProvider)(nil)
+)
@@ -0,0 +1,120 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owidtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SWAN-community/owid-go"
+)
+
+// TestMockStore checks the mock store returns its creators, records requests
+// and can be given a function to fail.
+func TestMockStore(t *testing.T) {
+	s := NewMockStore(FixedCreator())
+	c, err := s.GetCreator(Domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil || c.Domain() != Domain {
+		t.Fatal("fixed creator missing")
+	}
+	if len(s.GetCreators()) != 1 {
+		t.Fatal("expected one creator")
+	}
+	e := errors.New("unavailable")
+	s.GetCreatorFunc = func(string) (*owid.Creator, error) { return nil, e }
+	_, err = s.GetCreator(OtherDomain)
+	if err != e {
+		t.Fatalf("expected '%v' but got '%v'", e, err)
+	}
+	g := s.GetCreatorCalls()
+	if len(g) != 2 || g[0] != Domain || g[1] != OtherDomain {
+		t.Fatalf("unexpected calls '%v'", g)
+	}
+}
+
+// TestMockStoreServices checks services can sign with a creator from the mock
+// store.
+func TestMockStoreServices(t *testing.T) {
+	var c owid.Configuration
+	c.SetStore(NewMockStore(FixedCreator()))
+	st, err := owid.NewStoreWithError(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := owid.NewServices(c, st, owid.NewAccessSimple([]string{AccessKey}))
+	o, err := s.Sign(Domain, []byte(Payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := o.VerifyWithPublicKey(PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID should verify")
+	}
+}
+
+// TestMockVerifier checks the mock verifier returns the configured outcome.
+func TestMockVerifier(t *testing.T) {
+	var v owid.OWIDVerifier = &MockVerifier{Valid: true}
+	r, err := v.VerifyWithReport(OWID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Valid == false || r.Domain != Domain {
+		t.Fatal("report should be valid for the domain")
+	}
+	m := &MockVerifier{Err: errors.New("refused")}
+	b, err := m.Verify(OWID())
+	if err == nil || b {
+		t.Fatal("expected error")
+	}
+	if len(m.VerifyCalls()) != 1 {
+		t.Fatal("expected one call")
+	}
+}
+
+// TestMockSigningProvider checks OWIDs signed by the mock verify with the
+// fixed public key.
+func TestMockSigningProvider(t *testing.T) {
+	o, err := owid.NewOwid("mock.example.com", time.Now(), []byte(Payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p owid.SigningProvider = &MockSigningProvider{}
+	err = p.Sign(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := o.VerifyWithPublicKey(PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID should verify")
+	}
+	e := errors.New("failed")
+	err = (&MockSigningProvider{Err: e}).Sign(o)
+	if err != e {
+		t.Fatalf("expected '%v' but got '%v'", e, err)
+	}
+}
@@ -0,0 +1,88 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owidtest
+
+import (
+	"testing"
+)
+
+// TestOWIDCanned checks the canned OWID is still produced by the fixed creator
+// and verifies with its public key.
+func TestOWIDCanned(t *testing.T) {
+	o, err := NewOWID(FixedCreator(), []byte(Payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := o.AsBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != OWIDBase64 {
+		t.Fatalf("expected '%s' but got '%s'", OWIDBase64, b)
+	}
+	v, err := OWID().VerifyWithPublicKey(PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("canned OWID should verify")
+	}
+}
+
+// TestOWIDOtherCreator checks the second fixed creator signs OWIDs that don't
+// verify with the first creator's key.
+func TestOWIDOtherCreator(t *testing.T) {
+	o, err := NewOWID(OtherFixedCreator(), []byte(Payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := o.VerifyWithPublicKey(PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v {
+		t.Fatal("OWID should not verify with the other creator's key")
+	}
+}
+
+// TestNewServices checks a generated creator is available from the services
+// and can sign.
+func TestNewServices(t *testing.T) {
+	c, err := NewCreator("owidtest.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServices(t, c, FixedCreator())
+	g, err := s.GetCreator("owidtest.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g == nil {
+		t.Fatal("creator missing from store")
+	}
+	o, err := g.CreateOWIDandSign([]byte(Payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := g.Verify(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID should verify")
+	}
+}
@@ -0,0 +1,112 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MaxPayloadLength is the maximum length in bytes of a payload accepted by
+// NewOwid, FromBuffer and the batch signer. It can be reduced to limit the
+// memory and hashing cost of OWIDs from untrusted parties but values above
+// the format limit of 65536 bytes are ignored.
+var MaxPayloadLength = maxPayloadLength
+
+// Allowance in bytes for the fields of an OWID other than the payload when
+// limiting the size of requests.
+const owidOverheadLength = 4096
+
+// PayloadTooLargeError is returned when a payload is longer than the limit.
+type PayloadTooLargeError struct {
+	Length int // Length of the payload in bytes
+	Max    int // Maximum length permitted
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("payload length '%d' exceeds '%d'", e.Length, e.Max)
+}
+
+// payloadLimit returns the smaller of the limit and the package level limit,
+// ignoring limits that are zero or negative.
+func payloadLimit(limit int) int {
+	m := MaxPayloadLength
+	if m <= 0 || m > maxPayloadLength {
+		m = maxPayloadLength
+	}
+	if limit > 0 && limit < m {
+		return limit
+	}
+	return m
+}
+
+// checkPayload returns a PayloadTooLargeError if the payload is longer than
+// the limit.
+func checkPayload(payload []byte, limit int) error {
+	m := payloadLimit(limit)
+	if len(payload) > m {
+		return &PayloadTooLargeError{Length: len(payload), Max: m}
+	}
+	return nil
+}
+
+// readPayload reads a length prefixed payload returning a PayloadTooLargeError
+// before the payload is read if it is longer than the package level limit.
+func readPayload(b *bytes.Buffer) ([]byte, error) {
+	if b.Len() >= 4 {
+		l := int(binary.LittleEndian.Uint32(b.Bytes()))
+		if m := payloadLimit(0); l > m {
+			return nil, &PayloadTooLargeError{Length: l, Max: m}
+		}
+	}
+	return readByteArray(b, maxPayloadLength)
+}
+
+// payloadLimit returns the maximum payload length for OWIDs signed or verified
+// by the service.
+func (c *Configuration) payloadLimit() int {
+	return payloadLimit(c.MaxPayloadLength)
+}
+
+// limitRequestBody limits the request body to the size of the number of base
+// 64 OWIDs with the largest payloads permitted, plus an allowance for other
+// form fields.
+func limitRequestBody(
+	s *Services,
+	w http.ResponseWriter,
+	r *http.Request,
+	owids int) {
+	l := owids*base64.StdEncoding.EncodedLen(
+		s.Config().payloadLimit()+owidOverheadLength) +
+		owidOverheadLength
+	r.Body = http.MaxBytesReader(w, r.Body, int64(l))
+}
+
+// payloadErrorStatus returns 413 if the error is because a request or payload
+// was too large, otherwise the default status code.
+func payloadErrorStatus(err error, code int) int {
+	var p *PayloadTooLargeError
+	var m *http.MaxBytesError
+	if errors.As(err, &p) || errors.As(err, &m) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return code
+}
@@ -0,0 +1,101 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPayloadLimit checks payloads longer than the package level limit are
+// refused when created and when read.
+func TestPayloadLimit(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign(bytes.Repeat([]byte{1}, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := o.AsByteArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(m int) { MaxPayloadLength = m }(MaxPayloadLength)
+	MaxPayloadLength = 10
+	var e *PayloadTooLargeError
+	_, err = FromByteArray(b)
+	if errors.As(err, &e) == false || e.Length != 100 || e.Max != 10 {
+		t.Fatalf("expected PayloadTooLargeError but got '%v'", err)
+	}
+	_, err = NewOwid(testDomain, time.Now(), make([]byte, 11))
+	if errors.As(err, &e) == false {
+		t.Fatalf("expected PayloadTooLargeError but got '%v'", err)
+	}
+	MaxPayloadLength = maxPayloadLength + 1
+	if payloadLimit(0) != maxPayloadLength {
+		t.Fatal("limit above the format limit should be ignored")
+	}
+}
+
+// TestPayloadLimitServices checks the configured limit is applied when signing
+// and by the verify handler.
+func TestPayloadLimitServices(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := s.Sign(testDomain, bytes.Repeat([]byte{1}, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := *s.Config()
+	c.MaxPayloadLength = 10
+	s.SetConfig(c)
+	_, err = s.Sign(testDomain, bytes.Repeat([]byte{1}, 100))
+	var e *PayloadTooLargeError
+	if errors.As(err, &e) == false {
+		t.Fatalf("expected PayloadTooLargeError but got '%v'", err)
+	}
+	v, err := o.AsBase64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []url.Values{
+		{"owid": {v}},
+		{"owid": {base64.StdEncoding.EncodeToString(make([]byte, 20000))}}} {
+		r := httptest.NewRequest(
+			"POST",
+			"/owid/api/v1/verify",
+			strings.NewReader(q.Encode()))
+		r.Host = testDomain
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		HandlerVerify(s)(w, r)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected 413 but got '%d'", w.Code)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// VerificationPolicy is used by a Verifier to refuse OWIDs from parties that
+// are not approved before any cryptographic checks are performed.
+type VerificationPolicy struct {
+
+	// TrustedDomains when not empty are the only domains that are accepted.
+	TrustedDomains []string
+
+	// BlockedDomains are never accepted.
+	BlockedDomains []string
+
+	// MinimumKeyAge is the minimum time that must have elapsed since the
+	// creator's keys were created. Zero disables the check.
+	MinimumKeyAge time.Duration
+
+	// ContractURLPatterns when not empty requires the creator's contract URL
+	// to match at least one of the patterns.
+	ContractURLPatterns []*regexp.Regexp
+
+	// AllowSuspended accepts OWIDs from creators that advertise a suspended
+	// status. Suspended creators are refused by default.
+	AllowSuspended bool
+}
+
+// requiresCreator returns true if the policy needs the public information
+// associated with the creator in addition to the domain.
+func (p *VerificationPolicy) requiresCreator() bool {
+	return p.MinimumKeyAge > 0 || len(p.ContractURLPatterns) > 0
+}
+
+// checkDomain returns an error if the domain is not allowed by the policy.
+func (p *VerificationPolicy) checkDomain(domain string) error {
+	if containsDomain(p.BlockedDomains, domain) {
+		return fmt.Errorf("domain '%s' blocked by policy", domain)
+	}
+	if len(p.TrustedDomains) > 0 &&
+		containsDomain(p.TrustedDomains, domain) == false {
+		return fmt.Errorf("domain '%s' not trusted by policy", domain)
+	}
+	return nil
+}
+
+// checkCreator returns an error if the creator's public information is not
+// allowed by the policy at the time provided.
+func (p *VerificationPolicy) checkCreator(c *PublicCreator, now time.Time) error {
+	if p.MinimumKeyAge > 0 {
+		if c.Created.IsZero() {
+			return fmt.Errorf("domain '%s' key age unknown", c.Domain)
+		}
+		if now.Sub(c.Created) < p.MinimumKeyAge {
+			return fmt.Errorf(
+				"domain '%s' key created '%s' too recently",
+				c.Domain,
+				c.Created.Format(time.RFC3339))
+		}
+	}
+	if len(p.ContractURLPatterns) > 0 {
+		for _, r := range p.ContractURLPatterns {
+			if r.MatchString(c.ContractURL) {
+				return nil
+			}
+		}
+		return fmt.Errorf(
+			"domain '%s' contract URL '%s' not allowed by policy",
+			c.Domain,
+			c.ContractURL)
+	}
+	return nil
+}
+
+// containsDomain returns true if the domain is in the list ignoring case and
+// the form of internationalized domain names.
+func containsDomain(l []string, domain string) bool {
+	for _, d := range l {
+		if sameDomain(d, domain) {
+			return true
+		}
+	}
+	return false
+}
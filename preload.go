@@ -0,0 +1,190 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PinError is returned when the public key for a domain does not match any of
+// the pins set for the domain.
+type PinError struct {
+	Domain string // Domain with the unexpected key
+	Pin    string // Pin of the key found
+}
+
+func (e *PinError) Error() string {
+	return fmt.Sprintf(
+		"domain '%s' public key '%s' does not match the pinned keys",
+		e.Domain,
+		e.Pin)
+}
+
+// PinSHA256 returns the base 64 encoded SHA-256 hash of the DER encoded subject
+// public key info in the PEM provided. Used as the pin for a domain.
+func PinSHA256(spki string) (string, error) {
+	b, _ := pem.Decode([]byte(spki))
+	if b == nil {
+		return "", errors.New("public key is not PEM encoded")
+	}
+	h := sha256.Sum256(b.Bytes)
+	return base64.StdEncoding.EncodeToString(h[:]), nil
+}
+
+// SetPins sets the expected public key pins for domains. Public keys for the
+// domains that don't match one of the pins are refused with a PinError.
+// Several pins can be set for a domain to allow for key rotation. Domains
+// without pins are not checked. Must be called before the verifier is used.
+func (v *Verifier) SetPins(pins map[string][]string) {
+	v.pins = make(map[string][]string, len(pins))
+	for d, p := range pins {
+		v.pins[normalizeDomain(d)] = p
+	}
+}
+
+// checkPin returns a PinError if the domain is pinned and the public key does
+// not match any of the pins.
+func (v *Verifier) checkPin(domain string, spki string) error {
+	ps := v.pins[normalizeDomain(domain)]
+	if len(ps) == 0 {
+		return nil
+	}
+	p, err := PinSHA256(spki)
+	if err != nil {
+		return err
+	}
+	for _, i := range ps {
+		if i == p {
+			return nil
+		}
+	}
+	return &PinError{Domain: domain, Pin: p}
+}
+
+// SetPreloadInterval sets the time between background refreshes of preloaded
+// public information. Must be called before Preload.
+func (v *Verifier) SetPreloadInterval(d time.Duration) { v.preloadInterval = d }
+
+// Preload fetches and caches the public information for the partner domains
+// so that their OWIDs are verified without a request. The information is
+// refreshed in the background until Close is called. An error is returned for
+// domains that could not be fetched but the others are still cached and the
+// failed domains are retried at the next refresh.
+func (v *Verifier) Preload(domains []string) error {
+	err := v.preload(domains)
+	v.preloadMutex.Lock()
+	if v.stop != nil {
+		close(v.stop)
+	}
+	s := make(chan struct{})
+	v.stop = s
+	v.preloadMutex.Unlock()
+	go v.refreshPreloaded(domains, s)
+	return err
+}
+
+// Close stops refreshing preloaded public information.
+func (v *Verifier) Close() {
+	v.preloadMutex.Lock()
+	defer v.preloadMutex.Unlock()
+	if v.stop != nil {
+		close(v.stop)
+		v.stop = nil
+	}
+}
+
+// refreshPreloaded preloads the domains every interval until stopped.
+func (v *Verifier) refreshPreloaded(domains []string, stop chan struct{}) {
+	t := time.NewTicker(v.preloadInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			err := v.preload(domains)
+			if err != nil {
+				log.Printf("OWID:preload refresh failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+// preload fetches the public information for each domain. Information that
+// is not valid or does not match the pins is not cached and previously cached
+// information for the domain is retained.
+func (v *Verifier) preload(domains []string) error {
+	var errs []error
+	for _, d := range domains {
+		d = normalizeDomain(d)
+		p, err := v.fetchPreload(d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("domain '%s': %w", d, err))
+			continue
+		}
+		v.preloadMutex.Lock()
+		if v.preloaded == nil {
+			v.preloaded = make(map[string]*PublicCreator)
+		}
+		v.preloaded[d] = p
+		v.preloadMutex.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// fetchPreload returns the public information for the domain after checking
+// the signature, domain and pins.
+func (v *Verifier) fetchPreload(domain string) (*PublicCreator, error) {
+	o := OWID{Domain: domain}
+	p, err := o.getPublicCreator(
+		context.Background(),
+		v.httpClient(),
+		v.scheme)
+	if err != nil {
+		return nil, err
+	}
+	if sameDomain(p.Domain, domain) == false {
+		return nil, fmt.Errorf("public information is for '%s'", p.Domain)
+	}
+	s, err := p.VerifySelfSignature()
+	if err != nil {
+		return nil, err
+	}
+	if s == false {
+		return nil, errors.New("public information signature is not valid")
+	}
+	err = v.checkPin(domain, p.PublicKeySPKI)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// getPreloaded returns the preloaded public information for the normalized
+// domain or nil if the domain has not been preloaded.
+func (v *Verifier) getPreloaded(domain string) *PublicCreator {
+	v.preloadMutex.RLock()
+	defer v.preloadMutex.RUnlock()
+	return v.preloaded[domain]
+}
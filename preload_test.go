@@ -0,0 +1,90 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestVerifierPreload checks preloaded public information is used after the
+// partner becomes unavailable and that pins are enforced.
+func TestVerifierPreload(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := c.SubjectPublicKeyInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := PinSHA256(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A wrong pin prevents the domain being preloaded and verified.
+	v := NewVerifier(u.Scheme, nil)
+	defer v.Close()
+	v.SetPins(map[string][]string{u.Host: {"wrong"}})
+	err = v.Preload([]string{u.Host})
+	var e *PinError
+	if errors.As(err, &e) == false || e.Pin != p {
+		t.Fatalf("expected pin error, found '%v'", err)
+	}
+	_, err = v.Verify(o)
+	if errors.As(err, &e) == false {
+		t.Fatalf("expected pin error, found '%v'", err)
+	}
+
+	// The correct pin allows the domain to be preloaded and then verified
+	// without the partner.
+	v.SetPins(map[string][]string{u.Host: {"wrong", p}})
+	err = v.Preload([]string{u.Host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	r, err := v.VerifyWithReport(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Valid == false || r.KeySource != keySourcePreload {
+		t.Fatalf("expected valid from preload, found '%t' '%s'",
+			r.Valid,
+			r.KeySource)
+	}
+}
@@ -0,0 +1,47 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+// SigningProvider is implemented by types that sign OWIDs, for example a
+// Creator. Depend on it rather than a concrete type so that a mock can be used
+// in unit tests.
+type SigningProvider interface {
+
+	// Sign the OWID by updating the signature field. Any other OWIDs are
+	// included in the data signed.
+	Sign(o *OWID, others ...*OWID) error
+}
+
+// OWIDVerifier is implemented by Verifier. Depend on it rather than Verifier
+// so that OWIDs can be verified in unit tests without fetching public
+// information over HTTP.
+type OWIDVerifier interface {
+
+	// Verify returns true if the OWID and any others were signed by the
+	// creator for the OWID's domain.
+	Verify(o *OWID, others ...*OWID) (bool, error)
+
+	// VerifyWithReport verifies the OWID and any others returning a report
+	// that explains the outcome.
+	VerifyWithReport(o *OWID, others ...*OWID) (*VerifyReport, error)
+}
+
+// Confirm the implementations satisfy the interfaces.
+var (
+	_ SigningProvider = (*Creator)(nil)
+	_ OWIDVerifier    = (*Verifier)(nil)
+)
@@ -0,0 +1,159 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache control headers for published files. The content addressed files
+// never change so can be cached forever. The manifest changes whenever the
+// creator changes so is only cached briefly.
+const (
+	publishImmutableCache = "public, max-age=31536000, immutable"
+	publishManifestCache  = "public, max-age=60"
+)
+
+// Name of the manifest file published for each domain.
+const publishManifestName = "manifest.json"
+
+// Publisher is implemented by static hosts, such as storage buckets behind a
+// CDN, that the public information for creators is copied to whenever it
+// changes.
+type Publisher interface {
+
+	// Put writes the data to the name with the content type and cache control
+	// headers provided, replacing any existing data.
+	Put(name string, contentType string, cacheControl string, data []byte) error
+}
+
+// PublishManifest is published for each domain and references the current
+// content addressed files. Clients fetch the manifest and then the files it
+// names which can be cached indefinitely.
+type PublishManifest struct {
+	Domain    string    `json:"domain"`    // Domain of the creator
+	Updated   time.Time `json:"updated"`   // When the creator last changed
+	Creator   string    `json:"creator"`   // Name of the public information
+	PublicKey string    `json:"publicKey"` // Name of the PEM public key
+}
+
+// SetPublisher sets the static host that public information is published to
+// whenever a creator changes. Nil disables publishing.
+func (s *Services) SetPublisher(p Publisher) { s.publisher = p }
+
+// publish copies the public information for the creator to the publisher in
+// the background if one is set. Failures are logged.
+func (s *Services) publish(c *Creator) {
+	if s.publisher == nil {
+		return
+	}
+	p := s.publisher
+	go func() {
+		err := publishCreator(p, c)
+		if err != nil {
+			log.Printf("publish for '%s' failed: %s", c.domain, err.Error())
+		}
+	}()
+}
+
+// publishCreator writes the public information and public key for the creator
+// under the domain using content addressed names, followed by the manifest
+// that references them.
+func publishCreator(p Publisher, c *Creator) error {
+	i, err := publicCreator(c)
+	if err != nil {
+		return err
+	}
+	j, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	m := PublishManifest{
+		Domain:    c.domain,
+		Updated:   time.Now().UTC(),
+		Creator:   publishName("creator", j, ".json"),
+		PublicKey: publishName("public-key", []byte(c.publicKey), ".pem")}
+	err = p.Put(
+		c.domain+"/"+m.Creator,
+		"application/json; charset=utf-8",
+		publishImmutableCache,
+		j)
+	if err != nil {
+		return err
+	}
+	err = p.Put(
+		c.domain+"/"+m.PublicKey,
+		"application/x-pem-file",
+		publishImmutableCache,
+		[]byte(c.publicKey))
+	if err != nil {
+		return err
+	}
+	j, err = json.Marshal(&m)
+	if err != nil {
+		return err
+	}
+	return p.Put(
+		c.domain+"/"+publishManifestName,
+		"application/json; charset=utf-8",
+		publishManifestCache,
+		j)
+}
+
+// publishName returns a name for the data that changes whenever the data
+// changes.
+func publishName(prefix string, data []byte, ext string) string {
+	h := sha256.Sum256(data)
+	return fmt.Sprintf("%s.%s%s", prefix, hex.EncodeToString(h[:8]), ext)
+}
+
+// PublisherFile publishes to a local directory, for example one that is
+// synchronized with a CDN. The headers are not recorded.
+type PublisherFile struct {
+	dir string
+}
+
+// NewPublisherFile creates a publisher that writes to the directory.
+func NewPublisherFile(dir string) *PublisherFile {
+	return &PublisherFile{dir: dir}
+}
+
+// Put writes the data to the file with the name under the directory.
+func (f *PublisherFile) Put(
+	name string,
+	contentType string,
+	cacheControl string,
+	data []byte) error {
+	p := filepath.Join(f.dir, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+	t := p + ".tmp"
+	err = os.WriteFile(t, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(t, p)
+}
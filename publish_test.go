@@ -0,0 +1,86 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPublishCreator publishes a creator to a directory and checks the
+// manifest references files containing the public information and key.
+func TestPublishCreator(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := t.TempDir()
+	err = publishCreator(NewPublisherFile(d), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(d, testDomain, publishManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m PublishManifest
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Domain != testDomain {
+		t.Fatalf("manifest domain '%s' not '%s'", m.Domain, testDomain)
+	}
+	b, err = os.ReadFile(filepath.Join(d, testDomain, m.Creator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p PublicCreator
+	err = json.Unmarshal(b, &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := p.VerifySelfSignature()
+	if err != nil || v == false {
+		t.Fatal("published public information not valid")
+	}
+	b, err = os.ReadFile(filepath.Join(d, testDomain, m.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != c.publicKey {
+		t.Fatal("published public key differs")
+	}
+	err = publishCreator(NewPublisherFile(d), c.withStatus(CreatorSuspended))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = os.ReadFile(filepath.Join(d, testDomain, publishManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n PublishManifest
+	err = json.Unmarshal(b, &n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Creator == m.Creator || n.PublicKey != m.PublicKey {
+		t.Fatal("only the changed public information should have a new name")
+	}
+}
@@ -0,0 +1,167 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// QuarantinedCreator is a creator excluded from a store because its record or
+// keys could not be loaded. The other creators continue to be used.
+type QuarantinedCreator struct {
+	Domain string    `json:"domain"` // Domain of the creator, or record key
+	Reason string    `json:"reason"` // Why the creator was excluded
+	Since  time.Time `json:"since"`  // When the creator was first excluded
+}
+
+// quarantiner is implemented by stores that exclude creators that can't be
+// loaded.
+type quarantiner interface {
+
+	// Quarantined returns the creators excluded at the last refresh.
+	Quarantined() []*QuarantinedCreator
+
+	// setQuarantineHandler sets the function called each time a creator is
+	// newly excluded. The function is called immediately for creators already
+	// excluded.
+	setQuarantineHandler(f func(q *QuarantinedCreator))
+}
+
+// loadError returns an error if the creator's keys can't be used. Creators
+// without a private key are verify only and are not an error.
+func (c *Creator) loadError() error {
+	if c.privateKey != "" {
+		_, err := c.NewCryptoSignOnly()
+		if err != nil {
+			return fmt.Errorf("private key invalid: %s", err.Error())
+		}
+	}
+	_, err := c.NewCryptoVerifyOnly()
+	if err != nil {
+		return fmt.Errorf("public key invalid: %s", err.Error())
+	}
+	return nil
+}
+
+// Quarantined returns the creators excluded at the last refresh ordered by
+// domain.
+func (c *common) Quarantined() []*QuarantinedCreator {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	a := make([]*QuarantinedCreator, 0, len(c.quarantined))
+	for _, q := range c.quarantined {
+		a = append(a, q)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Domain < a[j].Domain })
+	return a
+}
+
+func (c *common) setQuarantineHandler(f func(q *QuarantinedCreator)) {
+	c.mutex.Lock()
+	c.onQuarantine = f
+	c.mutex.Unlock()
+	for _, q := range c.Quarantined() {
+		f(q)
+	}
+}
+
+// quarantine removes the creators that can't be used from the map and
+// replaces the quarantined creators with those removed and the records that
+// failed to load. Creators newly quarantined are logged and passed to the
+// handler.
+func (c *common) quarantine(
+	cs map[string]*Creator,
+	failed map[string]error) {
+	n := time.Now().UTC()
+	q := make(map[string]*QuarantinedCreator)
+	for d, err := range failed {
+		q[d] = &QuarantinedCreator{Domain: d, Reason: err.Error(), Since: n}
+	}
+	for d, v := range cs {
+		err := v.loadError()
+		if err == nil && c.selfTest {
+			err = v.SelfTest()
+		}
+		if err != nil {
+			delete(cs, d)
+			q[d] = &QuarantinedCreator{Domain: d, Reason: err.Error(), Since: n}
+		}
+	}
+	c.mutex.Lock()
+	var a []*QuarantinedCreator
+	for d, v := range q {
+		if p, ok := c.quarantined[d]; ok && p.Reason == v.Reason {
+			v.Since = p.Since
+		} else {
+			a = append(a, v)
+		}
+	}
+	c.quarantined = q
+	f := c.onQuarantine
+	c.mutex.Unlock()
+	for _, v := range a {
+		log.Printf("OWID:quarantined '%s': %s", v.Domain, v.Reason)
+		if f != nil {
+			f(v)
+		}
+	}
+}
+
+// Quarantined returns the creators the store excluded because they could not
+// be loaded, or an empty list if the store does not quarantine creators.
+func (s *Services) Quarantined() []*QuarantinedCreator {
+	if q, ok := s.store.(quarantiner); ok {
+		return q.Quarantined()
+	}
+	return []*QuarantinedCreator{}
+}
+
+// alertQuarantine posts a quarantine event to the configured webhooks.
+func (s *Services) alertQuarantine(q *QuarantinedCreator) {
+	s.postWebhooks(&WebhookEvent{
+		Operation: AuditQuarantine,
+		Domain:    q.Domain,
+		Timestamp: s.now().UTC(),
+		Reason:    q.Reason})
+}
+
+// unmarshalCreators returns the creators from the JSON object keyed on domain
+// and the errors for any creators that can't be unmarshalled.
+func unmarshalCreators(
+	data []byte) (map[string]*Creator, map[string]error, error) {
+	var m map[string]json.RawMessage
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, nil, err
+	}
+	cs := make(map[string]*Creator, len(m))
+	f := make(map[string]error)
+	for d, j := range m {
+		var c Creator
+		err = json.Unmarshal(j, &c)
+		if err != nil {
+			f[d] = err
+		} else {
+			cs[d] = &c
+		}
+	}
+	return cs, f, nil
+}
@@ -0,0 +1,110 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// TestQuarantine loads a local store containing a creator with a corrupt key
+// and a record that can't be read, and checks the other creators are used,
+// the bad records are quarantined and kept, and webhooks are alerted.
+func TestQuarantine(t *testing.T) {
+	g, err := newTestCreator("good.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newCreator("bad.com", "corrupt", g.publicKey, testOrgName,
+		registerContractURL, g.created, Contact{}, CreatorActive)
+	gj, err := g.MarshalStorageJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bj, err := b.MarshalStorageJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string]interface{}{
+		"good.com":   json.RawMessage(gj),
+		"bad.com":    json.RawMessage(bj),
+		"broken.com": []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "creators.json")
+	err = ioutil.WriteFile(p, j, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := l.GetCreator("good.com")
+	if err != nil || c == nil {
+		t.Fatalf("good creator not loaded '%v'", err)
+	}
+	q := l.Quarantined()
+	if len(q) != 2 || q[0].Domain != "bad.com" || q[1].Domain != "broken.com" {
+		t.Fatal("bad records not quarantined")
+	}
+
+	// Check webhooks are alerted for creators already quarantined.
+	e := make(chan WebhookEvent, 2)
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var v WebhookEvent
+			json.NewDecoder(r.Body).Decode(&v)
+			e <- v
+		}))
+	defer h.Close()
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := *s.Config()
+	f.Webhooks = []Webhook{{URL: h.URL, Secret: "secret"}}
+	NewServices(f, l, s.access)
+	for i := 0; i < 2; i++ {
+		v := <-e
+		if v.Operation != AuditQuarantine || v.Reason == "" {
+			t.Fatal("quarantine event not expected")
+		}
+	}
+
+	// Check writing a creator keeps the quarantined records.
+	n, err := newTestCreator("new.com", testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.setCreator(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err = NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Quarantined()) != 2 || len(l.GetCreators()) != 2 {
+		t.Fatal("quarantined records not kept")
+	}
+}
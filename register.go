@@ -18,13 +18,20 @@ package owid
 
 // Register contains HTML template data used to register a creator
 type Register struct {
-	Services         *Services
-	Domain           string
-	Name             string
-	ContractURL      string
-	Error            string
-	NameError        string
-	ContractURLError string
-	ReadOnly         bool
-	DisplayErrors    bool
+	Services          *Services
+	Domain            string
+	Name              string
+	ContractURL       string
+	Contact           Contact
+	AccessKey         string
+	Error             string
+	NameError         string
+	ContractURLError  string
+	ContactError      string
+	Pending           bool
+	ChallengeToken    string
+	ChallengeLocation string
+	ChallengeError    string
+	ReadOnly          bool
+	DisplayErrors     bool
 }
@@ -0,0 +1,111 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"log"
+)
+
+// Replicated is an implementation of owid.Store that writes to a primary store
+// and one or more replicas, and reads from the replicas when the primary is
+// unavailable. Used to ensure a storage outage does not stop signing.
+type Replicated struct {
+	primary  Store   // Store used for all reads and writes when available
+	replicas []Store // Stores written to after the primary and read on failure
+	strict   bool    // True if a failed write to any replica is an error
+}
+
+// NewReplicated creates a new store with the primary and replicas provided. If
+// strict is true then a write fails if any replica can't be written to,
+// otherwise replica write failures are logged and ignored.
+func NewReplicated(primary Store, strict bool, replicas ...Store) *Replicated {
+	return &Replicated{primary: primary, replicas: replicas, strict: strict}
+}
+
+// GetCreator returns the creator from the primary store, or from the first
+// replica that responds without error if the primary is unavailable.
+func (r *Replicated) GetCreator(domain string) (*Creator, error) {
+	c, err := r.primary.GetCreator(domain)
+	if err == nil {
+		return c, nil
+	}
+	for _, s := range r.replicas {
+		c, rerr := s.GetCreator(domain)
+		if rerr == nil {
+			log.Printf(
+				"OWID:primary store failed for '%s', using replica: %s",
+				domain,
+				err.Error())
+			return c, nil
+		}
+	}
+	return nil, err
+}
+
+// GetCreators returns the creators from the primary store, or from the first
+// replica with creators if the primary has none.
+func (r *Replicated) GetCreators() map[string]*Creator {
+	cs := r.primary.GetCreators()
+	if len(cs) > 0 {
+		return cs
+	}
+	for _, s := range r.replicas {
+		cs = s.GetCreators()
+		if len(cs) > 0 {
+			return cs
+		}
+	}
+	return cs
+}
+
+// setPublicOnly removes private keys from the creators loaded by the primary
+// and the replicas that support it.
+func (r *Replicated) setPublicOnly() {
+	for _, s := range append([]Store{r.primary}, r.replicas...) {
+		if p, ok := s.(publicOnlyStore); ok {
+			p.setPublicOnly()
+		}
+	}
+}
+
+// setCreator writes the creator to the primary and then all the replicas. The
+// primary must always succeed.
+func (r *Replicated) setCreator(c *Creator) error {
+	err := r.primary.setCreator(c)
+	if err != nil {
+		return err
+	}
+	for i, s := range r.replicas {
+		err = s.setCreator(c)
+		if err != nil {
+			if r.strict {
+				return fmt.Errorf(
+					"replica '%d' for '%s' %s",
+					i,
+					c.domain,
+					err.Error())
+			}
+			log.Printf(
+				"OWID:replica '%d' for '%s' failed: %s",
+				i,
+				c.domain,
+				err.Error())
+		}
+	}
+	return nil
+}
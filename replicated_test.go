@@ -0,0 +1,89 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"errors"
+	"testing"
+)
+
+// failStore is a store that is unavailable.
+type failStore struct{}
+
+func (f *failStore) GetCreator(domain string) (*Creator, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (f *failStore) GetCreators() map[string]*Creator { return nil }
+
+func (f *failStore) setCreator(c *Creator) error {
+	return errors.New("unavailable")
+}
+
+func TestReplicatedWrite(t *testing.T) {
+	p := newTestStore()
+	r := newTestStore()
+	s := NewReplicated(p, true, r)
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetCreators()[testDomain] != c || r.GetCreators()[testDomain] != c {
+		t.Fatal("creator not written to primary and replica")
+	}
+	err = NewReplicated(p, true, &failStore{}).setCreator(c)
+	if err == nil {
+		t.Fatal("strict write with failed replica should error")
+	}
+	err = NewReplicated(p, false, &failStore{}).setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewReplicated(&failStore{}, false, r).setCreator(c)
+	if err == nil {
+		t.Fatal("write with failed primary should error")
+	}
+}
+
+func TestReplicatedReadFailover(t *testing.T) {
+	r := newTestStore()
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.setCreator(c)
+	s := NewReplicated(&failStore{}, false, r)
+	f, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != c {
+		t.Fatal("creator not read from replica")
+	}
+	if len(s.GetCreators()) != 1 {
+		t.Fatal("creators not read from replica")
+	}
+	_, err = NewReplicated(&failStore{}, false, &failStore{}).GetCreator(
+		testDomain)
+	if err == nil {
+		t.Fatal("all stores failing should error")
+	}
+}
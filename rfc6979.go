@@ -0,0 +1,53 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+)
+
+// signDeterministic signs the hash with the private key using the
+// deterministic generation of k described in RFC 6979 section 3.2 with SHA-256
+// as the HMAC hash function. The same private key and hash always result in the
+// same signature. The standard library's constant time implementation is used
+// so that timing does not reveal the nonce or the private key.
+func signDeterministic(
+	priv *ecdsa.PrivateKey,
+	hash []byte) (*big.Int, *big.Int, error) {
+	b, err := priv.Sign(nil, hash, crypto.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseSignature(b)
+}
+
+// parseSignature returns the integers from an ASN.1 DER ECDSA signature.
+func parseSignature(b []byte) (*big.Int, *big.Int, error) {
+	var v struct{ R, S *big.Int }
+	rest, err := asn1.Unmarshal(b, &v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("trailing data after ASN.1 signature")
+	}
+	return v.R, v.S, nil
+}
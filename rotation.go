@@ -0,0 +1,112 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"time"
+)
+
+// The time verifiers may cache public information fetched from the creator
+// end points, matching the Cache-Control header those end points send.
+const publicInfoCacheTTL = 60 * time.Second
+
+// RotationPreview reports what replacing a creator's key would do without
+// generating or writing any keys.
+type RotationPreview struct {
+	Domain         string                 `json:"domain"`         // Domain of the creator
+	Status         CreatorStatus          `json:"status"`         // Status of the creator
+	KeyCreated     time.Time              `json:"keyCreated"`     // When the current key was created
+	KeyAge         time.Duration          `json:"keyAge"`         // Age of the current key
+	MaxOWIDAge     time.Duration          `json:"maxOWIDAge"`     // Maximum age of OWIDs that must still verify
+	CurrentPrune   time.Time              `json:"currentPrune"`   // When the current key could be pruned if retired now
+	CacheTTL       time.Duration          `json:"cacheTTL"`       // Time verifiers fetching public information may use the current key
+	PreloadRefresh time.Duration          `json:"preloadRefresh"` // Time preloading verifiers may use the current key by default
+	Archived       []*RotationArchivedKey `json:"archived"`       // Keys already retired, if an archive is available
+	Warnings       []string               `json:"warnings"`       // Issues to resolve before rotating
+}
+
+// RotationArchivedKey describes when a retired key can be pruned.
+type RotationArchivedKey struct {
+	Created    time.Time `json:"created"`    // When the key was created
+	Retired    time.Time `json:"retired"`    // When the key stopped being used
+	PrunableAt time.Time `json:"prunableAt"` // When no OWID within the maximum age needs the key
+	Prunable   bool      `json:"prunable"`   // True if the key can be pruned now
+}
+
+// PreviewRotation returns what retiring the creator's current key at the time
+// provided would do given that OWIDs up to the maximum age must still verify.
+// The archive is optional and used to report when retired keys can be pruned.
+func PreviewRotation(
+	c *Creator,
+	a KeyArchive,
+	maxOWIDAge time.Duration,
+	now time.Time) (*RotationPreview, error) {
+	if maxOWIDAge < 0 {
+		return nil, fmt.Errorf(
+			"maximum OWID age '%s' must not be negative",
+			maxOWIDAge)
+	}
+	p := RotationPreview{
+		Domain:         c.domain,
+		Status:         c.status,
+		KeyCreated:     c.created,
+		KeyAge:         now.Sub(c.created),
+		MaxOWIDAge:     maxOWIDAge,
+		CurrentPrune:   now.Add(maxOWIDAge),
+		CacheTTL:       publicInfoCacheTTL,
+		PreloadRefresh: defaultPreloadInterval}
+	if c.status == "" {
+		p.Status = CreatorActive
+	}
+	if c.status != "" && c.status != CreatorActive {
+		p.Warnings = append(p.Warnings, fmt.Sprintf(
+			"creator is %s so OWIDs are not being signed",
+			c.status))
+	}
+	if a == nil {
+		p.Warnings = append(p.Warnings,
+			"no key archive so OWIDs signed with the current key will not "+
+				"verify after rotation")
+		return &p, nil
+	}
+	ks, err := a.GetArchivedKeys(c.domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range ks {
+		r := RotationArchivedKey{
+			Created:    k.Created,
+			Retired:    k.Retired,
+			PrunableAt: k.Retired.Add(maxOWIDAge)}
+		r.Prunable = now.Before(r.PrunableAt) == false
+		p.Archived = append(p.Archived, &r)
+	}
+	return &p, nil
+}
+
+// PreviewRotation returns what retiring the current key of the creator for
+// the domain would do. Returns nil if the domain is not registered.
+func (s *Services) PreviewRotation(
+	domain string,
+	maxOWIDAge time.Duration) (*RotationPreview, error) {
+	c, err := s.store.GetCreator(domain)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	return PreviewRotation(c, s.archive, maxOWIDAge, s.now().UTC())
+}
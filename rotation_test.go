@@ -0,0 +1,108 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPreviewRotation checks the preview reports the key age and when the
+// current and archived keys can be pruned.
+func TestPreviewRotation(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := c.created.Add(48 * time.Hour)
+	p, err := PreviewRotation(c, nil, 24*time.Hour, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.KeyAge != 48*time.Hour {
+		t.Fatalf("key age '%s' not expected", p.KeyAge)
+	}
+	if p.CurrentPrune.Equal(n.Add(24*time.Hour)) == false {
+		t.Fatal("current key prune time not expected")
+	}
+	if len(p.Warnings) != 1 {
+		t.Fatal("missing archive should be warned")
+	}
+	a := NewKeyArchiveFile(filepath.Join(t.TempDir(), "archive.jsonl"))
+	err = ArchiveCreatorKey(a, c, n.Add(-36*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ArchiveCreatorKey(a, c, n.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = PreviewRotation(c, a, 24*time.Hour, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Archived) != 2 ||
+		p.Archived[0].Prunable == false ||
+		p.Archived[1].Prunable {
+		t.Fatal("archived keys prunable not expected")
+	}
+	_, err = PreviewRotation(c, a, -time.Hour, n)
+	if err == nil {
+		t.Fatal("negative maximum age should error")
+	}
+}
+
+// TestRotationPreviewHandler checks the handler returns the preview for the
+// domain without changing the creator.
+func TestRotationPreviewHandler(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := url.Values{}
+	q.Set("maxAge", "60")
+	rr := send(t, HandlerRotationPreview(s), testDomain, "", q)
+	if rr == nil {
+		return
+	}
+	var p RotationPreview
+	err = json.Unmarshal([]byte(decompressAsString(t, rr)), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Domain != testDomain || p.MaxOWIDAge != time.Hour {
+		t.Fatal("preview not expected")
+	}
+	a, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.publicKey != c.publicKey {
+		t.Fatal("preview changed the creator")
+	}
+}
@@ -0,0 +1,157 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+)
+
+// Keys in the ext object of an OpenRTB supply chain node.
+const (
+	// SupplyChainExtOWID is the key for the base 64 OWID of the hop. The OWID
+	// is signed with the OWID of the previous hop, if any.
+	SupplyChainExtOWID = "owid"
+
+	// SupplyChainExtVerified is the key set by Annotate to the result of
+	// verifying the hop.
+	SupplyChainExtVerified = "owidverified"
+)
+
+// SupplyChain is the OpenRTB 2.5 SupplyChain object, schain, describing the
+// entities that participated in selling an impression.
+type SupplyChain struct {
+	Complete int                    `json:"complete"`
+	Nodes    []SupplyChainNode      `json:"nodes"`
+	Ver      string                 `json:"ver"`
+	Ext      map[string]interface{} `json:"ext,omitempty"`
+}
+
+// SupplyChainNode is a hop in an OpenRTB supply chain. ASI is the domain of
+// the advertising system and must be the domain of the hop's OWID.
+type SupplyChainNode struct {
+	ASI    string                 `json:"asi"`
+	SID    string                 `json:"sid"`
+	HP     *int                   `json:"hp,omitempty"`
+	RID    string                 `json:"rid,omitempty"`
+	Name   string                 `json:"name,omitempty"`
+	Domain string                 `json:"domain,omitempty"`
+	Ext    map[string]interface{} `json:"ext,omitempty"`
+}
+
+func init() {
+	RegisterValueType[SupplyChainNode]("openrtb.schain.node")
+}
+
+// OWID returns the OWID in the ext object of the hop, or nil if there is not
+// one.
+func (h *SupplyChainNode) OWID() (*OWID, error) {
+	v, ok := h.Ext[SupplyChainExtOWID]
+	if ok == false {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if ok == false {
+		return nil, fmt.Errorf("schain node '%s' OWID not a string", h.ASI)
+	}
+	return FromBase64(s)
+}
+
+// SetOWID sets the OWID in the ext object of the hop.
+func (h *SupplyChainNode) SetOWID(o *OWID) error {
+	s, err := o.AsBase64()
+	if err != nil {
+		return err
+	}
+	if h.Ext == nil {
+		h.Ext = make(map[string]interface{})
+	}
+	h.Ext[SupplyChainExtOWID] = s
+	return nil
+}
+
+// NodeFromSupplyChain returns a tree with a node for each hop in the supply
+// chain. The first hop is the root and each further hop is the only child of
+// the one before it, so Node.VerifyOWID includes the previous hop's OWID. The
+// value of each node is the SupplyChainNode. Every hop must have an OWID.
+func NodeFromSupplyChain(sc *SupplyChain) (*Node, error) {
+	var r, p *Node
+	for i := range sc.Nodes {
+		o, err := sc.Nodes[i].OWID()
+		if err != nil {
+			return nil, err
+		}
+		if o == nil {
+			return nil, fmt.Errorf(
+				"schain node '%d' for '%s' has no OWID",
+				i,
+				sc.Nodes[i].ASI)
+		}
+		n := &Node{Value: sc.Nodes[i]}
+		n.OWID, err = o.AsByteArray()
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			r = n
+		} else {
+			_, err = p.AddChild(n)
+			if err != nil {
+				return nil, err
+			}
+		}
+		p = n
+	}
+	if r == nil {
+		return nil, fmt.Errorf("schain has no nodes")
+	}
+	return r, nil
+}
+
+// Annotate verifies the OWID of each hop with the OWID of the previous hop and
+// sets SupplyChainExtVerified in the hop's ext object to the result. A hop is
+// only verified if it has an OWID from the domain in its ASI and the previous
+// hop, if any, has an OWID. Errors fetching public information are returned
+// and leave the remaining hops unchanged.
+func (sc *SupplyChain) Annotate(v *Verifier) error {
+	var p *OWID
+	for i := range sc.Nodes {
+		h := &sc.Nodes[i]
+		o, err := h.OWID()
+		if err != nil {
+			return err
+		}
+		ok := o != nil &&
+			(i == 0 || p != nil) &&
+			normalizeDomain(o.Domain) == normalizeDomain(h.ASI)
+		if ok {
+			if p == nil {
+				ok, err = v.Verify(o)
+			} else {
+				ok, err = v.Verify(o, p)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if h.Ext == nil {
+			h.Ext = make(map[string]interface{})
+		}
+		h.Ext[SupplyChainExtVerified] = ok
+		p = o
+	}
+	return nil
+}
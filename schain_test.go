@@ -0,0 +1,117 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestSupplyChain builds a tree from a signed supply chain, checks the values
+// survive JSON and that Annotate marks a hop with the wrong ASI as unverified.
+func TestSupplyChain(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	sc := &SupplyChain{Complete: 1, Ver: "1.0"}
+	var p *OWID
+	for _, sid := range []string{"1", "2", "3"} {
+		var o *OWID
+		if p == nil {
+			o, err = c.CreateOWIDandSign([]byte(sid))
+		} else {
+			o, err = c.CreateOWIDandSign([]byte(sid), p)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := SupplyChainNode{ASI: u.Host, SID: sid}
+		err = n.SetOWID(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc.Nodes = append(sc.Nodes, n)
+		p = o
+	}
+	r, err := NodeFromSupplyChain(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := r.GetLeaf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.GetIndexAsString() != "0,0" {
+		t.Fatalf("leaf index '%s' not '0,0'", l.GetIndexAsString())
+	}
+	ok, err := l.VerifyOWID(NewVerifier(u.Scheme, nil))
+	if err != nil || ok == false {
+		t.Fatalf("leaf not verified '%v'", err)
+	}
+	j, err := r.AsJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = NodeFromJSON(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := r.Value.(SupplyChainNode); ok == false || v.SID != "1" {
+		t.Fatalf("root value '%v' not the first hop", r.Value)
+	}
+	sc.Nodes[1].ASI = "other.com"
+	err = sc.Annotate(NewVerifier(u.Scheme, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a SupplyChain
+	err = json.Unmarshal(b, &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range []bool{true, false, true} {
+		if a.Nodes[i].Ext[SupplyChainExtVerified] != e {
+			t.Errorf("hop '%d' verified not '%v'", i, e)
+		}
+	}
+	delete(sc.Nodes[2].Ext, SupplyChainExtOWID)
+	_, err = NodeFromSupplyChain(sc)
+	if err == nil {
+		t.Error("hop without OWID should error")
+	}
+}
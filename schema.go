@@ -0,0 +1,189 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"log"
+)
+
+// Keys of the record that holds the schema version. Table stores keep it in
+// the creators table under a different partition so it is never read as a
+// creator. Firestore keeps it in its own collection.
+const (
+	schemaTableName    = "owidschema"
+	schemaPartitionKey = "schema"
+	schemaRowKey       = "version"
+)
+
+// Backend names used to select the migrations for a store.
+const (
+	schemaBackendLocal = "local"
+	schemaBackendAWS   = "aws"
+	schemaBackendAzure = "azure"
+	schemaBackendGCP   = "gcp"
+)
+
+// schemaVersioned is implemented by stores that record the version of the
+// schema their records are written with.
+type schemaVersioned interface {
+
+	// schemaBackend returns the name used to select the store's migrations.
+	schemaBackend() string
+
+	// getSchemaVersion returns the schema version, or zero if none has been
+	// recorded.
+	getSchemaVersion() (int, error)
+
+	// setSchemaVersion records the schema version.
+	setSchemaVersion(v int) error
+}
+
+// Migration changes the records in a store from the previous schema version
+// to Version. Migrate is passed the store for the backend and must be safe to
+// run again if it fails part way through.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(s Store) error
+}
+
+// migrationBaseline records the schema version of stores created before the
+// version was recorded. The records are unchanged.
+var migrationBaseline = &Migration{
+	Version:     1,
+	Description: "record schema version",
+	Migrate:     func(s Store) error { return nil }}
+
+// migrations for each backend in ascending version order.
+var migrations = map[string][]*Migration{
+	schemaBackendLocal: {migrationBaseline},
+	schemaBackendAWS:   {migrationBaseline},
+	schemaBackendAzure: {migrationBaseline},
+	schemaBackendGCP:   {migrationBaseline}}
+
+// latestSchemaVersion returns the version the backend's records are written
+// with by this version of the package.
+func latestSchemaVersion(backend string) int {
+	m := migrations[backend]
+	if len(m) == 0 {
+		return 0
+	}
+	return m[len(m)-1].Version
+}
+
+// schemaStores returns the versioned stores underlying the store, including
+// those behind replicated and projected stores.
+func schemaStores(s Store) []schemaVersioned {
+	switch v := s.(type) {
+	case *Replicated:
+		a := schemaStores(v.primary)
+		for _, r := range v.replicas {
+			a = append(a, schemaStores(r)...)
+		}
+		return a
+	case *Projected:
+		return schemaStores(v.store)
+	case schemaVersioned:
+		return []schemaVersioned{v}
+	}
+	return nil
+}
+
+// MigrateSchema applies the migrations needed to bring every store underlying
+// the store to the latest schema version. The version is recorded after each
+// migration so that a failed run resumes from the migration that failed.
+func MigrateSchema(s Store) error {
+	for _, v := range schemaStores(s) {
+		c, err := v.getSchemaVersion()
+		if err != nil {
+			return err
+		}
+		b := v.schemaBackend()
+		if c > latestSchemaVersion(b) {
+			return &SchemaVersionError{
+				Backend: b,
+				Version: c,
+				Latest:  latestSchemaVersion(b)}
+		}
+		for _, m := range migrations[b] {
+			if m.Version <= c {
+				continue
+			}
+			err = m.Migrate(v.(Store))
+			if err != nil {
+				return fmt.Errorf(
+					"%s migration '%d' (%s) failed: %s",
+					b,
+					m.Version,
+					m.Description,
+					err.Error())
+			}
+			err = v.setSchemaVersion(m.Version)
+			if err != nil {
+				return err
+			}
+			log.Printf("OWID:%s schema migrated to '%d' (%s)",
+				b,
+				m.Version,
+				m.Description)
+			c = m.Version
+		}
+	}
+	return nil
+}
+
+// SchemaVersionError is returned when a store's records were written by a
+// newer version of the package that this version can't safely use.
+type SchemaVersionError struct {
+	Backend string // Name of the store backend
+	Version int    // Schema version recorded in the store
+	Latest  int    // Latest schema version this package supports
+}
+
+func (e *SchemaVersionError) Error() string {
+	return fmt.Sprintf(
+		"%s store schema version '%d' is newer than supported version '%d'",
+		e.Backend,
+		e.Version,
+		e.Latest)
+}
+
+// checkSchema returns an error if any store underlying the store has a newer
+// schema version than this package supports, and logs stores that need
+// migrating.
+func checkSchema(s Store) error {
+	for _, v := range schemaStores(s) {
+		c, err := v.getSchemaVersion()
+		if err != nil {
+			return err
+		}
+		b := v.schemaBackend()
+		l := latestSchemaVersion(b)
+		if c > l {
+			return &SchemaVersionError{Backend: b, Version: c, Latest: l}
+		}
+		if c < l {
+			log.Printf(
+				"OWID:%s store schema version '%d' needs migrating to '%d'",
+				b,
+				c,
+				l)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// TestMigrateSchema checks a local store without a schema version is migrated
+// to the latest version and that a store with a newer version is refused.
+func TestMigrateSchema(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "creators.json")
+	l, err := NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := l.getSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 0 {
+		t.Fatalf("expected no version but got '%d'", v)
+	}
+	err = MigrateSchema(NewReplicated(l, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = l.getSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != latestSchemaVersion(schemaBackendLocal) {
+		t.Fatalf("expected latest version but got '%d'", v)
+	}
+	err = ioutil.WriteFile(p+localSchemaSuffix, []byte("999"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Configuration{OwidFile: p}
+	_, err = NewStoreWithError(&c)
+	if err == nil {
+		t.Fatal("store with newer schema should be refused")
+	}
+	var e *SchemaVersionError
+	if errors.As(MigrateSchema(l), &e) == false || e.Version != 999 {
+		t.Fatal("expected schema version error")
+	}
+}
+
+// TestMigrateSchemaOrder checks migrations are applied in order from the
+// current version and a failure leaves the version at the last success.
+func TestMigrateSchemaOrder(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "creators.json")
+	l, err := NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a []int
+	o := migrations[schemaBackendLocal]
+	defer func() { migrations[schemaBackendLocal] = o }()
+	step := func(v int, err error) *Migration {
+		return &Migration{v, "test", func(Store) error {
+			a = append(a, v)
+			return err
+		}}
+	}
+	migrations[schemaBackendLocal] = []*Migration{
+		step(1, nil),
+		step(2, nil),
+		step(3, errors.New("failed"))}
+	err = l.setSchemaVersion(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = MigrateSchema(l)
+	if err == nil {
+		t.Fatal("expected migration to fail")
+	}
+	v, err := l.getSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 2 || len(a) != 2 || a[0] != 2 || a[1] != 3 {
+		t.Fatalf("unexpected version '%d' and migrations '%v'", v, a)
+	}
+}
@@ -0,0 +1,100 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import "fmt"
+
+// selfTestPayload is the probe payload signed and verified by SelfTest.
+var selfTestPayload = []byte("owid-self-test")
+
+// selfTester is implemented by stores that can self test creators each time
+// they are refreshed from storage.
+type selfTester interface {
+	setSelfTest(enabled bool)
+}
+
+// SelfTest signs a probe payload with the creator's private key and verifies
+// it with the public key after a round trip through the binary format.
+// Returns an error describing a missing, corrupt or mismatched key. The
+// status of the creator is ignored so that suspended creators are tested.
+func (c *Creator) SelfTest() error {
+	x, err := c.NewCryptoSignOnly()
+	if err != nil {
+		return fmt.Errorf(
+			"creator '%s' private key invalid: %s",
+			c.domain,
+			err.Error())
+	}
+	v, err := c.NewCryptoVerifyOnly()
+	if err != nil {
+		return fmt.Errorf(
+			"creator '%s' public key invalid: %s",
+			c.domain,
+			err.Error())
+	}
+	o, err := c.CreateOWID(selfTestPayload)
+	if err != nil {
+		return err
+	}
+	err = o.Sign(x, nil)
+	if err != nil {
+		return fmt.Errorf(
+			"creator '%s' signing failed: %s",
+			c.domain,
+			err.Error())
+	}
+	b, err := o.AsByteArray()
+	if err != nil {
+		return err
+	}
+	r, err := FromByteArray(b)
+	if err != nil {
+		return err
+	}
+	ok, err := r.VerifyWithCrypto(v, nil)
+	if err != nil {
+		return fmt.Errorf(
+			"creator '%s' verification failed: %s",
+			c.domain,
+			err.Error())
+	}
+	if ok == false {
+		return fmt.Errorf(
+			"creator '%s' public key does not match private key",
+			c.domain)
+	}
+	return nil
+}
+
+// SelfTestStore runs SelfTest for every creator in the store returning the
+// errors keyed on domain. An empty map means every creator passed.
+func SelfTestStore(s Store) map[string]error {
+	f := make(map[string]error)
+	for d, c := range s.GetCreators() {
+		err := c.SelfTest()
+		if err != nil {
+			f[d] = err
+		}
+	}
+	return f
+}
+
+// SelfTest runs SelfTest for every creator in the store returning the errors
+// keyed on domain.
+func (s *Services) SelfTest() map[string]error {
+	return SelfTestStore(s.store)
+}
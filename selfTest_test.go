@@ -0,0 +1,86 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestSelfTest checks creators with corrupt or mismatched keys fail the self
+// test.
+func TestSelfTest(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SelfTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, b := range map[string]*Creator{
+		"corrupt private key": newCreator(c.domain, c.privateKey[:40],
+			c.publicKey, c.name, c.contractURL, c.created, c.contact, c.status),
+		"corrupt public key": newCreator(c.domain, c.privateKey,
+			"invalid", c.name, c.contractURL, c.created, c.contact, c.status),
+		"mismatched keys": newCreator(c.domain, c.privateKey,
+			o.publicKey, c.name, c.contractURL, c.created, c.contact, c.status),
+		"suspended": c.withStatus(CreatorSuspended)} {
+		err = b.SelfTest()
+		if (n == "suspended") != (err == nil) {
+			t.Fatalf("%s self-test returned '%v'", n, err)
+		}
+	}
+}
+
+// TestSelfTestOnRefresh checks corrupt keys are logged when the store is
+// refreshed with self testing enabled.
+func TestSelfTestOnRefresh(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.SelfTest()) != 0 {
+		t.Fatal("valid creators failed self-test")
+	}
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	log.SetOutput(&b)
+	defer log.SetOutput(os.Stderr)
+	var m common
+	m.init()
+	m.setSelfTest(true)
+	m.setCreators(map[string]*Creator{testDomain: newCreator(
+		c.domain, "corrupt", c.publicKey, c.name, c.contractURL, c.created,
+		c.contact, c.status)}, nil)
+	if strings.Contains(b.String(), testDomain) == false {
+		t.Fatal("corrupt creator not logged")
+	}
+	if len(m.Quarantined()) != 1 || len(m.GetCreators()) != 0 {
+		t.Fatal("corrupt creator not quarantined")
+	}
+}
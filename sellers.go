@@ -0,0 +1,200 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The maximum number of bytes read from a sellers.json or ads.txt source.
+const maxSellersLength = 1 << 24
+
+// The default time a sellers source is used before it is fetched again.
+const defaultSellersTTL = time.Hour
+
+// SellersChecker checks that a domain appears in one of a list of sellers.json
+// or ads.txt files. A domain signing OWIDs that no source lists may be
+// spoofing a seller. Each source is fetched when first needed and again once
+// the time to live has passed.
+type SellersChecker struct {
+	sources []string      // URLs of the sellers.json or ads.txt files
+	ttl     time.Duration // Time a source is used before it is fetched again
+	clock   Clock         // Clock used for the time to live, or nil for the system clock
+	mutex   sync.Mutex    // Guards lists
+	lists   map[string]*sellersList
+}
+
+// sellersList is the registrable domains found in a source.
+type sellersList struct {
+	domains map[string]bool
+	fetched time.Time
+}
+
+// NewSellersChecker returns a checker for the sources. The format of each
+// source is found from its content so sellers.json and ads.txt files can be
+// mixed. A ttl of zero uses the default of an hour.
+func NewSellersChecker(sources []string, ttl time.Duration) *SellersChecker {
+	if ttl <= 0 {
+		ttl = defaultSellersTTL
+	}
+	return &SellersChecker{
+		sources: sources,
+		ttl:     ttl,
+		lists:   make(map[string]*sellersList)}
+}
+
+// SetClock sets the clock used to expire fetched sources. Nil uses the system
+// clock.
+func (s *SellersChecker) SetClock(c Clock) { s.clock = c }
+
+// Check returns the first source that lists the registrable domain of the
+// domain, or an empty string if none do. Sources that can't be fetched are
+// skipped. An error is only returned if no source lists the domain and at
+// least one could not be fetched.
+func (s *SellersChecker) Check(ctx context.Context, domain string) (string, error) {
+	d := registrableDomain(hostDomain(domain))
+	var err error
+	for _, u := range s.sources {
+		l, e := s.get(ctx, u)
+		if e != nil {
+			err = e
+			continue
+		}
+		if l.domains[d] {
+			return u, nil
+		}
+	}
+	return "", err
+}
+
+// get returns the list for the source fetching it if not present or expired.
+func (s *SellersChecker) get(ctx context.Context, u string) (*sellersList, error) {
+	n := clockNow(s.clock)
+	s.mutex.Lock()
+	l := s.lists[u]
+	s.mutex.Unlock()
+	if l != nil && n.Sub(l.fetched) < s.ttl {
+		return l, nil
+	}
+	d, err := fetchSellers(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	l = &sellersList{domains: d, fetched: n}
+	s.mutex.Lock()
+	s.lists[u] = l
+	s.mutex.Unlock()
+	return l, nil
+}
+
+// fetchSellers returns the registrable domains listed in the source.
+func fetchSellers(ctx context.Context, u string) (map[string]bool, error) {
+	q, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	r, err := client.Do(q)
+	if err != nil {
+		return nil, fmt.Errorf("sellers '%s' not reachable: %s", u, err.Error())
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"sellers '%s' returned status '%d'",
+			u,
+			r.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxSellersLength))
+	if err != nil {
+		return nil, err
+	}
+	return parseSellers(b)
+}
+
+// parseSellers returns the registrable domains in the sellers.json or ads.txt
+// content. Content starting with a brace is sellers.json.
+func parseSellers(b []byte) (map[string]bool, error) {
+	d := make(map[string]bool)
+	t := bytes.TrimSpace(b)
+	if bytes.HasPrefix(t, []byte("{")) {
+		var j struct {
+			Sellers []struct {
+				Domain string `json:"domain"`
+			} `json:"sellers"`
+		}
+		err := json.Unmarshal(t, &j)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range j.Sellers {
+			if s.Domain != "" {
+				d[registrableDomain(s.Domain)] = true
+			}
+		}
+		return d, nil
+	}
+	f := bufio.NewScanner(bytes.NewReader(t))
+	for f.Scan() {
+		l, _, _ := strings.Cut(f.Text(), "#")
+		v := strings.Split(l, ",")
+		if len(v) < 3 {
+			continue
+		}
+		if s := strings.TrimSpace(v[0]); s != "" {
+			d[registrableDomain(s)] = true
+		}
+	}
+	return d, f.Err()
+}
+
+// checkSellers returns an error if the configuration has sellers sources and
+// none of them list the domain.
+func checkSellers(c *Configuration, domain string) error {
+	if len(c.SellersSources) == 0 {
+		return nil
+	}
+	return checkSellersListed(
+		c,
+		NewSellersChecker(c.SellersSources, 0),
+		domain)
+}
+
+// checkSellersListed returns an error if the checker's sources do not list the
+// domain. The configured contract URL timeout limits the time taken.
+func checkSellersListed(c *Configuration, s *SellersChecker, domain string) error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(c.ContractURLTimeout)*time.Second)
+	defer cancel()
+	u, err := s.Check(ctx, domain)
+	if err != nil {
+		return err
+	}
+	if u == "" {
+		return fmt.Errorf("domain '%s' not listed in any sellers source", domain)
+	}
+	return nil
+}
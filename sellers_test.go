@@ -0,0 +1,117 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestParseSellers checks domains are found in sellers.json and ads.txt.
+func TestParseSellers(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		content string
+	}{
+		{"sellers.json", `{"sellers":[{"seller_id":"1","domain":"Example.com"},
+			{"seller_id":"2","domain":"other.org"}]}`},
+		{"ads.txt", "# comment\nwww.example.com, 1, DIRECT, abc\n" +
+			"contact=admin@other.org\nother.org, 2, RESELLER # note\n"}} {
+		d, err := parseSellers([]byte(c.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d) != 2 || d["example.com"] == false || d["other.org"] == false {
+			t.Errorf("'%s' unexpected domains '%v'", c.name, d)
+		}
+	}
+	_, err := parseSellers([]byte("{invalid"))
+	if err == nil {
+		t.Error("invalid sellers.json should error")
+	}
+}
+
+// TestSellersChecker checks sources are cached for the time to live and that
+// the result is added to the verify report.
+func TestSellersChecker(t *testing.T) {
+	var f int32
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.HandleFunc("/ads.txt", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&f, 1)
+		w.Write([]byte(u.Hostname() + ", 1, DIRECT\n"))
+	})
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	k := NewManualClock(time.Now())
+	a := NewSellersChecker([]string{h.URL + "/missing", h.URL + "/ads.txt"}, 0)
+	a.SetClock(k)
+	v := NewVerifier(u.Scheme, nil)
+	v.SetSellersChecker(a)
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		r, err := v.VerifyWithReport(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Valid == false ||
+			r.SellersListed == false ||
+			r.SellersSource != h.URL+"/ads.txt" {
+			t.Fatalf("unexpected report '%v'", r)
+		}
+	}
+	if atomic.LoadInt32(&f) != 1 {
+		t.Fatalf("ads.txt fetched '%d' times", f)
+	}
+	k.Advance(2 * defaultSellersTTL)
+	d, err := a.Check(context.Background(), "unlisted.com")
+	if err == nil || d != "" {
+		t.Fatal("unlisted domain with a failed source should error")
+	}
+	if atomic.LoadInt32(&f) != 2 {
+		t.Fatalf("ads.txt not fetched again after expiry")
+	}
+	g := s.Config()
+	g.SellersSources = []string{h.URL + "/ads.txt"}
+	if checkSellers(g, u.Host) != nil {
+		t.Error("listed domain should be allowed to register")
+	}
+	if checkSellers(g, "unlisted.com") == nil {
+		t.Error("unlisted domain should not be allowed to register")
+	}
+}
@@ -18,14 +18,25 @@ package owid
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"sync/atomic"
+	"time"
 )
 
 // Services references all the information needed for every method.
 type Services struct {
-	config Configuration // Configuration used by the server.
-	store  Store         // Instance of storage service for node data
-	access Access        // Instance of access service
+	config    atomic.Pointer[Configuration] // Configuration used by the server.
+	store     Store                         // Instance of storage service for node data
+	access    Access                        // Instance of access service
+	auditSink AuditSink                     // Optional audit log for creator changes
+	archive   KeyArchive                    // Optional archive of retired keys
+	publisher Publisher                     // Optional static host for public information
+	snapshot  *SnapshotWriter               // Optional writer of static snapshots of all creators
+	verifier  *Verifier                     // Optional verifier for OWIDs from any domain
+	bundles   bundleCache                   // Signed bundles reused by HandlerBundle
+	clock     Clock                         // Clock used for OWID dates and events, or nil for the system clock
+	locks     domainLocks                   // Serializes changes to each domain's creator
 }
 
 // NewServices a set of services to use with Shared Web State. These provide
@@ -36,30 +47,257 @@ func NewServices(
 	store Store,
 	access Access) *Services {
 	var s Services
-	s.config = config
+	s.config.Store(&config)
 	s.store = store
 	s.access = access
+	if q, ok := store.(quarantiner); ok {
+		q.setQuarantineHandler(s.alertQuarantine)
+	}
 	return &s
 }
 
-// Config returns the configuration service.
-func (s *Services) Config() *Configuration { return &s.config }
+// SetAuditSink sets the sink used to record changes to creators. Nil disables
+// the audit log.
+func (s *Services) SetAuditSink(a AuditSink) { s.auditSink = a }
+
+// SetKeyArchive sets the archive of retired keys used by Verify when an OWID
+// does not verify with the creator's current key. Nil disables the archive.
+func (s *Services) SetKeyArchive(a KeyArchive) { s.archive = a }
+
+// SetVerifier sets the verifier HandlerVerify uses for remote verification of
+// OWIDs from any domain. If the verifier's policy trusts domains then only
+// those are fetched, otherwise domains that are IP addresses or connect to
+// internal addresses are refused. The verifier's HTTP client is replaced with
+// one that enforces this when connecting, does not follow redirects and times
+// out. Nil disables remote verification.
+func (s *Services) SetVerifier(v *Verifier) {
+	if v != nil {
+		v.SetHTTPClient(newRemoteClient(
+			v.policy != nil && len(v.policy.TrustedDomains) > 0))
+	}
+	s.verifier = v
+}
+
+// SetClock sets the clock used to date OWIDs created with Sign and to time
+// stamp audit and webhook events. Nil uses the system clock.
+func (s *Services) SetClock(c Clock) { s.clock = c }
+
+// now returns the time from the services' clock.
+func (s *Services) now() time.Time { return clockNow(s.clock) }
+
+// Config returns the current configuration. The configuration returned must
+// not be modified as it may be replaced at any time with SetConfig.
+func (s *Services) Config() *Configuration { return s.config.Load() }
+
+// SetConfig replaces the configuration used by the handlers. Settings used only
+// when the services are created, such as the store and template file system,
+// are not changed.
+func (s *Services) SetConfig(c Configuration) {
+	c.store = s.config.Load().store
+	c.keyStore = s.config.Load().keyStore
+	c.templates = s.config.Load().templates
+	s.config.Store(&c)
+}
 
 // GetCreator returns the store service
 func (s *Services) GetCreator(host string) (*Creator, error) {
 	return s.store.GetCreator(host)
 }
 
+// Sign creates a new OWID for the domain containing the payload signed by the
+// creator for the domain from the store. Used by applications that host many
+// domains in a single process.
+func (s *Services) Sign(
+	domain string,
+	payload []byte,
+	others ...*OWID) (*OWID, error) {
+	err := checkPayload(payload, s.Config().MaxPayloadLength)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.getCreatorForDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+	o, err := c.createOWIDAt(payload, s.now())
+	if err != nil {
+		return nil, err
+	}
+	err = c.Sign(o, others...)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Verify returns true if the OWID and any others were signed by the creator
+// for the domain from the store.
+func (s *Services) Verify(
+	domain string,
+	o *OWID,
+	others ...*OWID) (bool, error) {
+	c, err := s.getCreatorForDomain(domain)
+	if err != nil {
+		return false, err
+	}
+	v, err := c.Verify(o, others...)
+	if err != nil || v || s.archive == nil {
+		return v, err
+	}
+	k, err := verifyArchivedKeys(s.archive, o, others)
+	return k != "", err
+}
+
+// getCreatorForDomain returns the creator for the domain or an error if the
+// domain is not in the store.
+func (s *Services) getCreatorForDomain(domain string) (*Creator, error) {
+	c, err := s.store.GetCreator(domain)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, &NotRegisteredError{Domain: domain}
+	}
+	return c, nil
+}
+
+// audit records the event if an audit sink is configured. Failures are logged
+// as the operation has already completed.
+func (s *Services) audit(
+	o AuditOperation,
+	domain string,
+	accessKey string,
+	before *Creator,
+	after *Creator) {
+	if s.auditSink == nil {
+		return
+	}
+	err := s.auditSink.Write(&AuditEvent{
+		Operation: o,
+		Domain:    domain,
+		Actor:     auditActor(accessKey),
+		Timestamp: s.now().UTC(),
+		Before:    auditCreator(before),
+		After:     auditCreator(after)})
+	if err != nil {
+		log.Printf("audit '%s' for '%s' failed: %s", o, domain, err.Error())
+	}
+}
+
+// SetCreatorStatus suspends or resumes the creator for the domain. The access
+// key is recorded in the audit log. Returns the updated creator, or nil if the
+// domain is not registered.
+func (s *Services) SetCreatorStatus(
+	domain string,
+	status CreatorStatus,
+	accessKey string) (*Creator, error) {
+	defer s.locks.lock(domain)()
+	c, err := s.store.GetCreator(domain)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	if c.status == status {
+		return c, nil
+	}
+	n := c.withStatus(status)
+	n.modified = s.now().UTC()
+	err = s.store.setCreator(n)
+	if err != nil {
+		return nil, err
+	}
+	o := AuditResume
+	if status == CreatorSuspended {
+		o = AuditSuspend
+	} else if c.status == CreatorPending {
+		o = AuditActivate
+	}
+	s.audit(o, n.domain, accessKey, c, n)
+	s.notify(o, n)
+	return n, nil
+}
+
+// SetPreferredVersion sets the version of the OWIDs the creator for the domain
+// creates. Zero restores the default version. The access key is recorded in
+// the audit log. Returns nil if the domain is not registered.
+func (s *Services) SetPreferredVersion(
+	domain string,
+	version byte,
+	accessKey string) (*Creator, error) {
+	if version != 0 {
+		err := validOWIDVersion(version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer s.locks.lock(domain)()
+	c, err := s.store.GetCreator(domain)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	if c.version == version {
+		return c, nil
+	}
+	n := c.withVersion(version)
+	err = s.store.setCreator(n)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(AuditVersion, n.domain, accessKey, c, n)
+	s.notify(AuditVersion, n)
+	return n, nil
+}
+
 // Returns true if the request is allowed to access the handler, otherwise false.
 // If false is returned then no further action is needed as the method will have
 // responded to the request already.
-func (s *Services) getAccessAllowed(w http.ResponseWriter, r *http.Request) bool {
+func (s *Services) getAccessAllowed(
+	w http.ResponseWriter,
+	r *http.Request,
+	scope Scope) bool {
 	err := r.ParseForm()
 	if err != nil {
 		returnAPIError(s, w, err, http.StatusInternalServerError)
 		return false
 	}
-	v, err := s.access.GetAllowed(r.FormValue("accesskey"))
+	return s.checkAccess(w, r.FormValue("accesskey"), scope)
+}
+
+// getAccessAllowedPost is getAccessAllowed for end points that change state.
+// Only POST is accepted and the access key is taken from the access key header
+// or the form body so that it does not appear in URLs, logs or caches. Returns
+// the access key and true if the operation is allowed.
+func (s *Services) getAccessAllowedPost(
+	w http.ResponseWriter,
+	r *http.Request,
+	scope Scope) (string, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		returnAPIError(
+			s,
+			w,
+			fmt.Errorf("method '%s' not allowed", r.Method),
+			http.StatusMethodNotAllowed)
+		return "", false
+	}
+	err := r.ParseForm()
+	if err != nil {
+		returnAPIError(s, w, err, http.StatusBadRequest)
+		return "", false
+	}
+	k := r.Header.Get(accessKeyHeader)
+	if k == "" {
+		k = r.PostFormValue("accesskey")
+	}
+	return k, s.checkAccess(w, k, scope)
+}
+
+// checkAccess returns true if the access key is allowed to perform operations
+// with the scope, otherwise responds with access denied and returns false.
+func (s *Services) checkAccess(
+	w http.ResponseWriter,
+	accessKey string,
+	scope Scope) bool {
+	v, err := s.getAllowed(accessKey, scope)
 	if v == false || err != nil {
 		returnAPIError(
 			s,
@@ -70,3 +308,12 @@ func (s *Services) getAccessAllowed(w http.ResponseWriter, r *http.Request) bool
 	}
 	return true
 }
+
+// getAllowed returns true if the access key is allowed to perform operations
+// with the scope. Uses the scope if the access service supports scopes.
+func (s *Services) getAllowed(accessKey string, scope Scope) (bool, error) {
+	if a, ok := s.access.(AccessScoped); ok {
+		return a.GetAllowedScope(accessKey, scope)
+	}
+	return s.access.GetAllowed(accessKey)
+}
@@ -0,0 +1,53 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"testing"
+)
+
+// TestServicesSignVerify signs and verifies OWIDs for multiple domains hosted
+// by the same services.
+func TestServicesSignVerify(t *testing.T) {
+	s := NewServices(Configuration{}, newTestStore(), nil)
+	for _, d := range []string{"a.com", "b.com"} {
+		err := s.store.(*testStore).addCreator(d, testOrgName, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := s.Sign("a.com", []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Sign("b.com", []byte(testPayload), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Verify("b.com", b, a)
+	if err != nil || v == false {
+		t.Fatal("OWID should verify for its domain")
+	}
+	_, err = s.Verify("a.com", b, a)
+	if err == nil {
+		t.Fatal("OWID for another domain should error")
+	}
+	_, err = s.Sign("c.com", []byte(testPayload))
+	if err == nil {
+		t.Fatal("unregistered domain should error")
+	}
+}
@@ -0,0 +1,131 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Name of the snapshot index listing every domain.
+const snapshotIndexName = "index.json"
+
+// SnapshotIndex is published alongside the snapshot and lists the public
+// information file for every domain in the store.
+type SnapshotIndex struct {
+	Updated time.Time       `json:"updated"` // When the snapshot was written
+	Domains []SnapshotEntry `json:"domains"` // Domains in alphabetical order
+}
+
+// SnapshotEntry is a domain in the snapshot index.
+type SnapshotEntry struct {
+	Domain string `json:"domain"` // Domain of the creator
+	Status string `json:"status"` // Active, suspended or pending
+	Path   string `json:"path"`   // Name of the public information file
+}
+
+// SnapshotWriter renders the public information for every creator in a store
+// to static JSON files so that a CDN or object store can answer requests for
+// public keys instead of the service. Each domain's file is named after the
+// well known path so that a host based CDN can serve it unchanged.
+type SnapshotWriter struct {
+	publisher Publisher
+	mutex     sync.Mutex // Stops snapshots interleaving
+}
+
+// NewSnapshotWriter creates a snapshot writer that writes to the publisher.
+func NewSnapshotWriter(p Publisher) *SnapshotWriter {
+	return &SnapshotWriter{publisher: p}
+}
+
+// Write renders the public information for the creators followed by the
+// index. Creators that can't be rendered, for example because the store holds
+// neither a private key nor a signature for the public information, are logged
+// and omitted.
+func (w *SnapshotWriter) Write(cs map[string]*Creator) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	ds := make([]string, 0, len(cs))
+	for d := range cs {
+		ds = append(ds, d)
+	}
+	sort.Strings(ds)
+	i := SnapshotIndex{
+		Updated: time.Now().UTC(),
+		Domains: make([]SnapshotEntry, 0, len(ds))}
+	for _, d := range ds {
+		c := cs[d]
+		p, err := publicCreator(c)
+		if err != nil {
+			log.Printf("snapshot for '%s' failed: %s", d, err.Error())
+			continue
+		}
+		j, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		n := snapshotPath(d)
+		err = w.publisher.Put(
+			n,
+			"application/json; charset=utf-8",
+			publishManifestCache,
+			j)
+		if err != nil {
+			return err
+		}
+		i.Domains = append(i.Domains, SnapshotEntry{
+			Domain: d,
+			Status: string(c.status),
+			Path:   n})
+	}
+	j, err := json.Marshal(&i)
+	if err != nil {
+		return err
+	}
+	return w.publisher.Put(
+		snapshotIndexName,
+		"application/json; charset=utf-8",
+		publishManifestCache,
+		j)
+}
+
+// snapshotPath returns the name of the public information file for the domain.
+func snapshotPath(domain string) string {
+	return normalizeDomain(domain) + wellKnownPath
+}
+
+// SetSnapshotWriter sets the writer that renders a snapshot of all the
+// creators whenever a creator changes. Nil disables snapshots.
+func (s *Services) SetSnapshotWriter(w *SnapshotWriter) { s.snapshot = w }
+
+// writeSnapshot writes a snapshot of the store in the background if a
+// snapshot writer is set. Failures are logged.
+func (s *Services) writeSnapshot() {
+	if s.snapshot == nil {
+		return
+	}
+	w := s.snapshot
+	go func() {
+		err := w.Write(s.store.GetCreators())
+		if err != nil {
+			log.Printf("snapshot failed: %s", err.Error())
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSnapshotWriter writes a snapshot of two creators, a verify only creator
+// and a creator without a signature, and checks the index and public
+// information files.
+func TestSnapshotWriter(t *testing.T) {
+	cs := make(map[string]*Creator)
+	for _, d := range []string{"b.com", "a.com", "c.com"} {
+		c, err := newTestCreator(d, testOrgName, registerContractURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs[d] = c
+	}
+	cs["c.com"] = cs["c.com"].withoutPrivateKey()
+	cs["d.com"] = cs["c.com"].withoutPrivateKey()
+	cs["d.com"].domain = "d.com"
+	cs["d.com"].signature = nil
+	d := t.TempDir()
+	err := NewSnapshotWriter(NewPublisherFile(d)).Write(cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(d, snapshotIndexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var i SnapshotIndex
+	err = json.Unmarshal(b, &i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(i.Domains) != 3 ||
+		i.Domains[0].Domain != "a.com" ||
+		i.Domains[1].Domain != "b.com" ||
+		i.Domains[2].Domain != "c.com" {
+		t.Fatalf("unexpected index '%s'", b)
+	}
+	for _, e := range []SnapshotEntry{i.Domains[0], i.Domains[2]} {
+		b, err = os.ReadFile(filepath.Join(d, filepath.FromSlash(e.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p PublicCreator
+		err = json.Unmarshal(b, &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := p.VerifySelfSignature()
+		if err != nil || v == false || p.Domain != e.Domain {
+			t.Fatalf("snapshot public information for '%s' not valid",
+				e.Domain)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Statuses of a store.
+const (
+	healthOK     = "ok"     // The last refresh succeeded
+	healthStale  = "stale"  // The last refresh failed and stale creators are served
+	healthFailed = "failed" // The creators are older than the maximum staleness
+)
+
+// staleReporter is implemented by stores that keep serving the creators they
+// hold when a refresh fails.
+type staleReporter interface {
+
+	// health returns how current the creators are.
+	health() StoreHealth
+
+	// setMaxStaleness sets how long creators can be served after refreshes
+	// start failing. Zero serves them indefinitely.
+	setMaxStaleness(d time.Duration)
+}
+
+// setMaxStaleness sets how long creators can be served after refreshes start
+// failing.
+func (c *common) setMaxStaleness(d time.Duration) { c.maxStaleness = d }
+
+// refreshError returns nil if the refresh succeeded, or failed but the
+// creators already held can still be served. Otherwise the error is returned.
+func (c *common) refreshError(err error) error {
+	if err == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.refreshErr = err
+	if c.refreshed.IsZero() ||
+		(c.maxStaleness > 0 && time.Since(c.refreshed) > c.maxStaleness) {
+		return err
+	}
+	log.Printf("OWID:refresh failed, serving stale creators: %s", err.Error())
+	return nil
+}
+
+// health returns how current the creators are.
+func (c *common) health() StoreHealth {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	h := StoreHealth{
+		Status:      healthOK,
+		LastRefresh: c.refreshed,
+		Staleness:   int(time.Since(c.refreshed).Seconds()),
+		Creators:    len(c.GetCreators())}
+	if c.refreshErr != nil {
+		h.LastError = c.refreshErr.Error()
+		h.Status = healthStale
+		if c.maxStaleness > 0 && time.Since(c.refreshed) > c.maxStaleness {
+			h.Status = healthFailed
+		}
+	}
+	return h
+}
+
+// health returns the health of the primary store.
+func (r *Replicated) health() StoreHealth { return storeHealth(r.primary) }
+
+// setMaxStaleness sets the maximum staleness of the primary and replicas.
+func (r *Replicated) setMaxStaleness(d time.Duration) {
+	for _, s := range append([]Store{r.primary}, r.replicas...) {
+		if v, ok := s.(staleReporter); ok {
+			v.setMaxStaleness(d)
+		}
+	}
+}
+
+// health returns the health of the underlying store.
+func (p *Projected) health() StoreHealth { return storeHealth(p.store) }
+
+// setMaxStaleness sets the maximum staleness of the underlying store.
+func (p *Projected) setMaxStaleness(d time.Duration) {
+	if v, ok := p.store.(staleReporter); ok {
+		v.setMaxStaleness(d)
+	}
+}
+
+// storeHealth returns the health of the store, or ok for stores that don't
+// refresh.
+func storeHealth(s Store) StoreHealth {
+	if v, ok := s.(staleReporter); ok {
+		return v.health()
+	}
+	return StoreHealth{Status: healthOK, Creators: len(s.GetCreators())}
+}
+
+// Health returns how current the creators held by the store are.
+func (s *Services) Health() StoreHealth { return storeHealth(s.store) }
+
+// HandlerHealth returns the health of the store for load balancers and
+// monitoring. The status code is 503 once the creators are older than the
+// maximum staleness. The error from the last refresh is only included in
+// debug mode.
+func HandlerHealth(s *Services) http.HandlerFunc {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		h := s.Health()
+		if s.Config().Debug == false {
+			h.LastError = ""
+		}
+		j, err := json.Marshal(&h)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		c := http.StatusOK
+		if h.Status == healthFailed {
+			c = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		sendResponseStatus(s, w, r, "application/json; charset=utf-8", c, j)
+	})
+}
@@ -0,0 +1,91 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestStaleCreators checks a local store keeps serving its creators when the
+// file can't be read until they are older than the maximum staleness.
+func TestStaleCreators(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "creators.json")
+	l, err := NewLocalStore(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.setCreator(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.setNegativeTTL(-1)
+	err = os.WriteFile(p, []byte("{"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := l.GetCreator("missing.com")
+	if err != nil || n != nil {
+		t.Fatal("stale creators should be served when refresh fails")
+	}
+	h := l.health()
+	if h.Status != healthStale || h.LastError == "" || h.Creators != 1 {
+		t.Fatalf("unexpected health '%v'", h)
+	}
+	l.setMaxStaleness(time.Minute)
+	l.mutex.Lock()
+	l.refreshed = l.refreshed.Add(-time.Hour)
+	l.mutex.Unlock()
+	_, err = l.GetCreator("missing.com")
+	if err == nil {
+		t.Fatal("creators older than the maximum staleness should not be served")
+	}
+	if l.health().Status != healthFailed {
+		t.Fatal("store should have failed")
+	}
+	s := NewServices(Configuration{}, l, nil)
+	r := httptest.NewRecorder()
+	HandlerHealth(s)(r, httptest.NewRequest("GET", "/owid/api/v3/health", nil))
+	if r.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 but got '%d'", r.Code)
+	}
+	var v StoreHealth
+	err = json.Unmarshal(r.Body.Bytes(), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Status != healthFailed || v.LastError != "" {
+		t.Fatalf("unexpected response '%s'", r.Body.String())
+	}
+	g, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := sendCors(t, HandlerHealth(g), "https://any.com").Get(
+		"Access-Control-Allow-Origin"); o != "*" {
+		t.Errorf("expected '*' origin, found '%s'", o)
+	}
+}
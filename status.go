@@ -0,0 +1,74 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import "fmt"
+
+// CreatorStatus indicates whether a creator can sign OWIDs.
+type CreatorStatus string
+
+// Statuses of a creator. Creators stored before the status was recorded are
+// active.
+const (
+	CreatorActive    CreatorStatus = "active"    // Creator can sign OWIDs
+	CreatorSuspended CreatorStatus = "suspended" // Creator refuses to sign OWIDs
+	CreatorPending   CreatorStatus = "pending"   // Domain challenge not yet met
+)
+
+// SuspendedError is returned when a suspended creator is asked to sign.
+type SuspendedError struct {
+	Domain string // Domain of the suspended creator
+}
+
+func (e *SuspendedError) Error() string {
+	return fmt.Sprintf("creator '%s' is suspended", e.Domain)
+}
+
+// PendingError is returned when a creator that has not yet met the domain
+// challenge is asked to sign.
+type PendingError struct {
+	Domain string // Domain of the pending creator
+}
+
+func (e *PendingError) Error() string {
+	return fmt.Sprintf("creator '%s' has not met the domain challenge", e.Domain)
+}
+
+// parseCreatorStatus returns the status for the string treating empty as
+// active, or an error if the status is not known.
+func parseCreatorStatus(s string) (CreatorStatus, error) {
+	switch CreatorStatus(s) {
+	case "", CreatorActive:
+		return CreatorActive, nil
+	case CreatorSuspended:
+		return CreatorSuspended, nil
+	case CreatorPending:
+		return CreatorPending, nil
+	}
+	return "", fmt.Errorf("status '%s' not supported", s)
+}
+
+// isSuspended returns true if the public information shows the creator is
+// suspended.
+func (p *PublicCreator) isSuspended() bool {
+	return CreatorStatus(p.Status) == CreatorSuspended
+}
+
+// isActive returns true if the public information shows the creator can sign.
+func (p *PublicCreator) isActive() bool {
+	return p.Status == "" || CreatorStatus(p.Status) == CreatorActive
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 )
 
 // Interface used for the storing of keys for signing, domains and organization
@@ -35,6 +36,13 @@ const (
 	privateKeyFieldName           = "privateKey"
 	nameFieldName                 = "name"
 	contractURLFieldName          = "contractURL"
+	createdFieldName              = "created"
+	emailFieldName                = "email"
+	dpoURLFieldName               = "dpoURL"
+	jurisdictionFieldName         = "jurisdiction"
+	statusFieldName               = "status"
+	preferredVersionFieldName     = "preferredVersion"
+	modifiedFieldName             = "modified"
 )
 
 // Store is an interface for accessing persistent data.
@@ -46,54 +54,104 @@ type Store interface {
 	// GetCreators return a map of all the known creators keyed on domain.
 	GetCreators() map[string]*Creator
 
-	// setCreator inserts a new creator.
+	// setCreator inserts a new creator or replaces an existing one.
 	setCreator(c *Creator) error
 }
 
 // NewStore returns a work implementation of the Store interface for the
 // configuration supplied.
+//
+// Deprecated: NewStore panics if the store can't be created. Use
+// NewStoreWithError instead.
 func NewStore(c Configuration) Store {
+	s, err := NewStoreWithError(&c)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewStoreWithError returns a work implementation of the Store interface for
+// the configuration supplied, or an error describing why the store could not
+// be created. If a store has been provided with SetStore it is returned
+// without using the other configuration values except the mode. In sign or
+// verify mode the store is a projection that only returns the creators, or
+// the keys, the mode permits.
+func NewStoreWithError(c *Configuration) (Store, error) {
 	var owidStore Store
 	var err error
 
-	if (len(c.AzureStorageAccount) > 0 || len(c.AzureStorageAccessKey) > 0) &&
+	if c.store != nil {
+		return c.projectStore(c.store), nil
+	}
+
+	if c.azureConfigured() &&
 		(c.OwidStore == "" || c.OwidStore == "azure") {
-		if len(c.AzureStorageAccount) == 0 || len(c.AzureStorageAccessKey) == 0 {
-			panic(errors.New("Either the AZURE_STORAGE_ACCOUNT or " +
-				"AZURE_STORAGE_ACCESS_KEY environment variable is not set"))
+		if c.AzureConnectionString == "" && len(c.AzureStorageAccount) == 0 {
+			return nil, errors.New("AZURE_STORAGE_ACCESS_KEY requires " +
+				"AZURE_STORAGE_ACCOUNT to be set")
 		}
 		log.Printf("OWID:Using Azure Table Storage")
-		owidStore, err = NewAzure(
-			c.AzureStorageAccount,
-			c.AzureStorageAccessKey)
+		owidStore, err = c.newAzure()
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("OWID:Azure Table Storage %s", err.Error())
 		}
 	} else if len(c.GcpProject) > 0 &&
 		(c.OwidStore == "" || c.OwidStore == "gcp") {
 		log.Printf("OWID:Using Google Firebase")
-		owidStore, err = NewFirebase(c.GcpProject)
+		owidStore, err = c.newFirebase()
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("OWID:Google Firebase %s", err.Error())
 		}
 	} else if len(c.OwidFile) > 0 &&
 		(c.OwidStore == "" || c.OwidStore == "local") {
 		log.Printf("OWID:Using local storage")
 		owidStore, err = NewLocalStore(c.OwidFile)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf(
+				"OWID:local storage '%s' %s",
+				c.OwidFile,
+				err.Error())
 		}
 	} else if c.AwsEnabled &&
 		(c.OwidStore == "" || c.OwidStore == "aws") {
 		log.Printf("OWID:Using AWS DynamoDB")
-		owidStore, err = NewAWS()
+		owidStore, err = c.newAWS()
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("OWID:AWS DynamoDB %s", err.Error())
 		}
 	}
 
+	// Creators loaded when the store was created are tested now and those
+	// loaded by later refreshes as they are loaded.
+	if t, ok := owidStore.(selfTester); ok && c.SelfTestOnRefresh {
+		t.setSelfTest(true)
+		for _, err := range SelfTestStore(owidStore) {
+			log.Printf("OWID:self-test %s", err.Error())
+		}
+	}
+
+	if n, ok := owidStore.(negativeCacher); ok {
+		n.setNegativeTTL(time.Duration(c.NegativeCacheTTL) * time.Second)
+	}
+	if s, ok := owidStore.(staleReporter); ok {
+		s.setMaxStaleness(time.Duration(c.MaxStaleness) * time.Second)
+	}
+
+	if owidStore != nil && c.OwidReplicaFile != "" {
+		log.Printf("OWID:Using local storage replica")
+		r, err := NewLocalStore(c.OwidReplicaFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"OWID:local storage replica '%s' %s",
+				c.OwidReplicaFile,
+				err.Error())
+		}
+		owidStore = NewReplicated(owidStore, false, r)
+	}
+
 	if owidStore == nil {
-		panic(fmt.Errorf("OWID:no store has been configured.\r\n" +
+		return nil, fmt.Errorf("OWID:no store has been configured.\r\n" +
 			"Provide details for store by specifying one or more sets of " +
 			"environment variables:\r\n" +
 			"(1) Azure Storage account details 'AZURE_STORAGE_ACCOUNT' & 'AZURE_STORAGE_ACCESS_KEY'\r\n" +
@@ -101,7 +159,7 @@ func NewStore(c Configuration) Store {
 			"(3) Local storage file paths in 'OWID_FILE'\r\n" +
 			"(4) AWS Dynamo DB by setting 'AWS_ENABLED' to true\r\n" +
 			"Refer to https://github.com/SWAN-community/owid-go/blob/main/README.md " +
-			"for specifics on setting up each storage solution"))
+			"for specifics on setting up each storage solution")
 	} else if c.Debug {
 
 		// If in debug more log the nodes at startup.
@@ -110,5 +168,17 @@ func NewStore(c Configuration) Store {
 		}
 	}
 
-	return owidStore
+	// Refuse stores written by a newer version of the package.
+	err = checkSchema(owidStore)
+	if err != nil {
+		return nil, fmt.Errorf("OWID:%s", err.Error())
+	}
+
+	// Verify mode never uses private keys so the key store is not needed.
+	if c.keyStore != nil && c.Mode != ModeVerify {
+		log.Printf("OWID:Using separate key store")
+		owidStore = NewCompositeStore(owidStore, c.keyStore)
+	}
+
+	return c.projectStore(owidStore), nil
 }
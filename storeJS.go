@@ -0,0 +1,37 @@
+//go:build js
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import "errors"
+
+// The cloud store SDKs do not build for JavaScript so the stores are not
+// available in WebAssembly. Stores can still be provided with SetStore.
+var errStoreNotSupported = errors.New("store not supported in WebAssembly")
+
+func (c *Configuration) newAzure() (Store, error) {
+	return nil, errStoreNotSupported
+}
+
+func (c *Configuration) newFirebase() (Store, error) {
+	return nil, errStoreNotSupported
+}
+
+func (c *Configuration) newAWS() (Store, error) {
+	return nil, errStoreNotSupported
+}
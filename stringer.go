@@ -0,0 +1,96 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// redacted replaces private key material in strings and logs.
+const redacted = "[REDACTED]"
+
+// String returns the OWID as a base 64 string.
+func (o *OWID) String() string { return o.AsString() }
+
+// LogValue returns the fields of the OWID for structured logging.
+func (o *OWID) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("version", int(o.Version)),
+		slog.String("domain", o.Domain),
+		slog.Time("date", o.Date),
+		slog.String("payload", o.PayloadAsBase64()))
+}
+
+// String returns the public fields of the creator. The private key is always
+// redacted.
+func (c *Creator) String() string {
+	return fmt.Sprintf(
+		"Creator{domain: %s, name: %s, contractURL: %s, created: %s, "+
+			"privateKey: %s}",
+		c.domain,
+		c.name,
+		c.contractURL,
+		c.created.Format(time.RFC3339),
+		redacted)
+}
+
+// GoString is used for the %#v verb and redacts the private key in the same
+// way as String.
+func (c *Creator) GoString() string { return c.String() }
+
+// LogValue returns the fields of the creator for structured logging with the
+// private key redacted.
+func (c *Creator) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("domain", c.domain),
+		slog.String("name", c.name),
+		slog.String("contractURL", c.contractURL),
+		slog.Time("created", c.created),
+		slog.String("privateKey", redacted))
+}
+
+// Redacted returns a copy of the creator with the private key replaced so that
+// it can be used for diagnostics. The copy can't be used for signing.
+func (c *Creator) Redacted() *Creator {
+	n := newCreator(
+		c.domain,
+		redacted,
+		c.publicKey,
+		c.name,
+		c.contractURL,
+		c.created,
+		c.contact,
+		c.status)
+	n.version = c.version
+	n.modified = c.modified
+	return n
+}
+
+// String describes which keys the Crypto instance has without revealing the
+// private key.
+func (c *Crypto) String() string {
+	return fmt.Sprintf(
+		"Crypto{algorithm: %s, privateKey: %t, publicKey: %t}",
+		c.Algorithm(),
+		c.privateKey != nil,
+		c.publicKey != nil)
+}
+
+// GoString is used for the %#v verb in the same way as String.
+func (c *Crypto) GoString() string { return c.String() }
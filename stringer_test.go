@@ -0,0 +1,54 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestCreatorRedacted checks that formatting and logging a creator never
+// includes the private key.
+func TestCreatorRedacted(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := c.NewCryptoSignOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	slog.New(slog.NewTextHandler(&b, nil)).Info("test", "creator", c)
+	for _, s := range []string{
+		fmt.Sprint(c),
+		fmt.Sprintf("%v %+v %#v %s", c, c, c, c),
+		fmt.Sprintf("%v %+v %#v", x, x, x),
+		fmt.Sprintf("%+v", c.Redacted()),
+		c.Redacted().privateKey,
+		b.String()} {
+		if strings.Contains(s, "PRIVATE KEY") {
+			t.Fatalf("'%s' contains the private key", s)
+		}
+	}
+	if strings.Contains(b.String(), testDomain) == false {
+		t.Fatal("log does not contain the domain")
+	}
+}
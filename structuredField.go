@@ -0,0 +1,263 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StructuredFieldHeader is the name of the HTTP header that carries an OWID
+// as an RFC 8941 structured field dictionary.
+const StructuredFieldHeader = "Sec-OWID"
+
+// The maximum length of an OWID structured field. Larger values are refused
+// before parsing as many servers and CDNs limit the size of headers.
+const maxStructuredFieldLength = 8192
+
+// Keys of the members of an OWID structured field dictionary.
+const (
+	sfVersion   = "v" // Integer version
+	sfDomain    = "d" // String domain
+	sfDate      = "t" // Integer Unix time
+	sfPayload   = "p" // Byte sequence payload
+	sfSignature = "s" // Byte sequence signature
+)
+
+// AsStructuredField returns the OWID as an RFC 8941 structured field
+// dictionary for use in the Sec-OWID header. For example:
+//
+//	v=3, d="example.com", t=1618884420, p=:cGF5bG9hZA==:, s=:...:
+func (o *OWID) AsStructuredField() (string, error) {
+	for i := 0; i < len(o.Domain); i++ {
+		if o.Domain[i] < 0x20 || o.Domain[i] > 0x7e {
+			return "", fmt.Errorf(
+				"domain '%s' can't be a structured field string",
+				o.Domain)
+		}
+	}
+	e := base64.StdEncoding
+	f := fmt.Sprintf(
+		"%s=%d, %s=%s, %s=%d, %s=:%s:, %s=:%s:",
+		sfVersion, o.Version,
+		sfDomain, sfQuote(o.Domain),
+		sfDate, o.Date.Unix(),
+		sfPayload, e.EncodeToString(o.Payload),
+		sfSignature, e.EncodeToString(o.Signature))
+	if len(f) > maxStructuredFieldLength {
+		return "", fmt.Errorf(
+			"structured field length '%d' exceeds '%d'",
+			len(f),
+			maxStructuredFieldLength)
+	}
+	return f, nil
+}
+
+// FromStructuredField returns the OWID from the RFC 8941 structured field
+// dictionary created by AsStructuredField. Unknown members and parameters are
+// ignored as required by RFC 8941.
+func FromStructuredField(f string) (*OWID, error) {
+	if len(f) > maxStructuredFieldLength {
+		return nil, fmt.Errorf(
+			"structured field length '%d' exceeds '%d'",
+			len(f),
+			maxStructuredFieldLength)
+	}
+	d, err := parseSFDictionary(f)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := d[sfVersion].(int64)
+	if ok == false || v < 0 || v > 255 {
+		return nil, errors.New("structured field version missing or invalid")
+	}
+	n, ok := d[sfDomain].(string)
+	if ok == false {
+		return nil, errors.New("structured field domain missing or invalid")
+	}
+	t, ok := d[sfDate].(int64)
+	if ok == false {
+		return nil, errors.New("structured field date missing or invalid")
+	}
+	p, ok := d[sfPayload].([]byte)
+	if ok == false {
+		return nil, errors.New("structured field payload missing or invalid")
+	}
+	s, ok := d[sfSignature].([]byte)
+	if ok == false {
+		return nil, errors.New("structured field signature missing or invalid")
+	}
+	o, err := NewOwid(n, time.Unix(t, 0).UTC(), p)
+	if err != nil {
+		return nil, err
+	}
+	o.Version = byte(v)
+	o.Signature = s
+	return o, nil
+}
+
+// sfQuote returns the string as an RFC 8941 string.
+func sfQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// parseSFDictionary parses an RFC 8941 dictionary whose members are integers,
+// strings, byte sequences or booleans. Parameters are skipped. Returns the
+// values keyed on member key with later duplicates replacing earlier ones.
+func parseSFDictionary(f string) (map[string]interface{}, error) {
+	d := make(map[string]interface{})
+	i := sfSkipSpace(f, 0)
+	for i < len(f) {
+		k, j, err := sfParseKey(f, i)
+		if err != nil {
+			return nil, err
+		}
+		i = j
+		var v interface{} = true
+		if i < len(f) && f[i] == '=' {
+			v, i, err = sfParseBareItem(f, i+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		d[k] = v
+		i, err = sfSkipParameters(f, i)
+		if err != nil {
+			return nil, err
+		}
+		i = sfSkipSpace(f, i)
+		if i >= len(f) {
+			break
+		}
+		if f[i] != ',' {
+			return nil, fmt.Errorf("structured field expected ',' at '%d'", i)
+		}
+		i = sfSkipSpace(f, i+1)
+		if i >= len(f) {
+			return nil, errors.New("structured field ends with ','")
+		}
+	}
+	return d, nil
+}
+
+// sfSkipSpace returns the index of the first character from i that is not a
+// space or tab.
+func sfSkipSpace(f string, i int) int {
+	for i < len(f) && (f[i] == ' ' || f[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// sfParseKey returns the key starting at i and the index after it.
+func sfParseKey(f string, i int) (string, int, error) {
+	s := i
+	if i >= len(f) || ((f[i] < 'a' || f[i] > 'z') && f[i] != '*') {
+		return "", i, fmt.Errorf("structured field key expected at '%d'", i)
+	}
+	for i < len(f) &&
+		((f[i] >= 'a' && f[i] <= 'z') ||
+			(f[i] >= '0' && f[i] <= '9') ||
+			strings.IndexByte("_-.*", f[i]) >= 0) {
+		i++
+	}
+	return f[s:i], i, nil
+}
+
+// sfSkipParameters returns the index after any parameters starting at i.
+func sfSkipParameters(f string, i int) (int, error) {
+	for i < len(f) && f[i] == ';' {
+		_, j, err := sfParseKey(f, sfSkipSpace(f, i+1))
+		if err != nil {
+			return i, err
+		}
+		i = j
+		if i < len(f) && f[i] == '=' {
+			_, i, err = sfParseBareItem(f, i+1)
+			if err != nil {
+				return i, err
+			}
+		}
+	}
+	return i, nil
+}
+
+// sfParseBareItem returns the integer, string, byte sequence or boolean
+// starting at i and the index after it.
+func sfParseBareItem(f string, i int) (interface{}, int, error) {
+	if i >= len(f) {
+		return nil, i, errors.New("structured field item expected")
+	}
+	switch c := f[i]; {
+	case c == '"':
+		var b strings.Builder
+		for i++; i < len(f); i++ {
+			switch f[i] {
+			case '\\':
+				i++
+				if i >= len(f) || (f[i] != '"' && f[i] != '\\') {
+					return nil, i, errors.New("structured field invalid escape")
+				}
+				b.WriteByte(f[i])
+			case '"':
+				return b.String(), i + 1, nil
+			default:
+				if f[i] < 0x20 || f[i] > 0x7e {
+					return nil, i, errors.New(
+						"structured field invalid string character")
+				}
+				b.WriteByte(f[i])
+			}
+		}
+		return nil, i, errors.New("structured field string not terminated")
+	case c == ':':
+		e := strings.IndexByte(f[i+1:], ':')
+		if e < 0 {
+			return nil, i, errors.New(
+				"structured field byte sequence not terminated")
+		}
+		b, err := base64.StdEncoding.DecodeString(f[i+1 : i+1+e])
+		if err != nil {
+			return nil, i, err
+		}
+		return b, i + e + 2, nil
+	case c == '?':
+		if i+1 < len(f) && (f[i+1] == '0' || f[i+1] == '1') {
+			return f[i+1] == '1', i + 2, nil
+		}
+		return nil, i, errors.New("structured field invalid boolean")
+	case c == '-' || (c >= '0' && c <= '9'):
+		s := i
+		if c == '-' {
+			i++
+		}
+		for i < len(f) && f[i] >= '0' && f[i] <= '9' && i-s < 16 {
+			i++
+		}
+		n, err := strconv.ParseInt(f[s:i], 10, 64)
+		if err != nil {
+			return nil, i, err
+		}
+		return n, i, nil
+	}
+	return nil, i, fmt.Errorf("structured field item not supported at '%d'", i)
+}
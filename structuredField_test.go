@@ -0,0 +1,71 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStructuredField(t *testing.T) {
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := o.AsStructuredField()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(f, `v=3, d="`+testDomain+`", t=`) == false {
+		t.Fatalf("structured field '%s' not expected", f)
+	}
+
+	// Unknown members and parameters must be ignored.
+	p, err := FromStructuredField("x=?1;a=1, " + f + `;q="b", y=:AA==:`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Domain != o.Domain ||
+		p.Version != o.Version ||
+		bytes.Equal(p.Payload, o.Payload) == false ||
+		bytes.Equal(p.Signature, o.Signature) == false {
+		t.Fatal("OWID from structured field differs")
+	}
+	v, err := c.Verify(p)
+	if err != nil || v == false {
+		t.Fatal("OWID from structured field not valid")
+	}
+	for _, i := range []string{
+		"",
+		`v=3, d="a.com", t=1, p=:AA==:`,
+		`v=3, d="a.com, t=1, p=:AA==:, s=:AA==:`,
+		`v=3, d="a.com", t=1, p=:AA==, s=:AA==:`,
+		`v=3, d="a.com", t=1, p=:AA==:, s=:AA==:,`,
+		`v=3 d="a.com", t=1, p=:AA==:, s=:AA==:`,
+		`v=999, d="a.com", t=1, p=:AA==:, s=:AA==:`,
+		`V=3, d="a.com", t=1, p=:AA==:, s=:AA==:`,
+		strings.Repeat("a", maxStructuredFieldLength+1)} {
+		if _, err = FromStructuredField(i); err == nil {
+			t.Errorf("structured field '%.40s' should be refused", i)
+		}
+	}
+}
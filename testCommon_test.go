@@ -39,11 +39,11 @@ func newTestStore() *testStore {
 }
 
 func (ts *testStore) GetCreator(domain string) (*Creator, error) {
-	return ts.creators[domain], nil
+	return ts.getCreator(domain)
 }
 
 func (ts *testStore) setCreator(c *Creator) error {
-	ts.creators[c.domain] = c
+	ts.putCreator(c)
 	return nil
 }
 
@@ -68,7 +68,10 @@ func newTestCreator(
 		privateKey,
 		publicKey,
 		name,
-		contractURL)
+		contractURL,
+		time.Now().UTC(),
+		Contact{},
+		CreatorActive)
 	return c, nil
 }
 
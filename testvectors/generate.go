@@ -0,0 +1,44 @@
+//go:build ignore
+// +build ignore
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+// Writes the test vectors to testdata/vectors.json. Run using go generate.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/SWAN-community/owid-go/testvectors"
+)
+
+func main() {
+	vs, err := testvectors.Generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := os.Create("testdata/vectors.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	err = testvectors.Write(f, vs)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,99 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package testvectors
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestRoundTrip generates the vectors, writes and reads them as JSON, and
+// verifies the result.
+func TestRoundTrip(t *testing.T) {
+	vs, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = Write(&b, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != len(vs) {
+		t.Fatalf("expected '%d' vectors, found '%d'", len(vs), len(r))
+	}
+	for _, err := range VerifyAll(r) {
+		t.Error(err)
+	}
+}
+
+// TestDeterministic checks that the generated vectors are identical to those
+// published in testdata/vectors.json.
+func TestDeterministic(t *testing.T) {
+	vs, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	err = Write(&b, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(b.Bytes(), f) == false {
+		t.Error("generated vectors differ from testdata/vectors.json")
+	}
+}
+
+// TestTampered checks that altering a vector causes verification to fail.
+func TestTampered(t *testing.T) {
+	vs, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := vs[1]
+	v.SigningData = append([]byte{}, v.SigningData...)
+	v.SigningData[0]++
+	if Verify(v) == nil {
+		t.Error("altered signing data should fail verification")
+	}
+}
+
+// TestFile verifies the vectors published in testdata/vectors.json.
+func TestFile(t *testing.T) {
+	f, err := os.Open("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	vs, err := Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range VerifyAll(vs) {
+		t.Error(err)
+	}
+}
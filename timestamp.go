@@ -0,0 +1,446 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// Timestamp is an RFC 3161 time-stamp token from a timestamping authority
+// over an OWID's signature. It is stored alongside the OWID to prove when the
+// OWID was signed independently of the creator's clock.
+type Timestamp struct {
+	Token []byte    `json:"token"` // DER encoded time-stamp token
+	Time  time.Time `json:"time"`  // Time asserted by the authority
+}
+
+// Object identifiers used in time-stamp requests and tokens.
+var (
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+// tsMessageImprint is the hash of the data being time-stamped.
+type tsMessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// tsRequest is the TimeStampReq structure from RFC 3161.
+type tsRequest struct {
+	Version        int
+	MessageImprint tsMessageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+// tsStatus is the PKIStatusInfo structure from RFC 3161.
+type tsStatus struct {
+	Status       int
+	StatusString []string       `asn1:"optional,utf8"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+// tsResponse is the TimeStampResp structure from RFC 3161.
+type tsResponse struct {
+	Status         tsStatus
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// tsContentInfo is the CMS ContentInfo that wraps the signed data.
+type tsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// tsSignedData is the CMS SignedData structure containing the TSTInfo.
+type tsSignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo tsEncapContentInfo
+	Certificates     []asn1.RawValue `asn1:"optional,set,tag:0"`
+	CRLs             []asn1.RawValue `asn1:"optional,set,tag:1"`
+	SignerInfos      []tsSignerInfo  `asn1:"set"`
+}
+
+// tsEncapContentInfo contains the DER encoded TSTInfo.
+type tsEncapContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// tsSignerInfo is the CMS SignerInfo for the authority's signature.
+type tsSignerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        []asn1.RawValue `asn1:"optional,set,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      []asn1.RawValue `asn1:"optional,set,tag:1"`
+}
+
+// tsIssuerAndSerial identifies the authority's certificate.
+type tsIssuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+// tsAttribute is a CMS signed attribute.
+type tsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// tsAccuracy is the optional accuracy of the time in the TSTInfo.
+type tsAccuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// tsInfo is the TSTInfo structure from RFC 3161.
+type tsInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint tsMessageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time       `asn1:"generalized"`
+	Accuracy       tsAccuracy      `asn1:"optional"`
+	Ordering       bool            `asn1:"optional,default:false"`
+	Nonce          *big.Int        `asn1:"optional"`
+	TSA            asn1.RawValue   `asn1:"optional,explicit,tag:0"`
+	Extensions     []asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// RequestTimestamp asks the timestamping authority at the URL for a time-stamp
+// token over the OWID's signature. The token is checked to be for the
+// signature but the authority's certificate is not verified until Verify is
+// called.
+func RequestTimestamp(tsaURL string, o *OWID) (*Timestamp, error) {
+	if len(o.Signature) == 0 {
+		return nil, errors.New("OWID must be signed before time-stamping")
+	}
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(o.Signature)
+	q, err := asn1.Marshal(tsRequest{
+		Version: 1,
+		MessageImprint: tsMessageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			HashedMessage: h[:]},
+		Nonce:   n,
+		CertReq: true})
+	if err != nil {
+		return nil, err
+	}
+	r, err := client.Post(
+		tsaURL,
+		"application/timestamp-query",
+		bytes.NewReader(q))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"timestamp authority '%s' returned status '%d'",
+			tsaURL,
+			r.StatusCode)
+	}
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var p tsResponse
+	_, err = asn1.Unmarshal(b, &p)
+	if err != nil {
+		return nil, err
+	}
+	// Status 0 is granted and 1 is granted with modifications.
+	if p.Status.Status > 1 || len(p.TimeStampToken.FullBytes) == 0 {
+		return nil, fmt.Errorf(
+			"timestamp authority '%s' refused with status '%d' %v",
+			tsaURL,
+			p.Status.Status,
+			p.Status.StatusString)
+	}
+	t := Timestamp{Token: p.TimeStampToken.FullBytes}
+	i, _, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	if i.Nonce == nil || i.Nonce.Cmp(n) != 0 {
+		return nil, errors.New("timestamp nonce does not match request")
+	}
+	err = i.checkImprint(o)
+	if err != nil {
+		return nil, err
+	}
+	t.Time = i.GenTime.UTC()
+	return &t, nil
+}
+
+// Verify checks that the token is for the OWID's signature and was signed by
+// an authority whose certificate chains to the roots and is valid for
+// time-stamping. Returns the time asserted by the authority.
+func (t *Timestamp) Verify(o *OWID, roots *x509.CertPool) (time.Time, error) {
+	i, s, err := t.parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = i.checkImprint(o)
+	if err != nil {
+		return time.Time{}, err
+	}
+	c, err := s.verifySigner()
+	if err != nil {
+		return time.Time{}, err
+	}
+	_, err = c.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: s.certificatePool(),
+		CurrentTime:   i.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return i.GenTime.UTC(), nil
+}
+
+// parse returns the TSTInfo and signed data from the token.
+func (t *Timestamp) parse() (*tsInfo, *tsSignedData, error) {
+	var c tsContentInfo
+	_, err := asn1.Unmarshal(t.Token, &c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.ContentType.Equal(oidSignedData) == false {
+		return nil, nil, errors.New("timestamp token is not signed data")
+	}
+	var s tsSignedData
+	_, err = asn1.Unmarshal(c.Content.Bytes, &s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.EncapContentInfo.ContentType.Equal(oidTSTInfo) == false {
+		return nil, nil, errors.New("timestamp token does not contain TSTInfo")
+	}
+	var i tsInfo
+	_, err = asn1.Unmarshal(s.EncapContentInfo.Content, &i)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &i, &s, nil
+}
+
+// checkImprint returns an error if the message imprint is not the hash of the
+// OWID's signature.
+func (i *tsInfo) checkImprint(o *OWID) error {
+	h, err := tsHash(i.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	d := h.New()
+	d.Write(o.Signature)
+	if bytes.Equal(d.Sum(nil), i.MessageImprint.HashedMessage) == false {
+		return errors.New("timestamp is not for the OWID signature")
+	}
+	return nil
+}
+
+// certificates returns the certificates included in the signed data.
+func (s *tsSignedData) certificates() ([]*x509.Certificate, error) {
+	if len(s.Certificates) == 0 {
+		return nil, errors.New("timestamp token contains no certificates")
+	}
+	var cs []*x509.Certificate
+	for _, r := range s.Certificates {
+		c, err := x509.ParseCertificate(r.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// certificatePool returns the certificates in the signed data as a pool of
+// possible intermediates.
+func (s *tsSignedData) certificatePool() *x509.CertPool {
+	p := x509.NewCertPool()
+	cs, _ := s.certificates()
+	for _, c := range cs {
+		p.AddCert(c)
+	}
+	return p
+}
+
+// verifySigner checks the signed attributes contain the digest of the TSTInfo
+// and that the signature over them is valid for the signer's certificate,
+// which is returned.
+func (s *tsSignedData) verifySigner() (*x509.Certificate, error) {
+	if len(s.SignerInfos) != 1 {
+		return nil, errors.New("timestamp token must have one signer")
+	}
+	si := s.SignerInfos[0]
+	cs, err := s.certificates()
+	if err != nil {
+		return nil, err
+	}
+	c := si.findCertificate(cs)
+	if c == nil {
+		return nil, errors.New("timestamp signer certificate not found")
+	}
+	h, err := tsHash(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if len(si.SignedAttrs) == 0 {
+		return nil, errors.New("timestamp token has no signed attributes")
+	}
+	md, err := si.messageDigest()
+	if err != nil {
+		return nil, err
+	}
+	d := h.New()
+	d.Write(s.EncapContentInfo.Content)
+	if bytes.Equal(d.Sum(nil), md) == false {
+		return nil, errors.New("timestamp message digest does not match")
+	}
+	a, err := tsSignatureAlgorithm(h, c)
+	if err != nil {
+		return nil, err
+	}
+	// The signature is over the DER encoding of the attributes as a SET
+	// rather than the implicitly tagged form in the signer info.
+	var v []byte
+	for _, r := range si.SignedAttrs {
+		v = append(v, r.FullBytes...)
+	}
+	b, err := asn1.Marshal(asn1.RawValue{
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      v})
+	if err != nil {
+		return nil, err
+	}
+	err = c.CheckSignature(a, b, si.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// findCertificate returns the certificate identified by the signer info, or
+// nil if not present.
+func (si *tsSignerInfo) findCertificate(
+	cs []*x509.Certificate) *x509.Certificate {
+	var is tsIssuerAndSerial
+	_, err := asn1.Unmarshal(si.SID.FullBytes, &is)
+	for _, c := range cs {
+		if err == nil {
+			if c.SerialNumber.Cmp(is.Serial) == 0 &&
+				bytes.Equal(c.RawIssuer, is.Issuer.FullBytes) {
+				return c
+			}
+		} else if si.SID.Tag == 0 &&
+			bytes.Equal(c.SubjectKeyId, si.SID.Bytes) {
+			return c
+		}
+	}
+	return nil
+}
+
+// messageDigest returns the message digest signed attribute.
+func (si *tsSignerInfo) messageDigest() ([]byte, error) {
+	for _, r := range si.SignedAttrs {
+		var a tsAttribute
+		_, err := asn1.Unmarshal(r.FullBytes, &a)
+		if err != nil {
+			return nil, err
+		}
+		if a.Type.Equal(oidMessageDigest) {
+			var d []byte
+			_, err = asn1.Unmarshal(a.Values.Bytes, &d)
+			return d, err
+		}
+	}
+	return nil, errors.New("timestamp message digest attribute missing")
+}
+
+// tsHash returns the hash for the digest algorithm identifier.
+func tsHash(o asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case o.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case o.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case o.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("timestamp digest algorithm '%s' not supported", o)
+}
+
+// tsSignatureAlgorithm returns the signature algorithm for the hash and the
+// type of public key in the certificate.
+func tsSignatureAlgorithm(
+	h crypto.Hash,
+	c *x509.Certificate) (x509.SignatureAlgorithm, error) {
+	switch c.PublicKeyAlgorithm {
+	case x509.ECDSA:
+		switch h {
+		case crypto.SHA256:
+			return x509.ECDSAWithSHA256, nil
+		case crypto.SHA384:
+			return x509.ECDSAWithSHA384, nil
+		case crypto.SHA512:
+			return x509.ECDSAWithSHA512, nil
+		}
+	case x509.RSA:
+		switch h {
+		case crypto.SHA256:
+			return x509.SHA256WithRSA, nil
+		case crypto.SHA384:
+			return x509.SHA384WithRSA, nil
+		case crypto.SHA512:
+			return x509.SHA512WithRSA, nil
+		}
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf(
+		"timestamp signature algorithm '%s' not supported",
+		c.PublicKeyAlgorithm)
+}
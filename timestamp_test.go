@@ -0,0 +1,226 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testTSA is a minimal RFC 3161 timestamping authority used to test requesting
+// and verifying time-stamp tokens.
+type testTSA struct {
+	roots *x509.CertPool
+	cert  *x509.Certificate
+	key   *ecdsa.PrivateKey
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	ck, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign}
+	cd, err := x509.CreateCertificate(rand.Reader, &ct, &ct, &ck.PublicKey, ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(cd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test TSA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}}
+	td, err := x509.CreateCertificate(rand.Reader, &tt, ca, &tk.PublicKey, ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := x509.ParseCertificate(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := x509.NewCertPool()
+	r.AddCert(ca)
+	return &testTSA{roots: r, cert: tc, key: tk}
+}
+
+func (a *testTSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	var q tsRequest
+	_, err := asn1.Unmarshal(b, &q)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	t, err := a.token(&q)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	p, _ := asn1.Marshal(tsResponse{
+		Status:         tsStatus{Status: 0},
+		TimeStampToken: asn1.RawValue{FullBytes: t}})
+	w.Header().Set("Content-Type", "application/timestamp-reply")
+	w.Write(p)
+}
+
+func (a *testTSA) token(q *tsRequest) ([]byte, error) {
+	e, err := asn1.Marshal(tsInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3},
+		MessageImprint: q.MessageImprint,
+		SerialNumber:   big.NewInt(time.Now().UnixNano()),
+		GenTime:        time.Now().UTC().Truncate(time.Second),
+		Nonce:          q.Nonce})
+	if err != nil {
+		return nil, err
+	}
+	d := sha256.Sum256(e)
+	md, _ := asn1.Marshal(d[:])
+	ct, _ := asn1.Marshal(oidTSTInfo)
+	var attrs []asn1.RawValue
+	var attrsDER []byte
+	for _, v := range []struct {
+		oid   asn1.ObjectIdentifier
+		value []byte
+	}{
+		{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}, ct},
+		{oidMessageDigest, md}} {
+		b, err := asn1.Marshal(struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		}{v.oid, []asn1.RawValue{{FullBytes: v.value}}})
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, asn1.RawValue{FullBytes: b})
+		attrsDER = append(attrsDER, b...)
+	}
+	set, _ := asn1.Marshal(asn1.RawValue{
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      attrsDER})
+	h := sha256.Sum256(set)
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, h[:])
+	if err != nil {
+		return nil, err
+	}
+	sid, _ := asn1.Marshal(tsIssuerAndSerial{
+		Issuer: asn1.RawValue{FullBytes: a.cert.RawIssuer},
+		Serial: a.cert.SerialNumber})
+	alg, _ := asn1.Marshal(pkix.AlgorithmIdentifier{Algorithm: oidSHA256})
+	s, err := asn1.Marshal(tsSignedData{
+		Version: 3,
+		DigestAlgorithms: asn1.RawValue{
+			Tag:        asn1.TagSet,
+			IsCompound: true,
+			Bytes:      alg},
+		EncapContentInfo: tsEncapContentInfo{
+			ContentType: oidTSTInfo,
+			Content:     e},
+		Certificates: []asn1.RawValue{{FullBytes: a.cert.Raw}},
+		SignerInfos: []tsSignerInfo{{
+			Version:         1,
+			SID:             asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			SignedAttrs:     attrs,
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+			Signature: sig}}})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidSignedData, asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        0,
+		IsCompound: true,
+		Bytes:      s}})
+}
+
+// TestTimestamp requests a time-stamp token for an OWID and checks it verifies
+// only for that OWID and only with the authority's root certificate.
+func TestTimestamp(t *testing.T) {
+	a := newTestTSA(t)
+	h := httptest.NewServer(a)
+	defer h.Close()
+	c, err := newTestCreator(testDomain, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := RequestTimestamp(h.URL, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ts.Verify(o, a.roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Equal(ts.Time) == false || time.Since(v) > time.Minute {
+		t.Fatalf("timestamp time '%s' not expected", v)
+	}
+	_, err = ts.Verify(o, x509.NewCertPool())
+	if err == nil {
+		t.Fatal("timestamp verified without the authority's root")
+	}
+	p, err := c.CreateOWIDandSign([]byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.Verify(p, a.roots)
+	if err == nil {
+		t.Fatal("timestamp verified for a different OWID")
+	}
+	ts.Token[len(ts.Token)-1] ^= 0xff
+	_, err = ts.Verify(o, a.roots)
+	if err == nil {
+		t.Fatal("altered timestamp token verified")
+	}
+}
@@ -0,0 +1,502 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Verifier verifies OWIDs by fetching the public information from the domain
+// associated with the OWID and applying an optional policy before any
+// cryptographic checks.
+type Verifier struct {
+	scheme          string              // The scheme used to fetch public information
+	policy          *VerificationPolicy // Optional policy, or nil for no policy
+	archive         KeyArchive          // Optional archive of retired keys, or nil
+	futureTolerance time.Duration       // Time an OWID can be dated in the future
+	retries         int                 // Times a failed fetch is retried
+	backoff         time.Duration       // Delay before the first retry, then doubled
+	breaker         *circuitBreaker     // Optional per domain circuit breaker, or nil
+	pins            map[string][]string // Expected SPKI hashes keyed on domain
+	preloadInterval time.Duration       // Time between refreshes of preloaded domains
+	preloadMutex    sync.RWMutex        // Guards preloaded and stop
+	preloaded       map[string]*PublicCreator
+	stop            chan struct{}   // Closed to stop refreshing preloaded domains
+	clock           Clock           // Clock used for tolerance and key age, or nil for the system clock
+	hook            VerifyHook      // Optional hook called with every outcome, or nil
+	sellers         *SellersChecker // Optional sellers.json or ads.txt check, or nil
+	strict          *bool           // Strict mode, or nil to use VerifyStrict
+	client          *http.Client    // Client used to fetch public information, or nil for the default
+}
+
+// VerifyStrict when true causes verifiers that have not had SetStrict called
+// to refuse OWIDs that are only accepted for compatibility. Use SetStrict to
+// select the behaviour for a single verifier.
+var VerifyStrict = false
+
+// The default time between refreshes of preloaded public information.
+const defaultPreloadInterval = time.Hour
+
+// The default time an OWID can be dated after the time of verification to
+// allow for differences between clocks.
+const defaultFutureTolerance = 5 * time.Minute
+
+// NewVerifier creates a new verifier using the scheme to fetch public
+// information and the policy provided. The policy can be nil.
+func NewVerifier(scheme string, policy *VerificationPolicy) *Verifier {
+	return &Verifier{
+		scheme:          scheme,
+		policy:          policy,
+		futureTolerance: defaultFutureTolerance,
+		preloadInterval: defaultPreloadInterval}
+}
+
+// SetFutureTolerance sets the time an OWID can be dated after the time of
+// verification before it is refused without fetching the creator's key.
+func (v *Verifier) SetFutureTolerance(d time.Duration) { v.futureTolerance = d }
+
+// SetStrict sets whether the verifier refuses OWIDs with legacy versions,
+// domains that are not normalized or dates in the future, irrespective of the
+// VerifyStrict setting. When not strict the reason strict verification would
+// refuse the OWID is recorded in the report.
+func (v *Verifier) SetStrict(s bool) { v.strict = &s }
+
+// isStrict returns true if the verifier is in strict mode.
+func (v *Verifier) isStrict() bool {
+	if v.strict == nil {
+		return VerifyStrict
+	}
+	return *v.strict
+}
+
+// SetClock sets the clock used to check future dated OWIDs, OWID and key
+// ages, circuit breaker cooldowns and retry backoffs. Nil uses the system
+// clock.
+func (v *Verifier) SetClock(c Clock) { v.clock = c }
+
+// now returns the time from the verifier's clock in UTC.
+func (v *Verifier) now() time.Time { return clockNow(v.clock).UTC() }
+
+// SetVerifyHook sets the hook called with the outcome of every verification,
+// for example an AnomalyDetector. Nil disables the hook.
+func (v *Verifier) SetVerifyHook(h VerifyHook) { v.hook = h }
+
+// SetSellersChecker sets the check that the OWID's domain appears in a
+// sellers.json or ads.txt source. The result is recorded in the report and
+// does not change whether the OWID is valid. Nil disables the check.
+func (v *Verifier) SetSellersChecker(s *SellersChecker) { v.sellers = s }
+
+// SetHTTPClient sets the client used to fetch public information. Nil uses
+// the default client.
+func (v *Verifier) SetHTTPClient(c *http.Client) { v.client = c }
+
+// httpClient returns the client used to fetch public information.
+func (v *Verifier) httpClient() *http.Client {
+	if v.client == nil {
+		return client
+	}
+	return v.client
+}
+
+// SetKeyArchive sets the archive of retired keys used when an OWID does not
+// verify with the creator's current key. Nil disables the archive.
+func (v *Verifier) SetKeyArchive(a KeyArchive) { v.archive = a }
+
+// SetRetry sets the number of times fetching public information is retried
+// after a network error or server failure, and the delay before the first
+// retry. The delay doubles for each further retry. No retries are made by
+// default.
+func (v *Verifier) SetRetry(retries int, backoff time.Duration) {
+	v.retries = retries
+	v.backoff = backoff
+}
+
+// SetCircuitBreaker stops public information being fetched from a domain for
+// the cooldown once fetches have failed the threshold number of times in a
+// row. A single fetch is then tried and the circuit closes if it succeeds. A
+// threshold of zero disables the circuit breaker.
+func (v *Verifier) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	if threshold <= 0 {
+		v.breaker = nil
+	} else {
+		v.breaker = newCircuitBreaker(threshold, cooldown)
+	}
+}
+
+// BreakerMetrics returns the state of the circuit breaker for each domain
+// that has recently failed, or empty metrics if there is no circuit breaker.
+func (v *Verifier) BreakerMetrics() BreakerMetrics {
+	if v.breaker == nil {
+		return BreakerMetrics{}
+	}
+	return v.breaker.metrics()
+}
+
+// keySourceArchive is the report key source when an archived key was used.
+const keySourceArchive = "archive"
+
+// keySourcePreload is the report key source when preloaded public information
+// was used.
+const keySourcePreload = "preload"
+
+// VerifyReport explains the outcome of verifying an OWID.
+type VerifyReport struct {
+	Domain        string        `json:"domain"`        // Domain of the OWID
+	KeySource     string        `json:"keySource"`     // URL the public key was fetched from
+	PublicKeySPKI string        `json:"publicKeySPKI"` // Public key tried
+	CreatorDomain string        `json:"creatorDomain"` // Domain in the creator's public information, if available
+	DomainMatched bool          `json:"domainMatched"` // True if the creator's domain matched the OWID's domain
+	Status        string        `json:"status"`        // Status in the creator's public information, if available
+	Date          time.Time     `json:"date"`          // Date of the OWID
+	Age           int           `json:"age"`           // Complete minutes since the OWID was created
+	FutureDated   bool          `json:"futureDated"`   // True if the OWID date is after the time of verification
+	Policy        string        `json:"policy"`        // Reason the policy refused the OWID, or empty
+	Valid         bool          `json:"valid"`         // True if the signature matched the public key
+	Duration      time.Duration `json:"duration"`      // Total time taken to verify
+	SellersListed bool          `json:"sellersListed"` // True if a sellers source lists the domain
+	SellersSource string        `json:"sellersSource"` // Sellers source listing the domain, or empty
+	Strict        string        `json:"strict"`        // Reason strict verification refuses the OWID, or empty
+	keyCreated    time.Time     // Time the key was created, or zero if not known
+}
+
+// setKey copies the outcome of fetching the public key from k.
+func (r *VerifyReport) setKey(k *VerifyReport) {
+	r.KeySource = k.KeySource
+	r.PublicKeySPKI = k.PublicKeySPKI
+	r.CreatorDomain = k.CreatorDomain
+	r.DomainMatched = k.DomainMatched
+	r.Status = k.Status
+	r.keyCreated = k.keyCreated
+	if r.Policy == "" {
+		r.Policy = k.Policy
+	}
+	if r.Strict == "" {
+		r.Strict = k.Strict
+	}
+}
+
+// keyFetcher sets the public key for the OWID's domain in the report.
+type keyFetcher func(ctx context.Context, o *OWID, r *VerifyReport) error
+
+// Verify returns true if the OWID and any others were signed by the creator
+// for the OWID's domain. An error is returned if the policy refuses the OWID.
+func (v *Verifier) Verify(o *OWID, others ...*OWID) (bool, error) {
+	r, err := v.VerifyWithReport(o, others...)
+	if err != nil {
+		return false, err
+	}
+	return r.Valid, nil
+}
+
+// VerifyWithReport verifies the OWID and any others returning a report that
+// explains the outcome. The report is returned with any error so that the
+// steps completed before the failure are available.
+func (v *Verifier) VerifyWithReport(
+	o *OWID,
+	others ...*OWID) (*VerifyReport, error) {
+	return v.VerifyWithReportContext(context.Background(), o, others...)
+}
+
+// VerifyWithReportContext is VerifyWithReport where fetching public
+// information and waiting between retries stop when the context is done.
+func (v *Verifier) VerifyWithReportContext(
+	ctx context.Context,
+	o *OWID,
+	others ...*OWID) (*VerifyReport, error) {
+	return v.verifyWithReport(ctx, o, others, v.fetchPublicKeyWithRetry)
+}
+
+// verifyWithReport verifies the OWID using the fetcher to get the public key
+// for its domain.
+func (v *Verifier) verifyWithReport(
+	ctx context.Context,
+	o *OWID,
+	others []*OWID,
+	f keyFetcher) (*VerifyReport, error) {
+	s := time.Now()
+	n := v.now()
+	r := VerifyReport{
+		Domain:      o.Domain,
+		Date:        o.Date,
+		Age:         int(n.Sub(o.Date).Minutes()),
+		FutureDated: o.Date.After(n)}
+	err := v.verify(ctx, o, others, &r, f)
+	if err == nil && v.sellers != nil {
+		r.SellersSource, _ = v.sellers.Check(ctx, o.Domain)
+		r.SellersListed = r.SellersSource != ""
+	}
+	r.Duration = time.Since(s)
+	if v.hook != nil {
+		v.hook.Verified(o.Domain, r.Age, r.Valid, err)
+	}
+	return &r, err
+}
+
+func (v *Verifier) verify(
+	ctx context.Context,
+	o *OWID,
+	others []*OWID,
+	r *VerifyReport,
+	f keyFetcher) error {
+	err := v.precheck(o)
+	if err != nil {
+		return err
+	}
+	err = v.checkStrict(strictCheck(o, v.now()), r)
+	if err != nil {
+		return err
+	}
+	if v.policy != nil {
+		err := v.policy.checkDomain(o.Domain)
+		if err != nil {
+			r.Policy = err.Error()
+			return err
+		}
+	}
+	err = f(ctx, o, r)
+	if err != nil {
+		return err
+	}
+
+	// OWIDs are dated to the minute so only those dated before the minute the
+	// key was created can't have been signed with it.
+	if r.keyCreated.IsZero() == false &&
+		o.Date.Before(r.keyCreated.Truncate(time.Minute)) {
+		if v.archive == nil {
+			return nil
+		}
+		return v.verifyArchived(o, others, r)
+	}
+	r.Valid, err = o.VerifyWithPublicKey(r.PublicKeySPKI, others...)
+	if err != nil || r.Valid || v.archive == nil {
+		return err
+	}
+	return v.verifyArchived(o, others, r)
+}
+
+// verifyArchived tries the archived keys for the OWID's domain recording the
+// key used in the report.
+func (v *Verifier) verifyArchived(
+	o *OWID,
+	others []*OWID,
+	r *VerifyReport) error {
+	k, err := verifyArchivedKeys(v.archive, o, others)
+	if err != nil || k == "" {
+		return err
+	}
+	r.Valid = true
+	r.KeySource = keySourceArchive
+	r.PublicKeySPKI = k
+	return nil
+}
+
+// precheck returns an error for OWIDs that can't be valid so that they are
+// refused before the creator's key is fetched.
+func (v *Verifier) precheck(o *OWID) error {
+	switch o.Version {
+	case owidVersion1, owidVersion2, owidVersion3, owidVersion4, owidVersion5:
+	default:
+		return fmt.Errorf("OWID version '%d' not supported", o.Version)
+	}
+	if signatureLengthValid(o.Version, len(o.Signature)) == false {
+		return &SignatureLengthError{Length: len(o.Signature)}
+	}
+	if bytes.Count(o.Signature, []byte{0}) == len(o.Signature) {
+		return fmt.Errorf("OWID for '%s' has an empty signature", o.Domain)
+	}
+	if o.Date.After(v.now().Add(v.futureTolerance)) {
+		return fmt.Errorf(
+			"OWID for '%s' dated '%s' is in the future",
+			o.Domain,
+			o.Date.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// strictCheck returns an error if the OWID is only accepted for
+// compatibility.
+func strictCheck(o *OWID, now time.Time) error {
+	if o.Version < owidVersion3 {
+		return fmt.Errorf("OWID version '%d' is a legacy version", o.Version)
+	}
+	if o.Domain != normalizeDomain(o.Domain) {
+		return fmt.Errorf("OWID domain '%s' is not normalized", o.Domain)
+	}
+	if o.Date.After(now) {
+		return fmt.Errorf(
+			"OWID for '%s' dated '%s' is after the time of verification",
+			o.Domain,
+			o.Date.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkStrict records the reason strict verification refuses the OWID in the
+// report, returning it if the verifier is strict.
+func (v *Verifier) checkStrict(err error, r *VerifyReport) error {
+	if err == nil {
+		return nil
+	}
+	if r.Strict == "" {
+		r.Strict = err.Error()
+	}
+	if v.isStrict() {
+		return err
+	}
+	return nil
+}
+
+// fetchPublicKeyWithRetry fetches the public key retrying temporary failures
+// and recording the outcome with the circuit breaker. The wait between retries
+// uses the verifier's clock. Fetching and waiting stop if the context is done.
+func (v *Verifier) fetchPublicKeyWithRetry(
+	ctx context.Context,
+	o *OWID,
+	r *VerifyReport) error {
+	d := normalizeDomain(o.Domain)
+	if v.getPreloaded(d) != nil {
+		return v.fetchPublicKey(ctx, o, r)
+	}
+	if v.breaker != nil {
+		err := v.breaker.allow(d, v.now())
+		if err != nil {
+			return err
+		}
+	}
+	b := v.backoff
+	var err error
+	for i := 0; ; i++ {
+		err = v.fetchPublicKey(ctx, o, r)
+		if err == nil || i >= v.retries || temporaryError(err) == false {
+			break
+		}
+		err = v.wait(ctx, b)
+		if err != nil {
+			break
+		}
+		b *= 2
+	}
+	if v.breaker != nil {
+		if err != nil && ctx.Err() != nil {
+			v.breaker.abandon(d)
+		} else if temporaryError(err) {
+			v.breaker.failure(d, v.now())
+		} else {
+			v.breaker.success(d)
+		}
+	}
+	return err
+}
+
+// wait returns after the duration has elapsed on the verifier's clock, or
+// with the context's error if it is done first.
+func (v *Verifier) wait(ctx context.Context, d time.Duration) error {
+	t, stop := clockTimer(v.clock, d)
+	defer stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t:
+		return nil
+	}
+}
+
+// fetchPublicKey sets the public key for the OWID's domain in the report after
+// checking the creator's public information against the policy.
+func (v *Verifier) fetchPublicKey(
+	ctx context.Context,
+	o *OWID,
+	r *VerifyReport) error {
+	var err error
+	p := v.getPreloaded(normalizeDomain(o.Domain))
+	if p != nil {
+		r.KeySource = keySourcePreload
+	} else {
+		p, err = o.getPublicCreator(
+			ctx,
+			v.httpClient(),
+			v.scheme)
+		if err != nil {
+			if v.policy != nil && v.policy.requiresCreator() {
+				err = fmt.Errorf(
+					"domain '%s' public information required by policy: %s",
+					o.Domain,
+					err.Error())
+				r.Policy = err.Error()
+				return err
+			}
+			r.PublicKeySPKI, r.KeySource, err = o.getPublicKey(
+				ctx,
+				v.httpClient(),
+				v.scheme)
+			r.DomainMatched = err == nil
+			if err != nil {
+				return err
+			}
+			return v.checkPin(o.Domain, r.PublicKeySPKI)
+		}
+		r.KeySource = o.wellKnownURL(v.scheme).String()
+	}
+	r.CreatorDomain = p.Domain
+	r.DomainMatched = sameDomain(p.Domain, o.Domain)
+	if r.DomainMatched == false {
+		return fmt.Errorf(
+			"domain '%s' public information is for '%s'",
+			o.Domain,
+			p.Domain)
+	}
+	if p.Domain != o.Domain {
+		err = v.checkStrict(fmt.Errorf(
+			"domain '%s' public information is for '%s'",
+			o.Domain,
+			p.Domain), r)
+		if err != nil {
+			r.DomainMatched = false
+			return err
+		}
+	}
+	r.Status = p.Status
+	if p.isSuspended() && (v.policy == nil || v.policy.AllowSuspended == false) {
+		err = fmt.Errorf("domain '%s' creator is suspended", o.Domain)
+		r.Policy = err.Error()
+		return err
+	}
+	if CreatorStatus(p.Status) == CreatorPending {
+		err = fmt.Errorf("domain '%s' creator is pending", o.Domain)
+		r.Policy = err.Error()
+		return err
+	}
+	if v.policy != nil {
+		err = v.policy.checkCreator(p, v.now())
+		if err != nil {
+			r.Policy = err.Error()
+			return err
+		}
+	}
+	err = v.checkPin(o.Domain, p.PublicKeySPKI)
+	if err != nil {
+		return err
+	}
+	r.PublicKeySPKI = p.PublicKeySPKI
+	r.keyCreated = p.Created
+	return nil
+}
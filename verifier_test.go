@@ -0,0 +1,308 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPolicyDomains(t *testing.T) {
+	p := VerificationPolicy{
+		TrustedDomains: []string{"trusted.com", "blocked.com"},
+		BlockedDomains: []string{"blocked.com"}}
+	if err := p.checkDomain("Trusted.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.checkDomain("blocked.com"); err == nil {
+		t.Fatal("blocked domain should error")
+	}
+	if err := p.checkDomain("other.com"); err == nil {
+		t.Fatal("untrusted domain should error")
+	}
+}
+
+func TestPolicyCreator(t *testing.T) {
+	n := time.Now().UTC()
+	p := VerificationPolicy{
+		MinimumKeyAge: time.Hour,
+		ContractURLPatterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https://example\.com/`)}}
+	c := PublicCreator{
+		Domain:      testDomain,
+		ContractURL: "https://example.com/terms",
+		Created:     n.Add(-2 * time.Hour)}
+	if err := p.checkCreator(&c, n); err != nil {
+		t.Fatal(err)
+	}
+	c.Created = n.Add(-time.Minute)
+	if err := p.checkCreator(&c, n); err == nil {
+		t.Fatal("recently created key should error")
+	}
+	c.Created = time.Time{}
+	if err := p.checkCreator(&c, n); err == nil {
+		t.Fatal("unknown key age should error")
+	}
+	c.Created = n.Add(-2 * time.Hour)
+	c.ContractURL = "https://other.com/terms"
+	if err := p.checkCreator(&c, n); err == nil {
+		t.Fatal("contract URL not matching should error")
+	}
+}
+
+// TestVerifierPolicy verifies an OWID with a remote creator and checks that
+// the policy is applied.
+func TestVerifierPolicy(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(u.Scheme, &VerificationPolicy{
+		TrustedDomains: []string{u.Host}}).Verify(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v == false {
+		t.Fatal("OWID did not pass verification")
+	}
+	_, err = NewVerifier(u.Scheme, &VerificationPolicy{
+		BlockedDomains: []string{u.Host}}).Verify(o)
+	if err == nil {
+		t.Fatal("blocked domain should error")
+	}
+	_, err = NewVerifier(u.Scheme, &VerificationPolicy{
+		MinimumKeyAge: time.Hour}).Verify(o)
+	if err == nil {
+		t.Fatal("recently created key should error")
+	}
+}
+
+// TestVerifierReport checks the report explains the key used and the policy
+// outcome.
+func TestVerifierReport(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewVerifier(u.Scheme, nil).VerifyWithReport(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Valid == false || r.DomainMatched == false {
+		t.Fatal("OWID did not pass verification")
+	}
+	if r.KeySource != h.URL+wellKnownPath {
+		t.Fatalf("key source '%s' not expected", r.KeySource)
+	}
+	k, _ := c.SubjectPublicKeyInfo()
+	if r.PublicKeySPKI != k {
+		t.Fatal("public key not expected")
+	}
+	r, err = NewVerifier(u.Scheme, &VerificationPolicy{
+		BlockedDomains: []string{u.Host}}).VerifyWithReport(o)
+	if err == nil || r.Policy == "" || r.Valid {
+		t.Fatal("report should contain the policy failure")
+	}
+}
+
+// TestVerifierSuspended checks OWIDs from a creator that advertises a
+// suspended status are refused unless the policy allows them.
+func TestVerifierSuspended(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.SetCreatorStatus(u.Host, CreatorSuspended, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewVerifier(u.Scheme, nil).VerifyWithReport(o)
+	if err == nil || r.Status != string(CreatorSuspended) {
+		t.Fatal("suspended creator should be refused")
+	}
+	v, err := NewVerifier(u.Scheme, &VerificationPolicy{
+		AllowSuspended: true}).Verify(o)
+	if err != nil || v == false {
+		t.Fatalf("suspended creator allowed by policy refused '%v'", err)
+	}
+}
+
+// TestVerifierPrecheck checks OWIDs that can't be valid are refused without
+// fetching the creator's public information.
+func TestVerifierPrecheck(t *testing.T) {
+	var n int32
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&n, 1)
+			w.WriteHeader(http.StatusNotFound)
+		}))
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(u.Scheme, nil)
+	for _, f := range []func(o *OWID){
+		func(o *OWID) { o.Version = 9 },
+		func(o *OWID) { o.Signature = make([]byte, signatureLength) },
+		func(o *OWID) { o.Signature = o.Signature[:10] },
+		func(o *OWID) { o.Date = time.Now().Add(time.Hour) }} {
+		o, err := c.CreateOWIDandSign([]byte(testPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f(o)
+		_, err = v.Verify(o)
+		if err == nil {
+			t.Error("invalid OWID should be refused")
+		}
+	}
+	if atomic.LoadInt32(&n) != 0 {
+		t.Fatal("public information fetched for invalid OWIDs")
+	}
+}
+
+// TestVerifierStrict checks that OWIDs only accepted for compatibility are
+// reported when lenient and refused when strict, either for the verifier or
+// for the package.
+func TestVerifierStrict(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(wellKnownPath, HandlerCreator(s))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(u.Scheme, nil)
+	v.SetStrict(true)
+	r, err := v.VerifyWithReport(o)
+	if err != nil || r.Valid == false || r.Strict != "" {
+		t.Fatalf("conforming OWID refused in strict mode '%v'", err)
+	}
+	f := c.withVersion(owidVersion2).
+		WithClock(NewManualClock(time.Now().Add(2 * time.Minute)))
+	for _, l := range []*Creator{c.withVersion(owidVersion2), f} {
+		o, err := l.CreateOWIDandSign([]byte(testPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewVerifier(u.Scheme, nil).VerifyWithReport(o)
+		if err != nil || r.Valid == false || r.Strict == "" {
+			t.Fatal("lenient verifier should accept and report the OWID")
+		}
+		r, err = v.VerifyWithReport(o)
+		if err == nil || r.Valid || r.Strict != err.Error() {
+			t.Fatal("strict verifier should refuse the OWID")
+		}
+	}
+	o, err = c.withVersion(owidVersion1).CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	VerifyStrict = true
+	defer func() { VerifyStrict = false }()
+	_, err = NewVerifier(u.Scheme, nil).Verify(o)
+	if err == nil {
+		t.Fatal("VerifyStrict should refuse the OWID")
+	}
+	l := NewVerifier(u.Scheme, nil)
+	l.SetStrict(false)
+	b, err := l.Verify(o)
+	if err != nil || b == false {
+		t.Fatal("SetStrict should override VerifyStrict")
+	}
+	err = strictCheck(&OWID{
+		Version: owidVersion3,
+		Domain:  "Example.COM"}, time.Now())
+	if err == nil {
+		t.Fatal("domain that is not normalized should be refused")
+	}
+}
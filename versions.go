@@ -0,0 +1,96 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Versions of the API served. New end points are added to the latest version
+// so that clients of earlier versions are unaffected.
+const (
+	apiVersionMin    = 1
+	apiVersionLatest = 3
+)
+
+// apiRoute is an end point of the versioned API that is available from the
+// version it was introduced in onwards.
+type apiRoute struct {
+	name    string                           // Path after the version
+	since   int                              // First version with the end point
+	debug   bool                             // True if only available in debug mode
+	handler func(*Services) http.HandlerFunc // Creates the handler
+}
+
+// apiRoutes is the registry of versioned end points. The JWKS and JSON
+// register end points were introduced with v2 alongside the registry. End
+// points added after v3 became the latest version start from v3. Batch
+// verification uses the verify end point with the parent parameter so has no
+// separate route.
+var apiRoutes = []apiRoute{
+	{"public-key", 1, false, HandlerPublicKey},
+	{"creator", 1, false, HandlerCreator},
+	{"verify", 1, false, HandlerVerify},
+	{"bundle", 1, false, HandlerBundle},
+	{"status", 1, false, HandlerCreatorStatus},
+	{"preferred-version", 3, false, HandlerCreatorVersion},
+	{"owids", 1, true, HandlerOwidsJSON},
+	{"jwks", 2, false, HandlerJWKS},
+	{"rotation-preview", 3, false, HandlerRotationPreview},
+	{"health", 3, false, HandlerHealth},
+	{"decode", 1, false, HandlerDecode},
+	{"verify.js", 3, false, HandlerVerifyJS},
+	{"import", 3, false, HandlerImport},
+	{"register", 2, false, HandlerRegisterAPI}}
+
+// apiPath returns the path of the end point for the version.
+func apiPath(version int, name string) string {
+	return fmt.Sprintf("/owid/api/v%d/%s", version, name)
+}
+
+// addAPIHandlers adds every end point to each version that supports it.
+func addAPIHandlers(s *Services, m *http.ServeMux) {
+	m.HandleFunc("/owid/api/versions", HandlerAPIVersions(s))
+	for v := apiVersionMin; v <= apiVersionLatest; v++ {
+		for _, r := range apiRoutes {
+			if v < r.since || (r.debug && s.Config().Debug == false) {
+				continue
+			}
+			m.HandleFunc(apiPath(v, r.name), r.handler(s))
+		}
+	}
+}
+
+// HandlerAPIVersions returns the versions of the API supported.
+func HandlerAPIVersions(s *Services) http.HandlerFunc {
+	return handlerCors(s, func(w http.ResponseWriter, r *http.Request) {
+		var a APIVersions
+		for v := apiVersionMin; v <= apiVersionLatest; v++ {
+			a.Versions = append(a.Versions, v)
+		}
+		a.Latest = apiVersionLatest
+		j, err := json.Marshal(&a)
+		if err != nil {
+			returnAPIError(s, w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=3600")
+		sendResponse(s, w, r, "application/json; charset=utf-8", j)
+	})
+}
@@ -0,0 +1,143 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestAPIVersions checks end points are only mounted from the version they
+// were introduced in and that the supported versions are advertised.
+func TestAPIVersions(t *testing.T) {
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := http.NewServeMux()
+	addAPIHandlers(s, m)
+	get := func(p string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, p, nil)
+		r.Host = testDomain
+		rr := httptest.NewRecorder()
+		m.ServeHTTP(rr, r)
+		return rr
+	}
+	for _, n := range []string{"jwks", "register"} {
+		if rr := get(apiPath(1, n)); rr.Code != http.StatusNotFound {
+			t.Errorf("v1 %s returned '%d'", n, rr.Code)
+		}
+		if rr := get(apiPath(2, n)); rr.Code == http.StatusNotFound {
+			t.Errorf("v2 %s not mounted", n)
+		}
+	}
+	o, err := newOWID(s.store.GetCreators()[testDomain])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr := get(apiPath(1, "decode") + "?owid=" +
+		url.QueryEscape(o.AsString())); rr.Code != http.StatusOK {
+		t.Errorf("v1 decode returned '%d'", rr.Code)
+	}
+	rr := get("/owid/api/versions")
+	var a APIVersions
+	err = json.Unmarshal(rr.Body.Bytes(), &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Latest != apiVersionLatest || len(a.Versions) != apiVersionLatest {
+		t.Errorf("versions '%v' latest '%d' not expected", a.Versions, a.Latest)
+	}
+	rr = get(apiPath(2, "jwks"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("v2 jwks returned '%d'", rr.Code)
+	}
+	var k JWKSet
+	err = json.Unmarshal(rr.Body.Bytes(), &k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k.Keys) != 1 || k.Keys[0].Crv != "P-256" || k.Keys[0].Kid == "" {
+		t.Fatal("JWKS does not contain the P-256 key")
+	}
+	c, err := s.GetCreator(testDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := c.NewCryptoVerifyOnly()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     jwkInt(t, k.Keys[0].X),
+		Y:     jwkInt(t, k.Keys[0].Y)}
+	if p.Equal(x.publicKey) == false {
+		t.Fatal("JWK does not match the public key")
+	}
+}
+
+func jwkInt(t *testing.T, s string) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// TestPublicKeyVersionFallback verifies an OWID from a domain that only
+// supports the first version of the public key end point.
+func TestPublicKeyVersionFallback(t *testing.T) {
+	m := http.NewServeMux()
+	h := httptest.NewServer(m)
+	defer h.Close()
+	u, err := url.Parse(h.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestCreator(u.Host, testOrgName, registerContractURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.store.setCreator(c)
+	m.HandleFunc(apiPath(1, "public-key"), HandlerPublicKey(s))
+	o, err := c.CreateOWIDandSign([]byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewVerifier(u.Scheme, nil).VerifyWithReport(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Valid == false {
+		t.Fatal("OWID should verify using the first version")
+	}
+	if r.KeySource != h.URL+apiPath(1, "public-key")+"?format=pkcs" {
+		t.Fatalf("key source '%s' not expected", r.KeySource)
+	}
+}
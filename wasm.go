@@ -0,0 +1,105 @@
+//go:build js && wasm
+
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"errors"
+	"syscall/js"
+	"time"
+)
+
+// RegisterJS adds owidVerify(owidB64, dataB64, spkiPem) and owidDecode(owidB64)
+// to the JavaScript global object so browser extensions and edge workers can
+// use the same verification as the Go package. Failures are returned as
+// JavaScript Error values rather than thrown.
+func RegisterJS() {
+	js.Global().Set("owidVerify", js.FuncOf(jsVerify))
+	js.Global().Set("owidDecode", js.FuncOf(jsDecode))
+}
+
+// jsVerify returns true if the base 64 OWID was signed with the PEM public key.
+// The data is the base 64 OWID, or array of OWIDs, signed with the OWID and can
+// be empty, null or undefined if there are none.
+func jsVerify(this js.Value, args []js.Value) any {
+	if len(args) != 3 {
+		return jsError(errors.New("owidVerify expects owid, data and key"))
+	}
+	o, err := FromBase64(args[0].String())
+	if err != nil {
+		return jsError(err)
+	}
+	others, err := jsOWIDs(args[1])
+	if err != nil {
+		return jsError(err)
+	}
+	v, err := o.VerifyWithPublicKey(args[2].String(), others...)
+	if err != nil {
+		return jsError(err)
+	}
+	return v
+}
+
+// jsDecode returns an object with the fields of the base 64 OWID without
+// verifying it.
+func jsDecode(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return jsError(errors.New("owidDecode expects owid"))
+	}
+	o, err := FromBase64(args[0].String())
+	if err != nil {
+		return jsError(err)
+	}
+	j, err := json.Marshal(newDecodeResponse(o, time.Now().UTC()))
+	if err != nil {
+		return jsError(err)
+	}
+	return js.Global().Get("JSON").Call("parse", string(j))
+}
+
+// jsOWIDs returns the OWIDs in the string or array of base 64 strings.
+func jsOWIDs(v js.Value) ([]*OWID, error) {
+	var s []string
+	switch v.Type() {
+	case js.TypeUndefined, js.TypeNull:
+	case js.TypeString:
+		if v.String() != "" {
+			s = append(s, v.String())
+		}
+	case js.TypeObject:
+		for i := 0; i < v.Length(); i++ {
+			s = append(s, v.Index(i).String())
+		}
+	default:
+		return nil, errors.New("data must be a string or array of strings")
+	}
+	var r []*OWID
+	for _, b := range s {
+		o, err := FromBase64(b)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, o)
+	}
+	return r, nil
+}
+
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(err.Error())
+}
@@ -0,0 +1,122 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// webhookSignatureHeader is the HTTP header containing the hex encoded HMAC
+// SHA-256 of the body using the webhook's shared secret.
+const webhookSignatureHeader = "Owid-Signature"
+
+// WebhookEvent is the body posted to each webhook when a creator changes.
+// Receivers should invalidate any cached public keys for the domain.
+type WebhookEvent struct {
+	Operation AuditOperation `json:"operation"`        // The operation performed
+	Domain    string         `json:"domain"`           // Domain of the creator
+	Timestamp time.Time      `json:"timestamp"`        // When the operation completed
+	Creator   *PublicCreator `json:"creator"`          // State after, or nil if none
+	Reason    string         `json:"reason,omitempty"` // Why a creator was quarantined
+}
+
+// VerifyWebhookSignature returns true if the signature from the
+// Owid-Signature header is valid for the body and shared secret.
+func VerifyWebhookSignature(body []byte, signature string, secret string) bool {
+	s, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(s, webhookMAC(body, secret))
+}
+
+// notify posts the event to all the configured webhooks, publishes the
+// creator's public information and writes a snapshot in the background.
+// Failures are logged.
+func (s *Services) notify(o AuditOperation, c *Creator) {
+	s.publish(c)
+	s.writeSnapshot()
+	s.postWebhooks(&WebhookEvent{
+		Operation: o,
+		Domain:    c.domain,
+		Timestamp: s.now().UTC(),
+		Creator:   auditCreator(c)})
+}
+
+// postWebhooks posts the event to all the configured webhooks in the
+// background. Failures are logged.
+func (s *Services) postWebhooks(e *WebhookEvent) {
+	ws := s.Config().Webhooks
+	if len(ws) == 0 {
+		return
+	}
+	j, err := json.Marshal(e)
+	if err != nil {
+		log.Printf(
+			"webhook '%s' for '%s' failed: %s",
+			e.Operation,
+			e.Domain,
+			err.Error())
+		return
+	}
+	for _, w := range ws {
+		go func(w Webhook) {
+			err := postWebhook(&w, j)
+			if err != nil {
+				log.Printf(
+					"webhook '%s' for '%s' failed: %s",
+					w.URL,
+					e.Domain,
+					err.Error())
+			}
+		}(w)
+	}
+}
+
+func postWebhook(w *Webhook, body []byte) error {
+	r, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(
+		webhookSignatureHeader,
+		hex.EncodeToString(webhookMAC(body, w.Secret)))
+	p, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer p.Body.Close()
+	if p.StatusCode < 200 || p.StatusCode > 299 {
+		return fmt.Errorf("return code '%d'", p.StatusCode)
+	}
+	return nil
+}
+
+func webhookMAC(body []byte, secret string) []byte {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(body)
+	return m.Sum(nil)
+}
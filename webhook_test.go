@@ -0,0 +1,78 @@
+/* ****************************************************************************
+ * Copyright 2021 51 Degrees Mobile Experts Limited (51degrees.com)
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not
+ * use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+ * License for the specific language governing permissions and limitations
+ * under the License.
+ * ***************************************************************************/
+
+package owid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWebhookNotify registers a creator and checks that the configured webhook
+// receives a signed notification.
+func TestWebhookNotify(t *testing.T) {
+	type received struct {
+		body      []byte
+		signature string
+	}
+	c := make(chan received, 1)
+	h := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			c <- received{b, r.Header.Get(webhookSignatureHeader)}
+		}))
+	defer h.Close()
+	s, err := getServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := *s.Config()
+	n.Webhooks = []Webhook{{URL: h.URL, Secret: "secret"}}
+	s.SetConfig(n)
+	d := Register{
+		Services:    s,
+		Domain:      "webhook." + testDomain,
+		Name:        testOrgName,
+		ContractURL: registerContractURL}
+	err = storeCreator(s, &d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r received
+	select {
+	case r = <-c:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+	if VerifyWebhookSignature(r.body, r.signature, "secret") == false {
+		t.Fatal("webhook signature not valid")
+	}
+	if VerifyWebhookSignature(r.body, r.signature, "other") {
+		t.Fatal("webhook signature valid for wrong secret")
+	}
+	var e WebhookEvent
+	err = json.Unmarshal(r.body, &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Operation != AuditRegister || e.Domain != d.Domain {
+		t.Fatalf("unexpected event '%s' for '%s'", e.Operation, e.Domain)
+	}
+}